@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 
 	"github.com/jopari/preptoplate/internal/config"
 	"github.com/jopari/preptoplate/internal/database"
@@ -22,9 +23,9 @@ func main() {
 	}
 	defer db.Close()
 
-	// Default admin credentials
-	adminEmail := "admin@preptoplate.com"
-	adminPassword := "admin123" // Change this in production!
+	// First super admin; further staff are added through /api/admin/users
+	adminEmail := getEnv("SEED_ADMIN_EMAIL", "admin@preptoplate.com")
+	adminPassword := getEnv("SEED_ADMIN_PASSWORD", "admin123") // Change this in production!
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(adminPassword), bcrypt.DefaultCost)
@@ -47,12 +48,12 @@ func main() {
 
 	// Insert admin user
 	insertQuery := `
-		INSERT INTO users (email, password_hash, role, created_at) 
-		VALUES ($1, $2, $3, NOW())
+		INSERT INTO users (email, password_hash, role, created_at, email_verified_at) 
+		VALUES ($1, $2, $3, NOW(), NOW())
 		RETURNING id
 	`
 	var adminID int
-	err = db.QueryRow(context.Background(), insertQuery, adminEmail, string(hashedPassword), "admin").Scan(&adminID)
+	err = db.QueryRow(context.Background(), insertQuery, adminEmail, string(hashedPassword), "super_admin").Scan(&adminID)
 	if err != nil {
 		log.Fatalf("Failed to create admin user: %v", err)
 	}
@@ -62,4 +63,12 @@ func main() {
 	log.Printf("   Password: %s", adminPassword)
 	log.Printf("   ID: %d", adminID)
 	log.Println("\n⚠️  IMPORTANT: Change the admin password after first login!")
+	log.Println("⚠️  Set up two-factor authentication at /api/me/2fa before using staff endpoints (REQUIRE_ADMIN_2FA).")
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
 }
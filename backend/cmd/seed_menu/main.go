@@ -67,8 +67,8 @@ func main() {
 	}
 
 	addMealQuery := `
-		INSERT INTO menu_meals (menu_id, meal_id, initial_stock, available_stock) 
-		VALUES ($1, $2, $3, $3)
+		INSERT INTO menu_meals (menu_id, meal_id, initial_stock, available_stock, meal_version) 
+		VALUES ($1, $2, $3, $3, (SELECT current_version FROM meals WHERE id = $2))
 	`
 
 	for _, meal := range meals {
@@ -20,11 +20,15 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"github.com/jopari/preptoplate/internal/api"
 	"github.com/jopari/preptoplate/internal/config"
 	"github.com/jopari/preptoplate/internal/database"
+	"github.com/jopari/preptoplate/internal/repository"
+	"github.com/jopari/preptoplate/internal/service"
 )
 
 func main() {
@@ -36,6 +40,45 @@ func main() {
 	}
 	defer dbPool.Close()
 
+	// Recompute meal recommendations off the request path
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	recommendations := service.NewRecommendationService(
+		repository.NewRecommendationRepository(dbPool),
+		repository.NewWeeklyMenuRepository(dbPool),
+	)
+	go recommendations.Run(ctx, cfg.RecommendationInterval)
+
+	// Delete uploads that never got used
+	imageService, err := service.NewImageService(cfg)
+	if err != nil {
+		log.Fatalf("Could not set up image storage: %v", err)
+	}
+	mealImages := service.NewMealImageService(
+		repository.NewMealImageRepository(dbPool),
+		repository.NewUploadRepository(dbPool),
+		repository.NewMealRepository(dbPool),
+		imageService,
+	)
+	go mealImages.Run(ctx, cfg.UploadCleanupInterval)
+
+	// Delete expired "download my data" exports, sessions and login throttles
+	accounts := service.NewAccountService(
+		repository.NewUserRepository(dbPool),
+		repository.NewDataExportRepository(dbPool),
+		repository.NewOrderRepository(dbPool),
+		repository.NewCartRepository(dbPool),
+		repository.NewReviewRepository(dbPool),
+		repository.NewFavouriteRepository(dbPool),
+		repository.NewNutritionGoalsRepository(dbPool),
+		repository.NewSessionRepository(dbPool),
+		repository.NewLoginThrottleRepository(dbPool),
+		repository.NewAccountDeletionRepository(dbPool),
+		service.NewEmailService(cfg),
+		cfg,
+	)
+	go accounts.Run(ctx, time.Hour)
+
 	r := api.SetupRouter(dbPool, cfg)
 
 	log.Printf("Server starting on port %s", cfg.Port)
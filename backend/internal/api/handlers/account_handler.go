@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type AccountHandler struct {
+	service service.AccountService
+}
+
+func NewAccountHandler(service service.AccountService) *AccountHandler {
+	return &AccountHandler{service: service}
+}
+
+// @Summary      Request data export
+// @Description  Start preparing a copy of everything held about the authenticated user: profile, orders, cart, reviews, favourites and nutrition goals. The user is emailed when it is ready to download.
+// @Tags         account
+// @Produce      json
+// @Success      202  {object}  models.DataExport
+// @Failure      401  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/data-exports [post]
+func (h *AccountHandler) RequestDataExport(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	export, err := h.service.RequestDataExport(c.Request.Context(), userID.(int))
+	if err != nil {
+		if err.Error() == "a data export is already being prepared" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, export)
+}
+
+// @Summary      List data exports
+// @Description  List the authenticated user's data exports, newest first
+// @Tags         account
+// @Produce      json
+// @Success      200  {array}   models.DataExport
+// @Failure      401  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/data-exports [get]
+func (h *AccountHandler) ListDataExports(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	exports, err := h.service.ListDataExports(c.Request.Context(), userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, exports)
+}
+
+// @Summary      Download data export
+// @Description  Download a finished data export, as a ZIP archive with one JSON file per section or as a single JSON document
+// @Tags         account
+// @Produce      application/zip,json
+// @Param        id      path      int     true   "Data export ID"
+// @Param        format  query     string  false  "File format"  Enums(zip, json)  default(zip)
+// @Success      200     {object}  models.AccountData
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Failure      404     {object}  map[string]string
+// @Failure      409     {object}  map[string]string
+// @Failure      410     {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/data-exports/{id}/download [get]
+func (h *AccountHandler) DownloadDataExport(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid data export id"})
+		return
+	}
+	format := c.DefaultQuery("format", "zip")
+	if format != "zip" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, use zip or json"})
+		return
+	}
+
+	document, err := h.service.GetDataExport(c.Request.Context(), userID.(int), id)
+	if err != nil {
+		switch err.Error() {
+		case "data export not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "data export is not ready yet", "data export failed, please request a new one":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case "data export has expired":
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	filename := "preptoplate-data-" + strconv.Itoa(id) + "." + format
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	if format == "json" {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", document)
+		return
+	}
+
+	var archive bytes.Buffer
+	if err := service.WriteDataExportZIP(&archive, document); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+// @Summary      Request account deletion link
+// @Description  Email the authenticated user a link that confirms deleting their account, for users with no password to confirm it with, such as those who only sign in with an external provider. The token from the link is sent to DELETE /me.
+// @Tags         account
+// @Produce      json
+// @Success      202  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/delete-confirmation [post]
+func (h *AccountHandler) RequestAccountDeletion(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	err := h.service.RequestAccountDeletion(c.Request.Context(), userID.(int))
+	if err != nil {
+		writeDeleteAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "check your email to confirm deleting your account"})
+}
+
+// @Summary      Delete account
+// @Description  Permanently delete the authenticated user's account after confirming their password, or with the token from the link sent by POST /me/delete-confirmation. Personal details, cart, reviews, favourites and saved settings are removed; orders are kept for accounting without personal details. Orders still to be delivered must be delivered or cancelled first.
+// @Tags         account
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.DeleteAccountRequest  true  "Current password or emailed token"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me [delete]
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.service.DeleteAccount(c.Request.Context(), userID.(int), &req)
+	if err != nil {
+		writeDeleteAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "account deleted"})
+}
+
+// writeDeleteAccountError responds to a failed account deletion, or a
+// failed request for a deletion link.
+func writeDeleteAccountError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "password is incorrect", "invalid or expired confirmation link":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "staff accounts cannot be deleted, ask an administrator to change your role first":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "you have orders that have not been delivered yet":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
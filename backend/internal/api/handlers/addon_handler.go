@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type AddonHandler struct {
+	service service.AddonService
+}
+
+func NewAddonHandler(service service.AddonService) *AddonHandler {
+	return &AddonHandler{service: service}
+}
+
+// @Summary      List add-ons
+// @Description  Get the catalog of add-ons (snacks, drinks, sauces)
+// @Tags         addons
+// @Produce      json
+// @Param        category  query     string  false  "Filter by category (snack, drink, sauce)"
+// @Success      200       {array}   models.Addon
+// @Failure      500       {object}  map[string]string
+// @Router       /addons [get]
+func (h *AddonHandler) List(c *gin.Context) {
+	addons, err := h.service.GetAll(c.Request.Context(), c.Query("category"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, addons)
+}
+
+// @Summary      Get add-on by ID
+// @Description  Get a specific add-on by its ID
+// @Tags         addons
+// @Produce      json
+// @Param        id   path      int  true  "Add-on ID"
+// @Success      200  {object}  models.Addon
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /addons/{id} [get]
+func (h *AddonHandler) GetByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid add-on id"})
+		return
+	}
+
+	addon, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, addon)
+}
+
+// @Summary      Create add-on
+// @Description  Admin only - Create a new add-on
+// @Tags         addons,admin
+// @Accept       json
+// @Produce      json
+// @Param        addon  body      models.CreateAddonRequest  true  "Add-on data"
+// @Success      201    {object}  models.Addon
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /addons [post]
+func (h *AddonHandler) Create(c *gin.Context) {
+	var req models.CreateAddonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	addon, err := h.service.Create(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, addon)
+}
+
+// @Summary      Update add-on
+// @Description  Admin only - Update an existing add-on
+// @Tags         addons,admin
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                        true  "Add-on ID"
+// @Param        addon  body      models.UpdateAddonRequest  true  "Updated add-on data"
+// @Success      200    {object}  models.Addon
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /addons/{id} [put]
+func (h *AddonHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid add-on id"})
+		return
+	}
+
+	var req models.UpdateAddonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	addon, err := h.service.Update(c.Request.Context(), id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, addon)
+}
+
+// @Summary      Delete add-on
+// @Description  Admin only - Delete an add-on (not allowed while used in a menu or once ordered). It is removed from customers' carts.
+// @Tags         addons,admin
+// @Param        id   path      int  true  "Add-on ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /addons/{id} [delete]
+func (h *AddonHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid add-on id"})
+		return
+	}
+
+	err = h.service.Delete(c.Request.Context(), id)
+	if err != nil {
+		switch err.Error() {
+		case "add-on not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "cannot delete add-on: it is currently used in one or more weekly menus",
+			"cannot delete add-on: it is part of past orders":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "add-on deleted successfully"})
+}
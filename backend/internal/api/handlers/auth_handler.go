@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jopari/preptoplate/internal/models"
@@ -42,7 +44,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 }
 
 // @Summary      User login
-// @Description  Authenticate user and get JWT token
+// @Description  Authenticate user and get JWT token. Accounts with two-factor authentication get two_factor_required and a two_factor_token to complete at /auth/login/2fa instead.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -50,6 +52,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Success      200   {object}  models.AuthResponse
 // @Failure      400   {object}  map[string]string
 // @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      429   {object}  map[string]string
 // @Router       /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
@@ -58,7 +62,74 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	res, err := h.service.Login(c.Request.Context(), &req)
+	res, err := h.service.Login(c.Request.Context(), &req, c.ClientIP())
+	if err != nil {
+		switch err.Error() {
+		case "too many failed login attempts, try again later",
+			"account is temporarily locked, try again later or reset your password":
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case "account has been disabled",
+			"password reset required, check your email for a reset link":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary      Complete two-factor login
+// @Description  Finish a login with the two_factor_token from /auth/login and a code from the authenticator app or a recovery code
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.TwoFactorLoginRequest  true  "Two-factor token and code"
+// @Success      200      {object}  models.AuthResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      429      {object}  map[string]string
+// @Router       /auth/login/2fa [post]
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.service.CompleteTwoFactorLogin(c.Request.Context(), &req)
+	if err != nil {
+		switch err.Error() {
+		case "too many failed two-factor attempts, try again later":
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary      Refresh access token
+// @Description  Exchange a refresh token for a new access token and refresh token. Each refresh token can be used once; reusing one revokes the session.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        token  body      models.RefreshRequest  true  "Refresh token"
+// @Success      200    {object}  models.AuthResponse
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Router       /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.service.Refresh(c.Request.Context(), req.RefreshToken)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -66,3 +137,291 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	c.JSON(http.StatusOK, res)
 }
+
+// @Summary      Logout
+// @Description  Revoke the current session, or every session of the user with all=true. Its access and refresh tokens stop working immediately.
+// @Tags         auth
+// @Produce      json
+// @Param        all  query     bool  false  "Log out of all devices"
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+	all := c.Query("all") == "true"
+
+	if err := h.service.Logout(c.Request.Context(), userID.(int), sessionID.(int), all); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// @Summary      Forgot password
+// @Description  Email a single-use password reset link to the account, if one exists. The response is the same either way.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.ForgotPasswordRequest  true  "Account email"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "if an account exists for this email, a reset link has been sent"})
+}
+
+// @Summary      Reset password
+// @Description  Set a new password with the token from a reset email. Signs the user out of every session.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.ResetPasswordRequest  true  "Reset token and new password"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Router       /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.ResetPassword(c.Request.Context(), &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
+
+// @Summary      Change password
+// @Description  Change the password of the authenticated user. Other sessions are signed out; this one stays signed in.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.ChangePasswordRequest  true  "Current and new password"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/password [put]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.service.ChangePassword(c.Request.Context(), userID.(int), sessionID.(int), &req)
+	if err != nil {
+		if err.Error() == "current password is incorrect" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed"})
+}
+
+// @Summary      Change email
+// @Description  Start changing the authenticated user's email address. A confirmation link is sent to the new address, and the email changes once it is followed.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.ChangeEmailRequest  true  "New email and current password"
+// @Success      202      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      429      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/email [post]
+func (h *AuthHandler) ChangeEmail(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.service.RequestEmailChange(c.Request.Context(), userID.(int), &req)
+	if err != nil {
+		switch err.Error() {
+		case "too many verification emails, try again later":
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case "password is incorrect", "that is already your email address", "email already in use":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "check your new email address for a confirmation link"})
+}
+
+// @Summary      Verify email
+// @Description  Verify the account's email address, or confirm a change of email address, with the token from the emailed link
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.VerifyEmailRequest  true  "Verification token"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Router       /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+// @Summary      Resend verification email
+// @Description  Send the authenticated user a new verification link. Limited to one a minute and five a day.
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /auth/resend-verification [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	err := h.service.ResendVerification(c.Request.Context(), userID.(int))
+	if err != nil {
+		switch err.Error() {
+		case "too many verification emails, try again later":
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case "email is already verified":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "verification email sent"})
+}
+
+// @Summary      Verify user email
+// @Description  Admin only - Mark a user's email as verified without the emailed link
+// @Tags         admin,auth
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/verify-email [put]
+func (h *AuthHandler) MarkEmailVerified(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.service.MarkEmailVerified(c.Request.Context(), id); err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email marked as verified"})
+}
+
+// @Summary      Unlock user login
+// @Description  Admin only - Lift a user's lockout after too many failed logins
+// @Tags         admin,auth
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/unlock [post]
+func (h *AuthHandler) UnlockAccount(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.service.UnlockAccount(c.Request.Context(), id); err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "account unlocked"})
+}
+
+// @Summary      Unlock IP address
+// @Description  Admin only - Lift a client IP's lockout after too many failed logins
+// @Tags         admin,auth
+// @Produce      json
+// @Param        ip   path      string  true  "IP address"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/ips/{ip}/unlock [post]
+func (h *AuthHandler) UnlockIP(c *gin.Context) {
+	ip := net.ParseIP(c.Param("ip"))
+	if ip == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ip address"})
+		return
+	}
+
+	if err := h.service.UnlockIP(c.Request.Context(), ip.String()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ip address unlocked"})
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type BundleHandler struct {
+	service service.BundleService
+}
+
+func NewBundleHandler(service service.BundleService) *BundleHandler {
+	return &BundleHandler{service: service}
+}
+
+// @Summary      List this week's bundles
+// @Description  Get the bundles on sale with the active weekly menu, with their discount and how many are left. Signed-in subscribers see the regular price and discount against their plan's prices.
+// @Tags         bundles
+// @Produce      json
+// @Success      200  {array}   models.Bundle
+// @Failure      500  {object}  map[string]string
+// @Router       /bundles [get]
+func (h *BundleHandler) ListActive(c *gin.Context) {
+	// Signed in is optional here; 0 means anonymous
+	userID := 0
+	if id, exists := c.Get("user_id"); exists {
+		userID = id.(int)
+	}
+
+	bundles, err := h.service.GetActive(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, bundles)
+}
+
+// @Summary      List bundles
+// @Description  Admin only - Get all bundles, optionally for one weekly menu
+// @Tags         bundles,admin
+// @Produce      json
+// @Param        menu_id  query     int  false  "Filter by weekly menu ID"
+// @Success      200      {array}   models.Bundle
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/bundles [get]
+func (h *BundleHandler) List(c *gin.Context) {
+	menuID := 0
+	if raw := c.Query("menu_id"); raw != "" {
+		var err error
+		menuID, err = strconv.Atoi(raw)
+		if err != nil || menuID < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid menu id"})
+			return
+		}
+	}
+
+	bundles, err := h.service.GetAll(c.Request.Context(), menuID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, bundles)
+}
+
+// @Summary      Get bundle by ID
+// @Description  Admin only - Get a specific bundle by its ID
+// @Tags         bundles,admin
+// @Produce      json
+// @Param        id   path      int  true  "Bundle ID"
+// @Success      200  {object}  models.Bundle
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/bundles/{id} [get]
+func (h *BundleHandler) GetByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bundle id"})
+		return
+	}
+
+	bundle, err := h.service.GetByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+// @Summary      Create bundle
+// @Description  Admin only - Create a bundle of meals from a weekly menu, sold at a bundle price
+// @Tags         bundles,admin
+// @Accept       json
+// @Produce      json
+// @Param        bundle  body      models.CreateBundleRequest  true  "Bundle data"
+// @Success      201     {object}  models.Bundle
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/bundles [post]
+func (h *BundleHandler) Create(c *gin.Context) {
+	var req models.CreateBundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bundle, err := h.service.Create(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, bundle)
+}
+
+// @Summary      Update bundle
+// @Description  Admin only - Update a bundle; items, when given, replace the current ones
+// @Tags         bundles,admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                         true  "Bundle ID"
+// @Param        bundle  body      models.UpdateBundleRequest  true  "Updated bundle data"
+// @Success      200     {object}  models.Bundle
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/bundles/{id} [put]
+func (h *BundleHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bundle id"})
+		return
+	}
+
+	var req models.UpdateBundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bundle, err := h.service.Update(c.Request.Context(), id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+// @Summary      Delete bundle
+// @Description  Admin only - Delete a bundle (not allowed once it has been ordered; deactivate it instead)
+// @Tags         bundles,admin
+// @Param        id   path      int  true  "Bundle ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/bundles/{id} [delete]
+func (h *BundleHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bundle id"})
+		return
+	}
+
+	err = h.service.Delete(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "bundle deleted successfully"})
+}
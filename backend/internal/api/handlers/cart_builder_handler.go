@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type CartBuilderHandler struct {
+	service service.CartBuilderService
+}
+
+func NewCartBuilderHandler(service service.CartBuilderService) *CartBuilderHandler {
+	return &CartBuilderHandler{service: service}
+}
+
+// @Summary      Propose a cart
+// @Description  Propose a full cart from the active menu that best meets the user's macro targets within dietary restrictions, stock and budget. The same request and seed always give the same proposal.
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.BuildCartRequest  true  "Targets, restrictions and budget"
+// @Success      200      {object}  models.CartProposal
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /cart/build [post]
+func (h *CartBuilderHandler) BuildCart(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.BuildCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	proposal, err := h.service.BuildCart(c.Request.Context(), userID.(int), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, proposal)
+}
@@ -18,7 +18,7 @@ func NewCartHandler(service service.CartService) *CartHandler {
 }
 
 // @Summary      Get user's cart
-// @Description  Retrieve the authenticated user's cart with all items
+// @Description  Retrieve the authenticated user's cart with all items, at the prices on their plan
 // @Tags         cart
 // @Produce      json
 // @Success      200  {object}  models.Cart
@@ -156,3 +156,197 @@ func (h *CartHandler) ClearCart(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "cart cleared"})
 }
+
+// @Summary      Add add-on to cart
+// @Description  Add an extra (snack, drink, sauce) to the cart; does not count toward the meal limit
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Param        addon  body      models.AddAddonToCartRequest  true  "Add-on to add"
+// @Success      200    {object}  models.Cart
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /cart/addons [post]
+func (h *CartHandler) AddAddon(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.AddAddonToCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := h.service.AddAddon(c.Request.Context(), userID.(int), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// @Summary      Update cart add-on quantity
+// @Description  Update the quantity of an add-on in the cart (0 = remove)
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                           true  "Cart Add-on ID"
+// @Param        addon  body      models.UpdateCartItemRequest  true  "New quantity"
+// @Success      200    {object}  models.Cart
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /cart/addons/{id} [put]
+func (h *CartHandler) UpdateAddon(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	cartAddonID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid add-on id"})
+		return
+	}
+
+	var req models.UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := h.service.UpdateAddon(c.Request.Context(), userID.(int), cartAddonID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Quantity == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "add-on removed from cart"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// @Summary      Remove add-on from cart
+// @Description  Remove a specific add-on from the cart
+// @Tags         cart
+// @Param        id   path      int  true  "Cart Add-on ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /cart/addons/{id} [delete]
+func (h *CartHandler) RemoveAddon(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	cartAddonID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid add-on id"})
+		return
+	}
+
+	err = h.service.RemoveAddon(c.Request.Context(), userID.(int), cartAddonID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "add-on removed from cart"})
+}
+
+// @Summary      Add bundle to cart
+// @Description  Add a bundle of meals from this week's menu to the cart at the bundle price; its meals count toward the meal limit
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Param        bundle  body      models.AddBundleToCartRequest  true  "Bundle to add"
+// @Success      200     {object}  models.Cart
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /cart/bundles [post]
+func (h *CartHandler) AddBundle(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.AddBundleToCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := h.service.AddBundle(c.Request.Context(), userID.(int), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// @Summary      Update cart bundle quantity
+// @Description  Update the quantity of a bundle in the cart (0 = remove)
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                           true  "Cart Bundle ID"
+// @Param        bundle  body      models.UpdateCartItemRequest  true  "New quantity"
+// @Success      200     {object}  models.Cart
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /cart/bundles/{id} [put]
+func (h *CartHandler) UpdateBundle(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	cartBundleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bundle id"})
+		return
+	}
+
+	var req models.UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := h.service.UpdateBundle(c.Request.Context(), userID.(int), cartBundleID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Quantity == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "bundle removed from cart"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// @Summary      Remove bundle from cart
+// @Description  Remove a specific bundle from the cart
+// @Tags         cart
+// @Param        id   path      int  true  "Cart Bundle ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /cart/bundles/{id} [delete]
+func (h *CartHandler) RemoveBundle(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	cartBundleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bundle id"})
+		return
+	}
+
+	err = h.service.RemoveBundle(c.Request.Context(), userID.(int), cartBundleID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "bundle removed from cart"})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type FavouriteHandler struct {
+	service service.FavouriteService
+}
+
+func NewFavouriteHandler(service service.FavouriteService) *FavouriteHandler {
+	return &FavouriteHandler{service: service}
+}
+
+// @Summary      List favourite meals
+// @Description  Get the authenticated user's favourite meals, most recent first, at the prices on their plan
+// @Tags         favourites
+// @Produce      json
+// @Success      200  {array}   models.Favourite
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /favourites [get]
+func (h *FavouriteHandler) List(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	favourites, err := h.service.GetAll(c.Request.Context(), userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, favourites)
+}
+
+// @Summary      Add favourite meal
+// @Description  Mark a meal as a favourite
+// @Tags         favourites
+// @Accept       json
+// @Produce      json
+// @Param        favourite  body      models.AddFavouriteRequest  true  "Meal to favourite"
+// @Success      201        {object}  map[string]string
+// @Failure      400        {object}  map[string]string
+// @Failure      401        {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /favourites [post]
+func (h *FavouriteHandler) Add(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.AddFavouriteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.Add(c.Request.Context(), userID.(int), &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "meal added to favourites"})
+}
+
+// @Summary      Remove favourite meal
+// @Description  Remove a meal from the user's favourites
+// @Tags         favourites
+// @Param        mealId  path      int  true  "Meal ID"
+// @Success      200     {object}  map[string]string
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /favourites/{mealId} [delete]
+func (h *FavouriteHandler) Remove(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	mealID, err := strconv.Atoi(c.Param("mealId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	if err := h.service.Remove(c.Request.Context(), userID.(int), mealID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "meal removed from favourites"})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type ImageHandler struct {
+	service service.ImageService
+}
+
+func NewImageHandler(service service.ImageService) *ImageHandler {
+	return &ImageHandler{service: service}
+}
+
+// @Summary      Get image
+// @Description  Serve an image kept in local storage. Images are immutable, so responses are cached for a year.
+// @Tags         upload
+// @Produce      image/jpeg,image/png,image/webp
+// @Param        key  path  string  true  "Storage key, e.g. meals/bowl-1700000000.jpg"
+// @Success      200
+// @Failure      404  {object}  map[string]string
+// @Router       /images/{key} [get]
+func (h *ImageHandler) Serve(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	f, err := h.service.OpenImage(key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Sniff the content type from the file rather than trusting its name
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", http.DetectContentType(head[:n]))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(c.Writer, c.Request, "", info.ModTime(), f)
+}
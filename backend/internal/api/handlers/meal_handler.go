@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jopari/preptoplate/internal/models"
@@ -18,14 +23,22 @@ func NewMealHandler(service service.MealService) *MealHandler {
 }
 
 // @Summary      List all meals
-// @Description  Get list of all available meals
+// @Description  Get list of all available meals at their standard prices; the menu, cart and favourites show a subscriber's plan prices. Names, descriptions and ingredients are in the language picked by lang or Accept-Language, falling back to the default language.
 // @Tags         meals
 // @Produce      json
-// @Success      200  {array}   models.Meal
-// @Failure      500  {object}  map[string]string
+// @Param        lang             query     string  false  "Preferred language, e.g. fr"
+// @Param        Accept-Language  header    string  false  "Preferred languages"
+// @Success      200              {array}   models.Meal
+// @Failure      400              {object}  map[string]string
+// @Failure      500              {object}  map[string]string
 // @Router       /meals [get]
 func (h *MealHandler) List(c *gin.Context) {
-	meals, err := h.service.GetAll(c.Request.Context())
+	languages, ok := requestLanguages(c)
+	if !ok {
+		return
+	}
+
+	meals, err := h.service.GetAll(c.Request.Context(), languages)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -35,13 +48,15 @@ func (h *MealHandler) List(c *gin.Context) {
 }
 
 // @Summary      Get meal by ID
-// @Description  Get a specific meal by its ID
+// @Description  Get a specific meal by its ID at its standard price, in the language picked by lang or Accept-Language
 // @Tags         meals
 // @Produce      json
-// @Param        id   path      int  true  "Meal ID"
-// @Success      200  {object}  models.Meal
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
+// @Param        id               path      int     true   "Meal ID"
+// @Param        lang             query     string  false  "Preferred language, e.g. fr"
+// @Param        Accept-Language  header    string  false  "Preferred languages"
+// @Success      200              {object}  models.Meal
+// @Failure      400              {object}  map[string]string
+// @Failure      404              {object}  map[string]string
 // @Router       /meals/{id} [get]
 func (h *MealHandler) GetByID(c *gin.Context) {
 	idParam := c.Param("id")
@@ -51,7 +66,12 @@ func (h *MealHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	meal, err := h.service.GetByID(c.Request.Context(), id)
+	languages, ok := requestLanguages(c)
+	if !ok {
+		return
+	}
+
+	meal, err := h.service.GetByID(c.Request.Context(), id, languages)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -78,13 +98,15 @@ func (h *MealHandler) GetByID(c *gin.Context) {
 // @Security     BearerAuth
 // @Router       /meals [post]
 func (h *MealHandler) Create(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
 	var req models.CreateMealRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	meal, err := h.service.Create(c.Request.Context(), &req)
+	meal, err := h.service.Create(c.Request.Context(), userID.(int), &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -94,7 +116,7 @@ func (h *MealHandler) Create(c *gin.Context) {
 }
 
 // @Summary      Update meal
-// @Description  Admin only - Update an existing meal
+// @Description  Admin only - Update an existing meal (records a new version)
 // @Tags         meals,admin
 // @Accept       json
 // @Produce      json
@@ -108,6 +130,8 @@ func (h *MealHandler) Create(c *gin.Context) {
 // @Security     BearerAuth
 // @Router       /meals/{id} [put]
 func (h *MealHandler) Update(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
@@ -121,7 +145,7 @@ func (h *MealHandler) Update(c *gin.Context) {
 		return
 	}
 
-	meal, err := h.service.Update(c.Request.Context(), id, &req)
+	meal, err := h.service.Update(c.Request.Context(), userID.(int), id, &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -156,3 +180,377 @@ func (h *MealHandler) Delete(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "meal deleted successfully"})
 }
+
+// @Summary      List meal versions
+// @Description  Admin only - Get the change history of a meal, newest first
+// @Tags         meals,admin
+// @Produce      json
+// @Param        id   path      int  true  "Meal ID"
+// @Success      200  {array}   models.MealVersion
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/versions [get]
+func (h *MealHandler) ListVersions(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	versions, err := h.service.GetVersions(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// @Summary      Get meal version
+// @Description  Admin only - Get a specific version of a meal
+// @Tags         meals,admin
+// @Produce      json
+// @Param        id       path      int  true  "Meal ID"
+// @Param        version  path      int  true  "Version number"
+// @Success      200      {object}  models.MealVersion
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/versions/{version} [get]
+func (h *MealHandler) GetVersion(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return
+	}
+
+	v, err := h.service.GetVersion(c.Request.Context(), id, version)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, v)
+}
+
+// @Summary      Diff meal versions
+// @Description  Admin only - List the fields that changed between two versions of a meal
+// @Tags         meals,admin
+// @Produce      json
+// @Param        id    path      int  true  "Meal ID"
+// @Param        from  query     int  true  "Base version"
+// @Param        to    query     int  true  "Compared version"
+// @Success      200   {object}  models.MealVersionDiff
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/diff [get]
+func (h *MealHandler) DiffVersions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from version"})
+		return
+	}
+
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to version"})
+		return
+	}
+
+	diff, err := h.service.DiffVersions(c.Request.Context(), id, from, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// @Summary      Revert meal to version
+// @Description  Admin only - Restore a previous version of a meal (recorded as a new version)
+// @Tags         meals,admin
+// @Produce      json
+// @Param        id       path      int  true  "Meal ID"
+// @Param        version  path      int  true  "Version number to restore"
+// @Success      200      {object}  models.Meal
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/versions/{version}/revert [post]
+func (h *MealHandler) RevertToVersion(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return
+	}
+
+	meal, err := h.service.RevertToVersion(c.Request.Context(), userID.(int), id, version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, meal)
+}
+
+// @Summary      Create meal variant
+// @Description  Admin only - Add a portion variant (e.g. Large) with its own price and nutrition
+// @Tags         meals,admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                              true  "Meal ID"
+// @Param        variant  body      models.CreateMealVariantRequest  true  "Variant data"
+// @Success      201      {object}  models.MealVariant
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/variants [post]
+func (h *MealHandler) CreateVariant(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	var req models.CreateMealVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variant, err := h.service.CreateVariant(c.Request.Context(), id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, variant)
+}
+
+// @Summary      Update meal variant
+// @Description  Admin only - Update a meal's portion variant
+// @Tags         meals,admin
+// @Accept       json
+// @Produce      json
+// @Param        id         path      int                              true  "Meal ID"
+// @Param        variantId  path      int                              true  "Variant ID"
+// @Param        variant    body      models.UpdateMealVariantRequest  true  "Updated variant data"
+// @Success      200        {object}  models.MealVariant
+// @Failure      400        {object}  map[string]string
+// @Failure      401        {object}  map[string]string
+// @Failure      403        {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/variants/{variantId} [put]
+func (h *MealHandler) UpdateVariant(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	variantID, err := strconv.Atoi(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant id"})
+		return
+	}
+
+	var req models.UpdateMealVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variant, err := h.service.UpdateVariant(c.Request.Context(), id, variantID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, variant)
+}
+
+// @Summary      Delete meal variant
+// @Description  Admin only - Delete a meal's portion variant (not allowed while used in a menu)
+// @Tags         meals,admin
+// @Param        id         path      int  true  "Meal ID"
+// @Param        variantId  path      int  true  "Variant ID"
+// @Success      200        {object}  map[string]string
+// @Failure      400        {object}  map[string]string
+// @Failure      401        {object}  map[string]string
+// @Failure      403        {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/variants/{variantId} [delete]
+func (h *MealHandler) DeleteVariant(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	variantID, err := strconv.Atoi(c.Param("variantId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid variant id"})
+		return
+	}
+
+	err = h.service.DeleteVariant(c.Request.Context(), id, variantID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "variant deleted successfully"})
+}
+
+// @Summary      Import meals
+// @Description  Admin only - Create or update meals by SKU from a CSV or JSON file, sent as the request body or as a multipart "file" of up to 5 MB. Nothing is saved if any row is invalid or any meal fails to save; the report lists row-level errors. Use dry_run to validate without saving.
+// @Tags         meals,admin
+// @Accept       text/csv,json,mpfd
+// @Produce      json
+// @Param        format   query     string  false  "File format, detected from the content type or file name when omitted"  Enums(csv, json)
+// @Param        dry_run  query     bool    false  "Validate and report without saving"
+// @Success      200      {object}  models.MealImportReport
+// @Failure      400      {object}  models.MealImportReport
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      413      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/meals/import [post]
+func (h *MealHandler) Import(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run value"})
+			return
+		}
+		dryRun = parsed
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxImportBytes)
+	var tooLarge *http.MaxBytesError
+
+	format := c.Query("format")
+	var body io.Reader
+	file, err := c.FormFile("file")
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrImportTooLarge.Error()})
+		return
+	}
+	if err == nil {
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to open file"})
+			return
+		}
+		defer f.Close()
+		body = f
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+		}
+	} else {
+		// Read the whole body up front so an oversized file is refused
+		// before any of it is parsed
+		data, err := io.ReadAll(c.Request.Body)
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrImportTooLarge.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		body = bytes.NewReader(data)
+
+		if format == "" {
+			switch c.ContentType() {
+			case "text/csv":
+				format = "csv"
+			case "application/json":
+				format = "json"
+			}
+		}
+	}
+
+	report, err := h.service.Import(c.Request.Context(), userID.(int), format, body, dryRun)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(report.Errors) > 0 {
+		c.JSON(http.StatusBadRequest, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary      Export meals
+// @Description  Admin only - Download every meal in the import format
+// @Tags         meals,admin
+// @Produce      text/csv,json
+// @Param        format  query     string  false  "File format"  Enums(csv, json)  default(json)
+// @Success      200     {array}   models.MealRecord
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/meals/export [get]
+func (h *MealHandler) Export(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, use csv or json"})
+		return
+	}
+
+	records, err := h.service.Export(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=meals."+format)
+	if format == "json" {
+		c.JSON(http.StatusOK, records)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := service.WriteMealCSV(c.Writer, records); err != nil {
+		c.Error(err)
+	}
+}
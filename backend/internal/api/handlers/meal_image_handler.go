@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type MealImageHandler struct {
+	service service.MealImageService
+}
+
+func NewMealImageHandler(service service.MealImageService) *MealImageHandler {
+	return &MealImageHandler{service: service}
+}
+
+// @Summary      List meal images
+// @Description  Get a meal's image gallery in display order
+// @Tags         meals
+// @Produce      json
+// @Param        id   path      int  true  "Meal ID"
+// @Success      200  {array}   models.MealImage
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /meals/{id}/images [get]
+func (h *MealImageHandler) List(c *gin.Context) {
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	images, err := h.service.GetImages(c.Request.Context(), mealID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, images)
+}
+
+// @Summary      Add meal image
+// @Description  Admin only - Add an uploaded image to the end of a meal's gallery
+// @Tags         meals,admin
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                         true  "Meal ID"
+// @Param        image  body      models.AddMealImageRequest  true  "Image data"
+// @Success      201    {object}  models.MealImage
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/images [post]
+func (h *MealImageHandler) Add(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	var req models.AddMealImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	image, err := h.service.AddImage(c.Request.Context(), userID.(int), mealID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, image)
+}
+
+// @Summary      Update meal image
+// @Description  Admin only - Change an image's alt text or make it the primary image
+// @Tags         meals,admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                            true  "Meal ID"
+// @Param        imageId  path      int                            true  "Image ID"
+// @Param        image    body      models.UpdateMealImageRequest  true  "Image changes"
+// @Success      200      {object}  models.MealImage
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/images/{imageId} [put]
+func (h *MealImageHandler) Update(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	imageID, err := strconv.Atoi(c.Param("imageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid image id"})
+		return
+	}
+
+	var req models.UpdateMealImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	image, err := h.service.UpdateImage(c.Request.Context(), userID.(int), mealID, imageID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, image)
+}
+
+// @Summary      Reorder meal images
+// @Description  Admin only - Set the gallery order by listing every image ID
+// @Tags         meals,admin
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                              true  "Meal ID"
+// @Param        order  body      models.ReorderMealImagesRequest  true  "Image IDs in display order"
+// @Success      200    {array}   models.MealImage
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/images/order [put]
+func (h *MealImageHandler) Reorder(c *gin.Context) {
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	var req models.ReorderMealImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	images, err := h.service.ReorderImages(c.Request.Context(), mealID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, images)
+}
+
+// @Summary      Remove meal image
+// @Description  Admin only - Remove an image from a meal's gallery
+// @Tags         meals,admin
+// @Param        id       path      int  true  "Meal ID"
+// @Param        imageId  path      int  true  "Image ID"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/images/{imageId} [delete]
+func (h *MealImageHandler) Delete(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	imageID, err := strconv.Atoi(c.Param("imageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid image id"})
+		return
+	}
+
+	if err := h.service.DeleteImage(c.Request.Context(), userID.(int), mealID, imageID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "image removed"})
+}
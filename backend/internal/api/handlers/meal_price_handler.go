@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type MealPriceHandler struct {
+	service service.PriceService
+}
+
+func NewMealPriceHandler(service service.PriceService) *MealPriceHandler {
+	return &MealPriceHandler{service: service}
+}
+
+// @Summary      List meal prices
+// @Description  Admin only - Get a meal's price history and scheduled price changes on the standard and plan price lists
+// @Tags         meals,admin
+// @Produce      json
+// @Param        id   path      int  true  "Meal ID"
+// @Success      200  {array}   models.MealPrice
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/prices [get]
+func (h *MealPriceHandler) List(c *gin.Context) {
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	prices, err := h.service.List(c.Request.Context(), mealID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prices)
+}
+
+// @Summary      Schedule meal price
+// @Description  Admin only - Set a meal's (or variant's) price from effective_from onwards, on the standard price list or a subscription plan's list. Without effective_from the price applies immediately. Carts and checkout use whichever price is in effect at the time.
+// @Tags         meals,admin
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                            true  "Meal ID"
+// @Param        price  body      models.CreateMealPriceRequest  true  "Price list entry"
+// @Success      201    {object}  models.MealPrice
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Failure      404    {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/prices [post]
+func (h *MealPriceHandler) Create(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	var req models.CreateMealPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	price, err := h.service.Create(c.Request.Context(), mealID, userID.(int), &req)
+	if err != nil {
+		if err.Error() == "meal not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, price)
+}
+
+// @Summary      Cancel scheduled meal price
+// @Description  Admin only - Delete a price list entry that has not taken effect yet
+// @Tags         meals,admin
+// @Param        id       path      int  true  "Meal ID"
+// @Param        priceId  path      int  true  "Price ID"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/prices/{priceId} [delete]
+func (h *MealPriceHandler) Delete(c *gin.Context) {
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	priceID, err := strconv.Atoi(c.Param("priceId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid price id"})
+		return
+	}
+
+	err = h.service.Delete(c.Request.Context(), mealID, priceID)
+	if err != nil {
+		if err.Error() == "price not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "scheduled price deleted"})
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type MealTranslationHandler struct {
+	service service.MealTranslationService
+}
+
+func NewMealTranslationHandler(service service.MealTranslationService) *MealTranslationHandler {
+	return &MealTranslationHandler{service: service}
+}
+
+// @Summary      List meal translations
+// @Description  Admin only - Get a meal's name, description and ingredients in every language it has been translated into
+// @Tags         meals,admin
+// @Produce      json
+// @Param        id   path      int  true  "Meal ID"
+// @Success      200  {array}   models.MealTranslation
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/translations [get]
+func (h *MealTranslationHandler) List(c *gin.Context) {
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	translations, err := h.service.List(c.Request.Context(), mealID)
+	if err != nil {
+		if err.Error() == "meal not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, translations)
+}
+
+// @Summary      Set meal translation
+// @Description  Admin only - Create or replace a meal's translation into a language such as "fr" or "pt-BR". An empty description or ingredient list falls back to the default language. Translations are not tied to a meal version, so they also replace the text of the version pinned on a weekly menu.
+// @Tags         meals,admin
+// @Accept       json
+// @Produce      json
+// @Param        id           path      int                               true  "Meal ID"
+// @Param        lang         path      string                            true  "Language tag"
+// @Param        translation  body      models.SetMealTranslationRequest  true  "Translated text"
+// @Success      200          {object}  models.MealTranslation
+// @Failure      400          {object}  map[string]string
+// @Failure      401          {object}  map[string]string
+// @Failure      403          {object}  map[string]string
+// @Failure      404          {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/translations/{lang} [put]
+func (h *MealTranslationHandler) Set(c *gin.Context) {
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	var req models.SetMealTranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	translation, err := h.service.Set(c.Request.Context(), mealID, c.Param("lang"), &req)
+	if err != nil {
+		if err.Error() == "meal not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
+
+// @Summary      Delete meal translation
+// @Description  Admin only - Remove a meal's translation; the meal falls back to the default language
+// @Tags         meals,admin
+// @Produce      json
+// @Param        id    path      int     true  "Meal ID"
+// @Param        lang  path      string  true  "Language tag"
+// @Success      200   {object}  map[string]string
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/translations/{lang} [delete]
+func (h *MealTranslationHandler) Delete(c *gin.Context) {
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), mealID, c.Param("lang")); err != nil {
+		if err.Error() == "meal translation not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "translation deleted successfully"})
+}
+
+// requestLanguages reads the preferred languages for meal text: the lang
+// query parameter first, then Accept-Language. It returns false after
+// responding 400 to an invalid lang.
+func requestLanguages(c *gin.Context) ([]string, bool) {
+	// Responses differ by language, so caches must key on it
+	c.Header("Vary", "Accept-Language")
+
+	languages := service.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+	if value := c.Query("lang"); value != "" {
+		lang, ok := service.NormalizeLanguage(value)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lang"})
+			return nil, false
+		}
+		languages = append([]string{lang}, languages...)
+	}
+	return languages, true
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type NutritionHandler struct {
+	service service.NutritionService
+}
+
+func NewNutritionHandler(service service.NutritionService) *NutritionHandler {
+	return &NutritionHandler{service: service}
+}
+
+// @Summary      Get nutrition goals
+// @Description  Get the authenticated user's calorie and macro goals
+// @Tags         nutrition
+// @Produce      json
+// @Success      200  {object}  models.NutritionGoals
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/nutrition-goals [get]
+func (h *NutritionHandler) GetGoals(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	goals, err := h.service.GetGoals(c.Request.Context(), userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if goals == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "nutrition goals not set"})
+		return
+	}
+
+	c.JSON(http.StatusOK, goals)
+}
+
+// @Summary      Set nutrition goals
+// @Description  Set the authenticated user's daily or weekly calorie and macro goals
+// @Tags         nutrition
+// @Accept       json
+// @Produce      json
+// @Param        goals  body      models.SetNutritionGoalsRequest  true  "Nutrition goals"
+// @Success      200    {object}  models.NutritionGoals
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/nutrition-goals [put]
+func (h *NutritionHandler) SetGoals(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.SetNutritionGoalsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	goals, err := h.service.SetGoals(c.Request.Context(), userID.(int), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, goals)
+}
+
+// @Summary      Clear nutrition goals
+// @Description  Remove the authenticated user's nutrition goals
+// @Tags         nutrition
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/nutrition-goals [delete]
+func (h *NutritionHandler) DeleteGoals(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := h.service.DeleteGoals(c.Request.Context(), userID.(int)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "nutrition goals cleared"})
+}
+
+// @Summary      Get dietary preferences
+// @Description  Get the dietary tags the authenticated user's meals should carry
+// @Tags         nutrition
+// @Produce      json
+// @Success      200  {object}  models.DietaryPreferences
+// @Failure      401  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/dietary-preferences [get]
+func (h *NutritionHandler) GetDietaryPreferences(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	preferences, err := h.service.GetDietaryPreferences(c.Request.Context(), userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// @Summary      Set dietary preferences
+// @Description  Set the dietary tags (e.g. "vegetarian", "gluten_free") the authenticated user's meals should carry
+// @Tags         nutrition
+// @Accept       json
+// @Produce      json
+// @Param        preferences  body      models.SetDietaryPreferencesRequest  true  "Dietary preferences"
+// @Success      200          {object}  models.DietaryPreferences
+// @Failure      400          {object}  map[string]string
+// @Failure      401          {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/dietary-preferences [put]
+func (h *NutritionHandler) SetDietaryPreferences(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.SetDietaryPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preferences, err := h.service.SetDietaryPreferences(c.Request.Context(), userID.(int), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
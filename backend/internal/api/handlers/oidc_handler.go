@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+// oidcStateCookie holds the state of a login in the browser that started
+// it, so the callback only completes a login in that same browser
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	service service.OIDCService
+	// frontendURL is where users are sent back to after signing in
+	frontendURL string
+	// secureCookies is set when the API is served over HTTPS
+	secureCookies bool
+}
+
+func NewOIDCHandler(service service.OIDCService, frontendURL string, secureCookies bool) *OIDCHandler {
+	return &OIDCHandler{service: service, frontendURL: frontendURL, secureCookies: secureCookies}
+}
+
+// @Summary      List sign-in providers
+// @Description  Names of the external identity providers users can sign in with
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  map[string][]string
+// @Router       /auth/oidc [get]
+func (h *OIDCHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.service.Providers()})
+}
+
+// @Summary      Sign in with a provider
+// @Description  Redirect to the identity provider to sign in, using the OpenID Connect authorization code flow with PKCE. Sets a short-lived oidc_state cookie tying the login to this browser.
+// @Tags         auth
+// @Param        provider  path  string  true  "Provider name, e.g. google"
+// @Success      302
+// @Failure      404  {object}  map[string]string
+// @Failure      502  {object}  map[string]string
+// @Router       /auth/oidc/{provider}/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, state, err := h.service.AuthURL(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		switch err.Error() {
+		case "unknown provider":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "provider unavailable":
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.setStateCookie(c, state, int(service.OIDCLoginTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// @Summary      Provider callback
+// @Description  Where the identity provider sends the user back, in the browser holding the login's oidc_state cookie. Redirects to the web app's /auth/callback with the tokens (or a two_factor_token, or an error) in the URL fragment.
+// @Tags         auth
+// @Param        provider  path   string  true   "Provider name"
+// @Param        code      query  string  false  "Authorization code"
+// @Param        state     query  string  true   "Login state"
+// @Success      302
+// @Router       /auth/oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	// The state cookie is used up along with the state
+	cookie, _ := c.Cookie(oidcStateCookie)
+	h.setStateCookie(c, "", -1)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		h.redirect(c, url.Values{"error": {"sign-in was cancelled or failed at the provider"}})
+		return
+	}
+
+	state := c.Query("state")
+	if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		h.redirect(c, url.Values{"error": {"invalid or expired login, please try again"}})
+		return
+	}
+
+	res, err := h.service.Callback(c.Request.Context(), c.Param("provider"), c.Query("code"), state)
+	if err != nil {
+		h.redirect(c, url.Values{"error": {callbackError(c.Param("provider"), err)}})
+		return
+	}
+
+	h.redirect(c, authFragment(res))
+}
+
+func (h *OIDCHandler) setStateCookie(c *gin.Context, state string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, maxAge, "/api/auth/oidc", "", h.secureCookies, true)
+}
+
+// callbackError is the message shown to the user for a failed sign-in.
+// Unexpected errors are logged and replaced with a generic message, so no
+// internal detail ends up in the web app's URL.
+func callbackError(provider string, err error) string {
+	message := err.Error()
+	switch {
+	case message == "unknown provider",
+		message == "invalid or expired login, please try again",
+		message == "provider unavailable",
+		message == "could not complete sign-in with provider",
+		message == "provider did not return an ID token",
+		message == "account has been disabled",
+		message == "an account with this email exists but is not verified, sign in with your password and verify it first",
+		strings.HasPrefix(message, "your email address is not verified by "):
+		return message
+	default:
+		log.Printf("OIDC sign-in with %s failed: %v", provider, err)
+		return "sign-in failed, please try again"
+	}
+}
+
+// redirect sends the user back to the web app. The result goes in the
+// fragment so tokens are not sent to servers or kept in their logs.
+func (h *OIDCHandler) redirect(c *gin.Context, values url.Values) {
+	c.Redirect(http.StatusFound, h.frontendURL+"/auth/callback#"+values.Encode())
+}
+
+func authFragment(res *models.AuthResponse) url.Values {
+	if res.TwoFactorRequired {
+		return url.Values{"two_factor_token": {res.TwoFactorToken}}
+	}
+	return url.Values{
+		"token":         {res.Token},
+		"refresh_token": {res.RefreshToken},
+		"expires_in":    {strconv.Itoa(res.ExpiresIn)},
+	}
+}
@@ -18,7 +18,7 @@ func NewOrderHandler(service service.OrderService) *OrderHandler {
 }
 
 // @Summary      Checkout
-// @Description  Convert user's cart to an order (requires exactly 10 meals)
+// @Description  Convert user's cart to an order (requires exactly 10 meals, a verified email and a delivery address in the profile, which is copied onto the order)
 // @Tags         orders
 // @Accept       json
 // @Produce      json
@@ -26,6 +26,7 @@ func NewOrderHandler(service service.OrderService) *OrderHandler {
 // @Success      201       {object}  models.Order
 // @Failure      400       {object}  map[string]string
 // @Failure      401       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
 // @Security     BearerAuth
 // @Router       /orders/checkout [post]
 func (h *OrderHandler) Checkout(c *gin.Context) {
@@ -39,6 +40,10 @@ func (h *OrderHandler) Checkout(c *gin.Context) {
 
 	order, err := h.service.Checkout(c.Request.Context(), userID.(int), &req)
 	if err != nil {
+		if err.Error() == "verify your email address before checking out" {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -96,3 +101,162 @@ func (h *OrderHandler) GetByID(c *gin.Context) {
 
 	c.JSON(http.StatusOK, order)
 }
+
+// @Summary      Update order status
+// @Description  Admin only - Move an order through pending, confirmed, delivered or cancelled
+// @Tags         orders,admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                              true  "Order ID"
+// @Param        status  body      models.UpdateOrderStatusRequest  true  "New status"
+// @Success      200     {object}  models.Order
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/orders/{id}/status [put]
+func (h *OrderHandler) UpdateStatus(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	var req models.UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := h.service.UpdateStatus(c.Request.Context(), orderID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// @Summary      Order again
+// @Description  Copy a past order's items into the cart, limited to meals on the current menu with stock; reports anything that could not be added
+// @Tags         orders
+// @Produce      json
+// @Param        id   path      int  true  "Order ID"
+// @Success      200  {object}  models.ReorderResult
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /orders/{id}/reorder [post]
+func (h *OrderHandler) Reorder(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	result, err := h.service.Reorder(c.Request.Context(), userID.(int), orderID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary      List all orders
+// @Description  Staff with orders:read - Get every customer's orders, newest first, optionally filtered
+// @Tags         orders,admin
+// @Produce      json
+// @Param        status         query     string  false  "pending, confirmed, delivered or cancelled"
+// @Param        delivery_date  query     string  false  "Delivery date (YYYY-MM-DD)"
+// @Success      200            {array}   models.Order
+// @Failure      400            {object}  map[string]string
+// @Failure      401            {object}  map[string]string
+// @Failure      403            {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/orders [get]
+func (h *OrderHandler) List(c *gin.Context) {
+	orders, err := h.service.List(c.Request.Context(), c.Query("status"), c.Query("delivery_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// @Summary      Get any order
+// @Description  Staff with orders:read - Get details of any customer's order
+// @Tags         orders,admin
+// @Produce      json
+// @Param        id   path      int  true  "Order ID"
+// @Success      200  {object}  models.Order
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/orders/{id} [get]
+func (h *OrderHandler) GetForStaff(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	order, err := h.service.GetForStaff(c.Request.Context(), orderID)
+	if err != nil {
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// @Summary      Production list
+// @Description  Staff with production:read - How many of each meal and variant to make for pending and confirmed orders on a delivery date
+// @Tags         orders,admin
+// @Produce      json
+// @Param        delivery_date  query     string  true  "Delivery date (YYYY-MM-DD)"
+// @Success      200            {array}   models.ProductionItem
+// @Failure      400            {object}  map[string]string
+// @Failure      401            {object}  map[string]string
+// @Failure      403            {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/production [get]
+func (h *OrderHandler) ProductionList(c *gin.Context) {
+	items, err := h.service.GetProductionList(c.Request.Context(), c.Query("delivery_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// @Summary      Deliveries
+// @Description  Staff with deliveries:read - The orders to deliver on a date, leaving out cancelled ones
+// @Tags         orders,admin
+// @Produce      json
+// @Param        delivery_date  query     string  true  "Delivery date (YYYY-MM-DD)"
+// @Success      200            {array}   models.Delivery
+// @Failure      400            {object}  map[string]string
+// @Failure      401            {object}  map[string]string
+// @Failure      403            {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/deliveries [get]
+func (h *OrderHandler) Deliveries(c *gin.Context) {
+	deliveries, err := h.service.GetDeliveries(c.Request.Context(), c.Query("delivery_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
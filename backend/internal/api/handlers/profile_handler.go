@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type ProfileHandler struct {
+	service service.ProfileService
+}
+
+func NewProfileHandler(service service.ProfileService) *ProfileHandler {
+	return &ProfileHandler{service: service}
+}
+
+// @Summary      Get profile
+// @Description  Get the authenticated user's profile: contact details, default delivery address, dietary and communication preferences
+// @Tags         profile
+// @Produce      json
+// @Success      200  {object}  models.Profile
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me [get]
+func (h *ProfileHandler) Get(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	profile, err := h.service.GetProfile(c.Request.Context(), userID.(int))
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary      Update profile
+// @Description  Update the fields that are sent and leave the rest. Use clear_address to remove the default address, and /me/email to change the email address.
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        profile  body      models.UpdateProfileRequest  true  "Profile changes"
+// @Success      200      {object}  models.Profile
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me [put]
+func (h *ProfileHandler) Update(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.service.UpdateProfile(c.Request.Context(), userID.(int), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type RecommendationHandler struct {
+	service service.RecommendationService
+}
+
+func NewRecommendationHandler(service service.RecommendationService) *RecommendationHandler {
+	return &RecommendationHandler{service: service}
+}
+
+// @Summary      Refresh recommendations
+// @Description  Recompute every user's meal recommendations for the active menu now instead of waiting for the background job (Admin only)
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/recommendations/refresh [post]
+func (h *RecommendationHandler) Refresh(c *gin.Context) {
+	if err := h.service.Refresh(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "recommendations refreshed"})
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type ReviewHandler struct {
+	service service.ReviewService
+}
+
+func NewReviewHandler(service service.ReviewService) *ReviewHandler {
+	return &ReviewHandler{service: service}
+}
+
+// @Summary      Review a meal
+// @Description  Rate a meal 1-5 from a delivered order (one review per meal per week)
+// @Tags         reviews
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                         true  "Meal ID"
+// @Param        review  body      models.CreateReviewRequest  true  "Review data"
+// @Success      201     {object}  models.Review
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /meals/{id}/reviews [post]
+func (h *ReviewHandler) Create(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	var req models.CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := h.service.Create(c.Request.Context(), userID.(int), mealID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// @Summary      List meal reviews
+// @Description  Get the published reviews for a meal
+// @Tags         reviews
+// @Produce      json
+// @Param        id   path      int  true  "Meal ID"
+// @Success      200  {array}   models.PublicReview
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /meals/{id}/reviews [get]
+func (h *ReviewHandler) ListForMeal(c *gin.Context) {
+	mealID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+		return
+	}
+
+	reviews, err := h.service.GetMealReviews(c.Request.Context(), mealID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// @Summary      List reviews for moderation
+// @Description  Admin only - Get all reviews, optionally filtered by status
+// @Tags         reviews,admin
+// @Produce      json
+// @Param        status  query     string  false  "Filter by status (published, hidden)"
+// @Success      200     {array}   models.Review
+// @Failure      401     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/reviews [get]
+func (h *ReviewHandler) List(c *gin.Context) {
+	reviews, err := h.service.GetAll(c.Request.Context(), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// @Summary      Moderate review
+// @Description  Admin only - Publish or hide a review
+// @Tags         reviews,admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                           true  "Review ID"
+// @Param        status  body      models.ModerateReviewRequest  true  "New status"
+// @Success      200     {object}  models.Review
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/reviews/{id} [put]
+func (h *ReviewHandler) Moderate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review id"})
+		return
+	}
+
+	var req models.ModerateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := h.service.Moderate(c.Request.Context(), id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// @Summary      Delete review
+// @Description  Admin only - Permanently delete a review
+// @Tags         reviews,admin
+// @Param        id   path      int  true  "Review ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/reviews/{id} [delete]
+func (h *ReviewHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review id"})
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "review deleted successfully"})
+}
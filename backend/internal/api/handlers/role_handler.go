@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type RoleHandler struct {
+	service service.RoleService
+}
+
+func NewRoleHandler(service service.RoleService) *RoleHandler {
+	return &RoleHandler{service: service}
+}
+
+// @Summary      List roles
+// @Description  Staff with roles:write - Get every role with its permissions and how many users hold it
+// @Tags         roles,admin
+// @Produce      json
+// @Success      200  {array}   models.Role
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/roles [get]
+func (h *RoleHandler) List(c *gin.Context) {
+	roles, err := h.service.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// @Summary      Get role
+// @Description  Staff with roles:write - Get a role with its permissions
+// @Tags         roles,admin
+// @Produce      json
+// @Param        name  path      string  true  "Role name"
+// @Success      200   {object}  models.Role
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/roles/{name} [get]
+func (h *RoleHandler) GetByName(c *gin.Context) {
+	role, err := h.service.GetByName(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		if err.Error() == "role not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// @Summary      List permissions
+// @Description  Staff with roles:write - Get every permission a role can be given
+// @Tags         roles,admin
+// @Produce      json
+// @Success      200  {array}   models.Permission
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/permissions [get]
+func (h *RoleHandler) ListPermissions(c *gin.Context) {
+	permissions, err := h.service.GetPermissions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, permissions)
+}
+
+// @Summary      Create role
+// @Description  Staff with roles:write - Create a role with a set of permissions
+// @Tags         roles,admin
+// @Accept       json
+// @Produce      json
+// @Param        role  body      models.CreateRoleRequest  true  "Role"
+// @Success      201   {object}  models.Role
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/roles [post]
+func (h *RoleHandler) Create(c *gin.Context) {
+	var req models.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.service.Create(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// @Summary      Update role
+// @Description  Staff with roles:write - Change a role's description and replace its permissions. Users' tokens pick up the change straight away.
+// @Tags         roles,admin
+// @Accept       json
+// @Produce      json
+// @Param        name  path      string                    true  "Role name"
+// @Param        role  body      models.UpdateRoleRequest  true  "Role"
+// @Success      200   {object}  models.Role
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/roles/{name} [put]
+func (h *RoleHandler) Update(c *gin.Context) {
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.service.Update(c.Request.Context(), c.Param("name"), &req)
+	if err != nil {
+		if err.Error() == "role not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// @Summary      Delete role
+// @Description  Staff with roles:write - Delete a role no user holds
+// @Tags         roles,admin
+// @Produce      json
+// @Param        name  path      string  true  "Role name"
+// @Success      200   {object}  map[string]string
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/roles/{name} [delete]
+func (h *RoleHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Request.Context(), c.Param("name")); err != nil {
+		if err.Error() == "role not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "role deleted successfully"})
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+)
+
+type TwoFactorHandler struct {
+	service service.TwoFactorService
+}
+
+func NewTwoFactorHandler(service service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{service: service}
+}
+
+// @Summary      Get two-factor status
+// @Description  Whether two-factor authentication is enabled for the authenticated user, and whether it is required
+// @Tags         two-factor
+// @Produce      json
+// @Success      200  {object}  models.TwoFactorStatus
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/2fa [get]
+func (h *TwoFactorHandler) Status(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	status, err := h.service.Status(c.Request.Context(), userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// @Summary      Start two-factor setup
+// @Description  Generate a TOTP secret and its otpauth:// provisioning URI to show as a QR code. Two-factor authentication is enabled once a code is confirmed.
+// @Tags         two-factor
+// @Produce      json
+// @Success      200  {object}  models.TwoFactorSetupResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/2fa/setup [post]
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	res, err := h.service.Setup(c.Request.Context(), userID.(int))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary      Enable two-factor authentication
+// @Description  Confirm setup with a code from the authenticator app. Returns single-use recovery codes, shown only once. Other sessions are signed out.
+// @Tags         two-factor
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.TwoFactorCodeRequest  true  "Authenticator code"
+// @Success      200      {object}  models.RecoveryCodesResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/2fa/enable [post]
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.service.Enable(c.Request.Context(), userID.(int), sessionID.(int), req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary      Disable two-factor authentication
+// @Description  Turn two-factor authentication off with the password and a current code. Not allowed for accounts where it is required.
+// @Tags         two-factor
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.DisableTwoFactorRequest  true  "Password and code"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/2fa/disable [post]
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.Disable(c.Request.Context(), userID.(int), &req); err != nil {
+		if err.Error() == "two-factor authentication is required for your account" {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// @Summary      Regenerate recovery codes
+// @Description  Replace the recovery codes with new ones, confirmed with a current code. The old codes stop working.
+// @Tags         two-factor
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.TwoFactorCodeRequest  true  "Authenticator or recovery code"
+// @Success      200      {object}  models.RecoveryCodesResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /me/2fa/recovery-codes [post]
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.service.RegenerateRecoveryCodes(c.Request.Context(), userID.(int), req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -1,56 +1,106 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"path/filepath"
-	"strings"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jopari/preptoplate/internal/service"
 )
 
 type UploadHandler struct {
-	imageService service.ImageService
+	mealImageService service.MealImageService
 }
 
-func NewUploadHandler(imageService service.ImageService) *UploadHandler {
-	return &UploadHandler{imageService: imageService}
+func NewUploadHandler(mealImageService service.MealImageService) *UploadHandler {
+	return &UploadHandler{mealImageService: mealImageService}
 }
 
 // @Summary      Upload image
-// @Description  Admin only - Upload an image to Cloudinary
+// @Description  Admin only - Upload a JPEG, PNG or WebP image of up to 10MB. The type is checked from the file's content, metadata such as EXIF is stripped, and thumbnail, card and hero sizes are generated as JPEG (PNG with transparency) plus WebP. With meal_id the image is added to that meal's gallery. Uploads not used by any meal or add-on are cleaned up after a day.
 // @Tags         upload,admin
 // @Accept       multipart/form-data
 // @Produce      json
-// @Param        file  formData  file  true  "Image file"
-// @Success      200   {object}  map[string]string
-// @Failure      400   {object}  map[string]string
-// @Failure      500   {object}  map[string]string
+// @Param        file        formData  file    true   "Image file"
+// @Param        meal_id     formData  int     false  "Meal to add the image to"
+// @Param        alt_text    formData  string  false  "Alt text for the gallery image"
+// @Param        is_primary  formData  bool    false  "Make it the meal's primary image"
+// @Success      200         {object}  models.UploadResult
+// @Failure      400         {object}  map[string]string
+// @Failure      413         {object}  map[string]string
+// @Failure      500         {object}  map[string]string
 // @Security     BearerAuth
 // @Router       /upload [post]
 func (h *UploadHandler) HandleImageUpload(c *gin.Context) {
-	// 1. Get file from request
+	userID, _ := c.Get("user_id")
+
+	// 1. Get file from request, leaving some room for the other form fields
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxImageBytes+1<<20)
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrImageTooLarge.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "no file uploaded"})
 		return
 	}
 	defer file.Close()
 
-	// 2. Validate file type (basic check)
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".webp" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file type. only jpg, jpeg, png, webp allowed"})
-		return
+	// 2. Optional gallery details
+	var mealID *int
+	if value := c.PostForm("meal_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid meal id"})
+			return
+		}
+		mealID = &id
+	}
+	isPrimary := false
+	if value := c.PostForm("is_primary"); value != "" {
+		isPrimary, err = strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid is_primary value"})
+			return
+		}
 	}
 
-	// 3. Upload to Cloudinary
-	url, err := h.imageService.UploadImage(c.Request.Context(), file, header.Filename)
+	// 3. Check the content, resize, store and link to the meal
+	result, err := h.mealImageService.Upload(c.Request.Context(), userID.(int), file, header.Filename, mealID, c.PostForm("alt_text"), isPrimary)
 	if err != nil {
+		if errors.Is(err, service.ErrImageTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrUnsupportedImage) || err.Error() == "meal not found" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to upload image: " + err.Error()})
 		return
 	}
 
-	// 4. Return URL
-	c.JSON(http.StatusOK, gin.H{"url": url})
+	// 4. Return URLs
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary      Clean up orphaned uploads
+// @Description  Admin only - Delete uploads older than a day that no meal gallery, meal, meal version or add-on uses
+// @Tags         upload,admin
+// @Produce      json
+// @Success      200  {object}  map[string]int
+// @Failure      500  {object}  map[string]string
+// @Security     BearerAuth
+// @Router       /admin/uploads/cleanup [post]
+func (h *UploadHandler) CleanupOrphans(c *gin.Context) {
+	removed, err := h.mealImageService.CleanupOrphans(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
 }
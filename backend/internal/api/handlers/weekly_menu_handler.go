@@ -189,15 +189,46 @@ func (h *WeeklyMenuHandler) Delete(c *gin.Context) {
 // Public endpoints
 
 // @Summary      Get active weekly menu
-// @Description  Get the currently active weekly menu with stock information
+// @Description  Get the currently active weekly menu with stock information and current prices; subscribers see their plan's prices. With sort=recommended the meals are ranked for the authenticated user. Meal text is in the language picked by lang or Accept-Language, falling back to the default language. Translations always show their latest text, even for a meal version pinned on the menu.
 // @Tags         weekly-menu
 // @Produce      json
-// @Success      200  {object}  models.WeeklyMenu
-// @Failure      404  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Param        sort             query     string  false  "Meal order"  Enums(recommended)
+// @Param        lang             query     string  false  "Preferred language, e.g. fr"
+// @Param        Accept-Language  header    string  false  "Preferred languages"
+// @Success      200              {object}  models.WeeklyMenu
+// @Failure      400              {object}  map[string]string
+// @Failure      401              {object}  map[string]string
+// @Failure      404              {object}  map[string]string
+// @Failure      500              {object}  map[string]string
 // @Router       /menu [get]
 func (h *WeeklyMenuHandler) GetActiveMenu(c *gin.Context) {
-	menu, err := h.service.GetActive(c.Request.Context())
+	languages, ok := requestLanguages(c)
+	if !ok {
+		return
+	}
+
+	var menu *models.WeeklyMenu
+	var err error
+
+	// Signed in is optional here; 0 means anonymous
+	userID := 0
+	if id, exists := c.Get("user_id"); exists {
+		userID = id.(int)
+	}
+
+	switch c.Query("sort") {
+	case "":
+		menu, err = h.service.GetActive(c.Request.Context(), userID, languages)
+	case "recommended":
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "login required for recommended sort"})
+			return
+		}
+		menu, err = h.service.GetActiveRecommended(c.Request.Context(), userID, languages)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort, use recommended"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
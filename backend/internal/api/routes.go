@@ -1,6 +1,8 @@
 package api
 
 import (
+	"log"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -21,6 +23,12 @@ import (
 func SetupRouter(db *pgxpool.Pool, cfg *config.Config) *gin.Engine {
 	r := gin.Default()
 
+	// Only our own proxies may set the client IP, which login throttling
+	// relies on, so clients cannot pick a fresh one for every attempt
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// CORS middleware
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", "https://preptoplate.netlify.app"},
@@ -34,32 +42,103 @@ func SetupRouter(db *pgxpool.Pool, cfg *config.Config) *gin.Engine {
 	// Repositories
 	userRepo := repository.NewUserRepository(db)
 	mealRepo := repository.NewMealRepository(db)
+	variantRepo := repository.NewMealVariantRepository(db)
+	addonRepo := repository.NewAddonRepository(db)
 	cartRepo := repository.NewCartRepository(db)
 	menuRepo := repository.NewWeeklyMenuRepository(db)
 	orderRepo := repository.NewOrderRepository(db)
+	reviewRepo := repository.NewReviewRepository(db)
+	favouriteRepo := repository.NewFavouriteRepository(db)
+	goalsRepo := repository.NewNutritionGoalsRepository(db)
+	recRepo := repository.NewRecommendationRepository(db)
+	uploadRepo := repository.NewUploadRepository(db)
+	mealImageRepo := repository.NewMealImageRepository(db)
+	translationRepo := repository.NewMealTranslationRepository(db)
+	bundleRepo := repository.NewBundleRepository(db)
+	priceRepo := repository.NewPriceRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	resetRepo := repository.NewPasswordResetRepository(db)
+	verifyRepo := repository.NewEmailVerificationRepository(db)
+	twoFARepo := repository.NewTwoFactorRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	throttleRepo := repository.NewLoginThrottleRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	exportRepo := repository.NewDataExportRepository(db)
+	deletionRepo := repository.NewAccountDeletionRepository(db)
 
-	// Services
-	authService := service.NewAuthService(userRepo, cfg)
-	mealService := service.NewMealService(mealRepo)
-	cartService := service.NewCartService(cartRepo, mealRepo)
-	menuService := service.NewWeeklyMenuService(menuRepo, mealRepo)
+	// Access tokens are checked against their session so logout and
+	// revocation take effect immediately
+	requireAuth := middleware.AuthMiddleware(cfg, sessionRepo)
+	optionalAuth := middleware.OptionalAuth(cfg, sessionRepo)
 
-	// Image Service (Cloudinary)
-	imageService, _ := service.NewImageService() // Ignore error, will fail gracefully on upload if not configured
+	// Staff routes check a permission of the user's role, e.g. "orders:read"
+	can := func(permission string) gin.HandlerFunc {
+		return middleware.RequirePermission(roleRepo, permission, cfg.RequireAdmin2FA)
+	}
 
 	// Email Service (Resend)
 	emailService := service.NewEmailService(cfg)
 
+	// Services
+	authService := service.NewAuthService(userRepo, sessionRepo, resetRepo, verifyRepo, twoFARepo, throttleRepo, emailService, cfg)
+	twoFactorService := service.NewTwoFactorService(twoFARepo, userRepo, sessionRepo, roleRepo, cfg)
+	oidcService := service.NewOIDCService(identityRepo, userRepo, authService, cfg)
+	mealService := service.NewMealService(mealRepo, variantRepo, reviewRepo, mealImageRepo, translationRepo, cfg.DefaultLanguage)
+	cartService := service.NewCartService(cartRepo, mealRepo, variantRepo, addonRepo, goalsRepo, bundleRepo, menuRepo, priceRepo, cfg.MealsPerDay)
+	addonService := service.NewAddonService(addonRepo)
+	favouriteService := service.NewFavouriteService(favouriteRepo, mealRepo, priceRepo)
+	nutritionService := service.NewNutritionService(goalsRepo, userRepo)
+	cartBuilderService := service.NewCartBuilderService(menuRepo, goalsRepo, userRepo, priceRepo, cfg.MealsPerDay)
+	menuService := service.NewWeeklyMenuService(menuRepo, mealRepo, variantRepo, addonRepo, reviewRepo, recRepo, priceRepo, translationRepo, cfg.DefaultLanguage)
+	translationService := service.NewMealTranslationService(translationRepo, mealRepo, cfg.DefaultLanguage)
+	bundleService := service.NewBundleService(bundleRepo, menuRepo, priceRepo)
+	priceService := service.NewPriceService(priceRepo, mealRepo, variantRepo)
+	roleService := service.NewRoleService(roleRepo)
+	userService := service.NewUserService(userRepo, roleRepo, sessionRepo, authService)
+	profileService := service.NewProfileService(userRepo)
+	// Expired exports are cleaned up in the background, see cmd/server
+	accountService := service.NewAccountService(userRepo, exportRepo, orderRepo, cartRepo, reviewRepo, favouriteRepo, goalsRepo, sessionRepo, throttleRepo, deletionRepo, emailService, cfg)
+
+	// Image Service (local disk, S3-compatible or Cloudinary)
+	imageService, err := service.NewImageService(cfg)
+	if err != nil {
+		log.Fatalf("Could not set up image storage: %v", err)
+	}
+	mealImageService := service.NewMealImageService(mealImageRepo, uploadRepo, mealRepo, imageService)
+
 	// Order Service
-	orderService := service.NewOrderService(orderRepo, cartRepo, menuRepo, emailService, userRepo)
+	orderService := service.NewOrderService(orderRepo, cartRepo, menuRepo, emailService, userRepo, goalsRepo, priceRepo, cfg.MealsPerDay)
+
+	// Review Service
+	reviewService := service.NewReviewService(reviewRepo, orderRepo)
+
+	// Recommendation Service (refreshed in the background, see cmd/server)
+	recommendationService := service.NewRecommendationService(recRepo, menuRepo)
 
 	// Handlers
 	authHandler := handlers.NewAuthHandler(authService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	oidcHandler := handlers.NewOIDCHandler(oidcService, cfg.FrontendURL, strings.HasPrefix(cfg.PublicURL, "https://"))
 	mealHandler := handlers.NewMealHandler(mealService)
+	addonHandler := handlers.NewAddonHandler(addonService)
 	cartHandler := handlers.NewCartHandler(cartService)
+	cartBuilderHandler := handlers.NewCartBuilderHandler(cartBuilderService)
 	menuHandler := handlers.NewWeeklyMenuHandler(menuService)
 	orderHandler := handlers.NewOrderHandler(orderService)
-	uploadHandler := handlers.NewUploadHandler(imageService)
+	reviewHandler := handlers.NewReviewHandler(reviewService)
+	favouriteHandler := handlers.NewFavouriteHandler(favouriteService)
+	nutritionHandler := handlers.NewNutritionHandler(nutritionService)
+	recommendationHandler := handlers.NewRecommendationHandler(recommendationService)
+	uploadHandler := handlers.NewUploadHandler(mealImageService)
+	mealImageHandler := handlers.NewMealImageHandler(mealImageService)
+	translationHandler := handlers.NewMealTranslationHandler(translationService)
+	imageHandler := handlers.NewImageHandler(imageService)
+	bundleHandler := handlers.NewBundleHandler(bundleService)
+	priceHandler := handlers.NewMealPriceHandler(priceService)
+	roleHandler := handlers.NewRoleHandler(roleService)
+	userHandler := handlers.NewUserHandler(userService)
+	profileHandler := handlers.NewProfileHandler(profileService)
+	accountHandler := handlers.NewAccountHandler(accountService)
 
 	// Routes
 	api := r.Group("/api")
@@ -68,46 +147,109 @@ func SetupRouter(db *pgxpool.Pool, cfg *config.Config) *gin.Engine {
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/login/2fa", authHandler.LoginTwoFactor)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", requireAuth, authHandler.Logout)
+			auth.POST("/forgot-password", authHandler.ForgotPassword)
+			auth.POST("/reset-password", authHandler.ResetPassword)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/resend-verification", requireAuth, authHandler.ResendVerification)
+
+			// Sign in with external identity providers
+			auth.GET("/oidc", oidcHandler.ListProviders)
+			auth.GET("/oidc/:provider/login", oidcHandler.Login)
+			auth.GET("/oidc/:provider/callback", oidcHandler.Callback)
 		}
 
+		// Locally stored images
+		api.GET("/images/*key", imageHandler.Serve)
+
 		// Admin Upload Route
-		api.POST("/upload", middleware.AuthMiddleware(cfg), middleware.RequireAdmin(), uploadHandler.HandleImageUpload)
+		api.POST("/upload", requireAuth, can("meals:write"), uploadHandler.HandleImageUpload)
 
 		meals := api.Group("/meals")
 		{
 			// Public routes
 			meals.GET("", mealHandler.List)
 			meals.GET("/:id", mealHandler.GetByID)
+			meals.GET("/:id/reviews", reviewHandler.ListForMeal)
+			meals.GET("/:id/images", mealImageHandler.List)
+
+			// Verified buyers only
+			meals.POST("/:id/reviews", requireAuth, reviewHandler.Create)
 
 			// Admin-only routes
 			admin := meals.Group("")
-			admin.Use(middleware.AuthMiddleware(cfg), middleware.RequireAdmin())
+			admin.Use(requireAuth, can("meals:write"))
 			{
 				admin.POST("", mealHandler.Create)
 				admin.PUT("/:id", mealHandler.Update)
 				admin.DELETE("/:id", mealHandler.Delete)
+				admin.GET("/:id/versions", mealHandler.ListVersions)
+				admin.GET("/:id/versions/:version", mealHandler.GetVersion)
+				admin.POST("/:id/versions/:version/revert", mealHandler.RevertToVersion)
+				admin.GET("/:id/diff", mealHandler.DiffVersions)
+				admin.POST("/:id/variants", mealHandler.CreateVariant)
+				admin.PUT("/:id/variants/:variantId", mealHandler.UpdateVariant)
+				admin.DELETE("/:id/variants/:variantId", mealHandler.DeleteVariant)
+				admin.POST("/:id/images", mealImageHandler.Add)
+				admin.PUT("/:id/images/order", mealImageHandler.Reorder)
+				admin.PUT("/:id/images/:imageId", mealImageHandler.Update)
+				admin.DELETE("/:id/images/:imageId", mealImageHandler.Delete)
+				admin.GET("/:id/translations", translationHandler.List)
+				admin.PUT("/:id/translations/:lang", translationHandler.Set)
+				admin.DELETE("/:id/translations/:lang", translationHandler.Delete)
+				admin.GET("/:id/prices", priceHandler.List)
+				admin.POST("/:id/prices", priceHandler.Create)
+				admin.DELETE("/:id/prices/:priceId", priceHandler.Delete)
+			}
+		}
+
+		addons := api.Group("/addons")
+		{
+			// Public routes
+			addons.GET("", addonHandler.List)
+			addons.GET("/:id", addonHandler.GetByID)
+
+			// Admin-only routes
+			admin := addons.Group("")
+			admin.Use(requireAuth, can("meals:write"))
+			{
+				admin.POST("", addonHandler.Create)
+				admin.PUT("/:id", addonHandler.Update)
+				admin.DELETE("/:id", addonHandler.Delete)
 			}
 		}
 
 		// Cart routes (authenticated users only)
 		cart := api.Group("/cart")
-		cart.Use(middleware.AuthMiddleware(cfg))
+		cart.Use(requireAuth)
 		{
 			cart.GET("", cartHandler.GetCart)
 			cart.POST("/items", cartHandler.AddItem)
 			cart.PUT("/items/:id", cartHandler.UpdateItem)
 			cart.DELETE("/items/:id", cartHandler.RemoveItem)
 			cart.DELETE("", cartHandler.ClearCart)
+			cart.POST("/addons", cartHandler.AddAddon)
+			cart.PUT("/addons/:id", cartHandler.UpdateAddon)
+			cart.DELETE("/addons/:id", cartHandler.RemoveAddon)
+			cart.POST("/bundles", cartHandler.AddBundle)
+			cart.PUT("/bundles/:id", cartHandler.UpdateBundle)
+			cart.DELETE("/bundles/:id", cartHandler.RemoveBundle)
+			cart.POST("/build", cartBuilderHandler.BuildCart)
 		}
 
 		// Public menu route
-		api.GET("/menu", menuHandler.GetActiveMenu)
+		api.GET("/menu", optionalAuth, menuHandler.GetActiveMenu)
+
+		// Public bundles on this week's menu
+		api.GET("/bundles", optionalAuth, bundleHandler.ListActive)
 
-		// Admin - Weekly Menu Management
+		// Staff routes, each needing a permission
 		admin := api.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(cfg), middleware.RequireAdmin())
+		admin.Use(requireAuth)
 		{
-			weeklyMenus := admin.Group("/weekly-menus")
+			weeklyMenus := admin.Group("/weekly-menus", can("menus:write"))
 			{
 				weeklyMenus.POST("", menuHandler.Create)
 				weeklyMenus.GET("", menuHandler.List)
@@ -116,15 +258,102 @@ func SetupRouter(db *pgxpool.Pool, cfg *config.Config) *gin.Engine {
 				weeklyMenus.DELETE("/:id", menuHandler.Delete)
 				weeklyMenus.PUT("/:id/activate", menuHandler.Activate)
 			}
+
+			bundles := admin.Group("/bundles", can("menus:write"))
+			{
+				bundles.POST("", bundleHandler.Create)
+				bundles.GET("", bundleHandler.List)
+				bundles.GET("/:id", bundleHandler.GetByID)
+				bundles.PUT("/:id", bundleHandler.Update)
+				bundles.DELETE("/:id", bundleHandler.Delete)
+			}
+
+			admin.POST("/meals/import", can("meals:write"), mealHandler.Import)
+			admin.GET("/meals/export", can("meals:write"), mealHandler.Export)
+
+			admin.GET("/orders", can("orders:read"), orderHandler.List)
+			admin.GET("/orders/:id", can("orders:read"), orderHandler.GetForStaff)
+			admin.PUT("/orders/:id/status", can("orders:write"), orderHandler.UpdateStatus)
+			admin.GET("/production", can("production:read"), orderHandler.ProductionList)
+			admin.GET("/deliveries", can("deliveries:read"), orderHandler.Deliveries)
+
+			reviews := admin.Group("/reviews", can("reviews:moderate"))
+			{
+				reviews.GET("", reviewHandler.List)
+				reviews.PUT("/:id", reviewHandler.Moderate)
+				reviews.DELETE("/:id", reviewHandler.Delete)
+			}
+
+			admin.POST("/recommendations/refresh", can("menus:write"), recommendationHandler.Refresh)
+			admin.POST("/uploads/cleanup", can("meals:write"), uploadHandler.CleanupOrphans)
+
+			users := admin.Group("/users")
+			{
+				users.GET("", can("users:read"), userHandler.List)
+				users.POST("", can("users:write"), userHandler.Create)
+				users.GET("/:id", can("users:read"), userHandler.GetByID)
+				users.PUT("/:id/role", can("users:write"), userHandler.SetRole)
+				users.POST("/:id/disable", can("users:write"), userHandler.Disable)
+				users.POST("/:id/enable", can("users:write"), userHandler.Enable)
+				users.POST("/:id/force-password-reset", can("users:write"), userHandler.ForcePasswordReset)
+				users.PUT("/:id/verify-email", can("users:write"), authHandler.MarkEmailVerified)
+				users.POST("/:id/unlock", can("users:write"), authHandler.UnlockAccount)
+			}
+			admin.POST("/ips/:ip/unlock", can("users:write"), authHandler.UnlockIP)
+
+			roles := admin.Group("/roles", can("roles:write"))
+			{
+				roles.GET("", roleHandler.List)
+				roles.POST("", roleHandler.Create)
+				roles.GET("/:name", roleHandler.GetByName)
+				roles.PUT("/:name", roleHandler.Update)
+				roles.DELETE("/:name", roleHandler.Delete)
+			}
+			admin.GET("/permissions", can("roles:write"), roleHandler.ListPermissions)
 		}
 
 		// User orders (authenticated)
 		orders := api.Group("/orders")
-		orders.Use(middleware.AuthMiddleware(cfg))
+		orders.Use(requireAuth)
 		{
 			orders.POST("/checkout", orderHandler.Checkout)
 			orders.GET("", orderHandler.GetOrders)
 			orders.GET("/:id", orderHandler.GetByID)
+			orders.POST("/:id/reorder", orderHandler.Reorder)
+		}
+
+		// User favourites (authenticated)
+		favourites := api.Group("/favourites")
+		favourites.Use(requireAuth)
+		{
+			favourites.GET("", favouriteHandler.List)
+			favourites.POST("", favouriteHandler.Add)
+			favourites.DELETE("/:mealId", favouriteHandler.Remove)
+		}
+
+		// Current user's profile and settings (authenticated)
+		me := api.Group("/me")
+		me.Use(requireAuth)
+		{
+			me.GET("", profileHandler.Get)
+			me.PUT("", profileHandler.Update)
+			me.DELETE("", accountHandler.DeleteAccount)
+			me.POST("/delete-confirmation", accountHandler.RequestAccountDeletion)
+			me.POST("/email", authHandler.ChangeEmail)
+			me.GET("/nutrition-goals", nutritionHandler.GetGoals)
+			me.PUT("/nutrition-goals", nutritionHandler.SetGoals)
+			me.DELETE("/nutrition-goals", nutritionHandler.DeleteGoals)
+			me.GET("/dietary-preferences", nutritionHandler.GetDietaryPreferences)
+			me.PUT("/dietary-preferences", nutritionHandler.SetDietaryPreferences)
+			me.PUT("/password", authHandler.ChangePassword)
+			me.GET("/2fa", twoFactorHandler.Status)
+			me.POST("/2fa/setup", twoFactorHandler.Setup)
+			me.POST("/2fa/enable", twoFactorHandler.Enable)
+			me.POST("/2fa/disable", twoFactorHandler.Disable)
+			me.POST("/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+			me.POST("/data-exports", accountHandler.RequestDataExport)
+			me.GET("/data-exports", accountHandler.ListDataExports)
+			me.GET("/data-exports/:id/download", accountHandler.DownloadDataExport)
 		}
 	}
 
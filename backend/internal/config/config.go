@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,8 +17,80 @@ type Config struct {
 	CloudinaryCloudName string
 	CloudinaryAPIKey    string
 	CloudinaryAPISecret string
-	EmailAPIKey         string
-	EmailFromAddress    string
+	// ImageStorage is "local", "cloudinary" or "s3". Empty picks Cloudinary
+	// when its credentials are set and local disk otherwise.
+	ImageStorage    string
+	LocalStorageDir string
+	// PublicURL is the externally reachable base URL of this API, used for
+	// links to locally stored images
+	PublicURL   string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	// S3PublicURL is where stored objects are served from, e.g. a CDN.
+	// Defaults to the bucket URL.
+	S3PublicURL      string
+	EmailAPIKey      string
+	EmailFromAddress string
+	// DefaultLanguage is the language meals are written in; other languages
+	// are translations
+	DefaultLanguage string
+	// RecommendationInterval is how often meal recommendations are recomputed
+	RecommendationInterval time.Duration
+	// UploadCleanupInterval is how often orphaned uploads are deleted
+	UploadCleanupInterval time.Duration
+	// AccessTokenTTL is how long an access token is valid; clients use their
+	// refresh token to get a new one
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL is how long an unused refresh token stays valid
+	RefreshTokenTTL time.Duration
+	// FrontendURL is the base URL of the web app, used for links in emails
+	FrontendURL string
+	// PasswordResetTTL is how long a password reset link is valid
+	PasswordResetTTL time.Duration
+	// EmailVerificationTTL is how long an email verification link is valid
+	EmailVerificationTTL time.Duration
+	// RequireAdmin2FA stops staff (users whose role has permissions) using
+	// staff endpoints until they have signed in with two-factor
+	// authentication
+	RequireAdmin2FA bool
+	// OIDCProviders are the external identity providers users can sign in
+	// with
+	OIDCProviders []OIDCProvider
+	// LoginMaxAttempts is how many failed logins in a row lock an account
+	LoginMaxAttempts int
+	// LoginMaxAttemptsPerIP is how many failed logins lock out a client IP
+	LoginMaxAttemptsPerIP int
+	// LoginLockoutDuration is how long a lockout lasts
+	LoginLockoutDuration time.Duration
+	// DataExportTTL is how long a "download my data" export can be
+	// downloaded before it is deleted
+	DataExportTTL time.Duration
+	// AccountDeletionTTL is how long an emailed account deletion link is
+	// valid
+	AccountDeletionTTL time.Duration
+	// MealsPerDay is how many meals make up a day. Nutrition is averaged
+	// over the days a cart's meals cover, and weekly goals are spread over
+	// the MaxCartItems / MealsPerDay days of a full cart, however full the
+	// cart is.
+	MealsPerDay int
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies
+	// in front of the API. Only they may set the client IP with
+	// X-Forwarded-For; with none, the client IP is the connection's address.
+	TrustedProxies []string
+}
+
+// OIDCProvider is an OpenID Connect identity provider, configured with
+// OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET and
+// optionally OIDC_<NAME>_SCOPES for each name listed in OIDC_PROVIDERS.
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
 }
 
 func LoadConfig() *Config {
@@ -24,15 +99,43 @@ func LoadConfig() *Config {
 		log.Println("No .env file found, relying on environment variables")
 	}
 
+	port := getEnv("PORT", "8080")
+
 	return &Config{
-		Port:                getEnv("PORT", "8080"),
-		DBUrl:               getEnv("DATABASE_URL", ""),
-		JWTSecret:           getEnv("JWT_SECRET", "secret"),
-		CloudinaryCloudName: getEnv("CLOUDINARY_CLOUD_NAME", ""),
-		CloudinaryAPIKey:    getEnv("CLOUDINARY_API_KEY", ""),
-		CloudinaryAPISecret: getEnv("CLOUDINARY_API_SECRET", ""),
-		EmailAPIKey:         getEnv("EMAIL_API_KEY", ""),
-		EmailFromAddress:    getEnv("EMAIL_FROM_ADDRESS", "orders@preptoplate.com"),
+		Port:                   port,
+		DBUrl:                  getEnv("DATABASE_URL", ""),
+		JWTSecret:              getEnv("JWT_SECRET", "secret"),
+		CloudinaryCloudName:    getEnv("CLOUDINARY_CLOUD_NAME", ""),
+		CloudinaryAPIKey:       getEnv("CLOUDINARY_API_KEY", ""),
+		CloudinaryAPISecret:    getEnv("CLOUDINARY_API_SECRET", ""),
+		ImageStorage:           getEnv("IMAGE_STORAGE", ""),
+		LocalStorageDir:        getEnv("LOCAL_STORAGE_DIR", "./uploads"),
+		PublicURL:              strings.TrimSuffix(getEnv("PUBLIC_URL", "http://localhost:"+port), "/"),
+		S3Endpoint:             getEnv("S3_ENDPOINT", ""),
+		S3Region:               getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:               getEnv("S3_BUCKET", ""),
+		S3AccessKey:            getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:            getEnv("S3_SECRET_KEY", ""),
+		S3PublicURL:            getEnv("S3_PUBLIC_URL", ""),
+		EmailAPIKey:            getEnv("EMAIL_API_KEY", ""),
+		EmailFromAddress:       getEnv("EMAIL_FROM_ADDRESS", "orders@preptoplate.com"),
+		DefaultLanguage:        getEnv("DEFAULT_LANGUAGE", "en"),
+		RecommendationInterval: getDuration("RECOMMENDATION_INTERVAL", time.Hour),
+		UploadCleanupInterval:  getDuration("UPLOAD_CLEANUP_INTERVAL", 24*time.Hour),
+		AccessTokenTTL:         getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:        getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		FrontendURL:            strings.TrimSuffix(getEnv("FRONTEND_URL", "http://localhost:5173"), "/"),
+		PasswordResetTTL:       getDuration("PASSWORD_RESET_TTL", time.Hour),
+		EmailVerificationTTL:   getDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		RequireAdmin2FA:        getEnv("REQUIRE_ADMIN_2FA", "true") == "true",
+		OIDCProviders:          getOIDCProviders(),
+		LoginMaxAttempts:       getInt("LOGIN_MAX_ATTEMPTS", 10),
+		LoginMaxAttemptsPerIP:  getInt("LOGIN_MAX_ATTEMPTS_PER_IP", 50),
+		LoginLockoutDuration:   getDuration("LOGIN_LOCKOUT_DURATION", 30*time.Minute),
+		DataExportTTL:          getDuration("DATA_EXPORT_TTL", 7*24*time.Hour),
+		AccountDeletionTTL:     getDuration("ACCOUNT_DELETION_TTL", time.Hour),
+		MealsPerDay:            getInt("MEALS_PER_DAY", 2),
+		TrustedProxies:         getList("TRUSTED_PROXIES"),
 	}
 }
 
@@ -42,3 +145,72 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getDuration parses a duration such as "30m", falling back on a missing or
+// invalid value.
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// getInt parses a positive integer, falling back on a missing or invalid
+// value.
+func getInt(key string, fallback int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Invalid %s %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// getList reads a comma-separated list, e.g. "10.0.0.0/8, 192.168.1.2",
+// leaving out empty entries.
+func getList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getOIDCProviders reads the providers listed in OIDC_PROVIDERS, e.g.
+// "google,okta". Providers missing an issuer or client ID are skipped.
+func getOIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OIDCProvider{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Printf("OIDC provider %q needs %sISSUER and %sCLIENT_ID, skipping", name, prefix, prefix)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -46,3 +47,72 @@ func TestGetEnv(t *testing.T) {
 		t.Errorf("Expected 'fallback', got '%s'", result)
 	}
 }
+
+func TestGetDuration(t *testing.T) {
+	os.Setenv("TEST_DURATION", "15m")
+	if d := getDuration("TEST_DURATION", time.Hour); d != 15*time.Minute {
+		t.Errorf("Expected 15m, got %s", d)
+	}
+
+	// Invalid values fall back
+	os.Setenv("TEST_DURATION", "soon")
+	if d := getDuration("TEST_DURATION", time.Hour); d != time.Hour {
+		t.Errorf("Expected fallback 1h, got %s", d)
+	}
+	os.Unsetenv("TEST_DURATION")
+
+	if d := getDuration("NON_EXISTENT_VAR", time.Hour); d != time.Hour {
+		t.Errorf("Expected fallback 1h, got %s", d)
+	}
+}
+
+func TestGetInt(t *testing.T) {
+	os.Setenv("TEST_INT", "25")
+	if n := getInt("TEST_INT", 10); n != 25 {
+		t.Errorf("Expected 25, got %d", n)
+	}
+
+	// Invalid values fall back
+	os.Setenv("TEST_INT", "-1")
+	if n := getInt("TEST_INT", 10); n != 10 {
+		t.Errorf("Expected fallback 10, got %d", n)
+	}
+	os.Unsetenv("TEST_INT")
+}
+
+func TestGetList(t *testing.T) {
+	os.Setenv("TEST_LIST", "10.0.0.0/8, ,192.168.1.2 ")
+	defer os.Unsetenv("TEST_LIST")
+
+	list := getList("TEST_LIST")
+	if len(list) != 2 || list[0] != "10.0.0.0/8" || list[1] != "192.168.1.2" {
+		t.Errorf("Expected [10.0.0.0/8 192.168.1.2], got %v", list)
+	}
+	if list := getList("TEST_UNSET_LIST"); len(list) != 0 {
+		t.Errorf("Expected an empty list, got %v", list)
+	}
+}
+
+func TestGetOIDCProviders(t *testing.T) {
+	os.Setenv("OIDC_PROVIDERS", "Google, incomplete")
+	os.Setenv("OIDC_GOOGLE_ISSUER", "https://accounts.google.com")
+	os.Setenv("OIDC_GOOGLE_CLIENT_ID", "client-id")
+	os.Setenv("OIDC_INCOMPLETE_ISSUER", "https://idp.example.com")
+	defer func() {
+		for _, key := range []string{"OIDC_PROVIDERS", "OIDC_GOOGLE_ISSUER", "OIDC_GOOGLE_CLIENT_ID", "OIDC_INCOMPLETE_ISSUER"} {
+			os.Unsetenv(key)
+		}
+	}()
+
+	providers := getOIDCProviders()
+	if len(providers) != 1 {
+		t.Fatalf("Expected only the complete provider, got %+v", providers)
+	}
+	p := providers[0]
+	if p.Name != "google" || p.Issuer != "https://accounts.google.com" || p.ClientID != "client-id" {
+		t.Errorf("Unexpected provider %+v", p)
+	}
+	if len(p.Scopes) != 3 || p.Scopes[0] != "openid" {
+		t.Errorf("Expected default scopes, got %v", p.Scopes)
+	}
+}
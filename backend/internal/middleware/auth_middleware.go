@@ -1,16 +1,26 @@
 package middleware
 
 import (
+	"context"
+	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jopari/preptoplate/internal/config"
+	"github.com/jopari/preptoplate/internal/models"
 	"github.com/jopari/preptoplate/internal/utils"
 )
 
-// AuthMiddleware extracts and validates JWT token, sets user_id and role in context
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// SessionStore looks up active login sessions, so that access tokens stop
+// working as soon as their session is revoked or the user is disabled. It
+// returns nil for a revoked session.
+type SessionStore interface {
+	GetActive(ctx context.Context, sessionID, userID int) (*models.Session, error)
+}
+
+// AuthMiddleware extracts and validates JWT token, sets user_id, session_id, role and two_factor in context
+func AuthMiddleware(cfg *config.Config, sessions SessionStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -19,45 +29,92 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization format"})
+		if status, message := authenticate(c, cfg, sessions, authHeader); status != 0 {
+			c.JSON(status, gin.H{"error": message})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		token := parts[1]
-		claims, err := utils.ValidateToken(token, cfg.JWTSecret)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
+// OptionalAuth sets user_id and role when a valid token is sent. Anonymous
+// requests and requests with an invalid, expired or revoked token are let
+// through without a user, so public endpoints keep working for signed-out
+// clients holding a stale token.
+func OptionalAuth(cfg *config.Config, sessions SessionStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
 			return
 		}
 
-		// Set user context
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
-			c.Abort()
-			return
-		}
+		authenticate(c, cfg, sessions, authHeader)
+		c.Next()
+	}
+}
 
-		role, ok := claims["role"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
-			c.Abort()
-			return
-		}
+// authenticate validates the bearer token and sets the user context. If the
+// token is not valid it returns the status and error to respond with.
+func authenticate(c *gin.Context, cfg *config.Config, sessions SessionStore, authHeader string) (int, string) {
+	// Extract token from "Bearer <token>"
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return http.StatusUnauthorized, "invalid authorization format"
+	}
 
-		c.Set("user_id", int(userID))
-		c.Set("role", role)
-		c.Next()
+	token := parts[1]
+	claims, err := utils.ValidateToken(token, cfg.JWTSecret)
+	if err != nil {
+		return http.StatusUnauthorized, "invalid token"
 	}
+
+	// Set user context
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return http.StatusUnauthorized, "invalid token claims"
+	}
+
+	if _, ok := claims["role"].(string); !ok {
+		return http.StatusUnauthorized, "invalid token claims"
+	}
+
+	sessionID, ok := claims["sid"].(float64)
+	if !ok {
+		return http.StatusUnauthorized, "invalid token claims"
+	}
+
+	session, err := sessions.GetActive(c.Request.Context(), int(sessionID), int(userID))
+	if err != nil {
+		log.Printf("Failed to check session %d: %v", int(sessionID), err)
+		return http.StatusInternalServerError, "could not verify session"
+	}
+	if session == nil {
+		return http.StatusUnauthorized, "session has been revoked"
+	}
+	if session.UserDisabled {
+		return http.StatusForbidden, "account has been disabled"
+	}
+
+	// The role comes from the database rather than the token, so role
+	// changes take effect straight away
+	c.Set("user_id", int(userID))
+	c.Set("session_id", int(sessionID))
+	c.Set("role", session.Role)
+	c.Set("two_factor", session.TwoFactor)
+	return 0, ""
 }
 
-// RequireAdmin checks if the authenticated user has admin role
-func RequireAdmin() gin.HandlerFunc {
+// PermissionStore looks up what a role may do.
+type PermissionStore interface {
+	HasPermission(ctx context.Context, role, permission string) (bool, error)
+}
+
+// RequirePermission checks that the authenticated user's role has the
+// permission, e.g. "orders:read". With requireTwoFactor the session must
+// also have been signed in with a second factor.
+func RequirePermission(permissions PermissionStore, permission string, requireTwoFactor bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
 		if !exists {
@@ -66,8 +123,21 @@ func RequireAdmin() gin.HandlerFunc {
 			return
 		}
 
-		if role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+		ok, err := permissions.HasPermission(c.Request.Context(), role.(string), permission)
+		if err != nil {
+			log.Printf("Failed to check permission %s for role %s: %v", permission, role, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not check permissions"})
+			c.Abort()
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission required: " + permission})
+			c.Abort()
+			return
+		}
+
+		if requireTwoFactor && !c.GetBool("two_factor") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "two-factor authentication required for staff access"})
 			c.Abort()
 			return
 		}
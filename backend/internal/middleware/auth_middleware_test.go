@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jopari/preptoplate/internal/config"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/utils"
+)
+
+// fakeSessions holds the active sessions by ID.
+type fakeSessions map[int]*models.Session
+
+func (f fakeSessions) GetActive(ctx context.Context, sessionID, userID int) (*models.Session, error) {
+	session := f[sessionID]
+	if session == nil || session.UserID != userID {
+		return nil, nil
+	}
+	return session, nil
+}
+
+func testToken(t *testing.T, cfg *config.Config, sessionID int, ttl time.Duration) string {
+	t.Helper()
+	token, err := utils.GenerateToken(7, sessionID, "customer", cfg.JWTSecret, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+func TestOptionalAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	sessions := fakeSessions{1: {ID: 1, UserID: 7, Role: "customer"}}
+
+	r := gin.New()
+	r.GET("/menu", OptionalAuth(cfg, sessions), func(c *gin.Context) {
+		c.String(http.StatusOK, "%d", c.GetInt("user_id"))
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		user   string
+	}{
+		{"anonymous", "", "0"},
+		{"valid token", testToken(t, cfg, 1, time.Minute), "7"},
+		{"expired token", testToken(t, cfg, 1, -time.Minute), "0"},
+		{"revoked session", testToken(t, cfg, 2, time.Minute), "0"},
+		{"malformed header", "Token abc", "0"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/menu", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d", tt.name, w.Code)
+		}
+		if w.Body.String() != tt.user {
+			t.Errorf("%s: expected user %s, got %s", tt.name, tt.user, w.Body.String())
+		}
+	}
+}
+
+func TestAuthMiddlewareRejectsInvalidToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	sessions := fakeSessions{1: {ID: 1, UserID: 7, Role: "customer"}}
+
+	r := gin.New()
+	r.GET("/me", AuthMiddleware(cfg, sessions), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, header := range []string{"", testToken(t, cfg, 1, -time.Minute), testToken(t, cfg, 2, time.Minute)} {
+		req := httptest.NewRequest("GET", "/me", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", w.Code)
+		}
+	}
+}
+
+// fakePermissions holds the permissions of each role.
+type fakePermissions map[string][]string
+
+func (f fakePermissions) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	for _, p := range f[role] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	sessions := fakeSessions{
+		1: {ID: 1, UserID: 7, Role: "kitchen", TwoFactor: true},
+		2: {ID: 2, UserID: 7, Role: "driver", TwoFactor: true},
+		3: {ID: 3, UserID: 7, Role: "support", TwoFactor: true},
+		4: {ID: 4, UserID: 7, Role: "support"},
+		5: {ID: 5, UserID: 7, Role: "customer"},
+	}
+	permissions := fakePermissions{
+		"kitchen": {"production:read"},
+		"driver":  {"deliveries:read"},
+		"support": {"orders:read"},
+	}
+
+	r := gin.New()
+	for _, permission := range []string{"production:read", "deliveries:read", "orders:read", "orders:write"} {
+		r.GET("/"+strings.ReplaceAll(permission, ":", "/"), AuthMiddleware(cfg, sessions), RequirePermission(permissions, permission, true), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+	}
+
+	tests := []struct {
+		name       string
+		sessionID  int
+		permission string
+		want       int
+	}{
+		{"kitchen production", 1, "production:read", http.StatusOK},
+		{"kitchen deliveries", 1, "deliveries:read", http.StatusForbidden},
+		{"kitchen orders", 1, "orders:read", http.StatusForbidden},
+		{"driver deliveries", 2, "deliveries:read", http.StatusOK},
+		{"driver production", 2, "production:read", http.StatusForbidden},
+		{"driver orders", 2, "orders:read", http.StatusForbidden},
+		{"support orders", 3, "orders:read", http.StatusOK},
+		{"support changing orders", 3, "orders:write", http.StatusForbidden},
+		{"support deliveries", 3, "deliveries:read", http.StatusForbidden},
+		{"support without two-factor", 4, "orders:read", http.StatusForbidden},
+		{"customer", 5, "orders:read", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/"+strings.ReplaceAll(tt.permission, ":", "/"), nil)
+		req.Header.Set("Authorization", testToken(t, cfg, tt.sessionID, time.Minute))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+}
+
+func TestRequirePermissionWithoutTwoFactorRequirement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	sessions := fakeSessions{1: {ID: 1, UserID: 7, Role: "support"}}
+
+	r := gin.New()
+	r.GET("/orders", AuthMiddleware(cfg, sessions), RequirePermission(fakePermissions{"support": {"orders:read"}}, "orders:read", false), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/orders", nil)
+	req.Header.Set("Authorization", testToken(t, cfg, 1, time.Minute))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 when two-factor is not required, got %d", w.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Addon is an extra (snack, drink, sauce) sold alongside meals. Add-ons do
+// not count toward the cart's meal allowance.
+type Addon struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	Category    string `json:"category"` // e.g., "snack", "drink", "sauce"
+	Calories    int    `json:"calories"`
+	Protein     int    `json:"protein"`
+	Carbs       int    `json:"carbs"`
+	Fat         int    `json:"fat"`
+	Price       int    `json:"price"` // stored in cents
+}
+
+type CreateAddonRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	Category    string `json:"category" binding:"required,oneof=snack drink sauce"`
+	Calories    int    `json:"calories"`
+	Protein     int    `json:"protein"`
+	Carbs       int    `json:"carbs"`
+	Fat         int    `json:"fat"`
+	Price       int    `json:"price" binding:"required"`
+}
+
+type UpdateAddonRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	ImageURL    *string `json:"image_url"`
+	Category    *string `json:"category" binding:"omitempty,oneof=snack drink sauce"`
+	Calories    *int    `json:"calories"`
+	Protein     *int    `json:"protein"`
+	Carbs       *int    `json:"carbs"`
+	Fat         *int    `json:"fat"`
+	Price       *int    `json:"price"`
+}
+
+type WeeklyMenuAddon struct {
+	Addon          Addon `json:"addon"`
+	InitialStock   int   `json:"initial_stock"`
+	AvailableStock int   `json:"available_stock"`
+}
+
+type MenuAddonInput struct {
+	AddonID int `json:"addon_id" binding:"required"`
+	Stock   int `json:"stock" binding:"required,min=1"`
+}
+
+type CartAddon struct {
+	ID        int       `json:"id"`
+	CartID    int       `json:"-"`
+	Addon     Addon     `json:"addon"`
+	AddonID   int       `json:"-"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AddAddonToCartRequest struct {
+	AddonID  int `json:"addon_id" binding:"required"`
+	Quantity int `json:"quantity" binding:"required,min=1"`
+}
+
+type OrderAddon struct {
+	OrderID  int   `json:"-"`
+	AddonID  int   `json:"-"`
+	Addon    Addon `json:"addon"`
+	Quantity int   `json:"quantity"`
+	Price    int   `json:"price"` // Price at time of order (in cents)
+}
@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// Bundle is a fixed set of meals from one weekly menu sold together at a
+// bundle price, e.g. a "High Protein 10-pack".
+type Bundle struct {
+	ID             int          `json:"id"`
+	MenuID         int          `json:"menu_id"`
+	Name           string       `json:"name"`
+	Description    string       `json:"description"`
+	ImageURL       string       `json:"image_url"`
+	Price          int          `json:"price"` // bundle price, in cents
+	IsActive       bool         `json:"is_active"`
+	Items          []BundleItem `json:"items"`
+	TotalItems     int          `json:"total_items"`     // meals per bundle
+	RegularPrice   int          `json:"regular_price"`   // the meals bought separately, in cents
+	Discount       int          `json:"discount"`        // RegularPrice - Price
+	AvailableStock int          `json:"available_stock"` // bundles the menu's stock can still fill
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+type BundleItem struct {
+	MealID         int          `json:"-"`
+	Meal           Meal         `json:"meal"`
+	VariantID      *int         `json:"-"`
+	Variant        *MealVariant `json:"variant,omitempty"`
+	Quantity       int          `json:"quantity"`
+	UnitPrice      int          `json:"unit_price"` // regular price, in cents
+	AvailableStock int          `json:"-"`
+}
+
+type BundleItemInput struct {
+	MealID    int  `json:"meal_id" binding:"required"`
+	VariantID *int `json:"variant_id"` // omit for the standard portion
+	Quantity  int  `json:"quantity" binding:"required,min=1"`
+}
+
+type CreateBundleRequest struct {
+	MenuID      int               `json:"menu_id" binding:"required"`
+	Name        string            `json:"name" binding:"required"`
+	Description string            `json:"description"`
+	ImageURL    string            `json:"image_url"`
+	Price       int               `json:"price" binding:"required,min=1"`
+	IsActive    *bool             `json:"is_active"` // defaults to true
+	Items       []BundleItemInput `json:"items" binding:"required,min=1,dive"`
+}
+
+type UpdateBundleRequest struct {
+	Name        *string           `json:"name"`
+	Description *string           `json:"description"`
+	ImageURL    *string           `json:"image_url"`
+	Price       *int              `json:"price" binding:"omitempty,min=1"`
+	IsActive    *bool             `json:"is_active"`
+	Items       []BundleItemInput `json:"items" binding:"omitempty,min=1,dive"` // nil leaves the items unchanged
+}
+
+// CartBundle is a bundle in the cart. Its meals count toward MaxCartItems.
+type CartBundle struct {
+	ID        int       `json:"id"`
+	CartID    int       `json:"-"`
+	Bundle    Bundle    `json:"bundle"`
+	BundleID  int       `json:"-"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AddBundleToCartRequest struct {
+	BundleID int `json:"bundle_id" binding:"required"`
+	Quantity int `json:"quantity" binding:"required,min=1"`
+}
+
+// OrderBundle records a bundle bought with an order. The bundle's meals are
+// among the order's items, tagged with its ID.
+type OrderBundle struct {
+	OrderID      int    `json:"-"`
+	BundleID     int    `json:"bundle_id"`
+	Name         string `json:"name"`
+	Quantity     int    `json:"quantity"`
+	Price        int    `json:"price"`         // bundle price at time of order, in cents
+	RegularPrice int    `json:"regular_price"` // the meals' regular price at time of order, in cents
+	Discount     int    `json:"discount"`      // per bundle, in cents
+}
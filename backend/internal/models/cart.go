@@ -3,27 +3,36 @@ package models
 import "time"
 
 type Cart struct {
-	ID         int        `json:"id"`
-	UserID     int        `json:"user_id"`
-	Items      []CartItem `json:"items"`
-	TotalItems int        `json:"total_items"`
-	TotalPrice int        `json:"total_price"` // in cents
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
+	ID          int               `json:"id"`
+	UserID      int               `json:"user_id"`
+	Items       []CartItem        `json:"items"`
+	Addons      []CartAddon       `json:"addons"`
+	Bundles     []CartBundle      `json:"bundles"`
+	TotalItems  int               `json:"total_items"`  // meals, including those in bundles; counts toward MaxCartItems
+	TotalAddons int               `json:"total_addons"` // add-ons, outside the meal allowance
+	TotalPrice  int               `json:"total_price"`  // meals, bundles and add-ons, in cents
+	Discount    int               `json:"discount"`     // saved by buying bundles, in cents
+	Nutrition   *NutritionSummary `json:"nutrition,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
 }
 
 type CartItem struct {
-	ID        int       `json:"id"`
-	CartID    int       `json:"-"`
-	Meal      Meal      `json:"meal"`
-	MealID    int       `json:"-"`
-	Quantity  int       `json:"quantity"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int          `json:"id"`
+	CartID    int          `json:"-"`
+	Meal      Meal         `json:"meal"`
+	MealID    int          `json:"-"`
+	Variant   *MealVariant `json:"variant,omitempty"`
+	VariantID *int         `json:"-"`
+	Quantity  int          `json:"quantity"`
+	UnitPrice int          `json:"unit_price"` // variant price if set, otherwise meal price (in cents)
+	CreatedAt time.Time    `json:"created_at"`
 }
 
 type AddToCartRequest struct {
-	MealID   int `json:"meal_id" binding:"required"`
-	Quantity int `json:"quantity" binding:"required,min=1"`
+	MealID    int  `json:"meal_id" binding:"required"`
+	VariantID *int `json:"variant_id"` // omit for the standard portion
+	Quantity  int  `json:"quantity" binding:"required,min=1"`
 }
 
 type UpdateCartItemRequest struct {
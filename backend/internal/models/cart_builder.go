@@ -0,0 +1,26 @@
+package models
+
+// BuildCartRequest asks for a proposed cart from the active menu. Targets are
+// per day; when omitted the user's saved nutrition goals are used.
+type BuildCartRequest struct {
+	Targets             *NutritionTotals `json:"targets"`
+	DietaryRestrictions []string         `json:"dietary_restrictions"`   // meals must carry every tag; defaults to the user's dietary preferences
+	Budget              int              `json:"budget" binding:"min=0"` // in cents, 0 for no limit
+	Seed                int64            `json:"seed"`
+}
+
+type ProposedCartItem struct {
+	Meal      Meal         `json:"meal"`
+	Variant   *MealVariant `json:"variant,omitempty"`
+	Quantity  int          `json:"quantity"`
+	UnitPrice int          `json:"unit_price"` // in cents
+}
+
+type CartProposal struct {
+	Items      []ProposedCartItem `json:"items"`
+	TotalItems int                `json:"total_items"`
+	TotalPrice int                `json:"total_price"` // in cents
+	Nutrition  *NutritionSummary  `json:"nutrition"`
+	Score      float64            `json:"score"` // squared relative distance from the targets, lower is better
+	Seed       int64              `json:"seed"`
+}
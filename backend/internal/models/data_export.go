@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// DataExport is a "download my data" request. It is prepared in the
+// background and can be downloaded once ready, until it expires.
+type DataExport struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"-"`
+	Status      string     `json:"status"` // "pending", "ready" or "failed"
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// AccountData is everything held about a user, as included in their data
+// export. Each field becomes a file in the ZIP download.
+type AccountData struct {
+	Profile        *Profile        `json:"profile"`
+	Orders         []Order         `json:"orders"`
+	Cart           *Cart           `json:"cart"`
+	Reviews        []Review        `json:"reviews"`
+	Favourites     []Favourite     `json:"favourites"`
+	NutritionGoals *NutritionGoals `json:"nutrition_goals"`
+}
+
+// DeleteAccountRequest confirms account deletion with the user's password,
+// or with the token from an emailed confirmation link.
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required_without=Token"`
+	Token    string `json:"token"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type Favourite struct {
+	Meal      Meal      `json:"meal"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AddFavouriteRequest struct {
+	MealID int `json:"meal_id" binding:"required"`
+}
@@ -1,35 +1,50 @@
 package models
 
 type Meal struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	ImageURL    string `json:"image_url"`
-	Calories    int    `json:"calories"`
-	Protein     int    `json:"protein"`
-	Carbs       int    `json:"carbs"`
-	Fat         int    `json:"fat"`
-	Price       int    `json:"price"` // stored in cents
+	ID            int           `json:"id"`
+	SKU           string        `json:"sku"`
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	ImageURL      string        `json:"image_url"`
+	Calories      int           `json:"calories"`
+	Protein       int           `json:"protein"`
+	Carbs         int           `json:"carbs"`
+	Fat           int           `json:"fat"`
+	Price         int           `json:"price"`                    // stored in cents
+	StandardPrice int           `json:"standard_price,omitempty"` // set when Price comes from the customer's plan
+	Version       int           `json:"version"`
+	DietaryTags   []string      `json:"dietary_tags"` // e.g. "vegetarian", "gluten_free"
+	Ingredients   []string      `json:"ingredients"`
+	Language      string        `json:"language,omitempty"` // language of the name, description and ingredients
+	Images        []MealImage   `json:"images,omitempty"`   // ordered gallery
+	Variants      []MealVariant `json:"variants,omitempty"`
+	Rating        *MealRating   `json:"rating,omitempty"`
 }
 
 type CreateMealRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	ImageURL    string `json:"image_url"`
-	Calories    int    `json:"calories"`
-	Protein     int    `json:"protein"`
-	Carbs       int    `json:"carbs"`
-	Fat         int    `json:"fat"`
-	Price       int    `json:"price" binding:"required"`
+	SKU         string   `json:"sku" binding:"max=64"` // generated from the ID when empty
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	ImageURL    string   `json:"image_url"`
+	Calories    int      `json:"calories"`
+	Protein     int      `json:"protein"`
+	Carbs       int      `json:"carbs"`
+	Fat         int      `json:"fat"`
+	Price       int      `json:"price" binding:"required"`
+	DietaryTags []string `json:"dietary_tags"`
+	Ingredients []string `json:"ingredients"`
 }
 
 type UpdateMealRequest struct {
-	Name        *string `json:"name"`
-	Description *string `json:"description"`
-	ImageURL    *string `json:"image_url"`
-	Calories    *int    `json:"calories"`
-	Protein     *int    `json:"protein"`
-	Carbs       *int    `json:"carbs"`
-	Fat         *int    `json:"fat"`
-	Price       *int    `json:"price"`
+	SKU         *string  `json:"sku" binding:"omitempty,min=1,max=64"`
+	Name        *string  `json:"name"`
+	Description *string  `json:"description"`
+	ImageURL    *string  `json:"image_url"`
+	Calories    *int     `json:"calories"`
+	Protein     *int     `json:"protein"`
+	Carbs       *int     `json:"carbs"`
+	Fat         *int     `json:"fat"`
+	Price       *int     `json:"price"`
+	DietaryTags []string `json:"dietary_tags"` // nil leaves the tags unchanged, [] clears them
+	Ingredients []string `json:"ingredients"`  // nil leaves the ingredients unchanged, [] clears them
 }
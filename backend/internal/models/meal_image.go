@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Upload is a file stored through the image service. URL is its largest
+// variant in a widely supported format.
+type Upload struct {
+	ID         int            `json:"id"`
+	URL        string         `json:"url"`
+	StorageKey string         `json:"-"`
+	Filename   string         `json:"filename"`
+	Variants   []ImageVariant `json:"variants"`
+	UploadedBy *int           `json:"-"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// ImageVariant is one resized rendition of an upload, for use in srcset.
+type ImageVariant struct {
+	Name        string `json:"name"`         // "thumbnail", "card" or "hero"
+	ContentType string `json:"content_type"` // e.g., "image/webp"
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	URL         string `json:"url"`
+	StorageKey  string `json:"-"`
+}
+
+// MealImage is one image in a meal's gallery. The primary image is also the
+// meal's ImageURL.
+type MealImage struct {
+	ID        int    `json:"id"`
+	MealID    int    `json:"meal_id"`
+	UploadID  int    `json:"upload_id"`
+	URL       string `json:"url"`
+	AltText   string `json:"alt_text"`
+	Position  int    `json:"position"`
+	IsPrimary bool   `json:"is_primary"`
+
+	Variants []ImageVariant `json:"variants"`
+}
+
+type AddMealImageRequest struct {
+	UploadID  int    `json:"upload_id" binding:"required"`
+	AltText   string `json:"alt_text"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+type UpdateMealImageRequest struct {
+	AltText   *string `json:"alt_text"`
+	IsPrimary *bool   `json:"is_primary"` // only true is accepted; pick another image to change the primary
+}
+
+type ReorderMealImagesRequest struct {
+	ImageIDs []int `json:"image_ids" binding:"required"`
+}
+
+// UploadResult is returned by the upload endpoint. Image is set when the
+// upload was attached to a meal.
+type UploadResult struct {
+	URL      string         `json:"url"`
+	UploadID int            `json:"upload_id"`
+	Variants []ImageVariant `json:"variants"`
+	Image    *MealImage     `json:"image,omitempty"`
+}
@@ -0,0 +1,35 @@
+package models
+
+// MealRecord is the flat form of a meal used for bulk import and export. In
+// CSV files dietary tags are separated by "|".
+type MealRecord struct {
+	SKU         string   `json:"sku"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	ImageURL    string   `json:"image_url"`
+	Calories    int      `json:"calories"`
+	Protein     int      `json:"protein"`
+	Carbs       int      `json:"carbs"`
+	Fat         int      `json:"fat"`
+	Price       int      `json:"price"` // in cents
+	DietaryTags []string `json:"dietary_tags"`
+}
+
+// MealImportError points at a problem in an import file. Rows are numbered as
+// a spreadsheet shows them (the CSV header is row 1); JSON records start at 1.
+// Row 0 means the file as a whole.
+type MealImportError struct {
+	Row     int    `json:"row"`
+	SKU     string `json:"sku,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type MealImportReport struct {
+	DryRun    bool              `json:"dry_run"`
+	Total     int               `json:"total"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Errors    []MealImportError `json:"errors"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// MealPrice is an entry in a price list: the price of a meal, or one of its
+// variants, from EffectiveFrom until a later entry in the same list takes over.
+type MealPrice struct {
+	ID            int       `json:"id"`
+	MealID        int       `json:"meal_id"`
+	VariantID     *int      `json:"variant_id,omitempty"`
+	PlanType      *string   `json:"plan_type,omitempty"` // nil for the standard price list
+	Price         int       `json:"price"`               // in cents
+	EffectiveFrom time.Time `json:"effective_from"`
+	CreatedBy     *int      `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type CreateMealPriceRequest struct {
+	VariantID     *int       `json:"variant_id"`                                 // omit for the standard portion
+	PlanType      *string    `json:"plan_type" binding:"omitempty,min=1,max=50"` // omit for the standard price list
+	Price         int        `json:"price" binding:"required,min=1"`             // in cents
+	EffectiveFrom *time.Time `json:"effective_from"`                             // defaults to now
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// MealTranslation holds a meal's text in a language other than the default
+// one. Empty fields fall back to the default language. Translations are not
+// versioned: they replace the text of whichever meal version is shown,
+// including the version pinned on a weekly menu.
+type MealTranslation struct {
+	MealID      int       `json:"meal_id"`
+	Language    string    `json:"language"` // e.g., "fr" or "pt-br"
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Ingredients []string  `json:"ingredients"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SetMealTranslationRequest struct {
+	Name        string   `json:"name" binding:"required,max=100"`
+	Description string   `json:"description"`
+	Ingredients []string `json:"ingredients"`
+}
@@ -0,0 +1,34 @@
+package models
+
+// MealVariant is an alternative portion of a meal (e.g. "Large") with its
+// own price and nutrition. Cart and order items without a variant use the
+// meal's standard portion.
+type MealVariant struct {
+	ID            int    `json:"id"`
+	MealID        int    `json:"meal_id"`
+	Name          string `json:"name"`
+	Calories      int    `json:"calories"`
+	Protein       int    `json:"protein"`
+	Carbs         int    `json:"carbs"`
+	Fat           int    `json:"fat"`
+	Price         int    `json:"price"`                    // stored in cents
+	StandardPrice int    `json:"standard_price,omitempty"` // set when Price comes from the customer's plan
+}
+
+type CreateMealVariantRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Calories int    `json:"calories"`
+	Protein  int    `json:"protein"`
+	Carbs    int    `json:"carbs"`
+	Fat      int    `json:"fat"`
+	Price    int    `json:"price" binding:"required"`
+}
+
+type UpdateMealVariantRequest struct {
+	Name     *string `json:"name"`
+	Calories *int    `json:"calories"`
+	Protein  *int    `json:"protein"`
+	Carbs    *int    `json:"carbs"`
+	Fat      *int    `json:"fat"`
+	Price    *int    `json:"price"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// MealVersion is an immutable snapshot of a meal's recipe, macros and price.
+// A new version is recorded every time the meal is created, updated or reverted.
+type MealVersion struct {
+	MealID      int       `json:"meal_id"`
+	Version     int       `json:"version"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	ImageURL    string    `json:"image_url"`
+	Calories    int       `json:"calories"`
+	Protein     int       `json:"protein"`
+	Carbs       int       `json:"carbs"`
+	Fat         int       `json:"fat"`
+	Price       int       `json:"price"` // stored in cents
+	CreatedBy   *int      `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type MealFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type MealVersionDiff struct {
+	MealID      int               `json:"meal_id"`
+	FromVersion int               `json:"from_version"`
+	ToVersion   int               `json:"to_version"`
+	Changes     []MealFieldChange `json:"changes"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// NutritionGoals are a user's calorie and macro targets for the given period.
+type NutritionGoals struct {
+	UserID    int       `json:"-"`
+	Period    string    `json:"period"` // "daily" or "weekly"
+	Calories  int       `json:"calories"`
+	Protein   int       `json:"protein"`
+	Carbs     int       `json:"carbs"`
+	Fat       int       `json:"fat"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type SetNutritionGoalsRequest struct {
+	Period   string `json:"period" binding:"required,oneof=daily weekly"`
+	Calories int    `json:"calories" binding:"min=0"`
+	Protein  int    `json:"protein" binding:"min=0"`
+	Carbs    int    `json:"carbs" binding:"min=0"`
+	Fat      int    `json:"fat" binding:"min=0"`
+}
+
+type NutritionTotals struct {
+	Calories int `json:"calories"`
+	Protein  int `json:"protein"`
+	Carbs    int `json:"carbs"`
+	Fat      int `json:"fat"`
+}
+
+type NutritionAverages struct {
+	Calories float64 `json:"calories"`
+	Protein  float64 `json:"protein"`
+	Carbs    float64 `json:"carbs"`
+	Fat      float64 `json:"fat"`
+}
+
+// MacroComparison compares the per-day average against the per-day goal.
+type MacroComparison struct {
+	Macro         string  `json:"macro"`
+	Goal          float64 `json:"goal"`
+	Actual        float64 `json:"actual"`
+	Difference    float64 `json:"difference"`      // actual - goal
+	PercentOfGoal float64 `json:"percent_of_goal"` // 0 when the goal is 0
+}
+
+type NutritionSummary struct {
+	Total      NutritionTotals   `json:"total"`
+	Days       int               `json:"days"`
+	PerDay     NutritionAverages `json:"per_day"`
+	Goals      *NutritionGoals   `json:"goals,omitempty"`
+	Comparison []MacroComparison `json:"comparison,omitempty"`
+}
@@ -3,22 +3,81 @@ package models
 import "time"
 
 type Order struct {
-	ID           int         `json:"id"`
-	UserID       int         `json:"user_id"`
-	WeekID       int         `json:"week_id"`
-	Status       string      `json:"status"`
-	TotalPrice   int         `json:"total_price"`
-	DeliveryDate time.Time   `json:"delivery_date"`
-	Items        []OrderItem `json:"items"`
-	CreatedAt    time.Time   `json:"created_at"`
+	ID              int               `json:"id"`
+	UserID          int               `json:"user_id"`
+	WeekID          int               `json:"week_id"`
+	Status          string            `json:"status"`
+	TotalPrice      int               `json:"total_price"`
+	Discount        int               `json:"discount"` // saved by buying bundles, in cents
+	DeliveryDate    time.Time         `json:"delivery_date"`
+	DeliveryAddress *Address          `json:"delivery_address"` // the customer's address at checkout
+	Items           []OrderItem       `json:"items"`
+	Addons          []OrderAddon      `json:"addons"`
+	Bundles         []OrderBundle     `json:"bundles"`
+	Nutrition       *NutritionSummary `json:"nutrition,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
 }
 
 type OrderItem struct {
-	OrderID  int  `json:"-"`
-	MealID   int  `json:"-"`
-	Meal     Meal `json:"meal"`
-	Quantity int  `json:"quantity"`
-	Price    int  `json:"price"` // Price at time of order (in cents)
+	OrderID     int          `json:"-"`
+	MealID      int          `json:"-"`
+	MealVersion int          `json:"-"`
+	Meal        Meal         `json:"meal"`
+	VariantID   *int         `json:"-"`
+	Variant     *MealVariant `json:"variant,omitempty"`
+	Quantity    int          `json:"quantity"`
+	Price       int          `json:"price"`               // Price at time of order (in cents)
+	BundleID    *int         `json:"bundle_id,omitempty"` // set when the meal came in a bundle
+}
+
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=pending confirmed delivered cancelled"`
+}
+
+// ReorderResult is the cart after copying a past order into it, plus
+// anything that could not be added in full.
+type ReorderResult struct {
+	Cart    *Cart              `json:"cart"`
+	Skipped []ReorderShortfall `json:"skipped"`
+}
+
+type ReorderShortfall struct {
+	MealID    int    `json:"meal_id,omitempty"`
+	VariantID *int   `json:"variant_id,omitempty"`
+	AddonID   int    `json:"addon_id,omitempty"`
+	Name      string `json:"name"`
+	Requested int    `json:"requested"`
+	Added     int    `json:"added"`
+	Reason    string `json:"reason"`
+}
+
+// OrderFilter narrows the staff order list. Empty fields match every order.
+type OrderFilter struct {
+	Status       string
+	DeliveryDate *time.Time
+}
+
+// ProductionItem is how many of a meal, or one of its variants, the kitchen
+// has to make for a delivery date.
+type ProductionItem struct {
+	MealID      int    `json:"meal_id"`
+	MealName    string `json:"meal_name"`
+	VariantID   *int   `json:"variant_id,omitempty"`
+	VariantName string `json:"variant_name,omitempty"`
+	Quantity    int    `json:"quantity"`
+}
+
+// Delivery is an order to drop off on a delivery date, at the customer's
+// default delivery address.
+type Delivery struct {
+	OrderID       int      `json:"order_id"`
+	CustomerName  string   `json:"customer_name"`
+	CustomerEmail string   `json:"customer_email"`
+	CustomerPhone string   `json:"customer_phone"`
+	Address       *Address `json:"address"`
+	Status        string   `json:"status"`
+	MealCount     int      `json:"meal_count"`
+	AddonCount    int      `json:"addon_count"`
 }
 
 type CheckoutRequest struct {
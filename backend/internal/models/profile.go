@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Profile is the signed-in user's account and settings, at /me.
+type Profile struct {
+	ID                 int                      `json:"id"`
+	Email              string                   `json:"email"`
+	EmailVerified      bool                     `json:"email_verified"`
+	PendingEmail       string                   `json:"pending_email,omitempty"` // waiting to be confirmed from the link sent to it
+	Role               string                   `json:"role"`
+	Name               string                   `json:"name"`
+	Phone              string                   `json:"phone"`
+	Address            *Address                 `json:"address"` // default delivery address
+	DietaryPreferences []string                 `json:"dietary_preferences"`
+	Communication      CommunicationPreferences `json:"communication"`
+	CreatedAt          time.Time                `json:"created_at"`
+}
+
+type Address struct {
+	Line1        string `json:"line1" binding:"required,max=200"`
+	Line2        string `json:"line2" binding:"max=200"`
+	City         string `json:"city" binding:"required,max=100"`
+	Postcode     string `json:"postcode" binding:"required,max=20"`
+	Instructions string `json:"instructions" binding:"max=500"` // for the driver
+}
+
+// CommunicationPreferences are what the user has agreed to be contacted
+// about. Account and security emails are always sent.
+type CommunicationPreferences struct {
+	OrderUpdateEmails bool `json:"order_update_emails"` // order receipts
+}
+
+// UpdateProfileRequest changes the fields that are set and leaves the rest.
+type UpdateProfileRequest struct {
+	Name               *string                   `json:"name" binding:"omitempty,max=100"`
+	Phone              *string                   `json:"phone"`
+	Address            *Address                  `json:"address"`
+	ClearAddress       bool                      `json:"clear_address"`       // remove the default address
+	DietaryPreferences []string                  `json:"dietary_preferences"` // nil leaves them unchanged, [] clears them
+	Communication      *CommunicationPreferences `json:"communication"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email,max=100"`
+	Password string `json:"password" binding:"required"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// MealRecommendation is a precomputed ranking score for a meal on a menu for
+// one user. Higher scores rank first.
+type MealRecommendation struct {
+	UserID     int       `json:"-"`
+	MenuID     int       `json:"-"`
+	MealID     int       `json:"-"`
+	Score      float64   `json:"score"`
+	Reason     string    `json:"reason"` // e.g. "ordered_before", "similar_to_liked", "popular_with_similar_customers"
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// MealInteraction is what a user has done with a meal: how many portions they
+// ordered and the rating they gave, if any.
+type MealInteraction struct {
+	UserID     int
+	MealID     int
+	OrderCount int
+	Rating     *int
+}
+
+type DietaryPreferences struct {
+	Preferences []string `json:"preferences"`
+}
+
+type SetDietaryPreferencesRequest struct {
+	Preferences []string `json:"preferences" binding:"required"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+type Review struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	MealID    int       `json:"meal_id"`
+	OrderID   int       `json:"order_id"`
+	WeekID    int       `json:"week_id"`
+	Rating    int       `json:"rating"`
+	Comment   string    `json:"comment"`
+	Status    string    `json:"status"` // "published" or "hidden"
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PublicReview is a published review as anyone can see it, without who
+// wrote it or which order it was for.
+type PublicReview struct {
+	ID        int       `json:"id"`
+	MealID    int       `json:"meal_id"`
+	Rating    int       `json:"rating"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MealRating is the aggregate of a meal's published reviews.
+type MealRating struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
+}
+
+type CreateReviewRequest struct {
+	OrderID int    `json:"order_id" binding:"required"`
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=2000"`
+}
+
+type ModerateReviewRequest struct {
+	Status string `json:"status" binding:"required,oneof=published hidden"`
+}
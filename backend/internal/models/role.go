@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Role is a set of permissions. Users hold one role, by name; "user" is a
+// customer with no staff permissions.
+type Role struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"` // e.g., "orders:read"
+	UserCount   int       `json:"user_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=20"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type UpdateRoleRequest struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Session is a login on one device. Its refresh tokens form a family: each
+// refresh swaps the current token for a new one, and revoking the session
+// ends all of them along with its access tokens.
+type Session struct {
+	ID            int        `json:"id"`
+	UserID        int        `json:"user_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUsedAt    time.Time  `json:"last_used_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason *string    `json:"revoked_reason,omitempty"` // "logout", "reuse_detected", ...
+	TwoFactor     bool       `json:"two_factor"`               // signed in with a second factor
+	Role          string     `json:"-"`                        // the user's current role
+	UserDisabled  bool       `json:"-"`
+}
+
+// RefreshToken is a stored (hashed) refresh token with the state of its
+// session.
+type RefreshToken struct {
+	ID               int
+	SessionID        int
+	UserID           int
+	ExpiresAt        time.Time
+	UsedAt           *time.Time // set once it has been exchanged
+	SessionRevokedAt *time.Time
+	TwoFactor        bool
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// PasswordResetToken is a stored (hashed) password reset token.
+type PasswordResetToken struct {
+	ID        int
+	UserID    int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// AccountDeletionToken is a stored (hashed) token that confirms deleting
+// an account.
+type AccountDeletionToken struct {
+	ID        int
+	UserID    int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// EmailVerificationToken is a stored (hashed) email verification token.
+// NewEmail is set when it confirms a change of email address.
+type EmailVerificationToken struct {
+	ID        int
+	UserID    int
+	NewEmail  *string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// TwoFactorSettings is a user's TOTP enrolment. Secret is set once setup
+// starts; two-factor authentication is on once EnabledAt is set.
+type TwoFactorSettings struct {
+	Secret                 *string
+	EnabledAt              *time.Time
+	LastStep               *int64
+	RecoveryCodesRemaining int
+}
+
+// TwoFactorChallenge is a login waiting for its second factor.
+type TwoFactorChallenge struct {
+	ID        int
+	UserID    int
+	ExpiresAt time.Time
+	Attempts  int
+	UsedAt    *time.Time
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+	Required               bool       `json:"required"` // enforced for this account's role
+}
+
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to show as a QR code
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // shown once, store them safely
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // authenticator or recovery code
+}
+
+type TwoFactorLoginRequest struct {
+	TwoFactorToken string `json:"two_factor_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // authenticator or recovery code
+}
@@ -8,6 +8,47 @@ type User struct {
 	PasswordHash string    `json:"-"`
 	Role         string    `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
+	// EmailVerified is false until the user follows the link emailed at
+	// registration; unverified users cannot check out
+	EmailVerified bool `json:"email_verified"`
+	// TwoFactorEnabled is true once TOTP enrolment has been confirmed
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
+	// DisabledAt is set while an admin has disabled the account
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	// PasswordResetRequired stops password logins until the user sets a new
+	// password from the emailed link
+	PasswordResetRequired bool `json:"password_reset_required"`
+	// DeletedAt is set once the user has deleted their account. Their
+	// personal data has been removed and only their orders remain.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// UserFilter narrows the admin user list. Empty fields match every user.
+type UserFilter struct {
+	Search string // part of the email address
+	Role   string
+	Status string // "active" or "disabled"
+	Page   int
+	Limit  int
+}
+
+// UserList is one page of the admin user list.
+type UserList struct {
+	Users []User `json:"users"`
+	Total int    `json:"total"`
+	Page  int    `json:"page"`
+	Limit int    `json:"limit"`
+}
+
+// AdminCreateUserRequest creates an account for someone else. They are
+// emailed a link to set their password.
+type AdminCreateUserRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role"` // defaults to "user"
+}
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
 }
 
 type CreateUserRequest struct {
@@ -20,7 +61,32 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// AuthResponse is returned by login and refresh. When the account uses
+// two-factor authentication, login instead returns TwoFactorRequired with a
+// TwoFactorToken to complete at /auth/login/2fa.
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token             string `json:"token,omitempty"`         // short-lived access token
+	ExpiresIn         int    `json:"expires_in,omitempty"`    // seconds until Token expires
+	RefreshToken      string `json:"refresh_token,omitempty"` // single use, exchange at /auth/refresh
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	TwoFactorToken    string `json:"two_factor_token,omitempty"`
+	User              User   `json:"user"`
 }
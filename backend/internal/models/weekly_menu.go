@@ -3,27 +3,45 @@ package models
 import "time"
 
 type WeeklyMenu struct {
-	ID            int              `json:"id"`
-	WeekStartDate time.Time        `json:"week_start_date"`
-	IsActive      bool             `json:"is_active"`
-	Meals         []WeeklyMenuMeal `json:"meals,omitempty"`
+	ID            int               `json:"id"`
+	WeekStartDate time.Time         `json:"week_start_date"`
+	IsActive      bool              `json:"is_active"`
+	Meals         []WeeklyMenuMeal  `json:"meals,omitempty"`
+	Addons        []WeeklyMenuAddon `json:"addons,omitempty"`
 }
 
 type WeeklyMenuMeal struct {
-	MenuID         int  `json:"-"`
-	Meal           Meal `json:"meal"`
-	InitialStock   int  `json:"initial_stock"`
-	AvailableStock int  `json:"available_stock"`
+	MenuID         int                 `json:"-"`
+	Meal           Meal                `json:"meal"`
+	InitialStock   int                 `json:"initial_stock"`
+	AvailableStock int                 `json:"available_stock"`
+	Variants       []WeeklyMenuVariant `json:"variants,omitempty"`
+	Recommendation *MealRecommendation `json:"recommendation,omitempty"`
+}
+
+// WeeklyMenuVariant tracks stock for a meal variant offered on a menu,
+// separately from the standard portion's stock.
+type WeeklyMenuVariant struct {
+	Variant        MealVariant `json:"variant"`
+	InitialStock   int         `json:"initial_stock"`
+	AvailableStock int         `json:"available_stock"`
 }
 
 type CreateWeeklyMenuRequest struct {
-	WeekStartDate string          `json:"week_start_date" binding:"required"`
-	Meals         []MenuMealInput `json:"meals" binding:"required,min=1"`
+	WeekStartDate string           `json:"week_start_date" binding:"required"`
+	Meals         []MenuMealInput  `json:"meals" binding:"required,min=1"`
+	Addons        []MenuAddonInput `json:"addons" binding:"dive"`
 }
 
 type MenuMealInput struct {
-	MealID int `json:"meal_id" binding:"required"`
-	Stock  int `json:"stock" binding:"required,min=1"`
+	MealID   int                `json:"meal_id" binding:"required"`
+	Stock    int                `json:"stock" binding:"required,min=1"`
+	Variants []MenuVariantInput `json:"variants" binding:"dive"`
+}
+
+type MenuVariantInput struct {
+	VariantID int `json:"variant_id" binding:"required"`
+	Stock     int `json:"stock" binding:"required,min=1"`
 }
 
 type UpdateStockRequest struct {
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type AccountDeletionRepository interface {
+	Create(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error
+	GetByHash(ctx context.Context, tokenHash string) (*models.AccountDeletionToken, error)
+}
+
+type accountDeletionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAccountDeletionRepository(db *pgxpool.Pool) AccountDeletionRepository {
+	return &accountDeletionRepository{db: db}
+}
+
+// Create stores a new deletion confirmation token for the user. Any earlier
+// unused tokens are retired so only the most recent link works. Tokens are
+// used up by deleting the account, which removes them.
+func (r *accountDeletionRepository) Create(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE account_deletion_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO account_deletion_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		userID, tokenHash, expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *accountDeletionRepository) GetByHash(ctx context.Context, tokenHash string) (*models.AccountDeletionToken, error) {
+	query := `SELECT id, user_id, expires_at, used_at FROM account_deletion_tokens WHERE token_hash = $1`
+	var t models.AccountDeletionToken
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(&t.ID, &t.UserID, &t.ExpiresAt, &t.UsedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
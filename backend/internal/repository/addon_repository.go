@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type AddonRepository interface {
+	Create(ctx context.Context, addon *models.Addon) error
+	GetByID(ctx context.Context, id int) (*models.Addon, error)
+	GetAll(ctx context.Context, category string) ([]models.Addon, error)
+	Update(ctx context.Context, id int, addon *models.Addon) error
+	Delete(ctx context.Context, id int) error
+	IsUsedInMenus(ctx context.Context, id int) (bool, error)
+	IsOrdered(ctx context.Context, id int) (bool, error)
+}
+
+type addonRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAddonRepository(db *pgxpool.Pool) AddonRepository {
+	return &addonRepository{db: db}
+}
+
+func (r *addonRepository) Create(ctx context.Context, addon *models.Addon) error {
+	query := `
+		INSERT INTO addons (name, description, image_url, category, calories, protein, carbs, fat, price)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+	return r.db.QueryRow(ctx, query,
+		addon.Name,
+		addon.Description,
+		addon.ImageURL,
+		addon.Category,
+		addon.Calories,
+		addon.Protein,
+		addon.Carbs,
+		addon.Fat,
+		addon.Price,
+	).Scan(&addon.ID)
+}
+
+func (r *addonRepository) GetByID(ctx context.Context, id int) (*models.Addon, error) {
+	query := `
+		SELECT id, name, description, image_url, category, calories, protein, carbs, fat, price
+		FROM addons
+		WHERE id = $1
+	`
+	var addon models.Addon
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&addon.ID,
+		&addon.Name,
+		&addon.Description,
+		&addon.ImageURL,
+		&addon.Category,
+		&addon.Calories,
+		&addon.Protein,
+		&addon.Carbs,
+		&addon.Fat,
+		&addon.Price,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &addon, nil
+}
+
+// GetAll lists add-ons, optionally filtered by category ("" for all).
+func (r *addonRepository) GetAll(ctx context.Context, category string) ([]models.Addon, error) {
+	query := `
+		SELECT id, name, description, image_url, category, calories, protein, carbs, fat, price
+		FROM addons
+		WHERE $1 = '' OR category = $1
+		ORDER BY category, id
+	`
+	rows, err := r.db.Query(ctx, query, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addons := []models.Addon{}
+	for rows.Next() {
+		var addon models.Addon
+		err := rows.Scan(
+			&addon.ID,
+			&addon.Name,
+			&addon.Description,
+			&addon.ImageURL,
+			&addon.Category,
+			&addon.Calories,
+			&addon.Protein,
+			&addon.Carbs,
+			&addon.Fat,
+			&addon.Price,
+		)
+		if err != nil {
+			return nil, err
+		}
+		addons = append(addons, addon)
+	}
+	return addons, nil
+}
+
+func (r *addonRepository) Update(ctx context.Context, id int, addon *models.Addon) error {
+	query := `
+		UPDATE addons
+		SET name = $1, description = $2, image_url = $3, category = $4, calories = $5,
+		    protein = $6, carbs = $7, fat = $8, price = $9
+		WHERE id = $10
+	`
+	result, err := r.db.Exec(ctx, query,
+		addon.Name,
+		addon.Description,
+		addon.ImageURL,
+		addon.Category,
+		addon.Calories,
+		addon.Protein,
+		addon.Carbs,
+		addon.Fat,
+		addon.Price,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("add-on not found")
+	}
+	return nil
+}
+
+// Delete removes the add-on, taking it out of any carts it is in.
+func (r *addonRepository) Delete(ctx context.Context, id int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM cart_addons WHERE addon_id = $1`, id); err != nil {
+		return err
+	}
+	result, err := tx.Exec(ctx, `DELETE FROM addons WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("add-on not found")
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *addonRepository) IsUsedInMenus(ctx context.Context, id int) (bool, error) {
+	query := `SELECT COUNT(*) FROM menu_addons WHERE addon_id = $1`
+	var count int
+	err := r.db.QueryRow(ctx, query, id).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// IsOrdered reports whether the add-on is on any order, which must keep it.
+func (r *addonRepository) IsOrdered(ctx context.Context, id int) (bool, error) {
+	var ordered bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM order_addons WHERE addon_id = $1)`, id).Scan(&ordered)
+	return ordered, err
+}
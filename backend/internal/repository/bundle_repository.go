@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type BundleRepository interface {
+	Create(ctx context.Context, bundle *models.Bundle) error
+	GetByID(ctx context.Context, id int) (*models.Bundle, error)
+	GetAll(ctx context.Context, menuID int) ([]models.Bundle, error)
+	Update(ctx context.Context, id int, bundle *models.Bundle) error
+	Delete(ctx context.Context, id int) error
+	IsOrdered(ctx context.Context, id int) (bool, error)
+}
+
+type bundleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBundleRepository(db *pgxpool.Pool) BundleRepository {
+	return &bundleRepository{db: db}
+}
+
+func (r *bundleRepository) Create(ctx context.Context, bundle *models.Bundle) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO bundles (menu_id, name, description, image_url, price, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRow(ctx, query,
+		bundle.MenuID,
+		bundle.Name,
+		bundle.Description,
+		bundle.ImageURL,
+		bundle.Price,
+		bundle.IsActive,
+	).Scan(&bundle.ID, &bundle.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertBundleItems(ctx, tx, bundle.ID, bundle.Items); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *bundleRepository) GetByID(ctx context.Context, id int) (*models.Bundle, error) {
+	query := `
+		SELECT id, menu_id, name, description, image_url, price, is_active, created_at
+		FROM bundles
+		WHERE id = $1
+	`
+	var b models.Bundle
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&b.ID, &b.MenuID, &b.Name, &b.Description, &b.ImageURL, &b.Price, &b.IsActive, &b.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	items, err := getBundleItems(ctx, r.db, []int{b.ID})
+	if err != nil {
+		return nil, err
+	}
+	b.Items = items[b.ID]
+	fillBundleTotals(&b)
+	return &b, nil
+}
+
+// GetAll lists the bundles of a menu, or of every menu when menuID is 0.
+func (r *bundleRepository) GetAll(ctx context.Context, menuID int) ([]models.Bundle, error) {
+	query := `
+		SELECT id, menu_id, name, description, image_url, price, is_active, created_at
+		FROM bundles
+		WHERE $1 = 0 OR menu_id = $1
+		ORDER BY menu_id DESC, id
+	`
+	rows, err := r.db.Query(ctx, query, menuID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bundles := []models.Bundle{}
+	var ids []int
+	for rows.Next() {
+		var b models.Bundle
+		err := rows.Scan(&b.ID, &b.MenuID, &b.Name, &b.Description, &b.ImageURL, &b.Price, &b.IsActive, &b.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, b)
+		ids = append(ids, b.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items, err := getBundleItems(ctx, r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bundles {
+		bundles[i].Items = items[bundles[i].ID]
+		fillBundleTotals(&bundles[i])
+	}
+	return bundles, nil
+}
+
+// Update saves the bundle's details and, when bundle.Items is not nil,
+// replaces its items.
+func (r *bundleRepository) Update(ctx context.Context, id int, bundle *models.Bundle) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE bundles
+		SET name = $1, description = $2, image_url = $3, price = $4, is_active = $5
+		WHERE id = $6
+	`
+	result, err := tx.Exec(ctx, query,
+		bundle.Name,
+		bundle.Description,
+		bundle.ImageURL,
+		bundle.Price,
+		bundle.IsActive,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("bundle not found")
+	}
+
+	if bundle.Items != nil {
+		if _, err := tx.Exec(ctx, `DELETE FROM bundle_items WHERE bundle_id = $1`, id); err != nil {
+			return err
+		}
+		if err := insertBundleItems(ctx, tx, id, bundle.Items); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *bundleRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM bundles WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("bundle not found")
+	}
+	return nil
+}
+
+func (r *bundleRepository) IsOrdered(ctx context.Context, id int) (bool, error) {
+	var ordered bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM order_bundles WHERE bundle_id = $1)`, id).Scan(&ordered)
+	return ordered, err
+}
+
+func insertBundleItems(ctx context.Context, tx pgx.Tx, bundleID int, items []models.BundleItem) error {
+	for _, item := range items {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO bundle_items (bundle_id, meal_id, variant_id, quantity) VALUES ($1, $2, $3, $4)`,
+			bundleID, item.MealID, item.VariantID, item.Quantity,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return errors.New("a meal can only appear once in a bundle")
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// getBundleItems loads the items of several bundles in one query, keyed by
+// bundle ID. Meals are shown at the version pinned to the bundle's menu,
+// along with the stock that menu has left.
+func getBundleItems(ctx context.Context, db *pgxpool.Pool, bundleIDs []int) (map[int][]models.BundleItem, error) {
+	query := `
+		SELECT bi.bundle_id, bi.meal_id, bi.variant_id, bi.quantity,
+		       m.id, mv.name, mv.description, mv.image_url, mv.calories, mv.protein, mv.carbs, mv.fat,
+		       effective_meal_price(m.id, NULL, NULL, NOW(), mv.price), mv.version,
+		       v.name, v.calories, v.protein, v.carbs, v.fat, effective_meal_price(m.id, v.id, NULL, NOW(), v.price),
+		       CASE WHEN bi.variant_id IS NULL THEN COALESCE(mm.available_stock, 0)
+		            ELSE COALESCE(mvs.available_stock, 0) END
+		FROM bundle_items bi
+		JOIN bundles b ON bi.bundle_id = b.id
+		JOIN meals m ON bi.meal_id = m.id
+		LEFT JOIN menu_meals mm ON mm.menu_id = b.menu_id AND mm.meal_id = bi.meal_id
+		JOIN meal_versions mv ON mv.meal_id = m.id AND mv.version = COALESCE(mm.meal_version, m.current_version)
+		LEFT JOIN meal_variants v ON bi.variant_id = v.id
+		LEFT JOIN menu_variant_stock mvs ON mvs.menu_id = b.menu_id AND mvs.variant_id = bi.variant_id
+		WHERE bi.bundle_id = ANY($1)
+		ORDER BY bi.bundle_id, bi.id
+	`
+	rows, err := db.Query(ctx, query, bundleIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byBundle := make(map[int][]models.BundleItem)
+	for rows.Next() {
+		var bundleID int
+		var item models.BundleItem
+		var variant nullableVariant
+		err := rows.Scan(
+			&bundleID, &item.MealID, &item.VariantID, &item.Quantity,
+			&item.Meal.ID, &item.Meal.Name, &item.Meal.Description, &item.Meal.ImageURL,
+			&item.Meal.Calories, &item.Meal.Protein, &item.Meal.Carbs, &item.Meal.Fat, &item.Meal.Price,
+			&item.Meal.Version,
+			&variant.Name, &variant.Calories, &variant.Protein, &variant.Carbs, &variant.Fat, &variant.Price,
+			&item.AvailableStock,
+		)
+		if err != nil {
+			return nil, err
+		}
+		item.Variant = variant.toModel(item.VariantID, item.MealID)
+		item.UnitPrice = item.Meal.Price
+		if item.Variant != nil {
+			item.UnitPrice = item.Variant.Price
+		}
+		byBundle[bundleID] = append(byBundle[bundleID], item)
+	}
+	return byBundle, rows.Err()
+}
+
+// fillBundleTotals works out the bundle's meal count, regular price,
+// discount and how many bundles the remaining stock covers.
+func fillBundleTotals(b *models.Bundle) {
+	if b.Items == nil {
+		b.Items = []models.BundleItem{}
+	}
+
+	b.TotalItems, b.RegularPrice, b.AvailableStock = 0, 0, 0
+	for i, item := range b.Items {
+		b.TotalItems += item.Quantity
+		b.RegularPrice += item.UnitPrice * item.Quantity
+
+		fits := item.AvailableStock / item.Quantity
+		if i == 0 || fits < b.AvailableStock {
+			b.AvailableStock = fits
+		}
+	}
+	b.Discount = b.RegularPrice - b.Price
+}
@@ -13,12 +13,20 @@ import (
 type CartRepository interface {
 	GetOrCreateByUserID(ctx context.Context, userID int) (*models.Cart, error)
 	GetByUserID(ctx context.Context, userID int) (*models.Cart, error)
-	AddItem(ctx context.Context, cartID, mealID, quantity int) error
+	AddItem(ctx context.Context, cartID, mealID int, variantID *int, quantity int) error
 	UpdateItemQuantity(ctx context.Context, itemID, quantity int) error
 	RemoveItem(ctx context.Context, itemID int) error
 	Clear(ctx context.Context, cartID int) error
 	GetItemCount(ctx context.Context, cartID int) (int, error)
-	GetItemByCartAndMeal(ctx context.Context, cartID, mealID int) (*models.CartItem, error)
+	GetItemByCartAndMeal(ctx context.Context, cartID, mealID int, variantID *int) (*models.CartItem, error)
+	AddAddon(ctx context.Context, cartID, addonID, quantity int) error
+	UpdateAddonQuantity(ctx context.Context, cartAddonID, quantity int) error
+	RemoveAddon(ctx context.Context, cartAddonID int) error
+	GetAddonByCartAndAddon(ctx context.Context, cartID, addonID int) (*models.CartAddon, error)
+	AddBundle(ctx context.Context, cartID, bundleID, quantity int) error
+	UpdateBundleQuantity(ctx context.Context, cartBundleID, quantity int) error
+	RemoveBundle(ctx context.Context, cartBundleID int) error
+	GetBundleByCartAndBundle(ctx context.Context, cartID, bundleID int) (*models.CartBundle, error)
 }
 
 type cartRepository struct {
@@ -41,7 +49,7 @@ func (r *cartRepository) GetOrCreateByUserID(ctx context.Context, userID int) (*
 
 	// Create new cart
 	query := `INSERT INTO carts (user_id) VALUES ($1) RETURNING id, created_at, updated_at`
-	cart = &models.Cart{UserID: userID, Items: []models.CartItem{}}
+	cart = &models.Cart{UserID: userID, Items: []models.CartItem{}, Addons: []models.CartAddon{}, Bundles: []models.CartBundle{}}
 	err = r.db.QueryRow(ctx, query, userID).Scan(&cart.ID, &cart.CreatedAt, &cart.UpdatedAt)
 	if err != nil {
 		return nil, err
@@ -62,12 +70,20 @@ func (r *cartRepository) GetByUserID(ctx context.Context, userID int) (*models.C
 		return nil, err
 	}
 
-	// Get cart items with meal details
+	// Get cart items with meal details, using the version pinned to the
+	// active menu so the cart matches what the customer saw on the menu.
+	// Prices are standard; the cart service applies the user's plan
 	itemsQuery := `
-		SELECT ci.id, ci.cart_id, ci.meal_id, ci.quantity, ci.created_at,
-		       m.id, m.name, m.description, m.image_url, m.calories, m.protein, m.carbs, m.fat, m.price
+		SELECT ci.id, ci.cart_id, ci.meal_id, ci.variant_id, ci.quantity, ci.created_at,
+		       m.id, mv.name, mv.description, mv.image_url, mv.calories, mv.protein, mv.carbs, mv.fat,
+		       effective_meal_price(m.id, NULL, NULL, NOW(), mv.price), mv.version,
+		       v.name, v.calories, v.protein, v.carbs, v.fat, effective_meal_price(m.id, v.id, NULL, NOW(), v.price)
 		FROM cart_items ci
 		JOIN meals m ON ci.meal_id = m.id
+		LEFT JOIN menu_meals mm ON mm.meal_id = m.id
+		     AND mm.menu_id = (SELECT id FROM weekly_menus WHERE is_active = true LIMIT 1)
+		JOIN meal_versions mv ON mv.meal_id = m.id AND mv.version = COALESCE(mm.meal_version, m.current_version)
+		LEFT JOIN meal_variants v ON ci.variant_id = v.id
 		WHERE ci.cart_id = $1
 		ORDER BY ci.created_at
 	`
@@ -83,25 +99,116 @@ func (r *cartRepository) GetByUserID(ctx context.Context, userID int) (*models.C
 
 	for rows.Next() {
 		var item models.CartItem
+		var variant nullableVariant
 		err := rows.Scan(
-			&item.ID, &item.CartID, &item.MealID, &item.Quantity, &item.CreatedAt,
+			&item.ID, &item.CartID, &item.MealID, &item.VariantID, &item.Quantity, &item.CreatedAt,
 			&item.Meal.ID, &item.Meal.Name, &item.Meal.Description, &item.Meal.ImageURL,
 			&item.Meal.Calories, &item.Meal.Protein, &item.Meal.Carbs, &item.Meal.Fat, &item.Meal.Price,
+			&item.Meal.Version,
+			&variant.Name, &variant.Calories, &variant.Protein, &variant.Carbs, &variant.Fat, &variant.Price,
 		)
 		if err != nil {
 			return nil, err
 		}
+		item.Variant = variant.toModel(item.VariantID, item.MealID)
+		item.UnitPrice = item.Meal.Price
+		if item.Variant != nil {
+			item.UnitPrice = item.Variant.Price
+		}
 		cart.Items = append(cart.Items, item)
 		cart.TotalItems += item.Quantity
-		cart.TotalPrice += item.Meal.Price * item.Quantity
+		cart.TotalPrice += item.UnitPrice * item.Quantity
+	}
+	rows.Close()
+
+	// Get add-ons, which don't count toward the meal allowance
+	addonsQuery := `
+		SELECT ca.id, ca.cart_id, ca.addon_id, ca.quantity, ca.created_at,
+		       a.id, a.name, a.description, a.image_url, a.category, a.calories, a.protein, a.carbs, a.fat, a.price
+		FROM cart_addons ca
+		JOIN addons a ON ca.addon_id = a.id
+		WHERE ca.cart_id = $1
+		ORDER BY ca.created_at
+	`
+	addonRows, err := r.db.Query(ctx, addonsQuery, cart.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer addonRows.Close()
+
+	cart.Addons = []models.CartAddon{}
+	for addonRows.Next() {
+		var ca models.CartAddon
+		err := addonRows.Scan(
+			&ca.ID, &ca.CartID, &ca.AddonID, &ca.Quantity, &ca.CreatedAt,
+			&ca.Addon.ID, &ca.Addon.Name, &ca.Addon.Description, &ca.Addon.ImageURL, &ca.Addon.Category,
+			&ca.Addon.Calories, &ca.Addon.Protein, &ca.Addon.Carbs, &ca.Addon.Fat, &ca.Addon.Price,
+		)
+		if err != nil {
+			return nil, err
+		}
+		cart.Addons = append(cart.Addons, ca)
+		cart.TotalAddons += ca.Quantity
+		cart.TotalPrice += ca.Addon.Price * ca.Quantity
+	}
+	addonRows.Close()
+
+	// Get bundles, whose meals count toward the meal allowance
+	bundlesQuery := `
+		SELECT cb.id, cb.cart_id, cb.bundle_id, cb.quantity, cb.created_at,
+		       b.id, b.menu_id, b.name, b.description, b.image_url, b.price, b.is_active, b.created_at
+		FROM cart_bundles cb
+		JOIN bundles b ON cb.bundle_id = b.id
+		WHERE cb.cart_id = $1
+		ORDER BY cb.created_at
+	`
+	bundleRows, err := r.db.Query(ctx, bundlesQuery, cart.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer bundleRows.Close()
+
+	cart.Bundles = []models.CartBundle{}
+	var bundleIDs []int
+	for bundleRows.Next() {
+		var cb models.CartBundle
+		err := bundleRows.Scan(
+			&cb.ID, &cb.CartID, &cb.BundleID, &cb.Quantity, &cb.CreatedAt,
+			&cb.Bundle.ID, &cb.Bundle.MenuID, &cb.Bundle.Name, &cb.Bundle.Description, &cb.Bundle.ImageURL,
+			&cb.Bundle.Price, &cb.Bundle.IsActive, &cb.Bundle.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		cart.Bundles = append(cart.Bundles, cb)
+		bundleIDs = append(bundleIDs, cb.BundleID)
+	}
+	if err := bundleRows.Err(); err != nil {
+		return nil, err
+	}
+	bundleRows.Close()
+
+	if len(bundleIDs) > 0 {
+		items, err := getBundleItems(ctx, r.db, bundleIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range cart.Bundles {
+			cb := &cart.Bundles[i]
+			cb.Bundle.Items = items[cb.BundleID]
+			fillBundleTotals(&cb.Bundle)
+			cart.TotalItems += cb.Bundle.TotalItems * cb.Quantity
+			cart.TotalPrice += cb.Bundle.Price * cb.Quantity
+			cart.Discount += cb.Bundle.Discount * cb.Quantity
+		}
 	}
 
 	return &cart, nil
 }
 
-func (r *cartRepository) AddItem(ctx context.Context, cartID, mealID, quantity int) error {
-	query := `INSERT INTO cart_items (cart_id, meal_id, quantity) VALUES ($1, $2, $3)`
-	_, err := r.db.Exec(ctx, query, cartID, mealID, quantity)
+func (r *cartRepository) AddItem(ctx context.Context, cartID, mealID int, variantID *int, quantity int) error {
+	query := `INSERT INTO cart_items (cart_id, meal_id, variant_id, quantity) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.Exec(ctx, query, cartID, mealID, variantID, quantity)
 	if err != nil {
 		return err
 	}
@@ -136,22 +243,54 @@ func (r *cartRepository) RemoveItem(ctx context.Context, itemID int) error {
 }
 
 func (r *cartRepository) Clear(ctx context.Context, cartID int) error {
-	query := `DELETE FROM cart_items WHERE cart_id = $1`
-	_, err := r.db.Exec(ctx, query, cartID)
-	return err
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := clearCart(ctx, tx, cartID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// clearCart removes the cart's items, add-ons and bundles.
+func clearCart(ctx context.Context, tx pgx.Tx, cartID int) error {
+	for _, query := range []string{
+		`DELETE FROM cart_items WHERE cart_id = $1`,
+		`DELETE FROM cart_addons WHERE cart_id = $1`,
+		`DELETE FROM cart_bundles WHERE cart_id = $1`,
+	} {
+		if _, err := tx.Exec(ctx, query, cartID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// GetItemCount counts the meals in the cart, including those in bundles.
 func (r *cartRepository) GetItemCount(ctx context.Context, cartID int) (int, error) {
-	query := `SELECT COALESCE(SUM(quantity), 0) FROM cart_items WHERE cart_id = $1`
+	query := `
+		SELECT COALESCE((SELECT SUM(quantity) FROM cart_items WHERE cart_id = $1), 0)
+		     + COALESCE((SELECT SUM(cb.quantity * bi.quantity)
+		                 FROM cart_bundles cb
+		                 JOIN bundle_items bi ON bi.bundle_id = cb.bundle_id
+		                 WHERE cb.cart_id = $1), 0)
+	`
 	var count int
 	err := r.db.QueryRow(ctx, query, cartID).Scan(&count)
 	return count, err
 }
 
-func (r *cartRepository) GetItemByCartAndMeal(ctx context.Context, cartID, mealID int) (*models.CartItem, error) {
-	query := `SELECT id, cart_id, meal_id, quantity, created_at FROM cart_items WHERE cart_id = $1 AND meal_id = $2`
+func (r *cartRepository) GetItemByCartAndMeal(ctx context.Context, cartID, mealID int, variantID *int) (*models.CartItem, error) {
+	query := `
+		SELECT id, cart_id, meal_id, variant_id, quantity, created_at
+		FROM cart_items
+		WHERE cart_id = $1 AND meal_id = $2 AND variant_id IS NOT DISTINCT FROM $3
+	`
 	var item models.CartItem
-	err := r.db.QueryRow(ctx, query, cartID, mealID).Scan(&item.ID, &item.CartID, &item.MealID, &item.Quantity, &item.CreatedAt)
+	err := r.db.QueryRow(ctx, query, cartID, mealID, variantID).Scan(&item.ID, &item.CartID, &item.MealID, &item.VariantID, &item.Quantity, &item.CreatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -160,3 +299,101 @@ func (r *cartRepository) GetItemByCartAndMeal(ctx context.Context, cartID, mealI
 	}
 	return &item, nil
 }
+
+func (r *cartRepository) AddAddon(ctx context.Context, cartID, addonID, quantity int) error {
+	query := `INSERT INTO cart_addons (cart_id, addon_id, quantity) VALUES ($1, $2, $3)`
+	_, err := r.db.Exec(ctx, query, cartID, addonID, quantity)
+	if err != nil {
+		return err
+	}
+
+	// Update cart updated_at
+	_, _ = r.db.Exec(ctx, `UPDATE carts SET updated_at = $1 WHERE id = $2`, time.Now(), cartID)
+	return nil
+}
+
+func (r *cartRepository) UpdateAddonQuantity(ctx context.Context, cartAddonID, quantity int) error {
+	query := `UPDATE cart_addons SET quantity = $1 WHERE id = $2`
+	result, err := r.db.Exec(ctx, query, quantity, cartAddonID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("cart add-on not found")
+	}
+	return nil
+}
+
+func (r *cartRepository) RemoveAddon(ctx context.Context, cartAddonID int) error {
+	query := `DELETE FROM cart_addons WHERE id = $1`
+	result, err := r.db.Exec(ctx, query, cartAddonID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("cart add-on not found")
+	}
+	return nil
+}
+
+func (r *cartRepository) GetAddonByCartAndAddon(ctx context.Context, cartID, addonID int) (*models.CartAddon, error) {
+	query := `SELECT id, cart_id, addon_id, quantity, created_at FROM cart_addons WHERE cart_id = $1 AND addon_id = $2`
+	var ca models.CartAddon
+	err := r.db.QueryRow(ctx, query, cartID, addonID).Scan(&ca.ID, &ca.CartID, &ca.AddonID, &ca.Quantity, &ca.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ca, nil
+}
+
+func (r *cartRepository) AddBundle(ctx context.Context, cartID, bundleID, quantity int) error {
+	query := `INSERT INTO cart_bundles (cart_id, bundle_id, quantity) VALUES ($1, $2, $3)`
+	_, err := r.db.Exec(ctx, query, cartID, bundleID, quantity)
+	if err != nil {
+		return err
+	}
+
+	// Update cart updated_at
+	_, _ = r.db.Exec(ctx, `UPDATE carts SET updated_at = $1 WHERE id = $2`, time.Now(), cartID)
+	return nil
+}
+
+func (r *cartRepository) UpdateBundleQuantity(ctx context.Context, cartBundleID, quantity int) error {
+	query := `UPDATE cart_bundles SET quantity = $1 WHERE id = $2`
+	result, err := r.db.Exec(ctx, query, quantity, cartBundleID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("cart bundle not found")
+	}
+	return nil
+}
+
+func (r *cartRepository) RemoveBundle(ctx context.Context, cartBundleID int) error {
+	query := `DELETE FROM cart_bundles WHERE id = $1`
+	result, err := r.db.Exec(ctx, query, cartBundleID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("cart bundle not found")
+	}
+	return nil
+}
+
+func (r *cartRepository) GetBundleByCartAndBundle(ctx context.Context, cartID, bundleID int) (*models.CartBundle, error) {
+	query := `SELECT id, cart_id, bundle_id, quantity, created_at FROM cart_bundles WHERE cart_id = $1 AND bundle_id = $2`
+	var cb models.CartBundle
+	err := r.db.QueryRow(ctx, query, cartID, bundleID).Scan(&cb.ID, &cb.CartID, &cb.BundleID, &cb.Quantity, &cb.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cb, nil
+}
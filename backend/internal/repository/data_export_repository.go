@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type DataExportRepository interface {
+	Create(ctx context.Context, userID int) (*models.DataExport, error)
+	GetByID(ctx context.Context, id int) (*models.DataExport, error)
+	GetByUserID(ctx context.Context, userID int) ([]models.DataExport, error)
+	GetData(ctx context.Context, id int) ([]byte, error)
+	HasPending(ctx context.Context, userID int) (bool, error)
+	Complete(ctx context.Context, id int, data []byte, expiresAt time.Time) error
+	Fail(ctx context.Context, id int, reason string) error
+	FailStale(ctx context.Context, olderThan time.Time) (int64, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type dataExportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDataExportRepository(db *pgxpool.Pool) DataExportRepository {
+	return &dataExportRepository{db: db}
+}
+
+const dataExportColumns = `id, user_id, status, COALESCE(error, ''), created_at, completed_at, expires_at`
+
+func scanDataExport(row pgx.Row) (*models.DataExport, error) {
+	var export models.DataExport
+	err := row.Scan(&export.ID, &export.UserID, &export.Status, &export.Error, &export.CreatedAt, &export.CompletedAt, &export.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// Create starts a pending export. A user can only have one pending at a
+// time, which the database enforces for requests made at the same moment.
+func (r *dataExportRepository) Create(ctx context.Context, userID int) (*models.DataExport, error) {
+	query := `INSERT INTO data_exports (user_id) VALUES ($1) RETURNING ` + dataExportColumns
+	export, err := scanDataExport(r.db.QueryRow(ctx, query, userID))
+	if isUniqueViolation(err) {
+		return nil, errors.New("a data export is already being prepared")
+	}
+	return export, err
+}
+
+func (r *dataExportRepository) GetByID(ctx context.Context, id int) (*models.DataExport, error) {
+	export, err := scanDataExport(r.db.QueryRow(ctx, `SELECT `+dataExportColumns+` FROM data_exports WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+// GetByUserID lists the user's exports, newest first.
+func (r *dataExportRepository) GetByUserID(ctx context.Context, userID int) ([]models.DataExport, error) {
+	query := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := []models.DataExport{}
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, *export)
+	}
+	return exports, rows.Err()
+}
+
+// GetData returns the export's JSON document, or nil if it is not ready.
+func (r *dataExportRepository) GetData(ctx context.Context, id int) ([]byte, error) {
+	var data []byte
+	err := r.db.QueryRow(ctx, `SELECT data FROM data_exports WHERE id = $1`, id).Scan(&data)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func (r *dataExportRepository) HasPending(ctx context.Context, userID int) (bool, error) {
+	var pending bool
+	query := `SELECT EXISTS(SELECT 1 FROM data_exports WHERE user_id = $1 AND status = 'pending')`
+	err := r.db.QueryRow(ctx, query, userID).Scan(&pending)
+	return pending, err
+}
+
+// Complete stores the finished export. Only pending exports are completed,
+// so one given up on as stale stays failed.
+func (r *dataExportRepository) Complete(ctx context.Context, id int, data []byte, expiresAt time.Time) error {
+	query := `
+		UPDATE data_exports SET status = 'ready', data = $1, completed_at = NOW(), expires_at = $2
+		WHERE id = $3 AND status = 'pending'
+	`
+	result, err := r.db.Exec(ctx, query, data, expiresAt, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("data export not found")
+	}
+	return nil
+}
+
+func (r *dataExportRepository) Fail(ctx context.Context, id int, reason string) error {
+	query := `UPDATE data_exports SET status = 'failed', error = $1, completed_at = NOW() WHERE id = $2 AND status = 'pending'`
+	_, err := r.db.Exec(ctx, query, reason, id)
+	return err
+}
+
+// FailStale gives up on exports still pending since before the given time,
+// e.g. because the server restarted while building them, so the user can
+// ask again.
+func (r *dataExportRepository) FailStale(ctx context.Context, olderThan time.Time) (int64, error) {
+	query := `
+		UPDATE data_exports SET status = 'failed', error = 'export was interrupted, please request a new one', completed_at = NOW()
+		WHERE status = 'pending' AND created_at < $1
+	`
+	result, err := r.db.Exec(ctx, query, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeleteExpired removes exports past their expiry time, and failed ones
+// after a day.
+func (r *dataExportRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `
+		DELETE FROM data_exports
+		WHERE expires_at < NOW() OR (status = 'failed' AND completed_at < NOW() - INTERVAL '1 day')
+	`
+	result, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
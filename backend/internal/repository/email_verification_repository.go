@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error
+	CreateEmailChange(ctx context.Context, userID int, newEmail, tokenHash string, expiresAt time.Time) error
+	GetByHash(ctx context.Context, tokenHash string) (*models.EmailVerificationToken, error)
+	Verify(ctx context.Context, tokenID, userID int) (bool, error)
+	GetSendStats(ctx context.Context, userID int, since time.Time) (int, *time.Time, error)
+}
+
+type emailVerificationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEmailVerificationRepository(db *pgxpool.Pool) EmailVerificationRepository {
+	return &emailVerificationRepository{db: db}
+}
+
+// Create stores a new verification token for the user. Earlier unused
+// tokens are retired so only the most recent link works.
+func (r *emailVerificationRepository) Create(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE email_verification_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL AND new_email IS NULL`,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO email_verification_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		userID, tokenHash, expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// CreateEmailChange stores a token confirming a change to newEmail. Earlier
+// unconfirmed changes are retired so only the most recent one can go
+// through.
+func (r *emailVerificationRepository) CreateEmailChange(ctx context.Context, userID int, newEmail, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE email_verification_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL AND new_email IS NOT NULL`,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO email_verification_tokens (user_id, new_email, token_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		userID, newEmail, tokenHash, expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *emailVerificationRepository) GetByHash(ctx context.Context, tokenHash string) (*models.EmailVerificationToken, error) {
+	query := `SELECT id, user_id, new_email, expires_at, used_at FROM email_verification_tokens WHERE token_hash = $1`
+	var t models.EmailVerificationToken
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(&t.ID, &t.UserID, &t.NewEmail, &t.ExpiresAt, &t.UsedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Verify uses up the token and marks the user's email as verified, first
+// switching to the new address if the token confirms an email change. It
+// returns false, changing nothing, when the token has already been used.
+func (r *emailVerificationRepository) Verify(ctx context.Context, tokenID, userID int) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var newEmail *string
+	err = tx.QueryRow(ctx,
+		`UPDATE email_verification_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL RETURNING new_email`,
+		tokenID,
+	).Scan(&newEmail)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if newEmail != nil {
+		_, err = tx.Exec(ctx, `UPDATE users SET email = $1, email_verified_at = NOW() WHERE id = $2`, *newEmail, userID)
+		if isUniqueViolation(err) {
+			return false, errors.New("email already in use")
+		}
+	} else {
+		_, err = tx.Exec(ctx,
+			`UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`,
+			userID,
+		)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// GetSendStats returns how many verification emails the user has been sent
+// since the given time, and when the last one was sent.
+func (r *emailVerificationRepository) GetSendStats(ctx context.Context, userID int, since time.Time) (int, *time.Time, error) {
+	query := `
+		SELECT COUNT(*) FILTER (WHERE created_at > $2), MAX(created_at)
+		FROM email_verification_tokens
+		WHERE user_id = $1
+	`
+	var count int
+	var last *time.Time
+	err := r.db.QueryRow(ctx, query, userID, since).Scan(&count, &last)
+	return count, last, err
+}
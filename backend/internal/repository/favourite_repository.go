@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type FavouriteRepository interface {
+	Add(ctx context.Context, userID, mealID int) error
+	Remove(ctx context.Context, userID, mealID int) error
+	GetByUserID(ctx context.Context, userID int) ([]models.Favourite, error)
+}
+
+type favouriteRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFavouriteRepository(db *pgxpool.Pool) FavouriteRepository {
+	return &favouriteRepository{db: db}
+}
+
+// Add is idempotent: favouriting a meal twice is not an error.
+func (r *favouriteRepository) Add(ctx context.Context, userID, mealID int) error {
+	query := `
+		INSERT INTO user_favourites (user_id, meal_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, meal_id) DO NOTHING
+	`
+	_, err := r.db.Exec(ctx, query, userID, mealID)
+	return err
+}
+
+func (r *favouriteRepository) Remove(ctx context.Context, userID, mealID int) error {
+	query := `DELETE FROM user_favourites WHERE user_id = $1 AND meal_id = $2`
+	result, err := r.db.Exec(ctx, query, userID, mealID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("favourite not found")
+	}
+	return nil
+}
+
+func (r *favouriteRepository) GetByUserID(ctx context.Context, userID int) ([]models.Favourite, error) {
+	query := `
+		SELECT f.created_at,
+		       m.id, m.name, m.description, m.image_url, m.calories, m.protein, m.carbs, m.fat,
+		       effective_meal_price(m.id, NULL, NULL, NOW(), m.price), m.current_version
+		FROM user_favourites f
+		JOIN meals m ON f.meal_id = m.id
+		WHERE f.user_id = $1
+		ORDER BY f.created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	favourites := []models.Favourite{}
+	for rows.Next() {
+		var f models.Favourite
+		err := rows.Scan(
+			&f.CreatedAt,
+			&f.Meal.ID, &f.Meal.Name, &f.Meal.Description, &f.Meal.ImageURL,
+			&f.Meal.Calories, &f.Meal.Protein, &f.Meal.Carbs, &f.Meal.Fat, &f.Meal.Price, &f.Meal.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		favourites = append(favourites, f)
+	}
+	return favourites, nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type LoginThrottleRepository interface {
+	Get(ctx context.Context, scope, subject string) (*models.LoginThrottle, error)
+	RecordFailure(ctx context.Context, scope, subject string, window time.Duration) (*models.LoginThrottle, error)
+	TakeAttempt(ctx context.Context, scope, subject string, max int, window time.Duration) (*models.LoginThrottle, error)
+	Lock(ctx context.Context, scope, subject string, until time.Time) (bool, error)
+	Reset(ctx context.Context, scope, subject string) error
+	DeleteStale(ctx context.Context, window time.Duration) (int64, error)
+}
+
+type loginThrottleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginThrottleRepository(db *pgxpool.Pool) LoginThrottleRepository {
+	return &loginThrottleRepository{db: db}
+}
+
+func (r *loginThrottleRepository) Get(ctx context.Context, scope, subject string) (*models.LoginThrottle, error) {
+	query := `
+		SELECT scope, subject, failures, last_failure_at, locked_until
+		FROM login_throttles
+		WHERE scope = $1 AND subject = $2
+	`
+	var t models.LoginThrottle
+	err := r.db.QueryRow(ctx, query, scope, subject).Scan(&t.Scope, &t.Subject, &t.Failures, &t.LastFailureAt, &t.LockedUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RecordFailure counts a failed login and returns the new totals. Failures
+// older than the window are forgotten, so the count starts again at one.
+func (r *loginThrottleRepository) RecordFailure(ctx context.Context, scope, subject string, window time.Duration) (*models.LoginThrottle, error) {
+	query := `
+		INSERT INTO login_throttles (scope, subject, failures, last_failure_at)
+		VALUES ($1, $2, 1, NOW())
+		ON CONFLICT (scope, subject) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttles.last_failure_at < NOW() - $3::interval THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failure_at = NOW()
+		RETURNING scope, subject, failures, last_failure_at, locked_until
+	`
+	var t models.LoginThrottle
+	err := r.db.QueryRow(ctx, query, scope, subject, window).Scan(
+		&t.Scope, &t.Subject, &t.Failures, &t.LastFailureAt, &t.LockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// TakeAttempt counts an attempt before it is checked, so parallel attempts
+// cannot get past the limit, and returns the new totals. Once max attempts
+// have been made within the window it returns nil, counting nothing, until
+// the window has passed since the last one. Reset clears the count after a
+// successful attempt.
+func (r *loginThrottleRepository) TakeAttempt(ctx context.Context, scope, subject string, max int, window time.Duration) (*models.LoginThrottle, error) {
+	query := `
+		INSERT INTO login_throttles (scope, subject, failures, last_failure_at)
+		VALUES ($1, $2, 1, NOW())
+		ON CONFLICT (scope, subject) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttles.last_failure_at < NOW() - $4::interval THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failure_at = NOW()
+		WHERE login_throttles.failures < $3 OR login_throttles.last_failure_at < NOW() - $4::interval
+		RETURNING scope, subject, failures, last_failure_at, locked_until
+	`
+	var t models.LoginThrottle
+	err := r.db.QueryRow(ctx, query, scope, subject, max, window).Scan(
+		&t.Scope, &t.Subject, &t.Failures, &t.LastFailureAt, &t.LockedUntil,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Lock blocks logins until the given time. It returns false if a lock was
+// already in force, so callers notify only once.
+func (r *loginThrottleRepository) Lock(ctx context.Context, scope, subject string, until time.Time) (bool, error) {
+	query := `
+		UPDATE login_throttles SET locked_until = $3
+		WHERE scope = $1 AND subject = $2 AND (locked_until IS NULL OR locked_until <= NOW())
+	`
+	result, err := r.db.Exec(ctx, query, scope, subject, until)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// Reset clears the failures and any lock, after a successful login or when
+// an admin unlocks.
+func (r *loginThrottleRepository) Reset(ctx context.Context, scope, subject string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM login_throttles WHERE scope = $1 AND subject = $2`, scope, subject)
+	return err
+}
+
+// DeleteStale removes throttles whose failures are older than the window
+// and that hold no lock, as they no longer slow anyone down.
+func (r *loginThrottleRepository) DeleteStale(ctx context.Context, window time.Duration) (int64, error) {
+	query := `
+		DELETE FROM login_throttles
+		WHERE last_failure_at < NOW() - $1::interval
+		  AND (locked_until IS NULL OR locked_until < NOW())
+	`
+	result, err := r.db.Exec(ctx, query, window)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type MealImageRepository interface {
+	Create(ctx context.Context, image *models.MealImage, makePrimary bool, changedBy int) error
+	GetByID(ctx context.Context, id int) (*models.MealImage, error)
+	GetByMealID(ctx context.Context, mealID int) ([]models.MealImage, error)
+	GetByMealIDs(ctx context.Context, mealIDs []int) (map[int][]models.MealImage, error)
+	UpdateAltText(ctx context.Context, id int, altText string) error
+	SetPrimary(ctx context.Context, mealID, id, changedBy int) error
+	Reorder(ctx context.Context, mealID int, imageIDs []int) error
+	Delete(ctx context.Context, mealID, id, changedBy int) error
+}
+
+type mealImageRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMealImageRepository(db *pgxpool.Pool) MealImageRepository {
+	return &mealImageRepository{db: db}
+}
+
+const mealImageColumns = `mi.id, mi.meal_id, mi.upload_id, u.url, mi.alt_text, mi.position, mi.is_primary`
+
+// Create appends the image to the end of the meal's gallery. It becomes the
+// primary image if makePrimary is set or the gallery has no primary image
+// yet, and the meal's image URL follows.
+func (r *mealImageRepository) Create(ctx context.Context, image *models.MealImage, makePrimary bool, changedBy int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockMeal(ctx, tx, image.MealID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO meal_images (meal_id, upload_id, alt_text, position)
+		SELECT $1, $2, $3, COALESCE(MAX(position) + 1, 0) FROM meal_images WHERE meal_id = $1
+		RETURNING id, position
+	`
+	err = tx.QueryRow(ctx, query, image.MealID, image.UploadID, image.AltText).Scan(&image.ID, &image.Position)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return errors.New("upload is already in this meal's gallery")
+		}
+		return err
+	}
+
+	if !makePrimary {
+		err = tx.QueryRow(ctx,
+			`SELECT NOT EXISTS(SELECT 1 FROM meal_images WHERE meal_id = $1 AND is_primary)`,
+			image.MealID,
+		).Scan(&makePrimary)
+		if err != nil {
+			return err
+		}
+	}
+	if makePrimary {
+		if err := setPrimaryImage(ctx, tx, image.MealID, image.ID, changedBy); err != nil {
+			return err
+		}
+		image.IsPrimary = true
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *mealImageRepository) GetByID(ctx context.Context, id int) (*models.MealImage, error) {
+	query := `SELECT ` + mealImageColumns + ` FROM meal_images mi JOIN uploads u ON mi.upload_id = u.id WHERE mi.id = $1`
+	var img models.MealImage
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&img.ID, &img.MealID, &img.UploadID, &img.URL, &img.AltText, &img.Position, &img.IsPrimary,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	variants, err := getUploadVariants(ctx, r.db, []int{img.UploadID})
+	if err != nil {
+		return nil, err
+	}
+	img.Variants = variants[img.UploadID]
+	return &img, nil
+}
+
+func (r *mealImageRepository) GetByMealID(ctx context.Context, mealID int) ([]models.MealImage, error) {
+	byMeal, err := r.GetByMealIDs(ctx, []int{mealID})
+	if err != nil {
+		return nil, err
+	}
+	return byMeal[mealID], nil
+}
+
+// GetByMealIDs loads the galleries of several meals in one query, keyed by meal ID.
+func (r *mealImageRepository) GetByMealIDs(ctx context.Context, mealIDs []int) (map[int][]models.MealImage, error) {
+	query := `
+		SELECT ` + mealImageColumns + `
+		FROM meal_images mi
+		JOIN uploads u ON mi.upload_id = u.id
+		WHERE mi.meal_id = ANY($1)
+		ORDER BY mi.meal_id, mi.position, mi.id
+	`
+	rows, err := r.db.Query(ctx, query, mealIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byMeal := make(map[int][]models.MealImage)
+	var uploadIDs []int
+	for rows.Next() {
+		var img models.MealImage
+		err := rows.Scan(&img.ID, &img.MealID, &img.UploadID, &img.URL, &img.AltText, &img.Position, &img.IsPrimary)
+		if err != nil {
+			return nil, err
+		}
+		byMeal[img.MealID] = append(byMeal[img.MealID], img)
+		uploadIDs = append(uploadIDs, img.UploadID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	variants, err := getUploadVariants(ctx, r.db, uploadIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, images := range byMeal {
+		for i := range images {
+			images[i].Variants = variants[images[i].UploadID]
+		}
+	}
+	return byMeal, nil
+}
+
+func (r *mealImageRepository) UpdateAltText(ctx context.Context, id int, altText string) error {
+	result, err := r.db.Exec(ctx, `UPDATE meal_images SET alt_text = $1 WHERE id = $2`, altText, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("meal image not found")
+	}
+	return nil
+}
+
+// SetPrimary makes the image the meal's only primary image and points the
+// meal's image URL at it.
+func (r *mealImageRepository) SetPrimary(ctx context.Context, mealID, id, changedBy int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockMeal(ctx, tx, mealID); err != nil {
+		return err
+	}
+	if err := setPrimaryImage(ctx, tx, mealID, id, changedBy); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Reorder sets each image's position to its index in imageIDs.
+func (r *mealImageRepository) Reorder(ctx context.Context, mealID int, imageIDs []int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for position, id := range imageIDs {
+		result, err := tx.Exec(ctx,
+			`UPDATE meal_images SET position = $1 WHERE id = $2 AND meal_id = $3`,
+			position, id, mealID,
+		)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return errors.New("meal image not found")
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Delete removes the image from the meal's gallery. If it was the primary
+// image the first remaining image takes its place, and the meal's image URL
+// follows.
+func (r *mealImageRepository) Delete(ctx context.Context, mealID, id, changedBy int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockMeal(ctx, tx, mealID); err != nil {
+		return err
+	}
+
+	var wasPrimary bool
+	err = tx.QueryRow(ctx,
+		`DELETE FROM meal_images WHERE id = $1 AND meal_id = $2 RETURNING is_primary`,
+		id, mealID,
+	).Scan(&wasPrimary)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("meal image not found")
+		}
+		return err
+	}
+	if !wasPrimary {
+		return tx.Commit(ctx)
+	}
+
+	var nextID int
+	err = tx.QueryRow(ctx,
+		`SELECT id FROM meal_images WHERE meal_id = $1 ORDER BY position, id LIMIT 1`,
+		mealID,
+	).Scan(&nextID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		err = syncMealImageURL(ctx, tx, mealID, changedBy)
+	case err == nil:
+		err = setPrimaryImage(ctx, tx, mealID, nextID, changedBy)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lockMeal locks the meal's row so changes to its gallery are applied one
+// at a time.
+func lockMeal(ctx context.Context, tx pgx.Tx, mealID int) error {
+	var id int
+	err := tx.QueryRow(ctx, `SELECT id FROM meals WHERE id = $1 FOR UPDATE`, mealID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("meal not found")
+	}
+	return err
+}
+
+// setPrimaryImage moves the meal's primary flag to the image and syncs the
+// meal's image URL.
+func setPrimaryImage(ctx context.Context, tx pgx.Tx, mealID, id, changedBy int) error {
+	_, err := tx.Exec(ctx, `UPDATE meal_images SET is_primary = FALSE WHERE meal_id = $1 AND is_primary`, mealID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, `UPDATE meal_images SET is_primary = TRUE WHERE id = $1 AND meal_id = $2`, id, mealID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("meal image not found")
+	}
+
+	return syncMealImageURL(ctx, tx, mealID, changedBy)
+}
+
+// syncMealImageURL keeps Meal.ImageURL pointing at the primary image, or
+// empty once the gallery is. The change is recorded as a new meal version
+// like any other edit.
+func syncMealImageURL(ctx context.Context, tx pgx.Tx, mealID, changedBy int) error {
+	query := `
+		WITH primary_image AS (
+		    SELECT COALESCE((
+		        SELECT u.url FROM meal_images mi JOIN uploads u ON mi.upload_id = u.id
+		        WHERE mi.meal_id = $1 AND mi.is_primary
+		    ), '') AS url
+		)
+		UPDATE meals m
+		SET image_url = p.url, current_version = m.current_version + 1
+		FROM primary_image p
+		WHERE m.id = $1 AND m.image_url IS DISTINCT FROM p.url
+		RETURNING m.id, m.current_version, m.name, m.description, m.image_url,
+		          m.calories, m.protein, m.carbs, m.fat, m.price
+	`
+	var meal models.Meal
+	err := tx.QueryRow(ctx, query, mealID).Scan(
+		&meal.ID, &meal.Version, &meal.Name, &meal.Description, &meal.ImageURL,
+		&meal.Calories, &meal.Protein, &meal.Carbs, &meal.Fat, &meal.Price,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Already pointing at the primary image
+			return nil
+		}
+		return err
+	}
+	return insertMealVersion(ctx, tx, &meal, changedBy)
+}
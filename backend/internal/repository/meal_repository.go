@@ -3,19 +3,27 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jopari/preptoplate/internal/models"
 )
 
+// ErrDuplicateSKU is returned when another meal already uses the SKU.
+var ErrDuplicateSKU = errors.New("a meal with this SKU already exists")
+
 type MealRepository interface {
-	Create(ctx context.Context, meal *models.Meal) error
+	Create(ctx context.Context, meal *models.Meal, changedBy int) error
 	GetByID(ctx context.Context, id int) (*models.Meal, error)
+	GetBySKUs(ctx context.Context, skus []string) (map[string]*models.Meal, error)
 	GetAll(ctx context.Context) ([]models.Meal, error)
-	Update(ctx context.Context, id int, meal *models.Meal) error
+	Update(ctx context.Context, id int, meal *models.Meal, changedBy int) error
+	SaveAll(ctx context.Context, meals []*models.Meal, changedBy int) error
 	Delete(ctx context.Context, id int) error
 	IsUsedInMenus(ctx context.Context, id int) (bool, error)
+	GetVersions(ctx context.Context, mealID int) ([]models.MealVersion, error)
+	GetVersion(ctx context.Context, mealID, version int) (*models.MealVersion, error)
 }
 
 type mealRepository struct {
@@ -26,34 +34,74 @@ func NewMealRepository(db *pgxpool.Pool) MealRepository {
 	return &mealRepository{db: db}
 }
 
-func (r *mealRepository) Create(ctx context.Context, meal *models.Meal) error {
-	query := `
-		INSERT INTO meals (name, description, image_url, calories, protein, carbs, fat, price) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
-		RETURNING id
-	`
-	err := r.db.QueryRow(ctx, query,
-		meal.Name,
-		meal.Description,
-		meal.ImageURL,
-		meal.Calories,
-		meal.Protein,
-		meal.Carbs,
-		meal.Fat,
-		meal.Price,
-	).Scan(&meal.ID)
-	return err
+func (r *mealRepository) Create(ctx context.Context, meal *models.Meal, changedBy int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := createMeal(ctx, tx, meal, changedBy); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (r *mealRepository) GetByID(ctx context.Context, id int) (*models.Meal, error) {
+	return r.getOne(ctx, `id = $1`, id)
+}
+
+// GetBySKUs returns the meals with any of the SKUs, keyed by SKU.
+func (r *mealRepository) GetBySKUs(ctx context.Context, skus []string) (map[string]*models.Meal, error) {
 	query := `
-		SELECT id, name, description, image_url, calories, protein, carbs, fat, price 
+		SELECT id, COALESCE(sku, ''), name, description, image_url, calories, protein, carbs, fat,
+		       effective_meal_price(id, NULL, NULL, NOW(), price), current_version, dietary_tags, ingredients 
 		FROM meals 
-		WHERE id = $1
+		WHERE sku = ANY($1)
 	`
+	rows, err := r.db.Query(ctx, query, skus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meals := make(map[string]*models.Meal)
+	for rows.Next() {
+		var meal models.Meal
+		err := rows.Scan(
+			&meal.ID,
+			&meal.SKU,
+			&meal.Name,
+			&meal.Description,
+			&meal.ImageURL,
+			&meal.Calories,
+			&meal.Protein,
+			&meal.Carbs,
+			&meal.Fat,
+			&meal.Price,
+			&meal.Version,
+			&meal.DietaryTags,
+			&meal.Ingredients,
+		)
+		if err != nil {
+			return nil, err
+		}
+		meals[meal.SKU] = &meal
+	}
+	return meals, rows.Err()
+}
+
+func (r *mealRepository) getOne(ctx context.Context, where string, arg any) (*models.Meal, error) {
+	query := `
+		SELECT id, COALESCE(sku, ''), name, description, image_url, calories, protein, carbs, fat,
+		       effective_meal_price(id, NULL, NULL, NOW(), price), current_version, dietary_tags, ingredients 
+		FROM meals 
+		WHERE ` + where
 	var meal models.Meal
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := r.db.QueryRow(ctx, query, arg).Scan(
 		&meal.ID,
+		&meal.SKU,
 		&meal.Name,
 		&meal.Description,
 		&meal.ImageURL,
@@ -62,6 +110,9 @@ func (r *mealRepository) GetByID(ctx context.Context, id int) (*models.Meal, err
 		&meal.Carbs,
 		&meal.Fat,
 		&meal.Price,
+		&meal.Version,
+		&meal.DietaryTags,
+		&meal.Ingredients,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -74,7 +125,8 @@ func (r *mealRepository) GetByID(ctx context.Context, id int) (*models.Meal, err
 
 func (r *mealRepository) GetAll(ctx context.Context) ([]models.Meal, error) {
 	query := `
-		SELECT id, name, description, image_url, calories, protein, carbs, fat, price 
+		SELECT id, COALESCE(sku, ''), name, description, image_url, calories, protein, carbs, fat,
+		       effective_meal_price(id, NULL, NULL, NOW(), price), current_version, dietary_tags, ingredients 
 		FROM meals 
 		ORDER BY id
 	`
@@ -89,6 +141,7 @@ func (r *mealRepository) GetAll(ctx context.Context) ([]models.Meal, error) {
 		var meal models.Meal
 		err := rows.Scan(
 			&meal.ID,
+			&meal.SKU,
 			&meal.Name,
 			&meal.Description,
 			&meal.ImageURL,
@@ -97,6 +150,9 @@ func (r *mealRepository) GetAll(ctx context.Context) ([]models.Meal, error) {
 			&meal.Carbs,
 			&meal.Fat,
 			&meal.Price,
+			&meal.Version,
+			&meal.DietaryTags,
+			&meal.Ingredients,
 		)
 		if err != nil {
 			return nil, err
@@ -106,31 +162,41 @@ func (r *mealRepository) GetAll(ctx context.Context) ([]models.Meal, error) {
 	return meals, nil
 }
 
-func (r *mealRepository) Update(ctx context.Context, id int, meal *models.Meal) error {
-	query := `
-		UPDATE meals 
-		SET name = $1, description = $2, image_url = $3, calories = $4, 
-		    protein = $5, carbs = $6, fat = $7, price = $8 
-		WHERE id = $9
-	`
-	result, err := r.db.Exec(ctx, query,
-		meal.Name,
-		meal.Description,
-		meal.ImageURL,
-		meal.Calories,
-		meal.Protein,
-		meal.Carbs,
-		meal.Fat,
-		meal.Price,
-		id,
-	)
+func (r *mealRepository) Update(ctx context.Context, id int, meal *models.Meal, changedBy int) error {
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	if result.RowsAffected() == 0 {
-		return errors.New("meal not found")
+	defer tx.Rollback(ctx)
+
+	if err := updateMeal(ctx, tx, id, meal, changedBy); err != nil {
+		return err
 	}
-	return nil
+
+	return tx.Commit(ctx)
+}
+
+// SaveAll creates the meals without an ID and updates the rest, all in one
+// transaction, so either every meal is saved or none is.
+func (r *mealRepository) SaveAll(ctx context.Context, meals []*models.Meal, changedBy int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, meal := range meals {
+		if meal.ID == 0 {
+			err = createMeal(ctx, tx, meal, changedBy)
+		} else {
+			err = updateMeal(ctx, tx, meal.ID, meal, changedBy)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", meal.SKU, err)
+		}
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (r *mealRepository) Delete(ctx context.Context, id int) error {
@@ -154,3 +220,175 @@ func (r *mealRepository) IsUsedInMenus(ctx context.Context, id int) (bool, error
 	}
 	return count > 0, nil
 }
+
+func (r *mealRepository) GetVersions(ctx context.Context, mealID int) ([]models.MealVersion, error) {
+	query := `
+		SELECT meal_id, version, name, description, image_url, calories, protein, carbs, fat, price, created_by, created_at
+		FROM meal_versions
+		WHERE meal_id = $1
+		ORDER BY version DESC
+	`
+	rows, err := r.db.Query(ctx, query, mealID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []models.MealVersion{}
+	for rows.Next() {
+		var v models.MealVersion
+		err := rows.Scan(
+			&v.MealID, &v.Version, &v.Name, &v.Description, &v.ImageURL,
+			&v.Calories, &v.Protein, &v.Carbs, &v.Fat, &v.Price, &v.CreatedBy, &v.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (r *mealRepository) GetVersion(ctx context.Context, mealID, version int) (*models.MealVersion, error) {
+	query := `
+		SELECT meal_id, version, name, description, image_url, calories, protein, carbs, fat, price, created_by, created_at
+		FROM meal_versions
+		WHERE meal_id = $1 AND version = $2
+	`
+	var v models.MealVersion
+	err := r.db.QueryRow(ctx, query, mealID, version).Scan(
+		&v.MealID, &v.Version, &v.Name, &v.Description, &v.ImageURL,
+		&v.Calories, &v.Protein, &v.Carbs, &v.Fat, &v.Price, &v.CreatedBy, &v.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// createMeal inserts the meal and its first version.
+func createMeal(ctx context.Context, tx pgx.Tx, meal *models.Meal, changedBy int) error {
+	query := `
+		INSERT INTO meals (name, description, image_url, calories, protein, carbs, fat, price, dietary_tags, sku, ingredients) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11) 
+		RETURNING id, current_version
+	`
+	err := tx.QueryRow(ctx, query,
+		meal.Name,
+		meal.Description,
+		meal.ImageURL,
+		meal.Calories,
+		meal.Protein,
+		meal.Carbs,
+		meal.Fat,
+		meal.Price,
+		meal.DietaryTags,
+		meal.SKU,
+		meal.Ingredients,
+	).Scan(&meal.ID, &meal.Version)
+	if err != nil {
+		return mapSKUError(err)
+	}
+
+	// Meals created without a SKU get one derived from their ID
+	if meal.SKU == "" {
+		err = tx.QueryRow(ctx, `UPDATE meals SET sku = 'MEAL-' || id WHERE id = $1 RETURNING sku`, meal.ID).Scan(&meal.SKU)
+		if err != nil {
+			return mapSKUError(err)
+		}
+	}
+
+	// Record the initial version
+	return insertMealVersion(ctx, tx, meal, changedBy)
+}
+
+// updateMeal saves the meal's fields as its next version.
+func updateMeal(ctx context.Context, tx pgx.Tx, id int, meal *models.Meal, changedBy int) error {
+	// Compare with the price in effect, which is what GetByID shows, so
+	// saving a meal without touching its price records no price change
+	var oldPrice int
+	err := tx.QueryRow(ctx,
+		`SELECT effective_meal_price(id, NULL, NULL, NOW(), price) FROM meals WHERE id = $1 FOR UPDATE`, id,
+	).Scan(&oldPrice)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("meal not found")
+		}
+		return err
+	}
+
+	query := `
+		UPDATE meals 
+		SET name = $1, description = $2, image_url = $3, calories = $4, 
+		    protein = $5, carbs = $6, fat = $7, price = $8, dietary_tags = $9, sku = NULLIF($10, ''),
+		    ingredients = $11, current_version = current_version + 1 
+		WHERE id = $12
+		RETURNING id, current_version
+	`
+	err = tx.QueryRow(ctx, query,
+		meal.Name,
+		meal.Description,
+		meal.ImageURL,
+		meal.Calories,
+		meal.Protein,
+		meal.Carbs,
+		meal.Fat,
+		meal.Price,
+		meal.DietaryTags,
+		meal.SKU,
+		meal.Ingredients,
+		id,
+	).Scan(&meal.ID, &meal.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("meal not found")
+		}
+		return mapSKUError(err)
+	}
+
+	// Snapshot the new state as the next version
+	if err := insertMealVersion(ctx, tx, meal, changedBy); err != nil {
+		return err
+	}
+
+	return recordPriceChange(ctx, tx, id, nil, oldPrice, meal.Price, changedBy)
+}
+
+// insertMealVersion snapshots the meal's current fields under meal.Version.
+func insertMealVersion(ctx context.Context, tx pgx.Tx, meal *models.Meal, changedBy int) error {
+	// A changedBy of 0 is a system change, e.g. seeding
+	var createdBy *int
+	if changedBy != 0 {
+		createdBy = &changedBy
+	}
+
+	query := `
+		INSERT INTO meal_versions (meal_id, version, name, description, image_url, calories, protein, carbs, fat, price, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err := tx.Exec(ctx, query,
+		meal.ID,
+		meal.Version,
+		meal.Name,
+		meal.Description,
+		meal.ImageURL,
+		meal.Calories,
+		meal.Protein,
+		meal.Carbs,
+		meal.Fat,
+		meal.Price,
+		createdBy,
+	)
+	return err
+}
+
+// mapSKUError turns a unique violation on the SKU into ErrDuplicateSKU.
+func mapSKUError(err error) error {
+	if isUniqueViolation(err) {
+		return ErrDuplicateSKU
+	}
+	return err
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type MealTranslationRepository interface {
+	GetByMealID(ctx context.Context, mealID int) ([]models.MealTranslation, error)
+	GetForMeals(ctx context.Context, mealIDs []int, languages []string) (map[int]map[string]models.MealTranslation, error)
+	Upsert(ctx context.Context, translation *models.MealTranslation) error
+	Delete(ctx context.Context, mealID int, language string) error
+}
+
+type mealTranslationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMealTranslationRepository(db *pgxpool.Pool) MealTranslationRepository {
+	return &mealTranslationRepository{db: db}
+}
+
+func (r *mealTranslationRepository) GetByMealID(ctx context.Context, mealID int) ([]models.MealTranslation, error) {
+	query := `
+		SELECT meal_id, language, name, description, ingredients, updated_at
+		FROM meal_translations
+		WHERE meal_id = $1
+		ORDER BY language
+	`
+	rows, err := r.db.Query(ctx, query, mealID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := []models.MealTranslation{}
+	for rows.Next() {
+		var t models.MealTranslation
+		if err := rows.Scan(&t.MealID, &t.Language, &t.Name, &t.Description, &t.Ingredients, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		translations = append(translations, t)
+	}
+	return translations, rows.Err()
+}
+
+// GetForMeals loads the translations of several meals into the given
+// languages in one query, keyed by meal ID and language.
+func (r *mealTranslationRepository) GetForMeals(ctx context.Context, mealIDs []int, languages []string) (map[int]map[string]models.MealTranslation, error) {
+	query := `
+		SELECT meal_id, language, name, description, ingredients, updated_at
+		FROM meal_translations
+		WHERE meal_id = ANY($1) AND language = ANY($2)
+	`
+	rows, err := r.db.Query(ctx, query, mealIDs, languages)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byMeal := make(map[int]map[string]models.MealTranslation)
+	for rows.Next() {
+		var t models.MealTranslation
+		if err := rows.Scan(&t.MealID, &t.Language, &t.Name, &t.Description, &t.Ingredients, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if byMeal[t.MealID] == nil {
+			byMeal[t.MealID] = make(map[string]models.MealTranslation)
+		}
+		byMeal[t.MealID][t.Language] = t
+	}
+	return byMeal, rows.Err()
+}
+
+func (r *mealTranslationRepository) Upsert(ctx context.Context, translation *models.MealTranslation) error {
+	query := `
+		INSERT INTO meal_translations (meal_id, language, name, description, ingredients, updated_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+		ON CONFLICT (meal_id, language) DO UPDATE
+		SET name = EXCLUDED.name, description = EXCLUDED.description,
+		    ingredients = EXCLUDED.ingredients, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query,
+		translation.MealID,
+		translation.Language,
+		translation.Name,
+		translation.Description,
+		translation.Ingredients,
+	).Scan(&translation.UpdatedAt)
+}
+
+func (r *mealTranslationRepository) Delete(ctx context.Context, mealID int, language string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM meal_translations WHERE meal_id = $1 AND language = $2`, mealID, language)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("meal translation not found")
+	}
+	return nil
+}
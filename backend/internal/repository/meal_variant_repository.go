@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type MealVariantRepository interface {
+	Create(ctx context.Context, variant *models.MealVariant) error
+	GetByID(ctx context.Context, id int) (*models.MealVariant, error)
+	GetByMealID(ctx context.Context, mealID int) ([]models.MealVariant, error)
+	GetByMealIDs(ctx context.Context, mealIDs []int) (map[int][]models.MealVariant, error)
+	Update(ctx context.Context, id int, variant *models.MealVariant) error
+	Delete(ctx context.Context, id int) error
+	IsUsedInMenus(ctx context.Context, id int) (bool, error)
+}
+
+type mealVariantRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMealVariantRepository(db *pgxpool.Pool) MealVariantRepository {
+	return &mealVariantRepository{db: db}
+}
+
+func (r *mealVariantRepository) Create(ctx context.Context, variant *models.MealVariant) error {
+	query := `
+		INSERT INTO meal_variants (meal_id, name, calories, protein, carbs, fat, price)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	return r.db.QueryRow(ctx, query,
+		variant.MealID,
+		variant.Name,
+		variant.Calories,
+		variant.Protein,
+		variant.Carbs,
+		variant.Fat,
+		variant.Price,
+	).Scan(&variant.ID)
+}
+
+func (r *mealVariantRepository) GetByID(ctx context.Context, id int) (*models.MealVariant, error) {
+	query := `
+		SELECT id, meal_id, name, calories, protein, carbs, fat, effective_meal_price(meal_id, id, NULL, NOW(), price)
+		FROM meal_variants
+		WHERE id = $1
+	`
+	var v models.MealVariant
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&v.ID, &v.MealID, &v.Name, &v.Calories, &v.Protein, &v.Carbs, &v.Fat, &v.Price,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *mealVariantRepository) GetByMealID(ctx context.Context, mealID int) ([]models.MealVariant, error) {
+	byMeal, err := r.GetByMealIDs(ctx, []int{mealID})
+	if err != nil {
+		return nil, err
+	}
+	return byMeal[mealID], nil
+}
+
+// GetByMealIDs loads the variants of several meals in one query, keyed by meal ID.
+func (r *mealVariantRepository) GetByMealIDs(ctx context.Context, mealIDs []int) (map[int][]models.MealVariant, error) {
+	query := `
+		SELECT id, meal_id, name, calories, protein, carbs, fat, effective_meal_price(meal_id, id, NULL, NOW(), price)
+		FROM meal_variants
+		WHERE meal_id = ANY($1)
+		ORDER BY meal_id, price, id
+	`
+	rows, err := r.db.Query(ctx, query, mealIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byMeal := make(map[int][]models.MealVariant)
+	for rows.Next() {
+		var v models.MealVariant
+		err := rows.Scan(&v.ID, &v.MealID, &v.Name, &v.Calories, &v.Protein, &v.Carbs, &v.Fat, &v.Price)
+		if err != nil {
+			return nil, err
+		}
+		byMeal[v.MealID] = append(byMeal[v.MealID], v)
+	}
+	return byMeal, nil
+}
+
+func (r *mealVariantRepository) Update(ctx context.Context, id int, variant *models.MealVariant) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Compare with the price in effect, as GetByID shows it
+	var mealID, oldPrice int
+	err = tx.QueryRow(ctx,
+		`SELECT meal_id, effective_meal_price(meal_id, id, NULL, NOW(), price) FROM meal_variants WHERE id = $1 FOR UPDATE`, id,
+	).Scan(&mealID, &oldPrice)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("meal variant not found")
+		}
+		return err
+	}
+
+	query := `
+		UPDATE meal_variants
+		SET name = $1, calories = $2, protein = $3, carbs = $4, fat = $5, price = $6
+		WHERE id = $7
+	`
+	_, err = tx.Exec(ctx, query,
+		variant.Name,
+		variant.Calories,
+		variant.Protein,
+		variant.Carbs,
+		variant.Fat,
+		variant.Price,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := recordPriceChange(ctx, tx, mealID, &id, oldPrice, variant.Price, 0); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *mealVariantRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM meal_variants WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("meal variant not found")
+	}
+	return nil
+}
+
+func (r *mealVariantRepository) IsUsedInMenus(ctx context.Context, id int) (bool, error) {
+	query := `SELECT COUNT(*) FROM menu_variant_stock WHERE variant_id = $1`
+	var count int
+	err := r.db.QueryRow(ctx, query, id).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type NutritionGoalsRepository interface {
+	GetByUserID(ctx context.Context, userID int) (*models.NutritionGoals, error)
+	Upsert(ctx context.Context, goals *models.NutritionGoals) error
+	Delete(ctx context.Context, userID int) error
+}
+
+type nutritionGoalsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNutritionGoalsRepository(db *pgxpool.Pool) NutritionGoalsRepository {
+	return &nutritionGoalsRepository{db: db}
+}
+
+func (r *nutritionGoalsRepository) GetByUserID(ctx context.Context, userID int) (*models.NutritionGoals, error) {
+	query := `
+		SELECT user_id, period, calories, protein, carbs, fat, updated_at
+		FROM user_nutrition_goals
+		WHERE user_id = $1
+	`
+	var goals models.NutritionGoals
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&goals.UserID, &goals.Period, &goals.Calories, &goals.Protein, &goals.Carbs, &goals.Fat, &goals.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &goals, nil
+}
+
+func (r *nutritionGoalsRepository) Upsert(ctx context.Context, goals *models.NutritionGoals) error {
+	query := `
+		INSERT INTO user_nutrition_goals (user_id, period, calories, protein, carbs, fat)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE
+		SET period = EXCLUDED.period, calories = EXCLUDED.calories, protein = EXCLUDED.protein,
+		    carbs = EXCLUDED.carbs, fat = EXCLUDED.fat, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at
+	`
+	return r.db.QueryRow(ctx, query,
+		goals.UserID,
+		goals.Period,
+		goals.Calories,
+		goals.Protein,
+		goals.Carbs,
+		goals.Fat,
+	).Scan(&goals.UpdatedAt)
+}
+
+func (r *nutritionGoalsRepository) Delete(ctx context.Context, userID int) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM user_nutrition_goals WHERE user_id = $1`, userID)
+	return err
+}
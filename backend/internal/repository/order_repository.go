@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -10,10 +11,12 @@ import (
 )
 
 type OrderRepository interface {
-	Create(ctx context.Context, order *models.Order) error
-	AddItem(ctx context.Context, orderID int, item *models.OrderItem) error
+	Place(ctx context.Context, order *models.Order, cartID int) error
 	GetByID(ctx context.Context, id int) (*models.Order, error)
 	GetByUserID(ctx context.Context, userID int) ([]models.Order, error)
+	List(ctx context.Context, filter *models.OrderFilter) ([]models.Order, error)
+	GetProductionList(ctx context.Context, deliveryDate time.Time) ([]models.ProductionItem, error)
+	GetDeliveries(ctx context.Context, deliveryDate time.Time) ([]models.Delivery, error)
 	UpdateStatus(ctx context.Context, id int, status string) error
 }
 
@@ -25,39 +28,124 @@ func NewOrderRepository(db *pgxpool.Pool) OrderRepository {
 	return &orderRepository{db: db}
 }
 
-func (r *orderRepository) Create(ctx context.Context, order *models.Order) error {
+// Place saves a new order with its items, bundles and add-ons, takes their
+// stock from the order's weekly menu and empties the cart it was made from.
+// It is all one transaction, so running out of stock part way through, or
+// any other failure, leaves no order behind and the stock and cart as they
+// were.
+func (r *orderRepository) Place(ctx context.Context, order *models.Order, cartID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertOrder(ctx, tx, order); err != nil {
+		return err
+	}
+
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.OrderID = order.ID
+		if err := insertOrderItem(ctx, tx, order.ID, item); err != nil {
+			return err
+		}
+		if item.VariantID != nil {
+			err = decrementVariantStock(ctx, tx, order.WeekID, *item.VariantID, item.Quantity)
+		} else {
+			err = decrementMealStock(ctx, tx, order.WeekID, item.MealID, item.Quantity)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range order.Bundles {
+		bundle := &order.Bundles[i]
+		bundle.OrderID = order.ID
+		if err := insertOrderBundle(ctx, tx, order.ID, bundle); err != nil {
+			return err
+		}
+	}
+
+	for i := range order.Addons {
+		addon := &order.Addons[i]
+		addon.OrderID = order.ID
+		if err := insertOrderAddon(ctx, tx, order.ID, addon); err != nil {
+			return err
+		}
+		if err := decrementAddonStock(ctx, tx, order.WeekID, addon.AddonID, addon.Quantity); err != nil {
+			return err
+		}
+	}
+
+	if err := clearCart(ctx, tx, cartID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func insertOrder(ctx context.Context, tx pgx.Tx, order *models.Order) error {
 	query := `
-		INSERT INTO orders (user_id, week_id, status, total_price, delivery_date) 
-		VALUES ($1, $2, $3, $4, $5) 
+		INSERT INTO orders (user_id, week_id, status, total_price, delivery_date,
+		                    delivery_address_line1, delivery_address_line2, delivery_address_city,
+		                    delivery_address_postcode, delivery_address_instructions)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''))
 		RETURNING id, created_at
 	`
-	err := r.db.QueryRow(ctx, query,
+	var address models.Address
+	var line1 *string
+	if order.DeliveryAddress != nil {
+		address = *order.DeliveryAddress
+		line1 = &address.Line1
+	}
+	err := tx.QueryRow(ctx, query,
 		order.UserID,
 		order.WeekID,
 		order.Status,
 		order.TotalPrice,
 		order.DeliveryDate,
+		line1, address.Line2, address.City, address.Postcode, address.Instructions,
 	).Scan(&order.ID, &order.CreatedAt)
 	return err
 }
 
-func (r *orderRepository) AddItem(ctx context.Context, orderID int, item *models.OrderItem) error {
+// insertOrderItem adds an item to the order, keeping a copy of its
+// variant's name and nutrition as they are now.
+func insertOrderItem(ctx context.Context, tx pgx.Tx, orderID int, item *models.OrderItem) error {
 	query := `
-		INSERT INTO order_items (order_id, meal_id, quantity) 
-		VALUES ($1, $2, $3)
+		INSERT INTO order_items (order_id, meal_id, quantity, meal_version, variant_id, price, bundle_id,
+		                         variant_name, variant_calories, variant_protein, variant_carbs, variant_fat)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
-	_, err := r.db.Exec(ctx, query, orderID, item.MealID, item.Quantity)
+	var variant nullableVariant
+	if item.Variant != nil {
+		variant = nullableVariant{
+			Name:     &item.Variant.Name,
+			Calories: &item.Variant.Calories,
+			Protein:  &item.Variant.Protein,
+			Carbs:    &item.Variant.Carbs,
+			Fat:      &item.Variant.Fat,
+		}
+	}
+	_, err := tx.Exec(ctx, query, orderID, item.MealID, item.Quantity, item.MealVersion, item.VariantID, item.Price, item.BundleID,
+		variant.Name, variant.Calories, variant.Protein, variant.Carbs, variant.Fat)
 	return err
 }
 
 func (r *orderRepository) GetByID(ctx context.Context, id int) (*models.Order, error) {
 	// Get order
 	orderQuery := `
-		SELECT id, user_id, week_id, status, total_price, delivery_date, created_at 
+		SELECT id, user_id, week_id, status, total_price, delivery_date, created_at,
+		       COALESCE((SELECT SUM((ob.regular_price - ob.price) * ob.quantity) FROM order_bundles ob WHERE ob.order_id = orders.id), 0),
+		       delivery_address_line1, COALESCE(delivery_address_line2, ''), COALESCE(delivery_address_city, ''),
+		       COALESCE(delivery_address_postcode, ''), COALESCE(delivery_address_instructions, '')
 		FROM orders 
 		WHERE id = $1
 	`
 	var order models.Order
+	var line1 *string
+	var address models.Address
 	err := r.db.QueryRow(ctx, orderQuery, id).Scan(
 		&order.ID,
 		&order.UserID,
@@ -66,6 +154,8 @@ func (r *orderRepository) GetByID(ctx context.Context, id int) (*models.Order, e
 		&order.TotalPrice,
 		&order.DeliveryDate,
 		&order.CreatedAt,
+		&order.Discount,
+		&line1, &address.Line2, &address.City, &address.Postcode, &address.Instructions,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -73,14 +163,23 @@ func (r *orderRepository) GetByID(ctx context.Context, id int) (*models.Order, e
 		}
 		return nil, err
 	}
+	if line1 != nil {
+		address.Line1 = *line1
+		order.DeliveryAddress = &address
+	}
 
-	// Get order items with meal details
+	// Get order items with the meal and variant details as they were ordered
 	itemsQuery := `
-		SELECT oi.order_id, oi.meal_id, oi.quantity,
-		       m.id, m.name, m.description, m.image_url, m.calories, m.protein, m.carbs, m.fat, m.price
+		SELECT oi.order_id, oi.meal_id, oi.variant_id, oi.quantity, COALESCE(oi.price, mv.price), oi.bundle_id,
+		       m.id, mv.name, mv.description, mv.image_url, mv.calories, mv.protein, mv.carbs, mv.fat, mv.price, mv.version,
+		       COALESCE(oi.variant_name, v.name), COALESCE(oi.variant_calories, v.calories), COALESCE(oi.variant_protein, v.protein),
+		       COALESCE(oi.variant_carbs, v.carbs), COALESCE(oi.variant_fat, v.fat), COALESCE(oi.price, v.price)
 		FROM order_items oi
 		JOIN meals m ON oi.meal_id = m.id
+		JOIN meal_versions mv ON mv.meal_id = m.id AND mv.version = COALESCE(oi.meal_version, m.current_version)
+		LEFT JOIN meal_variants v ON oi.variant_id = v.id
 		WHERE oi.order_id = $1
+		ORDER BY oi.bundle_id NULLS FIRST, oi.meal_id, oi.variant_id NULLS FIRST
 	`
 	rows, err := r.db.Query(ctx, itemsQuery, id)
 	if err != nil {
@@ -91,24 +190,101 @@ func (r *orderRepository) GetByID(ctx context.Context, id int) (*models.Order, e
 	order.Items = []models.OrderItem{}
 	for rows.Next() {
 		var item models.OrderItem
+		var variant nullableVariant
 		err := rows.Scan(
-			&item.OrderID, &item.MealID, &item.Quantity,
+			&item.OrderID, &item.MealID, &item.VariantID, &item.Quantity, &item.Price, &item.BundleID,
 			&item.Meal.ID, &item.Meal.Name, &item.Meal.Description, &item.Meal.ImageURL,
 			&item.Meal.Calories, &item.Meal.Protein, &item.Meal.Carbs, &item.Meal.Fat, &item.Meal.Price,
+			&item.Meal.Version,
+			&variant.Name, &variant.Calories, &variant.Protein, &variant.Carbs, &variant.Fat, &variant.Price,
 		)
 		if err != nil {
 			return nil, err
 		}
-		item.Price = item.Meal.Price // Price at time of order
+		item.MealVersion = item.Meal.Version
+		item.Variant = variant.toModel(item.VariantID, item.MealID)
 		order.Items = append(order.Items, item)
 	}
+	rows.Close()
+
+	// Get order add-ons
+	addonsQuery := `
+		SELECT oa.order_id, oa.addon_id, oa.quantity, oa.price,
+		       a.id, a.name, a.description, a.image_url, a.category, a.calories, a.protein, a.carbs, a.fat, a.price
+		FROM order_addons oa
+		JOIN addons a ON oa.addon_id = a.id
+		WHERE oa.order_id = $1
+	`
+	addonRows, err := r.db.Query(ctx, addonsQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer addonRows.Close()
+
+	order.Addons = []models.OrderAddon{}
+	for addonRows.Next() {
+		var oa models.OrderAddon
+		err := addonRows.Scan(
+			&oa.OrderID, &oa.AddonID, &oa.Quantity, &oa.Price,
+			&oa.Addon.ID, &oa.Addon.Name, &oa.Addon.Description, &oa.Addon.ImageURL, &oa.Addon.Category,
+			&oa.Addon.Calories, &oa.Addon.Protein, &oa.Addon.Carbs, &oa.Addon.Fat, &oa.Addon.Price,
+		)
+		if err != nil {
+			return nil, err
+		}
+		order.Addons = append(order.Addons, oa)
+	}
+	addonRows.Close()
+
+	// Get order bundles
+	bundlesQuery := `
+		SELECT order_id, bundle_id, name, quantity, price, regular_price
+		FROM order_bundles
+		WHERE order_id = $1
+		ORDER BY bundle_id
+	`
+	bundleRows, err := r.db.Query(ctx, bundlesQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer bundleRows.Close()
+
+	order.Bundles = []models.OrderBundle{}
+	for bundleRows.Next() {
+		var ob models.OrderBundle
+		err := bundleRows.Scan(&ob.OrderID, &ob.BundleID, &ob.Name, &ob.Quantity, &ob.Price, &ob.RegularPrice)
+		if err != nil {
+			return nil, err
+		}
+		ob.Discount = ob.RegularPrice - ob.Price
+		order.Bundles = append(order.Bundles, ob)
+	}
 
 	return &order, nil
 }
 
+func insertOrderAddon(ctx context.Context, tx pgx.Tx, orderID int, addon *models.OrderAddon) error {
+	query := `
+		INSERT INTO order_addons (order_id, addon_id, quantity, price)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := tx.Exec(ctx, query, orderID, addon.AddonID, addon.Quantity, addon.Price)
+	return err
+}
+
+func insertOrderBundle(ctx context.Context, tx pgx.Tx, orderID int, bundle *models.OrderBundle) error {
+	query := `
+		INSERT INTO order_bundles (order_id, bundle_id, name, quantity, price, regular_price)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := tx.Exec(ctx, query, orderID, bundle.BundleID, bundle.Name, bundle.Quantity, bundle.Price, bundle.RegularPrice)
+	return err
+}
+
 func (r *orderRepository) GetByUserID(ctx context.Context, userID int) ([]models.Order, error) {
 	query := `
-		SELECT id, user_id, week_id, status, total_price, delivery_date, created_at 
+		SELECT id, user_id, week_id, status, total_price, delivery_date, created_at,
+		       COALESCE((SELECT SUM((ob.regular_price - ob.price) * ob.quantity) FROM order_bundles ob WHERE ob.order_id = orders.id), 0)
 		FROM orders 
 		WHERE user_id = $1 
 		ORDER BY created_at DESC
@@ -130,6 +306,7 @@ func (r *orderRepository) GetByUserID(ctx context.Context, userID int) ([]models
 			&order.TotalPrice,
 			&order.DeliveryDate,
 			&order.CreatedAt,
+			&order.Discount,
 		)
 		if err != nil {
 			return nil, err
@@ -151,3 +328,118 @@ func (r *orderRepository) UpdateStatus(ctx context.Context, id int, status strin
 	}
 	return nil
 }
+
+// List returns every customer's orders matching the filter, newest first,
+// without their items.
+func (r *orderRepository) List(ctx context.Context, filter *models.OrderFilter) ([]models.Order, error) {
+	query := `
+		SELECT id, user_id, week_id, status, total_price, delivery_date, created_at,
+		       COALESCE((SELECT SUM((ob.regular_price - ob.price) * ob.quantity) FROM order_bundles ob WHERE ob.order_id = orders.id), 0)
+		FROM orders
+		WHERE ($1 = '' OR status = $1)
+		  AND ($2::date IS NULL OR delivery_date = $2)
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, filter.Status, filter.DeliveryDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []models.Order{}
+	for rows.Next() {
+		var order models.Order
+		err := rows.Scan(
+			&order.ID,
+			&order.UserID,
+			&order.WeekID,
+			&order.Status,
+			&order.TotalPrice,
+			&order.DeliveryDate,
+			&order.CreatedAt,
+			&order.Discount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
+// GetProductionList totals the meals in pending and confirmed orders for a
+// delivery date, by meal and variant, under the name they were ordered by.
+func (r *orderRepository) GetProductionList(ctx context.Context, deliveryDate time.Time) ([]models.ProductionItem, error) {
+	query := `
+		SELECT oi.meal_id, mv.name, oi.variant_id, COALESCE(oi.variant_name, v.name, ''), SUM(oi.quantity)
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		JOIN meals m ON m.id = oi.meal_id
+		JOIN meal_versions mv ON mv.meal_id = m.id AND mv.version = COALESCE(oi.meal_version, m.current_version)
+		LEFT JOIN meal_variants v ON v.id = oi.variant_id
+		WHERE o.delivery_date = $1 AND o.status IN ('pending', 'confirmed')
+		GROUP BY oi.meal_id, mv.name, oi.variant_id, COALESCE(oi.variant_name, v.name, '')
+		ORDER BY mv.name, oi.variant_id NULLS FIRST
+	`
+	rows, err := r.db.Query(ctx, query, deliveryDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ProductionItem{}
+	for rows.Next() {
+		var item models.ProductionItem
+		if err := rows.Scan(&item.MealID, &item.MealName, &item.VariantID, &item.VariantName, &item.Quantity); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// GetDeliveries lists the orders due on a delivery date, leaving out
+// cancelled ones, with the address each was ordered to.
+func (r *orderRepository) GetDeliveries(ctx context.Context, deliveryDate time.Time) ([]models.Delivery, error) {
+	query := `
+		SELECT o.id, u.name, u.email, u.phone,
+		       o.delivery_address_line1, COALESCE(o.delivery_address_line2, ''), COALESCE(o.delivery_address_city, ''),
+		       COALESCE(o.delivery_address_postcode, ''), COALESCE(o.delivery_address_instructions, ''),
+		       o.status,
+		       COALESCE((SELECT SUM(oi.quantity) FROM order_items oi WHERE oi.order_id = o.id), 0),
+		       COALESCE((SELECT SUM(oa.quantity) FROM order_addons oa WHERE oa.order_id = o.id), 0)
+		FROM orders o
+		JOIN users u ON u.id = o.user_id
+		WHERE o.delivery_date = $1 AND o.status <> 'cancelled'
+		ORDER BY o.id
+	`
+	rows, err := r.db.Query(ctx, query, deliveryDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.Delivery{}
+	for rows.Next() {
+		var d models.Delivery
+		var line1 *string
+		var address models.Address
+		err := rows.Scan(
+			&d.OrderID, &d.CustomerName, &d.CustomerEmail, &d.CustomerPhone,
+			&line1, &address.Line2, &address.City, &address.Postcode, &address.Instructions,
+			&d.Status, &d.MealCount, &d.AddonCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if line1 != nil {
+			address.Line1 = *line1
+			d.Address = &address
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+// PriceKey identifies what a price is for: a meal's standard portion
+// (VariantID 0) or one of its variants.
+type PriceKey struct {
+	MealID    int
+	VariantID int
+}
+
+type PriceRepository interface {
+	Create(ctx context.Context, price *models.MealPrice) error
+	GetByID(ctx context.Context, id int) (*models.MealPrice, error)
+	GetByMealID(ctx context.Context, mealID int) ([]models.MealPrice, error)
+	Delete(ctx context.Context, id int) error
+	GetUserPlan(ctx context.Context, userID int) (*string, error)
+	GetPlanPrices(ctx context.Context, planType string, mealIDs []int) (map[PriceKey]int, error)
+}
+
+type priceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPriceRepository(db *pgxpool.Pool) PriceRepository {
+	return &priceRepository{db: db}
+}
+
+func (r *priceRepository) Create(ctx context.Context, price *models.MealPrice) error {
+	query := `
+		INSERT INTO meal_prices (meal_id, variant_id, plan_type, price, effective_from, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(ctx, query,
+		price.MealID,
+		price.VariantID,
+		price.PlanType,
+		price.Price,
+		price.EffectiveFrom,
+		price.CreatedBy,
+	).Scan(&price.ID, &price.CreatedAt)
+}
+
+func (r *priceRepository) GetByID(ctx context.Context, id int) (*models.MealPrice, error) {
+	query := `
+		SELECT id, meal_id, variant_id, plan_type, price, effective_from, created_by, created_at
+		FROM meal_prices
+		WHERE id = $1
+	`
+	var p models.MealPrice
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&p.ID, &p.MealID, &p.VariantID, &p.PlanType, &p.Price, &p.EffectiveFrom, &p.CreatedBy, &p.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetByMealID lists a meal's price history and scheduled changes across all
+// price lists, newest first within each list.
+func (r *priceRepository) GetByMealID(ctx context.Context, mealID int) ([]models.MealPrice, error) {
+	query := `
+		SELECT id, meal_id, variant_id, plan_type, price, effective_from, created_by, created_at
+		FROM meal_prices
+		WHERE meal_id = $1
+		ORDER BY variant_id NULLS FIRST, plan_type NULLS FIRST, effective_from DESC, id DESC
+	`
+	rows, err := r.db.Query(ctx, query, mealID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []models.MealPrice{}
+	for rows.Next() {
+		var p models.MealPrice
+		err := rows.Scan(&p.ID, &p.MealID, &p.VariantID, &p.PlanType, &p.Price, &p.EffectiveFrom, &p.CreatedBy, &p.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
+
+func (r *priceRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM meal_prices WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("price not found")
+	}
+	return nil
+}
+
+// GetUserPlan returns the plan of the user's active subscription, or nil
+// when they don't have one.
+func (r *priceRepository) GetUserPlan(ctx context.Context, userID int) (*string, error) {
+	query := `
+		SELECT plan_type
+		FROM subscriptions
+		WHERE user_id = $1 AND status = 'active' AND plan_type IS NOT NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	var planType string
+	err := r.db.QueryRow(ctx, query, userID).Scan(&planType)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &planType, nil
+}
+
+// GetPlanPrices returns the prices currently in effect on a plan's price
+// list for the given meals and their variants. Meals without a plan price
+// are left out.
+func (r *priceRepository) GetPlanPrices(ctx context.Context, planType string, mealIDs []int) (map[PriceKey]int, error) {
+	query := `
+		SELECT DISTINCT ON (meal_id, variant_id) meal_id, COALESCE(variant_id, 0), price
+		FROM meal_prices
+		WHERE plan_type = $1 AND meal_id = ANY($2) AND effective_from <= NOW()
+		ORDER BY meal_id, variant_id, effective_from DESC, id DESC
+	`
+	rows, err := r.db.Query(ctx, query, planType, mealIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[PriceKey]int)
+	for rows.Next() {
+		var key PriceKey
+		var price int
+		if err := rows.Scan(&key.MealID, &key.VariantID, &price); err != nil {
+			return nil, err
+		}
+		prices[key] = price
+	}
+	return prices, rows.Err()
+}
+
+// recordPriceChange adds a standard price list entry, effective now, when a
+// meal's or variant's catalogue price is changed directly, so it takes over
+// from earlier entries on the standard list.
+func recordPriceChange(ctx context.Context, tx pgx.Tx, mealID int, variantID *int, oldPrice, newPrice, changedBy int) error {
+	if oldPrice == newPrice {
+		return nil
+	}
+
+	// A changedBy of 0 is a system change, e.g. seeding
+	var createdBy *int
+	if changedBy != 0 {
+		createdBy = &changedBy
+	}
+
+	_, err := tx.Exec(ctx,
+		`INSERT INTO meal_prices (meal_id, variant_id, price, created_by) VALUES ($1, $2, $3, $4)`,
+		mealID, variantID, newPrice, createdBy,
+	)
+	return err
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type RecommendationRepository interface {
+	GetInteractions(ctx context.Context) ([]models.MealInteraction, error)
+	GetDietaryPreferences(ctx context.Context) (map[int][]string, error)
+	ReplaceForMenu(ctx context.Context, menuID int, recommendations []models.MealRecommendation) error
+	GetForUser(ctx context.Context, userID, menuID int) (map[int]models.MealRecommendation, error)
+}
+
+type recommendationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRecommendationRepository(db *pgxpool.Pool) RecommendationRepository {
+	return &recommendationRepository{db: db}
+}
+
+// GetInteractions combines every user's ordered quantities (excluding
+// cancelled orders) with the ratings they have given.
+func (r *recommendationRepository) GetInteractions(ctx context.Context) ([]models.MealInteraction, error) {
+	query := `
+		SELECT COALESCE(o.user_id, mr.user_id), COALESCE(o.meal_id, mr.meal_id), COALESCE(o.ordered, 0), mr.rating
+		FROM (
+			SELECT o.user_id, oi.meal_id, SUM(oi.quantity)::INTEGER AS ordered
+			FROM orders o
+			JOIN order_items oi ON oi.order_id = o.id
+			WHERE o.status <> 'cancelled'
+			GROUP BY o.user_id, oi.meal_id
+		) o
+		FULL OUTER JOIN (
+			SELECT user_id, meal_id, ROUND(AVG(rating))::INTEGER AS rating
+			FROM meal_reviews
+			GROUP BY user_id, meal_id
+		) mr ON mr.user_id = o.user_id AND mr.meal_id = o.meal_id
+		ORDER BY 1, 2
+	`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var interactions []models.MealInteraction
+	for rows.Next() {
+		var i models.MealInteraction
+		if err := rows.Scan(&i.UserID, &i.MealID, &i.OrderCount, &i.Rating); err != nil {
+			return nil, err
+		}
+		interactions = append(interactions, i)
+	}
+	return interactions, rows.Err()
+}
+
+// GetDietaryPreferences returns every user's dietary preferences, keyed by user ID.
+func (r *recommendationRepository) GetDietaryPreferences(ctx context.Context) (map[int][]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, dietary_preferences FROM users ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	preferences := make(map[int][]string)
+	for rows.Next() {
+		var userID int
+		var tags []string
+		if err := rows.Scan(&userID, &tags); err != nil {
+			return nil, err
+		}
+		preferences[userID] = tags
+	}
+	return preferences, rows.Err()
+}
+
+// ReplaceForMenu swaps the menu's recommendations for a freshly computed set
+// in one transaction, so readers never see a partial ranking.
+func (r *recommendationRepository) ReplaceForMenu(ctx context.Context, menuID int, recommendations []models.MealRecommendation) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM meal_recommendations WHERE menu_id = $1`, menuID); err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"meal_recommendations"},
+		[]string{"user_id", "menu_id", "meal_id", "score", "reason"},
+		pgx.CopyFromSlice(len(recommendations), func(i int) ([]any, error) {
+			rec := recommendations[i]
+			return []any{rec.UserID, menuID, rec.MealID, rec.Score, rec.Reason}, nil
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *recommendationRepository) GetForUser(ctx context.Context, userID, menuID int) (map[int]models.MealRecommendation, error) {
+	query := `
+		SELECT user_id, menu_id, meal_id, score, reason, computed_at
+		FROM meal_recommendations
+		WHERE user_id = $1 AND menu_id = $2
+	`
+	rows, err := r.db.Query(ctx, query, userID, menuID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byMeal := make(map[int]models.MealRecommendation)
+	for rows.Next() {
+		var rec models.MealRecommendation
+		err := rows.Scan(&rec.UserID, &rec.MenuID, &rec.MealID, &rec.Score, &rec.Reason, &rec.ComputedAt)
+		if err != nil {
+			return nil, err
+		}
+		byMeal[rec.MealID] = rec
+	}
+	return byMeal, rows.Err()
+}
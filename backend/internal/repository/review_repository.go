@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+var ErrDuplicateReview = errors.New("you have already reviewed this meal for this week")
+
+type ReviewRepository interface {
+	Create(ctx context.Context, review *models.Review) error
+	GetByID(ctx context.Context, id int) (*models.Review, error)
+	GetByMealID(ctx context.Context, mealID int, status string) ([]models.Review, error)
+	GetAll(ctx context.Context, status string) ([]models.Review, error)
+	GetByUserID(ctx context.Context, userID int) ([]models.Review, error)
+	UpdateStatus(ctx context.Context, id int, status string) error
+	Delete(ctx context.Context, id int) error
+	GetRatings(ctx context.Context, mealIDs []int) (map[int]models.MealRating, error)
+}
+
+type reviewRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewReviewRepository(db *pgxpool.Pool) ReviewRepository {
+	return &reviewRepository{db: db}
+}
+
+const reviewColumns = `id, user_id, meal_id, order_id, week_id, rating, comment, status, created_at, updated_at`
+
+func (r *reviewRepository) Create(ctx context.Context, review *models.Review) error {
+	query := `
+		INSERT INTO meal_reviews (user_id, meal_id, order_id, week_id, rating, comment, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query,
+		review.UserID,
+		review.MealID,
+		review.OrderID,
+		review.WeekID,
+		review.Rating,
+		review.Comment,
+		review.Status,
+	).Scan(&review.ID, &review.CreatedAt, &review.UpdatedAt)
+	if err != nil {
+		// One review per user per meal per week
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrDuplicateReview
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *reviewRepository) GetByID(ctx context.Context, id int) (*models.Review, error) {
+	query := `SELECT ` + reviewColumns + ` FROM meal_reviews WHERE id = $1`
+	var review models.Review
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&review.ID, &review.UserID, &review.MealID, &review.OrderID, &review.WeekID,
+		&review.Rating, &review.Comment, &review.Status, &review.CreatedAt, &review.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *reviewRepository) GetByMealID(ctx context.Context, mealID int, status string) ([]models.Review, error) {
+	query := `
+		SELECT ` + reviewColumns + `
+		FROM meal_reviews
+		WHERE meal_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+	`
+	return r.query(ctx, query, mealID, status)
+}
+
+// GetAll lists reviews for moderation, optionally filtered by status ("" for all).
+func (r *reviewRepository) GetAll(ctx context.Context, status string) ([]models.Review, error) {
+	query := `
+		SELECT ` + reviewColumns + `
+		FROM meal_reviews
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC
+	`
+	return r.query(ctx, query, status)
+}
+
+// GetByUserID lists every review the user has written, hidden ones included.
+func (r *reviewRepository) GetByUserID(ctx context.Context, userID int) ([]models.Review, error) {
+	query := `
+		SELECT ` + reviewColumns + `
+		FROM meal_reviews
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	return r.query(ctx, query, userID)
+}
+
+func (r *reviewRepository) UpdateStatus(ctx context.Context, id int, status string) error {
+	query := `UPDATE meal_reviews SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	result, err := r.db.Exec(ctx, query, status, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("review not found")
+	}
+	return nil
+}
+
+func (r *reviewRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM meal_reviews WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("review not found")
+	}
+	return nil
+}
+
+// GetRatings aggregates published reviews per meal. Meals without reviews are omitted.
+func (r *reviewRepository) GetRatings(ctx context.Context, mealIDs []int) (map[int]models.MealRating, error) {
+	query := `
+		SELECT meal_id, AVG(rating)::float8, COUNT(*)
+		FROM meal_reviews
+		WHERE meal_id = ANY($1) AND status = 'published'
+		GROUP BY meal_id
+	`
+	rows, err := r.db.Query(ctx, query, mealIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := make(map[int]models.MealRating)
+	for rows.Next() {
+		var mealID int
+		var rating models.MealRating
+		if err := rows.Scan(&mealID, &rating.Average, &rating.Count); err != nil {
+			return nil, err
+		}
+		ratings[mealID] = rating
+	}
+	return ratings, nil
+}
+
+func (r *reviewRepository) query(ctx context.Context, query string, args ...interface{}) ([]models.Review, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []models.Review{}
+	for rows.Next() {
+		var review models.Review
+		err := rows.Scan(
+			&review.ID, &review.UserID, &review.MealID, &review.OrderID, &review.WeekID,
+			&review.Rating, &review.Comment, &review.Status, &review.CreatedAt, &review.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, nil
+}
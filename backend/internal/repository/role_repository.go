@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type RoleRepository interface {
+	GetAll(ctx context.Context) ([]models.Role, error)
+	GetByName(ctx context.Context, name string) (*models.Role, error)
+	Create(ctx context.Context, role *models.Role) error
+	Update(ctx context.Context, role *models.Role) error
+	Delete(ctx context.Context, name string) error
+	GetPermissions(ctx context.Context) ([]models.Permission, error)
+	HasPermission(ctx context.Context, role, permission string) (bool, error)
+}
+
+type roleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRoleRepository(db *pgxpool.Pool) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+const roleColumns = `
+	r.name, r.description, r.created_at,
+	COALESCE((SELECT array_agg(rp.permission ORDER BY rp.permission) FROM role_permissions rp WHERE rp.role = r.name), '{}'),
+	(SELECT COUNT(*) FROM users u WHERE u.role = r.name)
+`
+
+func scanRole(row pgx.Row) (*models.Role, error) {
+	var role models.Role
+	err := row.Scan(&role.Name, &role.Description, &role.CreatedAt, &role.Permissions, &role.UserCount)
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) GetAll(ctx context.Context) ([]models.Role, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+roleColumns+` FROM roles r ORDER BY r.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+	return roles, rows.Err()
+}
+
+func (r *roleRepository) GetByName(ctx context.Context, name string) (*models.Role, error) {
+	role, err := scanRole(r.db.QueryRow(ctx, `SELECT `+roleColumns+` FROM roles r WHERE r.name = $1`, name))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *models.Role) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO roles (name, description) VALUES ($1, $2) RETURNING created_at`
+	if err := tx.QueryRow(ctx, query, role.Name, role.Description).Scan(&role.CreatedAt); err != nil {
+		return err
+	}
+	if err := insertRolePermissions(ctx, tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Update sets the role's description and replaces its permissions.
+func (r *roleRepository) Update(ctx context.Context, role *models.Role) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `UPDATE roles SET description = $1 WHERE name = $2`, role.Description, role.Name)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("role not found")
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role = $1`, role.Name); err != nil {
+		return err
+	}
+	if err := insertRolePermissions(ctx, tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *roleRepository) Delete(ctx context.Context, name string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM roles WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("role not found")
+	}
+	return nil
+}
+
+func (r *roleRepository) GetPermissions(ctx context.Context) ([]models.Permission, error) {
+	rows, err := r.db.Query(ctx, `SELECT name, description FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []models.Permission{}
+	for rows.Next() {
+		var p models.Permission
+		if err := rows.Scan(&p.Name, &p.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
+
+func (r *roleRepository) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM role_permissions WHERE role = $1 AND permission = $2)`
+	var ok bool
+	err := r.db.QueryRow(ctx, query, role, permission).Scan(&ok)
+	return ok, err
+}
+
+func insertRolePermissions(ctx context.Context, tx pgx.Tx, role string, permissions []string) error {
+	for _, permission := range permissions {
+		query := `INSERT INTO role_permissions (role, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(ctx, query, role, permission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+// nullableVariant holds the columns of a LEFT JOINed meal_variants row.
+type nullableVariant struct {
+	Name     *string
+	Calories *int
+	Protein  *int
+	Carbs    *int
+	Fat      *int
+	Price    *int
+}
+
+func (v nullableVariant) toModel(variantID *int, mealID int) *models.MealVariant {
+	if variantID == nil || v.Name == nil {
+		return nil
+	}
+	return &models.MealVariant{
+		ID:       *variantID,
+		MealID:   mealID,
+		Name:     *v.Name,
+		Calories: derefInt(v.Calories),
+		Protein:  derefInt(v.Protein),
+		Carbs:    derefInt(v.Carbs),
+		Fat:      derefInt(v.Fat),
+		Price:    derefInt(v.Price),
+	}
+}
+
+func derefInt(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, userID int, twoFactor bool, tokenHash string, expiresAt time.Time) (*models.Session, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldTokenID, sessionID int, newTokenHash string, expiresAt time.Time) (bool, error)
+	Revoke(ctx context.Context, sessionID int, reason string) error
+	RevokeAllForUser(ctx context.Context, userID int, reason string) error
+	RevokeOthersForUser(ctx context.Context, userID, keepSessionID int, reason string) error
+	GetActive(ctx context.Context, sessionID, userID int) (*models.Session, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type sessionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepository(db *pgxpool.Pool) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// Create starts a session with its first refresh token.
+func (r *sessionRepository) Create(ctx context.Context, userID int, twoFactor bool, tokenHash string, expiresAt time.Time) (*models.Session, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	session := &models.Session{UserID: userID, TwoFactor: twoFactor}
+	query := `
+		INSERT INTO user_sessions (user_id, two_factor)
+		VALUES ($1, $2)
+		RETURNING id, created_at, last_used_at
+	`
+	err = tx.QueryRow(ctx, query, userID, twoFactor).Scan(&session.ID, &session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		session.ID, tokenHash, expiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, tx.Commit(ctx)
+}
+
+func (r *sessionRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT rt.id, rt.session_id, s.user_id, rt.expires_at, rt.used_at, s.revoked_at, s.two_factor
+		FROM refresh_tokens rt
+		JOIN user_sessions s ON rt.session_id = s.id
+		WHERE rt.token_hash = $1
+	`
+	var rt models.RefreshToken
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&rt.ID, &rt.SessionID, &rt.UserID, &rt.ExpiresAt, &rt.UsedAt, &rt.SessionRevokedAt, &rt.TwoFactor,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rt, nil
+}
+
+// RotateRefreshToken marks the old token as used and issues its successor
+// in the same session. It returns false, changing nothing, when the old
+// token has already been used, e.g. by a concurrent refresh.
+func (r *sessionRepository) RotateRefreshToken(ctx context.Context, oldTokenID, sessionID int, newTokenHash string, expiresAt time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx,
+		`UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`,
+		oldTokenID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		sessionID, newTokenHash, expiresAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE user_sessions SET last_used_at = NOW() WHERE id = $1`, sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, sessionID int, reason string) error {
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = NOW(), revoked_reason = $2
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+	_, err := r.db.Exec(ctx, query, sessionID, reason)
+	return err
+}
+
+func (r *sessionRepository) RevokeAllForUser(ctx context.Context, userID int, reason string) error {
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = NOW(), revoked_reason = $2
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+	_, err := r.db.Exec(ctx, query, userID, reason)
+	return err
+}
+
+// RevokeOthersForUser revokes every session of the user except one, e.g.
+// the session that just changed the password.
+func (r *sessionRepository) RevokeOthersForUser(ctx context.Context, userID, keepSessionID int, reason string) error {
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = NOW(), revoked_reason = $3
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`
+	_, err := r.db.Exec(ctx, query, userID, keepSessionID, reason)
+	return err
+}
+
+// GetActive returns the session if it belongs to the user and has not been
+// revoked, or nil, along with the user's current role and whether they are
+// disabled. AuthMiddleware checks it on every request. Disabling a user
+// revokes their sessions, but those are still returned while the user is
+// disabled so they are told why they were signed out.
+func (r *sessionRepository) GetActive(ctx context.Context, sessionID, userID int) (*models.Session, error) {
+	query := `
+		SELECT s.id, s.user_id, s.created_at, s.last_used_at, s.two_factor, u.role, u.disabled_at IS NOT NULL
+		FROM user_sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.id = $1 AND s.user_id = $2 AND (s.revoked_at IS NULL OR u.disabled_at IS NOT NULL)
+	`
+	var s models.Session
+	err := r.db.QueryRow(ctx, query, sessionID, userID).Scan(&s.ID, &s.UserID, &s.CreatedAt, &s.LastUsedAt, &s.TwoFactor, &s.Role, &s.UserDisabled)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteExpired removes refresh tokens past their expiry time, then the
+// sessions left without any, which can no longer be refreshed. It returns
+// how many sessions were removed.
+func (r *sessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM refresh_tokens WHERE expires_at < NOW()`); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(ctx, `
+		DELETE FROM user_sessions s
+		WHERE NOT EXISTS (SELECT 1 FROM refresh_tokens t WHERE t.session_id = s.id)
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
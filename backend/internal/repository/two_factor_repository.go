@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type TwoFactorRepository interface {
+	GetSettings(ctx context.Context, userID int) (*models.TwoFactorSettings, error)
+	SetPendingSecret(ctx context.Context, userID int, secret string) error
+	Enable(ctx context.Context, userID, sessionID int, step int64, recoveryCodeHashes []string) error
+	Disable(ctx context.Context, userID int) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+	CreateChallenge(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error
+	GetChallenge(ctx context.Context, tokenHash string) (*models.TwoFactorChallenge, error)
+	TakeChallengeAttempt(ctx context.Context, id, max int) (bool, error)
+	CompleteChallenge(ctx context.Context, id int) (bool, error)
+}
+
+type twoFactorRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTwoFactorRepository(db *pgxpool.Pool) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+func (r *twoFactorRepository) GetSettings(ctx context.Context, userID int) (*models.TwoFactorSettings, error) {
+	query := `
+		SELECT u.totp_secret, u.totp_enabled_at, u.totp_last_step,
+		       (SELECT COUNT(*) FROM user_recovery_codes rc WHERE rc.user_id = u.id AND rc.used_at IS NULL)
+		FROM users u
+		WHERE u.id = $1
+	`
+	var s models.TwoFactorSettings
+	err := r.db.QueryRow(ctx, query, userID).Scan(&s.Secret, &s.EnabledAt, &s.LastStep, &s.RecoveryCodesRemaining)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SetPendingSecret starts (or restarts) enrolment. It does nothing once
+// two-factor authentication is enabled.
+func (r *twoFactorRepository) SetPendingSecret(ctx context.Context, userID int, secret string) error {
+	query := `UPDATE users SET totp_secret = $1 WHERE id = $2 AND totp_enabled_at IS NULL`
+	result, err := r.db.Exec(ctx, query, secret, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("two-factor authentication is already enabled")
+	}
+	return nil
+}
+
+// Enable confirms enrolment with the step of the code the user entered,
+// stores their recovery codes and marks the session they enrolled from as
+// signed in with a second factor.
+func (r *twoFactorRepository) Enable(ctx context.Context, userID, sessionID int, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $2
+		WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("two-factor authentication is already enabled")
+	}
+
+	if err := insertRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE user_sessions SET two_factor = TRUE WHERE id = $1 AND user_id = $2`, sessionID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *twoFactorRepository) Disable(ctx context.Context, userID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// insertRecoveryCodes replaces all of a user's recovery codes.
+func insertRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int, codeHashes []string) error {
+	_, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		_, err := tx.Exec(ctx, `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseTOTPStep records that the code for a time step has been used. It
+// returns false if that step, or a later one, was already used, so each
+// code works only once.
+func (r *twoFactorRepository) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	query := `
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`
+	result, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It returns false if
+// the code is unknown or already used.
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	query := `
+		UPDATE user_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	result, err := r.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func (r *twoFactorRepository) CreateChallenge(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	query := `INSERT INTO two_factor_challenges (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	_, err := r.db.Exec(ctx, query, userID, tokenHash, expiresAt)
+	return err
+}
+
+func (r *twoFactorRepository) GetChallenge(ctx context.Context, tokenHash string) (*models.TwoFactorChallenge, error) {
+	query := `SELECT id, user_id, expires_at, attempts, used_at FROM two_factor_challenges WHERE token_hash = $1`
+	var c models.TwoFactorChallenge
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(&c.ID, &c.UserID, &c.ExpiresAt, &c.Attempts, &c.UsedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// TakeChallengeAttempt counts an attempt at the challenge before its code
+// is checked. It returns false, counting nothing, once max attempts have
+// been made.
+func (r *twoFactorRepository) TakeChallengeAttempt(ctx context.Context, id, max int) (bool, error) {
+	query := `UPDATE two_factor_challenges SET attempts = attempts + 1 WHERE id = $1 AND attempts < $2`
+	result, err := r.db.Exec(ctx, query, id, max)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// CompleteChallenge marks the challenge as used. It returns false if it
+// already was.
+func (r *twoFactorRepository) CompleteChallenge(ctx context.Context, id int) (bool, error) {
+	query := `UPDATE two_factor_challenges SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
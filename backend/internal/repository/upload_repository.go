@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+type UploadRepository interface {
+	Create(ctx context.Context, upload *models.Upload) error
+	GetByID(ctx context.Context, id int) (*models.Upload, error)
+	GetOrphans(ctx context.Context, olderThan time.Time) ([]models.Upload, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type uploadRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUploadRepository(db *pgxpool.Pool) UploadRepository {
+	return &uploadRepository{db: db}
+}
+
+// Create records the upload together with its variants.
+func (r *uploadRepository) Create(ctx context.Context, upload *models.Upload) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO uploads (url, storage_key, filename, uploaded_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRow(ctx, query, upload.URL, upload.StorageKey, upload.Filename, upload.UploadedBy).
+		Scan(&upload.ID, &upload.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range upload.Variants {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO upload_variants (upload_id, name, content_type, width, height, url, storage_key)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, upload.ID, v.Name, v.ContentType, v.Width, v.Height, v.URL, v.StorageKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *uploadRepository) GetByID(ctx context.Context, id int) (*models.Upload, error) {
+	query := `SELECT id, url, storage_key, filename, uploaded_by, created_at FROM uploads WHERE id = $1`
+	var u models.Upload
+	err := r.db.QueryRow(ctx, query, id).Scan(&u.ID, &u.URL, &u.StorageKey, &u.Filename, &u.UploadedBy, &u.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	variants, err := getUploadVariants(ctx, r.db, []int{u.ID})
+	if err != nil {
+		return nil, err
+	}
+	u.Variants = variants[u.ID]
+	return &u, nil
+}
+
+// GetOrphans finds uploads older than the cut-off that no gallery, meal,
+// past meal version, add-on or bundle refers to.
+func (r *uploadRepository) GetOrphans(ctx context.Context, olderThan time.Time) ([]models.Upload, error) {
+	query := `
+		SELECT u.id, u.url, u.storage_key, u.filename, u.uploaded_by, u.created_at
+		FROM uploads u
+		WHERE u.created_at < $1
+		  AND NOT EXISTS (SELECT 1 FROM meal_images mi WHERE mi.upload_id = u.id)
+		  AND NOT EXISTS (SELECT 1 FROM meals m WHERE m.image_url = u.url)
+		  AND NOT EXISTS (SELECT 1 FROM meal_versions mv WHERE mv.image_url = u.url)
+		  AND NOT EXISTS (SELECT 1 FROM addons a WHERE a.image_url = u.url)
+		  AND NOT EXISTS (SELECT 1 FROM bundles b WHERE b.image_url = u.url)
+		ORDER BY u.id
+	`
+	rows, err := r.db.Query(ctx, query, olderThan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []models.Upload
+	for rows.Next() {
+		var u models.Upload
+		if err := rows.Scan(&u.ID, &u.URL, &u.StorageKey, &u.Filename, &u.UploadedBy, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(uploads))
+	for i, u := range uploads {
+		ids[i] = u.ID
+	}
+	variants, err := getUploadVariants(ctx, r.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range uploads {
+		uploads[i].Variants = variants[uploads[i].ID]
+	}
+	return uploads, nil
+}
+
+func (r *uploadRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM uploads WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("upload not found")
+	}
+	return nil
+}
+
+// getUploadVariants loads the variants of several uploads in one query, keyed
+// by upload ID, smallest first.
+func getUploadVariants(ctx context.Context, db *pgxpool.Pool, uploadIDs []int) (map[int][]models.ImageVariant, error) {
+	query := `
+		SELECT upload_id, name, content_type, width, height, url, storage_key
+		FROM upload_variants
+		WHERE upload_id = ANY($1)
+		ORDER BY upload_id, width, content_type
+	`
+	rows, err := db.Query(ctx, query, uploadIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byUpload := make(map[int][]models.ImageVariant)
+	for rows.Next() {
+		var uploadID int
+		var v models.ImageVariant
+		if err := rows.Scan(&uploadID, &v.Name, &v.ContentType, &v.Width, &v.Height, &v.URL, &v.StorageKey); err != nil {
+			return nil, err
+		}
+		byUpload[uploadID] = append(byUpload[uploadID], v)
+	}
+	return byUpload, rows.Err()
+}
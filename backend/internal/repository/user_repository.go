@@ -13,6 +13,17 @@ type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	GetByID(ctx context.Context, id int) (*models.User, error)
+	GetDietaryPreferences(ctx context.Context, id int) ([]string, error)
+	SetDietaryPreferences(ctx context.Context, id int, preferences []string) error
+	ChangePassword(ctx context.Context, id int, passwordHash string, keepSessionID int) error
+	MarkEmailVerified(ctx context.Context, id int) error
+	List(ctx context.Context, filter *models.UserFilter) ([]models.User, int, error)
+	UpdateRole(ctx context.Context, id int, role string) error
+	SetDisabled(ctx context.Context, id int, disabled bool) error
+	RequirePasswordReset(ctx context.Context, id int) error
+	GetProfile(ctx context.Context, id int) (*models.Profile, error)
+	UpdateProfile(ctx context.Context, profile *models.Profile) error
+	Anonymise(ctx context.Context, id int) error
 }
 
 type userRepository struct {
@@ -29,28 +40,327 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 	return err
 }
 
-func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	query := `SELECT id, email, password_hash, role, created_at FROM users WHERE email = $1`
+const userColumns = `id, email, password_hash, role, created_at, email_verified_at IS NOT NULL,
+	totp_enabled_at IS NOT NULL, disabled_at, password_reset_required, deleted_at`
+
+func scanUser(row pgx.Row) (*models.User, error) {
 	var user models.User
-	err := r.db.QueryRow(ctx, query, email).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt, &user.EmailVerified,
+		&user.TwoFactorEnabled, &user.DisabledAt, &user.PasswordResetRequired, &user.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1`, email))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
 }
 
 func (r *userRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
-	query := `SELECT id, email, password_hash, role, created_at FROM users WHERE id = $1`
-	var user models.User
-	err := r.db.QueryRow(ctx, query, id).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	user, err := scanUser(r.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
+}
+
+// List returns a page of users matching the filter, oldest first, and how
+// many match in all.
+func (r *userRepository) List(ctx context.Context, filter *models.UserFilter) ([]models.User, int, error) {
+	where := `
+		WHERE ($1 = '' OR position(lower($1) IN lower(email)) > 0)
+		  AND ($2 = '' OR role = $2)
+		  AND ($3 = '' OR ($3 = 'disabled') = (disabled_at IS NOT NULL))
+	`
+	args := []interface{}{filter.Search, filter.Role, filter.Status}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users` + where + ` ORDER BY id LIMIT $4 OFFSET $5`
+	rows, err := r.db.Query(ctx, query, append(args, filter.Limit, (filter.Page-1)*filter.Limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *user)
+	}
+	return users, total, rows.Err()
+}
+
+func (r *userRepository) GetDietaryPreferences(ctx context.Context, id int) ([]string, error) {
+	query := `SELECT dietary_preferences FROM users WHERE id = $1`
+	var preferences []string
+	err := r.db.QueryRow(ctx, query, id).Scan(&preferences)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return preferences, nil
+}
+
+func (r *userRepository) SetDietaryPreferences(ctx context.Context, id int, preferences []string) error {
+	query := `UPDATE users SET dietary_preferences = $1 WHERE id = $2`
+	result, err := r.db.Exec(ctx, query, preferences, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// ChangePassword sets a new password and signs the user out of every
+// session but the one that changed it, together so a failure leaves both
+// the old password and the sessions in place.
+func (r *userRepository) ChangePassword(ctx context.Context, id int, passwordHash string, keepSessionID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `UPDATE users SET password_hash = $1 WHERE id = $2`, passwordHash, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("user not found")
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE user_sessions
+		SET revoked_at = NOW(), revoked_reason = 'password_changed'
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`, id, keepSessionID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// MarkEmailVerified verifies the user's email. Already verified users keep
+// their original verification time.
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id int) error {
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id int, role string) error {
+	result, err := r.db.Exec(ctx, `UPDATE users SET role = $1 WHERE id = $2`, role, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// SetDisabled disables or re-enables the user. Disabling an already
+// disabled user keeps the original time.
+func (r *userRepository) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	query := `UPDATE users SET disabled_at = CASE WHEN $1 THEN COALESCE(disabled_at, NOW()) END WHERE id = $2`
+	result, err := r.db.Exec(ctx, query, disabled, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// RequirePasswordReset stops the user logging in with their password until
+// they reset it.
+func (r *userRepository) RequirePasswordReset(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx, `UPDATE users SET password_reset_required = TRUE WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// GetProfile returns the user's profile, with the address they are changing
+// their email to if a change is waiting to be confirmed.
+func (r *userRepository) GetProfile(ctx context.Context, id int) (*models.Profile, error) {
+	query := `
+		SELECT u.id, u.email, u.email_verified_at IS NOT NULL, u.role, u.name, u.phone,
+		       u.address_line1, COALESCE(u.address_line2, ''), COALESCE(u.address_city, ''),
+		       COALESCE(u.address_postcode, ''), COALESCE(u.address_instructions, ''),
+		       u.dietary_preferences, u.order_update_emails, u.created_at,
+		       COALESCE((
+		           SELECT t.new_email FROM email_verification_tokens t
+		           WHERE t.user_id = u.id AND t.new_email IS NOT NULL AND t.used_at IS NULL AND t.expires_at > NOW()
+		           ORDER BY t.created_at DESC LIMIT 1
+		       ), '')
+		FROM users u
+		WHERE u.id = $1
+	`
+	var p models.Profile
+	var line1 *string
+	var address models.Address
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&p.ID, &p.Email, &p.EmailVerified, &p.Role, &p.Name, &p.Phone,
+		&line1, &address.Line2, &address.City, &address.Postcode, &address.Instructions,
+		&p.DietaryPreferences, &p.Communication.OrderUpdateEmails,
+		&p.CreatedAt, &p.PendingEmail,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if line1 != nil {
+		address.Line1 = *line1
+		p.Address = &address
+	}
+	return &p, nil
+}
+
+// UpdateProfile saves the profile's editable fields. A nil address removes
+// the default delivery address.
+func (r *userRepository) UpdateProfile(ctx context.Context, p *models.Profile) error {
+	var address models.Address
+	if p.Address != nil {
+		address = *p.Address
+	}
+	query := `
+		UPDATE users
+		SET name = $1, phone = $2,
+		    address_line1 = $3, address_line2 = NULLIF($4, ''), address_city = NULLIF($5, ''),
+		    address_postcode = NULLIF($6, ''), address_instructions = NULLIF($7, ''),
+		    dietary_preferences = $8, order_update_emails = $9
+		WHERE id = $10
+	`
+	var line1 *string
+	if p.Address != nil {
+		line1 = &address.Line1
+	}
+	result, err := r.db.Exec(ctx, query,
+		p.Name, p.Phone,
+		line1, address.Line2, address.City, address.Postcode, address.Instructions,
+		p.DietaryPreferences, p.Communication.OrderUpdateEmails, p.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// Anonymise deletes the user's account while keeping their orders for
+// accounting. Personal details are blanked, the email is replaced so the
+// address can be registered again, and everything else held about the user
+// is deleted. Subscriptions are cancelled and the account is disabled, so
+// it cannot be signed in to.
+func (r *userRepository) Anonymise(ctx context.Context, id int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+	err = tx.QueryRow(ctx, `SELECT email FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errors.New("user not found")
+		}
+		return err
+	}
+
+	// Deleting the cart removes its items, add-ons and bundles, and deleting
+	// sessions removes their refresh tokens
+	deletes := []string{
+		`DELETE FROM carts WHERE user_id = $1`,
+		`DELETE FROM meal_reviews WHERE user_id = $1`,
+		`DELETE FROM user_favourites WHERE user_id = $1`,
+		`DELETE FROM user_nutrition_goals WHERE user_id = $1`,
+		`DELETE FROM meal_recommendations WHERE user_id = $1`,
+		`DELETE FROM user_sessions WHERE user_id = $1`,
+		`DELETE FROM password_reset_tokens WHERE user_id = $1`,
+		`DELETE FROM email_verification_tokens WHERE user_id = $1`,
+		`DELETE FROM user_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM two_factor_challenges WHERE user_id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+		`DELETE FROM data_exports WHERE user_id = $1`,
+		`DELETE FROM account_deletion_tokens WHERE user_id = $1`,
+	}
+	for _, query := range deletes {
+		if _, err := tx.Exec(ctx, query, id); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM login_throttles WHERE scope = 'account' AND subject = lower($1)`, email); err != nil {
+		return err
+	}
+	// A deleted account is no longer billed
+	if _, err := tx.Exec(ctx, `UPDATE subscriptions SET status = 'cancelled' WHERE user_id = $1`, id); err != nil {
+		return err
+	}
+	// Orders are kept, but not where they were delivered
+	_, err = tx.Exec(ctx, `
+		UPDATE orders
+		SET delivery_address_line1 = NULL, delivery_address_line2 = NULL, delivery_address_city = NULL,
+		    delivery_address_postcode = NULL, delivery_address_instructions = NULL
+		WHERE user_id = $1
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE users
+		SET email = 'deleted-' || id || '@deleted.invalid', password_hash = '',
+		    name = '', phone = '',
+		    address_line1 = NULL, address_line2 = NULL, address_city = NULL,
+		    address_postcode = NULL, address_instructions = NULL,
+		    dietary_preferences = '{}', order_update_emails = FALSE,
+		    totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL,
+		    password_reset_required = FALSE,
+		    disabled_at = COALESCE(disabled_at, NOW()), deleted_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, query, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
@@ -9,6 +9,9 @@ import (
 	"github.com/jopari/preptoplate/internal/models"
 )
 
+// ErrVariantNotOnMenu is returned for a variant the menu does not stock.
+var ErrVariantNotOnMenu = errors.New("variant not found in menu")
+
 type WeeklyMenuRepository interface {
 	Create(ctx context.Context, menu *models.WeeklyMenu) error
 	GetByID(ctx context.Context, id int) (*models.WeeklyMenu, error)
@@ -20,7 +23,10 @@ type WeeklyMenuRepository interface {
 	AddMeal(ctx context.Context, menuID, mealID, stock int) error
 	RemoveMeal(ctx context.Context, menuID, mealID int) error
 	GetMealStock(ctx context.Context, menuID, mealID int) (int, error)
-	DecrementStock(ctx context.Context, menuID, mealID, quantity int) error
+	AddVariant(ctx context.Context, menuID, variantID, stock int) error
+	GetVariantStock(ctx context.Context, menuID, variantID int) (int, error)
+	AddAddon(ctx context.Context, menuID, addonID, stock int) error
+	GetAddonStock(ctx context.Context, menuID, addonID int) (int, error)
 }
 
 type weeklyMenuRepository struct {
@@ -50,11 +56,14 @@ func (r *weeklyMenuRepository) GetByID(ctx context.Context, id int) (*models.Wee
 	}
 
 	// Get meals for this menu
+	// Meal details come from the version the menu was published with
 	mealsQuery := `
 		SELECT mm.menu_id, mm.meal_id, mm.initial_stock, mm.available_stock,
-		       m.id, m.name, m.description, m.image_url, m.calories, m.protein, m.carbs, m.fat, m.price
+		       m.id, mv.name, mv.description, mv.image_url, mv.calories, mv.protein, mv.carbs, mv.fat,
+		       effective_meal_price(m.id, NULL, NULL, NOW(), mv.price), mv.version, m.dietary_tags, m.ingredients
 		FROM menu_meals mm
 		JOIN meals m ON mm.meal_id = m.id
+		JOIN meal_versions mv ON mv.meal_id = m.id AND mv.version = COALESCE(mm.meal_version, m.current_version)
 		WHERE mm.menu_id = $1
 		ORDER BY m.id
 	`
@@ -71,12 +80,78 @@ func (r *weeklyMenuRepository) GetByID(ctx context.Context, id int) (*models.Wee
 			&menuMeal.MenuID, &menuMeal.Meal.ID, &menuMeal.InitialStock, &menuMeal.AvailableStock,
 			&menuMeal.Meal.ID, &menuMeal.Meal.Name, &menuMeal.Meal.Description, &menuMeal.Meal.ImageURL,
 			&menuMeal.Meal.Calories, &menuMeal.Meal.Protein, &menuMeal.Meal.Carbs, &menuMeal.Meal.Fat, &menuMeal.Meal.Price,
+			&menuMeal.Meal.Version, &menuMeal.Meal.DietaryTags, &menuMeal.Meal.Ingredients,
 		)
 		if err != nil {
 			return nil, err
 		}
 		menu.Meals = append(menu.Meals, menuMeal)
 	}
+	rows.Close()
+
+	// Attach stock for the variants offered on this menu
+	variantsQuery := `
+		SELECT v.id, v.meal_id, v.name, v.calories, v.protein, v.carbs, v.fat,
+		       effective_meal_price(v.meal_id, v.id, NULL, NOW(), v.price),
+		       vs.initial_stock, vs.available_stock
+		FROM menu_variant_stock vs
+		JOIN meal_variants v ON vs.variant_id = v.id
+		WHERE vs.menu_id = $1
+		ORDER BY v.meal_id, v.price, v.id
+	`
+	variantRows, err := r.db.Query(ctx, variantsQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer variantRows.Close()
+
+	for variantRows.Next() {
+		var mv models.WeeklyMenuVariant
+		err := variantRows.Scan(
+			&mv.Variant.ID, &mv.Variant.MealID, &mv.Variant.Name, &mv.Variant.Calories,
+			&mv.Variant.Protein, &mv.Variant.Carbs, &mv.Variant.Fat, &mv.Variant.Price,
+			&mv.InitialStock, &mv.AvailableStock,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for i := range menu.Meals {
+			if menu.Meals[i].Meal.ID == mv.Variant.MealID {
+				menu.Meals[i].Variants = append(menu.Meals[i].Variants, mv)
+				break
+			}
+		}
+	}
+	variantRows.Close()
+
+	// Get add-ons for this menu
+	addonsQuery := `
+		SELECT a.id, a.name, a.description, a.image_url, a.category, a.calories, a.protein, a.carbs, a.fat, a.price,
+		       ma.initial_stock, ma.available_stock
+		FROM menu_addons ma
+		JOIN addons a ON ma.addon_id = a.id
+		WHERE ma.menu_id = $1
+		ORDER BY a.category, a.id
+	`
+	addonRows, err := r.db.Query(ctx, addonsQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer addonRows.Close()
+
+	menu.Addons = []models.WeeklyMenuAddon{}
+	for addonRows.Next() {
+		var ma models.WeeklyMenuAddon
+		err := addonRows.Scan(
+			&ma.Addon.ID, &ma.Addon.Name, &ma.Addon.Description, &ma.Addon.ImageURL, &ma.Addon.Category,
+			&ma.Addon.Calories, &ma.Addon.Protein, &ma.Addon.Carbs, &ma.Addon.Fat, &ma.Addon.Price,
+			&ma.InitialStock, &ma.AvailableStock,
+		)
+		if err != nil {
+			return nil, err
+		}
+		menu.Addons = append(menu.Addons, ma)
+	}
 
 	return &menu, nil
 }
@@ -156,9 +231,10 @@ func (r *weeklyMenuRepository) Activate(ctx context.Context, id int) error {
 }
 
 func (r *weeklyMenuRepository) AddMeal(ctx context.Context, menuID, mealID, stock int) error {
+	// Pin the meal's current version to the menu
 	query := `
-		INSERT INTO menu_meals (menu_id, meal_id, initial_stock, available_stock) 
-		VALUES ($1, $2, $3, $3)
+		INSERT INTO menu_meals (menu_id, meal_id, initial_stock, available_stock, meal_version) 
+		VALUES ($1, $2, $3, $3, (SELECT current_version FROM meals WHERE id = $2))
 	`
 	_, err := r.db.Exec(ctx, query, menuID, mealID, stock)
 	return err
@@ -177,13 +253,15 @@ func (r *weeklyMenuRepository) GetMealStock(ctx context.Context, menuID, mealID
 	return stock, nil
 }
 
-func (r *weeklyMenuRepository) DecrementStock(ctx context.Context, menuID, mealID, quantity int) error {
+// decrementMealStock takes an order's meals from the menu's stock, failing
+// if there are not enough left.
+func decrementMealStock(ctx context.Context, tx pgx.Tx, menuID, mealID, quantity int) error {
 	query := `
 		UPDATE menu_meals 
 		SET available_stock = available_stock - $1 
 		WHERE menu_id = $2 AND meal_id = $3 AND available_stock >= $1
 	`
-	result, err := r.db.Exec(ctx, query, quantity, menuID, mealID)
+	result, err := tx.Exec(ctx, query, quantity, menuID, mealID)
 	if err != nil {
 		return err
 	}
@@ -212,16 +290,61 @@ func (r *weeklyMenuRepository) Update(ctx context.Context, id int, menu *models.
 		return errors.New("weekly menu not found")
 	}
 
-	// Remove all existing meals
-	_, err = tx.Exec(ctx, `DELETE FROM menu_meals WHERE menu_id = $1`, id)
+	// Remove all existing meals, variant stock and add-ons
+	_, err = tx.Exec(ctx, `DELETE FROM menu_variant_stock WHERE menu_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM menu_addons WHERE menu_id = $1`, id)
 	if err != nil {
 		return err
 	}
 
-	// Add new meals
+	// Meals that stay on the menu keep the version they were pinned to
+	rows, err := tx.Query(ctx, `DELETE FROM menu_meals WHERE menu_id = $1 RETURNING meal_id, meal_version`, id)
+	if err != nil {
+		return err
+	}
+	pinned := make(map[int]*int)
+	for rows.Next() {
+		var mealID int
+		var version *int
+		if err := rows.Scan(&mealID, &version); err != nil {
+			rows.Close()
+			return err
+		}
+		pinned[mealID] = version
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Add new meals, pinned to their current version
 	for _, menuMeal := range menu.Meals {
-		query := `INSERT INTO menu_meals (menu_id, meal_id, initial_stock, available_stock) VALUES ($1, $2, $3, $3)`
-		_, err = tx.Exec(ctx, query, id, menuMeal.Meal.ID, menuMeal.InitialStock)
+		query := `
+			INSERT INTO menu_meals (menu_id, meal_id, initial_stock, available_stock, meal_version)
+			VALUES ($1, $2, $3, $3, COALESCE($4, (SELECT current_version FROM meals WHERE id = $2)))
+		`
+		_, err = tx.Exec(ctx, query, id, menuMeal.Meal.ID, menuMeal.InitialStock, pinned[menuMeal.Meal.ID])
+		if err != nil {
+			return err
+		}
+
+		for _, menuVariant := range menuMeal.Variants {
+			query := `INSERT INTO menu_variant_stock (menu_id, variant_id, initial_stock, available_stock) VALUES ($1, $2, $3, $3)`
+			_, err = tx.Exec(ctx, query, id, menuVariant.Variant.ID, menuVariant.InitialStock)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Add new add-ons
+	for _, menuAddon := range menu.Addons {
+		query := `INSERT INTO menu_addons (menu_id, addon_id, initial_stock, available_stock) VALUES ($1, $2, $3, $3)`
+		_, err = tx.Exec(ctx, query, id, menuAddon.Addon.ID, menuAddon.InitialStock)
 		if err != nil {
 			return err
 		}
@@ -237,7 +360,17 @@ func (r *weeklyMenuRepository) Delete(ctx context.Context, id int) error {
 	}
 	defer tx.Rollback(ctx)
 
-	// Delete menu meals first
+	// Delete menu meals, variant stock and add-ons first
+	_, err = tx.Exec(ctx, `DELETE FROM menu_variant_stock WHERE menu_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM menu_addons WHERE menu_id = $1`, id)
+	if err != nil {
+		return err
+	}
+
 	_, err = tx.Exec(ctx, `DELETE FROM menu_meals WHERE menu_id = $1`, id)
 	if err != nil {
 		return err
@@ -266,3 +399,83 @@ func (r *weeklyMenuRepository) RemoveMeal(ctx context.Context, menuID, mealID in
 	}
 	return nil
 }
+
+func (r *weeklyMenuRepository) AddVariant(ctx context.Context, menuID, variantID, stock int) error {
+	query := `
+		INSERT INTO menu_variant_stock (menu_id, variant_id, initial_stock, available_stock)
+		VALUES ($1, $2, $3, $3)
+	`
+	_, err := r.db.Exec(ctx, query, menuID, variantID, stock)
+	return err
+}
+
+func (r *weeklyMenuRepository) GetVariantStock(ctx context.Context, menuID, variantID int) (int, error) {
+	query := `SELECT available_stock FROM menu_variant_stock WHERE menu_id = $1 AND variant_id = $2`
+	var stock int
+	err := r.db.QueryRow(ctx, query, menuID, variantID).Scan(&stock)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrVariantNotOnMenu
+		}
+		return 0, err
+	}
+	return stock, nil
+}
+
+func decrementVariantStock(ctx context.Context, tx pgx.Tx, menuID, variantID, quantity int) error {
+	query := `
+		UPDATE menu_variant_stock
+		SET available_stock = available_stock - $1
+		WHERE menu_id = $2 AND variant_id = $3 AND available_stock >= $1
+	`
+	result, err := tx.Exec(ctx, query, quantity, menuID, variantID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New("insufficient stock or variant not found")
+	}
+
+	return nil
+}
+
+func (r *weeklyMenuRepository) AddAddon(ctx context.Context, menuID, addonID, stock int) error {
+	query := `
+		INSERT INTO menu_addons (menu_id, addon_id, initial_stock, available_stock)
+		VALUES ($1, $2, $3, $3)
+	`
+	_, err := r.db.Exec(ctx, query, menuID, addonID, stock)
+	return err
+}
+
+func (r *weeklyMenuRepository) GetAddonStock(ctx context.Context, menuID, addonID int) (int, error) {
+	query := `SELECT available_stock FROM menu_addons WHERE menu_id = $1 AND addon_id = $2`
+	var stock int
+	err := r.db.QueryRow(ctx, query, menuID, addonID).Scan(&stock)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, errors.New("add-on not found in menu")
+		}
+		return 0, err
+	}
+	return stock, nil
+}
+
+func decrementAddonStock(ctx context.Context, tx pgx.Tx, menuID, addonID, quantity int) error {
+	query := `
+		UPDATE menu_addons
+		SET available_stock = available_stock - $1
+		WHERE menu_id = $2 AND addon_id = $3 AND available_stock >= $1
+	`
+	result, err := tx.Exec(ctx, query, quantity, menuID, addonID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New("insufficient stock or add-on not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/jopari/preptoplate/internal/config"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+	"github.com/jopari/preptoplate/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DataExportBuildTimeout is how long an export may stay pending before it is
+// given up on
+const DataExportBuildTimeout = 30 * time.Minute
+
+// AccountService lets users download a copy of everything held about them
+// and delete their account.
+type AccountService interface {
+	RequestDataExport(ctx context.Context, userID int) (*models.DataExport, error)
+	ListDataExports(ctx context.Context, userID int) ([]models.DataExport, error)
+	GetDataExport(ctx context.Context, userID, id int) ([]byte, error)
+	RequestAccountDeletion(ctx context.Context, userID int) error
+	DeleteAccount(ctx context.Context, userID int, req *models.DeleteAccountRequest) error
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type accountService struct {
+	userRepo      repository.UserRepository
+	exportRepo    repository.DataExportRepository
+	orderRepo     repository.OrderRepository
+	cartRepo      repository.CartRepository
+	reviewRepo    repository.ReviewRepository
+	favouriteRepo repository.FavouriteRepository
+	goalsRepo     repository.NutritionGoalsRepository
+	sessionRepo   repository.SessionRepository
+	throttleRepo  repository.LoginThrottleRepository
+	deletionRepo  repository.AccountDeletionRepository
+	emailService  EmailService
+	config        *config.Config
+}
+
+func NewAccountService(userRepo repository.UserRepository, exportRepo repository.DataExportRepository, orderRepo repository.OrderRepository, cartRepo repository.CartRepository, reviewRepo repository.ReviewRepository, favouriteRepo repository.FavouriteRepository, goalsRepo repository.NutritionGoalsRepository, sessionRepo repository.SessionRepository, throttleRepo repository.LoginThrottleRepository, deletionRepo repository.AccountDeletionRepository, emailService EmailService, cfg *config.Config) AccountService {
+	return &accountService{
+		userRepo:      userRepo,
+		exportRepo:    exportRepo,
+		orderRepo:     orderRepo,
+		cartRepo:      cartRepo,
+		reviewRepo:    reviewRepo,
+		favouriteRepo: favouriteRepo,
+		goalsRepo:     goalsRepo,
+		sessionRepo:   sessionRepo,
+		throttleRepo:  throttleRepo,
+		deletionRepo:  deletionRepo,
+		emailService:  emailService,
+		config:        cfg,
+	}
+}
+
+// RequestDataExport starts preparing a copy of the user's data. It is built
+// in the background and the user is emailed when it is ready.
+func (s *accountService) RequestDataExport(ctx context.Context, userID int) (*models.DataExport, error) {
+	pending, err := s.exportRepo.HasPending(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, errors.New("a data export is already being prepared")
+	}
+
+	export, err := s.exportRepo.Create(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.buildDataExport(export.ID, userID)
+	return export, nil
+}
+
+func (s *accountService) ListDataExports(ctx context.Context, userID int) ([]models.DataExport, error) {
+	return s.exportRepo.GetByUserID(ctx, userID)
+}
+
+// GetDataExport returns the user's finished export as a JSON document.
+func (s *accountService) GetDataExport(ctx context.Context, userID, id int) ([]byte, error) {
+	export, err := s.exportRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Other users' exports are reported as missing
+	if export == nil || export.UserID != userID {
+		return nil, errors.New("data export not found")
+	}
+	switch export.Status {
+	case "pending":
+		return nil, errors.New("data export is not ready yet")
+	case "failed":
+		return nil, errors.New("data export failed, please request a new one")
+	}
+	if export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt) {
+		return nil, errors.New("data export has expired")
+	}
+
+	data, err := s.exportRepo.GetData(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, errors.New("data export not found")
+	}
+	return data, nil
+}
+
+// buildDataExport collects the user's data and stores it on the export. It
+// runs after the request has returned, so it has its own context.
+func (s *accountService) buildDataExport(exportID, userID int) {
+	ctx, cancel := context.WithTimeout(context.Background(), DataExportBuildTimeout)
+	defer cancel()
+
+	data, err := s.collectAccountData(ctx, userID)
+	var document []byte
+	if err == nil {
+		document, err = json.MarshalIndent(data, "", "  ")
+	}
+	if err != nil {
+		log.Printf("Failed to build data export %d for user %d: %v", exportID, userID, err)
+		if err := s.exportRepo.Fail(ctx, exportID, "could not prepare your data, please request a new export"); err != nil {
+			log.Printf("Failed to mark data export %d as failed: %v", exportID, err)
+		}
+		return
+	}
+
+	expiresAt := time.Now().Add(s.config.DataExportTTL)
+	if err := s.exportRepo.Complete(ctx, exportID, document, expiresAt); err != nil {
+		log.Printf("Failed to save data export %d for user %d: %v", exportID, userID, err)
+		return
+	}
+
+	downloadURL := s.config.FrontendURL + "/account/privacy"
+	if err := s.emailService.SendDataExportReady(data.Profile.Email, downloadURL, expiresAt); err != nil {
+		log.Printf("Failed to send data export email to user %d: %v", userID, err)
+	}
+}
+
+func (s *accountService) collectAccountData(ctx context.Context, userID int) (*models.AccountData, error) {
+	data := &models.AccountData{}
+
+	profile, err := s.userRepo.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, errors.New("user not found")
+	}
+	data.Profile = profile
+
+	// The order list has no items, so each order is loaded in full
+	orders, err := s.orderRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	data.Orders = []models.Order{}
+	for _, o := range orders {
+		order, err := s.orderRepo.GetByID(ctx, o.ID)
+		if err != nil {
+			return nil, err
+		}
+		if order != nil {
+			data.Orders = append(data.Orders, *order)
+		}
+	}
+
+	if data.Cart, err = s.cartRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.Reviews, err = s.reviewRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.Favourites, err = s.favouriteRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.NutritionGoals, err = s.goalsRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// RequestAccountDeletion emails the user a link that confirms deleting
+// their account, for users who have no password to confirm it with, such as
+// those who only sign in with an external provider.
+func (s *accountService) RequestAccountDeletion(ctx context.Context, userID int) error {
+	user, err := s.deletableUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkOrdersDelivered(ctx, userID); err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	if err := s.deletionRepo.Create(ctx, user.ID, utils.HashToken(token), time.Now().Add(s.config.AccountDeletionTTL)); err != nil {
+		return err
+	}
+
+	confirmURL := s.config.FrontendURL + "/delete-account?token=" + url.QueryEscape(token)
+	go func() {
+		if err := s.emailService.SendAccountDeletionConfirmation(user.Email, confirmURL); err != nil {
+			log.Printf("Failed to send account deletion email to user %d: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+// DeleteAccount anonymises the user's account once they have confirmed
+// their password, or followed the link from RequestAccountDeletion. Orders
+// are kept for accounting without the personal details attached to them;
+// everything else is deleted.
+func (s *accountService) DeleteAccount(ctx context.Context, userID int, req *models.DeleteAccountRequest) error {
+	user, err := s.deletableUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if req.Token != "" {
+		stored, err := s.deletionRepo.GetByHash(ctx, utils.HashToken(req.Token))
+		if err != nil {
+			return err
+		}
+		if stored == nil || stored.UserID != userID || stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+			return errors.New("invalid or expired confirmation link")
+		}
+	} else if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return errors.New("password is incorrect")
+	}
+
+	if err := s.checkOrdersDelivered(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Anonymise(ctx, userID); err != nil {
+		return err
+	}
+	log.Printf("User %d deleted their account", userID)
+	return nil
+}
+
+// deletableUser returns the user if they may delete their own account.
+func (s *accountService) deletableUser(ctx context.Context, userID int) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.DeletedAt != nil {
+		return nil, errors.New("user not found")
+	}
+	// Staff accounts are removed by an administrator, who can first make
+	// sure nothing depends on them
+	if user.Role != DefaultRole {
+		return nil, errors.New("staff accounts cannot be deleted, ask an administrator to change your role first")
+	}
+	return user, nil
+}
+
+func (s *accountService) checkOrdersDelivered(ctx context.Context, userID int) error {
+	orders, err := s.orderRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if hasUndeliveredOrders(orders) {
+		return errors.New("you have orders that have not been delivered yet")
+	}
+	return nil
+}
+
+// hasUndeliveredOrders reports whether any order is still to be delivered.
+// The account is needed until then, e.g. for the delivery address.
+func hasUndeliveredOrders(orders []models.Order) bool {
+	for _, order := range orders {
+		if order.Status == "pending" || order.Status == "confirmed" {
+			return true
+		}
+	}
+	return false
+}
+
+// Run deletes expired exports and gives up on ones that were interrupted,
+// and deletes expired sessions and login throttles, on every interval until
+// ctx is cancelled.
+func (s *accountService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if failed, err := s.exportRepo.FailStale(ctx, time.Now().Add(-DataExportBuildTimeout)); err != nil {
+			log.Printf("Failed to check for interrupted data exports: %v", err)
+		} else if failed > 0 {
+			log.Printf("Marked %d interrupted data exports as failed", failed)
+		}
+
+		if removed, err := s.exportRepo.DeleteExpired(ctx); err != nil {
+			log.Printf("Failed to delete expired data exports: %v", err)
+		} else if removed > 0 {
+			log.Printf("Deleted %d expired data exports", removed)
+		}
+
+		if removed, err := s.sessionRepo.DeleteExpired(ctx); err != nil {
+			log.Printf("Failed to delete expired sessions: %v", err)
+		} else if removed > 0 {
+			log.Printf("Deleted %d expired sessions", removed)
+		}
+
+		if removed, err := s.throttleRepo.DeleteStale(ctx, s.config.LoginLockoutDuration); err != nil {
+			log.Printf("Failed to delete stale login throttles: %v", err)
+		} else if removed > 0 {
+			log.Printf("Deleted %d stale login throttles", removed)
+		}
+	}
+}
+
+// WriteDataExportZIP writes an export's JSON document as a ZIP archive with
+// one file per section, e.g. profile.json and orders.json.
+func WriteDataExportZIP(w io.Writer, document []byte) error {
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(document, &sections); err != nil {
+		return fmt.Errorf("invalid data export: %w", err)
+	}
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	archive := zip.NewWriter(w)
+	for _, name := range names {
+		var content bytes.Buffer
+		if err := json.Indent(&content, sections[name], "", "  "); err != nil {
+			return err
+		}
+		content.WriteString("\n")
+
+		f, err := archive.Create(name + ".json")
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(content.Bytes()); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
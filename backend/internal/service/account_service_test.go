@@ -0,0 +1,171 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+	"github.com/jopari/preptoplate/internal/utils"
+)
+
+// fakeAccountUsers is a UserRepository holding one user, which records
+// whether it was anonymised.
+type fakeAccountUsers struct {
+	repository.UserRepository
+	user       *models.User
+	anonymised bool
+}
+
+func (f *fakeAccountUsers) GetByID(ctx context.Context, id int) (*models.User, error) {
+	if f.user.ID != id {
+		return nil, nil
+	}
+	return f.user, nil
+}
+
+func (f *fakeAccountUsers) Anonymise(ctx context.Context, id int) error {
+	f.anonymised = true
+	return nil
+}
+
+// fakeDeliveredOrders is an OrderRepository for users with nothing left to
+// deliver.
+type fakeDeliveredOrders struct {
+	repository.OrderRepository
+}
+
+func (fakeDeliveredOrders) GetByUserID(ctx context.Context, userID int) ([]models.Order, error) {
+	return []models.Order{{Status: "delivered"}}, nil
+}
+
+// fakeDeletionTokens is an AccountDeletionRepository keyed by token hash.
+type fakeDeletionTokens struct {
+	repository.AccountDeletionRepository
+	tokens map[string]*models.AccountDeletionToken
+}
+
+func (f fakeDeletionTokens) GetByHash(ctx context.Context, tokenHash string) (*models.AccountDeletionToken, error) {
+	return f.tokens[tokenHash], nil
+}
+
+func TestDeleteAccountWithoutPassword(t *testing.T) {
+	passwordHash, err := unusablePasswordHash()
+	if err != nil {
+		t.Fatalf("unusablePasswordHash: %v", err)
+	}
+	tokens := fakeDeletionTokens{tokens: map[string]*models.AccountDeletionToken{
+		utils.HashToken("valid"):   {ID: 1, UserID: 7, ExpiresAt: time.Now().Add(time.Hour)},
+		utils.HashToken("expired"): {ID: 2, UserID: 7, ExpiresAt: time.Now().Add(-time.Minute)},
+		utils.HashToken("other"):   {ID: 3, UserID: 8, ExpiresAt: time.Now().Add(time.Hour)},
+	}}
+
+	tests := []struct {
+		name    string
+		req     models.DeleteAccountRequest
+		wantErr string
+	}{
+		{"guessed password", models.DeleteAccountRequest{Password: "password123"}, "password is incorrect"},
+		{"unknown token", models.DeleteAccountRequest{Token: "unknown"}, "invalid or expired confirmation link"},
+		{"expired token", models.DeleteAccountRequest{Token: "expired"}, "invalid or expired confirmation link"},
+		{"another user's token", models.DeleteAccountRequest{Token: "other"}, "invalid or expired confirmation link"},
+		{"emailed token", models.DeleteAccountRequest{Token: "valid"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeAccountUsers{user: &models.User{ID: 7, Email: "oidc@example.com", PasswordHash: passwordHash, Role: DefaultRole}}
+			s := &accountService{userRepo: users, orderRepo: fakeDeliveredOrders{}, deletionRepo: tokens}
+
+			err := s.DeleteAccount(context.Background(), 7, &tt.req)
+			if tt.wantErr == "" {
+				if err != nil || !users.anonymised {
+					t.Errorf("Expected the account to be deleted, got err %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected %q, got %v", tt.wantErr, err)
+			}
+			if users.anonymised {
+				t.Errorf("Expected the account to be kept")
+			}
+		})
+	}
+}
+
+func TestHasUndeliveredOrders(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     bool
+	}{
+		{nil, false},
+		{[]string{"delivered", "cancelled"}, false},
+		{[]string{"delivered", "pending"}, true},
+		{[]string{"confirmed"}, true},
+	}
+
+	for _, tt := range tests {
+		var orders []models.Order
+		for _, status := range tt.statuses {
+			orders = append(orders, models.Order{Status: status})
+		}
+		if got := hasUndeliveredOrders(orders); got != tt.want {
+			t.Errorf("hasUndeliveredOrders(%v) = %v, want %v", tt.statuses, got, tt.want)
+		}
+	}
+}
+
+func TestWriteDataExportZIP(t *testing.T) {
+	data := models.AccountData{
+		Profile: &models.Profile{ID: 7, Email: "jo@example.com"},
+		Orders:  []models.Order{{ID: 3, Status: "delivered"}},
+		Reviews: []models.Review{},
+	}
+	document, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDataExportZIP(&buf, document); err != nil {
+		t.Fatalf("WriteDataExportZIP: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Expected a valid ZIP archive: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+
+	for _, name := range []string{"profile.json", "orders.json", "cart.json", "reviews.json", "favourites.json", "nutrition_goals.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Expected %s in the archive, got %d files", name, len(files))
+		}
+	}
+
+	var profile models.Profile
+	if err := json.Unmarshal(files["profile.json"], &profile); err != nil {
+		t.Fatalf("profile.json is not valid JSON: %v", err)
+	}
+	if profile.Email != "jo@example.com" {
+		t.Errorf("Expected the profile's email, got %q", profile.Email)
+	}
+
+	if err := WriteDataExportZIP(io.Discard, []byte("not json")); err == nil {
+		t.Error("Expected an error for an invalid document")
+	}
+}
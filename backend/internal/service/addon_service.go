@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+type AddonService interface {
+	Create(ctx context.Context, req *models.CreateAddonRequest) (*models.Addon, error)
+	GetByID(ctx context.Context, id int) (*models.Addon, error)
+	GetAll(ctx context.Context, category string) ([]models.Addon, error)
+	Update(ctx context.Context, id int, req *models.UpdateAddonRequest) (*models.Addon, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type addonService struct {
+	repo repository.AddonRepository
+}
+
+func NewAddonService(repo repository.AddonRepository) AddonService {
+	return &addonService{repo: repo}
+}
+
+func (s *addonService) Create(ctx context.Context, req *models.CreateAddonRequest) (*models.Addon, error) {
+	addon := &models.Addon{
+		Name:        req.Name,
+		Description: req.Description,
+		ImageURL:    req.ImageURL,
+		Category:    req.Category,
+		Calories:    req.Calories,
+		Protein:     req.Protein,
+		Carbs:       req.Carbs,
+		Fat:         req.Fat,
+		Price:       req.Price,
+	}
+
+	if err := s.repo.Create(ctx, addon); err != nil {
+		return nil, err
+	}
+
+	return addon, nil
+}
+
+func (s *addonService) GetByID(ctx context.Context, id int) (*models.Addon, error) {
+	addon, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if addon == nil {
+		return nil, errors.New("add-on not found")
+	}
+	return addon, nil
+}
+
+func (s *addonService) GetAll(ctx context.Context, category string) ([]models.Addon, error) {
+	return s.repo.GetAll(ctx, category)
+}
+
+func (s *addonService) Update(ctx context.Context, id int, req *models.UpdateAddonRequest) (*models.Addon, error) {
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply updates only for non-nil fields
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Description != nil {
+		existing.Description = *req.Description
+	}
+	if req.ImageURL != nil {
+		existing.ImageURL = *req.ImageURL
+	}
+	if req.Category != nil {
+		existing.Category = *req.Category
+	}
+	if req.Calories != nil {
+		existing.Calories = *req.Calories
+	}
+	if req.Protein != nil {
+		existing.Protein = *req.Protein
+	}
+	if req.Carbs != nil {
+		existing.Carbs = *req.Carbs
+	}
+	if req.Fat != nil {
+		existing.Fat = *req.Fat
+	}
+	if req.Price != nil {
+		existing.Price = *req.Price
+	}
+
+	if err := s.repo.Update(ctx, id, existing); err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// Delete removes an add-on that is on no menu and has never been ordered.
+// Customers who have it in their cart lose it from the cart.
+func (s *addonService) Delete(ctx context.Context, id int) error {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	isUsed, err := s.repo.IsUsedInMenus(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if isUsed {
+		return errors.New("cannot delete add-on: it is currently used in one or more weekly menus")
+	}
+
+	// Orders keep the add-ons they were placed with
+	isOrdered, err := s.repo.IsOrdered(ctx, id)
+	if err != nil {
+		return err
+	}
+	if isOrdered {
+		return errors.New("cannot delete add-on: it is part of past orders")
+	}
+
+	return s.repo.Delete(ctx, id)
+}
@@ -3,6 +3,11 @@ package service
 import (
 	"context"
 	"errors"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jopari/preptoplate/internal/config"
 	"github.com/jopari/preptoplate/internal/models"
@@ -13,16 +18,67 @@ import (
 
 type AuthService interface {
 	Register(ctx context.Context, req *models.CreateUserRequest) (*models.AuthResponse, error)
-	Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error)
+	Login(ctx context.Context, req *models.LoginRequest, clientIP string) (*models.AuthResponse, error)
+	CompleteTwoFactorLogin(ctx context.Context, req *models.TwoFactorLoginRequest) (*models.AuthResponse, error)
+	SignIn(ctx context.Context, user *models.User) (*models.AuthResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*models.AuthResponse, error)
+	Logout(ctx context.Context, userID, sessionID int, all bool) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, req *models.ResetPasswordRequest) error
+	ChangePassword(ctx context.Context, userID, sessionID int, req *models.ChangePasswordRequest) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, userID int) error
+	MarkEmailVerified(ctx context.Context, userID int) error
+	RequestEmailChange(ctx context.Context, userID int, req *models.ChangeEmailRequest) error
+	UnlockAccount(ctx context.Context, userID int) error
+	UnlockIP(ctx context.Context, ip string) error
 }
 
+// Limits on resending the verification email, so the endpoint cannot be
+// used to flood an inbox
+const (
+	VerificationResendCooldown  = time.Minute
+	MaxVerificationEmailsPerDay = 5
+)
+
+// After LoginFreeAttempts failed logins, each further attempt has to wait,
+// starting at a second and doubling up to LoginMaxBackoff
+const (
+	LoginFreeAttempts = 3
+	LoginMaxBackoff   = 15 * time.Minute
+)
+
+// Limits on the second step of a two-factor login: attempts per challenge,
+// and per user across challenges within LoginLockoutDuration, so logging
+// in again does not give more guesses
+const (
+	TwoFactorChallengeTTL = 5 * time.Minute
+	MaxTwoFactorAttempts  = 5
+	MaxTwoFactorFailures  = 10
+)
+
 type authService struct {
-	repo   repository.UserRepository
-	config *config.Config
+	repo         repository.UserRepository
+	sessionRepo  repository.SessionRepository
+	resetRepo    repository.PasswordResetRepository
+	verifyRepo   repository.EmailVerificationRepository
+	twoFARepo    repository.TwoFactorRepository
+	throttleRepo repository.LoginThrottleRepository
+	emailService EmailService
+	config       *config.Config
 }
 
-func NewAuthService(repo repository.UserRepository, cfg *config.Config) AuthService {
-	return &authService{repo: repo, config: cfg}
+func NewAuthService(repo repository.UserRepository, sessionRepo repository.SessionRepository, resetRepo repository.PasswordResetRepository, verifyRepo repository.EmailVerificationRepository, twoFARepo repository.TwoFactorRepository, throttleRepo repository.LoginThrottleRepository, emailService EmailService, cfg *config.Config) AuthService {
+	return &authService{
+		repo:         repo,
+		sessionRepo:  sessionRepo,
+		resetRepo:    resetRepo,
+		verifyRepo:   verifyRepo,
+		twoFARepo:    twoFARepo,
+		throttleRepo: throttleRepo,
+		emailService: emailService,
+		config:       cfg,
+	}
 }
 
 func (s *authService) Register(ctx context.Context, req *models.CreateUserRequest) (*models.AuthResponse, error) {
@@ -42,44 +98,627 @@ func (s *authService) Register(ctx context.Context, req *models.CreateUserReques
 	user := &models.User{
 		Email:        req.Email,
 		PasswordHash: string(hashedPassword),
-		Role:         "user",
+		Role:         DefaultRole,
 	}
 
 	if err := s.repo.Create(ctx, user); err != nil {
 		return nil, err
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Role, s.config.JWTSecret)
+	// The account is usable straight away, but cannot check out until the
+	// email is verified
+	if err := s.sendVerification(ctx, user); err != nil {
+		log.Printf("Failed to create verification token for user %d: %v", user.ID, err)
+	}
+
+	return s.startSession(ctx, user, false)
+}
+
+// Login checks the email and password. Failed attempts are counted per
+// account and per client IP: after a few, further attempts are slowed down,
+// and too many lock the account or IP out for a while.
+func (s *authService) Login(ctx context.Context, req *models.LoginRequest, clientIP string) (*models.AuthResponse, error) {
+	// Accounts are throttled by email whether or not they exist, so the
+	// responses do not reveal which emails have accounts
+	account := strings.ToLower(strings.TrimSpace(req.Email))
+	if err := s.checkThrottle(ctx, "ip", clientIP); err != nil {
+		return nil, err
+	}
+	if err := s.checkThrottle(ctx, "account", account); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, err
 	}
+	if user == nil {
+		return nil, s.loginFailed(ctx, account, clientIP, nil)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, s.loginFailed(ctx, account, clientIP, user)
+	}
+
+	if err := s.throttleRepo.Reset(ctx, "account", account); err != nil {
+		return nil, err
+	}
+	if user.PasswordResetRequired && user.DisabledAt == nil {
+		return nil, errors.New("password reset required, check your email for a reset link")
+	}
+	return s.SignIn(ctx, user)
+}
+
+// SignIn finishes signing in a user whose identity has been checked, by
+// password or by an external provider. Users with two-factor
+// authentication get a challenge instead of tokens.
+func (s *authService) SignIn(ctx context.Context, user *models.User) (*models.AuthResponse, error) {
+	if user.DisabledAt != nil {
+		return nil, errors.New("account has been disabled")
+	}
+	if !user.TwoFactorEnabled {
+		return s.startSession(ctx, user, false)
+	}
+
+	// The login is finished at /auth/login/2fa with a code from the
+	// authenticator app
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFARepo.CreateChallenge(ctx, user.ID, utils.HashToken(token), time.Now().Add(TwoFactorChallengeTTL)); err != nil {
+		return nil, err
+	}
 
 	return &models.AuthResponse{
-		Token: token,
-		User:  *user,
+		TwoFactorRequired: true,
+		TwoFactorToken:    token,
+		User:              *user,
 	}, nil
 }
 
-func (s *authService) Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error) {
-	user, err := s.repo.GetByEmail(ctx, req.Email)
+// CompleteTwoFactorLogin finishes a login that needs a second factor, with
+// an authenticator or recovery code. A challenge allows a few attempts
+// before the user has to enter their password again, and a user a few more
+// across challenges before two-factor login is locked for a while.
+func (s *authService) CompleteTwoFactorLogin(ctx context.Context, req *models.TwoFactorLoginRequest) (*models.AuthResponse, error) {
+	challenge, err := s.twoFARepo.GetChallenge(ctx, utils.HashToken(req.TwoFactorToken))
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil || challenge.UsedAt != nil {
+		return nil, errors.New("invalid two-factor token")
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return nil, errors.New("two-factor token expired, log in again")
+	}
+
+	// Attempts are counted before the code is checked, so parallel guesses
+	// cannot get past the limits
+	allowed, err := s.twoFARepo.TakeChallengeAttempt(ctx, challenge.ID, MaxTwoFactorAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, errors.New("too many attempts, log in again")
+	}
+	subject := strconv.Itoa(challenge.UserID)
+	throttle, err := s.throttleRepo.TakeAttempt(ctx, "2fa", subject, MaxTwoFactorFailures, s.config.LoginLockoutDuration)
+	if err != nil {
+		return nil, err
+	}
+	if throttle == nil {
+		return nil, errors.New("too many failed two-factor attempts, try again later")
+	}
+
+	user, err := s.repo.GetByID(ctx, challenge.UserID)
 	if err != nil {
 		return nil, err
 	}
 	if user == nil {
-		return nil, errors.New("invalid credentials")
+		return nil, errors.New("invalid two-factor token")
+	}
+	if user.DisabledAt != nil {
+		return nil, errors.New("account has been disabled")
+	}
+	settings, err := s.twoFARepo.GetSettings(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := verifySecondFactor(ctx, s.twoFARepo, user.ID, settings, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if throttle.Failures >= MaxTwoFactorFailures {
+			// Someone has the password and is guessing codes
+			until := throttle.LastFailureAt.Add(s.config.LoginLockoutDuration)
+			log.Printf("Two-factor login locked for user %d until %s after %d failed attempts", user.ID, until.Format(time.RFC3339), throttle.Failures)
+			go func(email string) {
+				if err := s.emailService.SendAccountLocked(email, until); err != nil {
+					log.Printf("Failed to send account locked email to user %d: %v", user.ID, err)
+				}
+			}(user.Email)
+		}
+		return nil, errors.New("invalid two-factor code")
+	}
+	if err := s.throttleRepo.Reset(ctx, "2fa", subject); err != nil {
+		return nil, err
+	}
+
+	completed, err := s.twoFARepo.CompleteChallenge(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, errors.New("invalid two-factor token")
+	}
+
+	return s.startSession(ctx, user, true)
+}
+
+// Refresh exchanges a refresh token for a new access token and refresh
+// token. Each refresh token works once: presenting one that has already been
+// exchanged means it was copied, so the whole session is revoked and both
+// the thief and the user have to log in again.
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*models.AuthResponse, error) {
+	stored, err := s.sessionRepo.GetRefreshToken(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, errors.New("invalid refresh token")
+	}
+	if stored.SessionRevokedAt != nil {
+		return nil, errors.New("session has been revoked")
+	}
+	if stored.UsedAt != nil {
+		return nil, s.revokeReusedSession(ctx, stored)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, errors.New("refresh token expired")
+	}
+
+	user, err := s.repo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("invalid refresh token")
+	}
+	if user.DisabledAt != nil {
+		return nil, errors.New("account has been disabled")
+	}
+
+	newToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.sessionRepo.RotateRefreshToken(ctx, stored.ID, stored.SessionID,
+		utils.HashToken(newToken), time.Now().Add(s.config.RefreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another request exchanged the same token first
+		return nil, s.revokeReusedSession(ctx, stored)
+	}
+
+	return s.authResponse(user, stored.SessionID, newToken)
+}
+
+// Logout revokes the current session, or with all every session of the user.
+func (s *authService) Logout(ctx context.Context, userID, sessionID int, all bool) error {
+	if all {
+		return s.sessionRepo.RevokeAllForUser(ctx, userID, "logout")
+	}
+	return s.sessionRepo.Revoke(ctx, sessionID, "logout")
+}
+
+// ForgotPassword emails the user a link to reset their password. It succeeds
+// whether or not the email belongs to an account, so it cannot be used to
+// find out who has one.
+func (s *authService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.repo.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	if err := s.resetRepo.Create(ctx, user.ID, utils.HashToken(token), time.Now().Add(s.config.PasswordResetTTL)); err != nil {
+		return err
+	}
+
+	resetURL := s.config.FrontendURL + "/reset-password?token=" + url.QueryEscape(token)
+	// Send asynchronously so the response time does not give away whether
+	// the account exists
+	go func() {
+		if err := s.emailService.SendPasswordReset(user.Email, resetURL); err != nil {
+			log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+// ResetPassword sets a new password using an emailed reset token. The token
+// works once, and every existing session is revoked.
+func (s *authService) ResetPassword(ctx context.Context, req *models.ResetPasswordRequest) error {
+	stored, err := s.resetRepo.GetByHash(ctx, utils.HashToken(req.Token))
+	if err != nil {
+		return err
+	}
+	if stored == nil || stored.UsedAt != nil {
+		return errors.New("invalid or used reset token")
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return errors.New("reset token expired")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	reset, err := s.resetRepo.ResetPassword(ctx, stored.ID, stored.UserID, string(hashedPassword))
+	if err != nil {
+		return err
+	}
+	if !reset {
+		return errors.New("invalid or used reset token")
+	}
+	return nil
+}
+
+// ChangePassword sets a new password for a signed-in user who knows their
+// current one. The session making the change stays signed in; all others
+// are revoked.
+func (s *authService) ChangePassword(ctx context.Context, userID, sessionID int, req *models.ChangePasswordRequest) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		return errors.New("current password is incorrect")
 	}
 
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.ChangePassword(ctx, userID, string(hashedPassword), sessionID)
+}
+
+// VerifyEmail verifies the account the emailed token was issued for, or
+// confirms the change of email address it was sent to.
+func (s *authService) VerifyEmail(ctx context.Context, token string) error {
+	stored, err := s.verifyRepo.GetByHash(ctx, utils.HashToken(token))
+	if err != nil {
+		return err
+	}
+	if stored == nil || stored.UsedAt != nil {
+		return errors.New("invalid or used verification token")
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return errors.New("verification token expired")
+	}
+
+	verified, err := s.verifyRepo.Verify(ctx, stored.ID, stored.UserID)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return errors.New("invalid or used verification token")
+	}
+	return nil
+}
+
+// ResendVerification emails the user a new verification link, at most once
+// a minute and a few times a day.
+func (s *authService) ResendVerification(ctx context.Context, userID int) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+	if user.EmailVerified {
+		return errors.New("email is already verified")
+	}
+
+	now := time.Now()
+	sent, last, err := s.verifyRepo.GetSendStats(ctx, userID, now.Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if !canResendVerification(sent, last, now) {
+		return errors.New("too many verification emails, try again later")
+	}
+
+	return s.sendVerification(ctx, user)
+}
+
+// RequestEmailChange emails a confirmation link to the new address and a
+// notice to the current one, so the owner hears of a change they did not
+// ask for. The account keeps its current email until the link is followed,
+// and sends count towards the verification email limits.
+func (s *authService) RequestEmailChange(ctx context.Context, userID int, req *models.ChangeEmailRequest) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return nil, errors.New("invalid credentials")
+		return errors.New("password is incorrect")
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return errors.New("that is already your email address")
+	}
+	existing, err := s.repo.GetByEmail(ctx, newEmail)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.New("email already in use")
+	}
+
+	now := time.Now()
+	sent, last, err := s.verifyRepo.GetSendStats(ctx, userID, now.Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if !canResendVerification(sent, last, now) {
+		return errors.New("too many verification emails, try again later")
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	if err := s.verifyRepo.CreateEmailChange(ctx, userID, newEmail, utils.HashToken(token), now.Add(s.config.EmailVerificationTTL)); err != nil {
+		return err
+	}
+
+	verifyURL := s.config.FrontendURL + "/verify-email?token=" + url.QueryEscape(token)
+	go func() {
+		if err := s.emailService.SendEmailVerification(newEmail, verifyURL); err != nil {
+			log.Printf("Failed to send email change confirmation to user %d: %v", user.ID, err)
+		}
+		if err := s.emailService.SendEmailChangeNotice(user.Email, newEmail); err != nil {
+			log.Printf("Failed to send email change notice to user %d: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+// MarkEmailVerified lets an admin verify a user's email without the link,
+// e.g. for customers who cannot receive it.
+func (s *authService) MarkEmailVerified(ctx context.Context, userID int) error {
+	return s.repo.MarkEmailVerified(ctx, userID)
+}
+
+// UnlockAccount lets an admin lift a user's login lockout and backoff,
+// two-factor lockout included.
+func (s *authService) UnlockAccount(ctx context.Context, userID int) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
 	}
+	if err := s.throttleRepo.Reset(ctx, "2fa", strconv.Itoa(user.ID)); err != nil {
+		return err
+	}
+	return s.throttleRepo.Reset(ctx, "account", strings.ToLower(user.Email))
+}
+
+// UnlockIP lets an admin lift a client IP's login lockout and backoff.
+func (s *authService) UnlockIP(ctx context.Context, ip string) error {
+	return s.throttleRepo.Reset(ctx, "ip", ip)
+}
+
+// checkThrottle refuses a login attempt while the account is locked out or
+// backing off, or the IP is locked out. Everyone behind the same NAT or
+// proxy shares an IP, so IPs only get the hard lockout after many more
+// failures, and a successful login does not clear them: an attacker could
+// otherwise log in to their own account to carry on guessing.
+func (s *authService) checkThrottle(ctx context.Context, scope, subject string) error {
+	throttle, err := s.throttleRepo.Get(ctx, scope, subject)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if scope == "ip" {
+		if throttle != nil && throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+			return errors.New("too many failed login attempts, try again later")
+		}
+		return nil
+	}
+	if loginBlockedUntil(throttle, s.config.LoginLockoutDuration, now).IsZero() {
+		return nil
+	}
+	if scope == "account" && throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+		return errors.New("account is temporarily locked, try again later or reset your password")
+	}
+	return errors.New("too many failed login attempts, try again later")
+}
+
+// loginFailed records a failed login against the account and the IP, locks
+// whichever has reached its limit, and tells the user when their account
+// is locked. It returns the error for the caller.
+func (s *authService) loginFailed(ctx context.Context, account, clientIP string, user *models.User) error {
+	limits := []struct {
+		scope   string
+		subject string
+		max     int
+	}{
+		{"account", account, s.config.LoginMaxAttempts},
+		{"ip", clientIP, s.config.LoginMaxAttemptsPerIP},
+	}
+
+	for _, limit := range limits {
+		throttle, err := s.throttleRepo.RecordFailure(ctx, limit.scope, limit.subject, s.config.LoginLockoutDuration)
+		if err != nil {
+			return err
+		}
+		if throttle.Failures < limit.max {
+			continue
+		}
+
+		until := time.Now().Add(s.config.LoginLockoutDuration)
+		locked, err := s.throttleRepo.Lock(ctx, limit.scope, limit.subject, until)
+		if err != nil {
+			return err
+		}
+		if !locked {
+			continue
+		}
+
+		log.Printf("Login locked for %s %s until %s after %d failed attempts", limit.scope, limit.subject, until.Format(time.RFC3339), throttle.Failures)
+		if limit.scope == "account" && user != nil {
+			go func(email string) {
+				if err := s.emailService.SendAccountLocked(email, until); err != nil {
+					log.Printf("Failed to send account locked email to user %d: %v", user.ID, err)
+				}
+			}(user.Email)
+		}
+	}
+
+	return errors.New("invalid credentials")
+}
+
+// loginBlockedUntil returns when the next login attempt is allowed, or the
+// zero time if it is allowed now. Failures older than the window no longer
+// count.
+func loginBlockedUntil(throttle *models.LoginThrottle, window time.Duration, now time.Time) time.Time {
+	if throttle == nil {
+		return time.Time{}
+	}
+	if throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+		return *throttle.LockedUntil
+	}
+	if now.Sub(throttle.LastFailureAt) >= window {
+		return time.Time{}
+	}
+
+	next := throttle.LastFailureAt.Add(loginBackoff(throttle.Failures))
+	if now.Before(next) {
+		return next
+	}
+	return time.Time{}
+}
+
+// loginBackoff is how long to wait after a number of failed logins.
+func loginBackoff(failures int) time.Duration {
+	if failures < LoginFreeAttempts {
+		return 0
+	}
+	shift := failures - LoginFreeAttempts
+	if shift > 20 {
+		return LoginMaxBackoff
+	}
+	backoff := time.Second << shift
+	if backoff > LoginMaxBackoff {
+		return LoginMaxBackoff
+	}
+	return backoff
+}
+
+// unusablePasswordHash is the password hash for accounts created without a
+// password, which nobody can log in with until the password is reset.
+func unusablePasswordHash() (string, error) {
+	unusable, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(unusable), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
+// canResendVerification reports whether another verification email may be
+// sent, given how many were sent in the last day and when the last one was.
+func canResendVerification(sentToday int, lastSent *time.Time, now time.Time) bool {
+	if sentToday >= MaxVerificationEmailsPerDay {
+		return false
+	}
+	return lastSent == nil || now.Sub(*lastSent) >= VerificationResendCooldown
+}
+
+// sendVerification issues a new verification token and emails its link.
+func (s *authService) sendVerification(ctx context.Context, user *models.User) error {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	if err := s.verifyRepo.Create(ctx, user.ID, utils.HashToken(token), time.Now().Add(s.config.EmailVerificationTTL)); err != nil {
+		return err
+	}
+
+	verifyURL := s.config.FrontendURL + "/verify-email?token=" + url.QueryEscape(token)
+	go func() {
+		if err := s.emailService.SendEmailVerification(user.Email, verifyURL); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+func (s *authService) revokeReusedSession(ctx context.Context, stored *models.RefreshToken) error {
+	log.Printf("Refresh token reuse detected for user %d, revoking session %d", stored.UserID, stored.SessionID)
+	if err := s.sessionRepo.Revoke(ctx, stored.SessionID, "reuse_detected"); err != nil {
+		return err
+	}
+	return errors.New("refresh token reuse detected")
+}
+
+// startSession opens a new login session for the user and issues its first
+// tokens. twoFactor records that the user signed in with a second factor.
+func (s *authService) startSession(ctx context.Context, user *models.User, twoFactor bool) (*models.AuthResponse, error) {
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessionRepo.Create(ctx, user.ID, twoFactor, utils.HashToken(refreshToken),
+		time.Now().Add(s.config.RefreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authResponse(user, session.ID, refreshToken)
+}
 
-	token, err := utils.GenerateToken(user.ID, user.Role, s.config.JWTSecret)
+func (s *authService) authResponse(user *models.User, sessionID int, refreshToken string) (*models.AuthResponse, error) {
+	token, err := utils.GenerateToken(user.ID, sessionID, user.Role, s.config.JWTSecret, s.config.AccessTokenTTL)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.AuthResponse{
-		Token: token,
-		User:  *user,
+		Token:        token,
+		ExpiresIn:    int(s.config.AccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		User:         *user,
 	}, nil
 }
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jopari/preptoplate/internal/config"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestCanResendVerification(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+
+	tests := []struct {
+		name      string
+		sentToday int
+		lastSent  *time.Time
+		want      bool
+	}{
+		{"never sent", 0, nil, true},
+		{"sent long ago", 1, ago(time.Hour), true},
+		{"within cooldown", 1, ago(30 * time.Second), false},
+		{"cooldown just passed", 1, ago(VerificationResendCooldown), true},
+		{"daily limit reached", MaxVerificationEmailsPerDay, ago(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canResendVerification(tt.sentToday, tt.lastSent, now); got != tt.want {
+				t.Errorf("canResendVerification(%d, %v) = %v, want %v", tt.sentToday, tt.lastSent, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{LoginFreeAttempts - 1, 0},
+		{LoginFreeAttempts, time.Second},
+		{LoginFreeAttempts + 3, 8 * time.Second},
+		{LoginFreeAttempts + 40, LoginMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := loginBackoff(tt.failures); got != tt.want {
+			t.Errorf("loginBackoff(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginBlockedUntil(t *testing.T) {
+	now := time.Now()
+	window := 30 * time.Minute
+	lockedUntil := now.Add(10 * time.Minute)
+
+	tests := []struct {
+		name     string
+		throttle *models.LoginThrottle
+		want     time.Time
+	}{
+		{"no failures", nil, time.Time{}},
+		{"few failures", &models.LoginThrottle{Failures: 1, LastFailureAt: now}, time.Time{}},
+		{"backing off", &models.LoginThrottle{Failures: LoginFreeAttempts + 2, LastFailureAt: now.Add(-time.Second)}, now.Add(3 * time.Second)},
+		{"backoff over", &models.LoginThrottle{Failures: LoginFreeAttempts + 2, LastFailureAt: now.Add(-time.Minute)}, time.Time{}},
+		{"locked", &models.LoginThrottle{Failures: 10, LastFailureAt: now, LockedUntil: &lockedUntil}, lockedUntil},
+		{"failures forgotten", &models.LoginThrottle{Failures: 40, LastFailureAt: now.Add(-window)}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loginBlockedUntil(tt.throttle, window, now); !got.Equal(tt.want) {
+				t.Errorf("loginBlockedUntil = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeThrottles is a LoginThrottleRepository over fixed counters, keyed by
+// scope and subject.
+type fakeThrottles map[string]*models.LoginThrottle
+
+func (f fakeThrottles) Get(ctx context.Context, scope, subject string) (*models.LoginThrottle, error) {
+	return f[scope+":"+subject], nil
+}
+
+func (f fakeThrottles) RecordFailure(ctx context.Context, scope, subject string, window time.Duration) (*models.LoginThrottle, error) {
+	throttle := f[scope+":"+subject]
+	if throttle == nil {
+		throttle = &models.LoginThrottle{Scope: scope, Subject: subject}
+		f[scope+":"+subject] = throttle
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = time.Now()
+	return throttle, nil
+}
+
+func (f fakeThrottles) TakeAttempt(ctx context.Context, scope, subject string, max int, window time.Duration) (*models.LoginThrottle, error) {
+	return f.RecordFailure(ctx, scope, subject, window)
+}
+
+func (f fakeThrottles) Lock(ctx context.Context, scope, subject string, until time.Time) (bool, error) {
+	f[scope+":"+subject].LockedUntil = &until
+	return true, nil
+}
+
+func (f fakeThrottles) Reset(ctx context.Context, scope, subject string) error {
+	delete(f, scope+":"+subject)
+	return nil
+}
+
+func (f fakeThrottles) DeleteStale(ctx context.Context, window time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func TestCheckThrottle(t *testing.T) {
+	now := time.Now()
+	lockedUntil := now.Add(10 * time.Minute)
+	backingOff := func(scope, subject string) *models.LoginThrottle {
+		return &models.LoginThrottle{Scope: scope, Subject: subject, Failures: LoginFreeAttempts + 5, LastFailureAt: now}
+	}
+
+	throttles := fakeThrottles{
+		"ip:10.0.0.1":           backingOff("ip", "10.0.0.1"),
+		"ip:10.0.0.2":           {Scope: "ip", Subject: "10.0.0.2", Failures: 50, LastFailureAt: now, LockedUntil: &lockedUntil},
+		"account:a@example.com": backingOff("account", "a@example.com"),
+	}
+	s := &authService{throttleRepo: throttles, config: &config.Config{LoginLockoutDuration: 30 * time.Minute}}
+
+	tests := []struct {
+		name    string
+		scope   string
+		subject string
+		allowed bool
+	}{
+		// An IP is shared by everyone behind it, so failures from it never
+		// slow down logins; only the hard lockout refuses them
+		{"ip with recent failures", "ip", "10.0.0.1", true},
+		{"locked ip", "ip", "10.0.0.2", false},
+		{"unknown ip", "ip", "10.0.0.3", true},
+		{"account with recent failures", "account", "a@example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.checkThrottle(context.Background(), tt.scope, tt.subject)
+			if allowed := err == nil; allowed != tt.allowed {
+				t.Errorf("checkThrottle(%s, %s) = %v, want allowed %v", tt.scope, tt.subject, err, tt.allowed)
+			}
+		})
+	}
+}
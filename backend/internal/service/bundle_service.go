@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+type BundleService interface {
+	Create(ctx context.Context, req *models.CreateBundleRequest) (*models.Bundle, error)
+	GetByID(ctx context.Context, id int) (*models.Bundle, error)
+	GetAll(ctx context.Context, menuID int) ([]models.Bundle, error)
+	GetActive(ctx context.Context, userID int) ([]models.Bundle, error)
+	Update(ctx context.Context, id int, req *models.UpdateBundleRequest) (*models.Bundle, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type bundleService struct {
+	repo      repository.BundleRepository
+	menuRepo  repository.WeeklyMenuRepository
+	priceRepo repository.PriceRepository
+}
+
+func NewBundleService(repo repository.BundleRepository, menuRepo repository.WeeklyMenuRepository, priceRepo repository.PriceRepository) BundleService {
+	return &bundleService{repo: repo, menuRepo: menuRepo, priceRepo: priceRepo}
+}
+
+func (s *bundleService) Create(ctx context.Context, req *models.CreateBundleRequest) (*models.Bundle, error) {
+	menu, err := s.menuRepo.GetByID(ctx, req.MenuID)
+	if err != nil {
+		return nil, err
+	}
+	if menu == nil {
+		return nil, errors.New("weekly menu not found")
+	}
+
+	items, regularPrice, err := bundleItemsFromMenu(menu, req.Items)
+	if err != nil {
+		return nil, err
+	}
+	if req.Price > regularPrice {
+		return nil, errors.New("bundle price cannot be more than its meals cost separately")
+	}
+
+	bundle := &models.Bundle{
+		MenuID:      req.MenuID,
+		Name:        req.Name,
+		Description: req.Description,
+		ImageURL:    req.ImageURL,
+		Price:       req.Price,
+		IsActive:    true,
+		Items:       items,
+	}
+	if req.IsActive != nil {
+		bundle.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.Create(ctx, bundle); err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(ctx, bundle.ID)
+}
+
+func (s *bundleService) GetByID(ctx context.Context, id int) (*models.Bundle, error) {
+	bundle, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil {
+		return nil, errors.New("bundle not found")
+	}
+	return bundle, nil
+}
+
+func (s *bundleService) GetAll(ctx context.Context, menuID int) ([]models.Bundle, error) {
+	return s.repo.GetAll(ctx, menuID)
+}
+
+// GetActive lists the bundles customers can buy: active bundles on the
+// active weekly menu. A signed-in subscriber (userID is 0 when anonymous)
+// sees the regular price and discount against their plan's prices.
+func (s *bundleService) GetActive(ctx context.Context, userID int) ([]models.Bundle, error) {
+	menu, err := s.menuRepo.GetActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if menu == nil {
+		return []models.Bundle{}, nil
+	}
+
+	bundles, err := s.repo.GetAll(ctx, menu.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	active := []models.Bundle{}
+	for _, b := range bundles {
+		if b.IsActive {
+			active = append(active, b)
+		}
+	}
+
+	priced := make([]*models.Bundle, len(active))
+	for i := range active {
+		priced[i] = &active[i]
+	}
+	if err := applyBundlePlanPrices(ctx, s.priceRepo, userID, priced); err != nil {
+		return nil, err
+	}
+	return active, nil
+}
+
+func (s *bundleService) Update(ctx context.Context, id int, req *models.UpdateBundleRequest) (*models.Bundle, error) {
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply updates only for non-nil fields
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Description != nil {
+		existing.Description = *req.Description
+	}
+	if req.ImageURL != nil {
+		existing.ImageURL = *req.ImageURL
+	}
+	if req.Price != nil {
+		existing.Price = *req.Price
+	}
+	if req.IsActive != nil {
+		existing.IsActive = *req.IsActive
+	}
+
+	regularPrice := existing.RegularPrice
+	if req.Items != nil {
+		menu, err := s.menuRepo.GetByID(ctx, existing.MenuID)
+		if err != nil {
+			return nil, err
+		}
+		if menu == nil {
+			return nil, errors.New("weekly menu not found")
+		}
+
+		existing.Items, regularPrice, err = bundleItemsFromMenu(menu, req.Items)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Leave the items as they are
+		existing.Items = nil
+	}
+	if existing.Price > regularPrice {
+		return nil, errors.New("bundle price cannot be more than its meals cost separately")
+	}
+
+	if err := s.repo.Update(ctx, id, existing); err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(ctx, id)
+}
+
+// Delete removes a bundle that has never been ordered. Ordered bundles are
+// kept for the order history and should be deactivated instead.
+func (s *bundleService) Delete(ctx context.Context, id int) error {
+	ordered, err := s.repo.IsOrdered(ctx, id)
+	if err != nil {
+		return err
+	}
+	if ordered {
+		return errors.New("bundle has been ordered, deactivate it instead")
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// bundleItemsFromMenu checks that every requested meal (and variant) is on
+// the menu and returns the bundle's items along with their regular price.
+func bundleItemsFromMenu(menu *models.WeeklyMenu, inputs []models.BundleItemInput) ([]models.BundleItem, int, error) {
+	meals := make(map[int]*models.WeeklyMenuMeal)
+	variants := make(map[int]*models.MealVariant)
+	for i := range menu.Meals {
+		menuMeal := &menu.Meals[i]
+		meals[menuMeal.Meal.ID] = menuMeal
+		for j := range menuMeal.Variants {
+			variants[menuMeal.Variants[j].Variant.ID] = &menuMeal.Variants[j].Variant
+		}
+	}
+
+	items := make([]models.BundleItem, 0, len(inputs))
+	seen := make(map[stockKey]bool)
+	totalItems, regularPrice := 0, 0
+	for _, input := range inputs {
+		menuMeal, ok := meals[input.MealID]
+		if !ok {
+			return nil, 0, fmt.Errorf("meal %d is not on this menu", input.MealID)
+		}
+
+		price := menuMeal.Meal.Price
+		if input.VariantID != nil {
+			variant, ok := variants[*input.VariantID]
+			if !ok || variant.MealID != input.MealID {
+				return nil, 0, fmt.Errorf("variant %d of meal %d is not on this menu", *input.VariantID, input.MealID)
+			}
+			price = variant.Price
+		}
+
+		key := newStockKey(input.MealID, input.VariantID)
+		if seen[key] {
+			return nil, 0, errors.New("a meal can only appear once in a bundle")
+		}
+		seen[key] = true
+
+		items = append(items, models.BundleItem{
+			MealID:    input.MealID,
+			VariantID: input.VariantID,
+			Quantity:  input.Quantity,
+			UnitPrice: price,
+		})
+		totalItems += input.Quantity
+		regularPrice += price * input.Quantity
+	}
+
+	if totalItems > MaxCartItems {
+		return nil, 0, fmt.Errorf("a bundle can hold at most %d meals", MaxCartItems)
+	}
+	return items, regularPrice, nil
+}
+
+// stockKey identifies a line of menu stock: a meal's standard portion, or
+// one of its variants.
+type stockKey struct {
+	mealID    int
+	variantID int
+}
+
+func newStockKey(mealID int, variantID *int) stockKey {
+	if variantID != nil {
+		return stockKey{mealID: mealID, variantID: *variantID}
+	}
+	return stockKey{mealID: mealID}
+}
+
+// stockDemand is how much of one line of menu stock a cart needs.
+type stockDemand struct {
+	MealID    int
+	VariantID *int
+	Name      string
+	Quantity  int
+}
+
+// cartStockDemand totals what the cart needs from the menu's stock, counting
+// loose meals and the meals in bundles together, in the order they first
+// appear in the cart.
+func cartStockDemand(cart *models.Cart) []stockDemand {
+	var demand []stockDemand
+	index := make(map[stockKey]int)
+
+	add := func(meal *models.Meal, variantID *int, variant *models.MealVariant, quantity int) {
+		key := newStockKey(meal.ID, variantID)
+		if i, ok := index[key]; ok {
+			demand[i].Quantity += quantity
+			return
+		}
+
+		name := meal.Name
+		if variant != nil {
+			name += " (" + variant.Name + ")"
+		}
+		index[key] = len(demand)
+		demand = append(demand, stockDemand{MealID: meal.ID, VariantID: variantID, Name: name, Quantity: quantity})
+	}
+
+	for _, item := range cart.Items {
+		add(&item.Meal, item.VariantID, item.Variant, item.Quantity)
+	}
+	for _, cb := range cart.Bundles {
+		for _, item := range cb.Bundle.Items {
+			add(&item.Meal, item.VariantID, item.Variant, item.Quantity*cb.Quantity)
+		}
+	}
+	return demand
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestBundleItemsFromMenu(t *testing.T) {
+	menu := &models.WeeklyMenu{
+		Meals: []models.WeeklyMenuMeal{
+			{Meal: models.Meal{ID: 1, Price: 1000}},
+			{
+				Meal:     models.Meal{ID: 2, Price: 1200},
+				Variants: []models.WeeklyMenuVariant{{Variant: models.MealVariant{ID: 7, MealID: 2, Price: 1500}}},
+			},
+		},
+	}
+	variantID := 7
+
+	items, regularPrice, err := bundleItemsFromMenu(menu, []models.BundleItemInput{
+		{MealID: 1, Quantity: 4},
+		{MealID: 2, VariantID: &variantID, Quantity: 6},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(items) != 2 || items[1].UnitPrice != 1500 {
+		t.Fatalf("Unexpected items: %+v", items)
+	}
+	if regularPrice != 4*1000+6*1500 {
+		t.Errorf("Expected regular price %d, got %d", 4*1000+6*1500, regularPrice)
+	}
+
+	otherVariant := 8
+	tests := map[string][]models.BundleItemInput{
+		"meal not on menu":    {{MealID: 3, Quantity: 1}},
+		"variant not on menu": {{MealID: 1, VariantID: &otherVariant, Quantity: 1}},
+		"variant of another":  {{MealID: 1, VariantID: &variantID, Quantity: 1}},
+		"duplicate meal":      {{MealID: 1, Quantity: 1}, {MealID: 1, Quantity: 2}},
+		"over meal limit":     {{MealID: 1, Quantity: MaxCartItems + 1}},
+	}
+	for name, inputs := range tests {
+		if _, _, err := bundleItemsFromMenu(menu, inputs); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCartStockDemand(t *testing.T) {
+	variantID := 7
+	cart := &models.Cart{
+		Items: []models.CartItem{
+			{Meal: models.Meal{ID: 1, Name: "Chicken Bowl"}, MealID: 1, Quantity: 2},
+		},
+		Bundles: []models.CartBundle{
+			{
+				Quantity: 2,
+				Bundle: models.Bundle{Items: []models.BundleItem{
+					{Meal: models.Meal{ID: 1, Name: "Chicken Bowl"}, MealID: 1, Quantity: 1},
+					{
+						Meal:      models.Meal{ID: 2, Name: "Beef Stir-Fry"},
+						MealID:    2,
+						VariantID: &variantID,
+						Variant:   &models.MealVariant{ID: 7, Name: "Large"},
+						Quantity:  3,
+					},
+				}},
+			},
+		},
+	}
+
+	demand := cartStockDemand(cart)
+
+	if len(demand) != 2 {
+		t.Fatalf("Expected 2 stock lines, got %+v", demand)
+	}
+	if demand[0].MealID != 1 || demand[0].VariantID != nil || demand[0].Quantity != 4 {
+		t.Errorf("Expected loose and bundled meal 1 to add up to 4, got %+v", demand[0])
+	}
+	if demand[1].MealID != 2 || demand[1].Quantity != 6 || demand[1].Name != "Beef Stir-Fry (Large)" {
+		t.Errorf("Unexpected demand for the variant: %+v", demand[1])
+	}
+}
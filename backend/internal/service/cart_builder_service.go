@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+type CartBuilderService interface {
+	BuildCart(ctx context.Context, userID int, req *models.BuildCartRequest) (*models.CartProposal, error)
+}
+
+type cartBuilderService struct {
+	menuRepo    repository.WeeklyMenuRepository
+	goalsRepo   repository.NutritionGoalsRepository
+	userRepo    repository.UserRepository
+	priceRepo   repository.PriceRepository
+	mealsPerDay int
+}
+
+func NewCartBuilderService(menuRepo repository.WeeklyMenuRepository, goalsRepo repository.NutritionGoalsRepository, userRepo repository.UserRepository, priceRepo repository.PriceRepository, mealsPerDay int) CartBuilderService {
+	return &cartBuilderService{
+		menuRepo:    menuRepo,
+		goalsRepo:   goalsRepo,
+		userRepo:    userRepo,
+		priceRepo:   priceRepo,
+		mealsPerDay: mealsPerDay,
+	}
+}
+
+// BuildCart proposes a full cart from the active menu whose nutrition is as
+// close as possible to the user's targets, using only meals that satisfy the
+// dietary restrictions, are in stock and fit the budget. The proposal is not
+// saved to the cart.
+func (s *cartBuilderService) BuildCart(ctx context.Context, userID int, req *models.BuildCartRequest) (*models.CartProposal, error) {
+	goals, err := s.resolveTargets(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	activeMenu, err := s.menuRepo.GetActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if activeMenu == nil {
+		return nil, errors.New("no active weekly menu")
+	}
+
+	// Budget against the prices the user will pay
+	if err := applyPlanPrices(ctx, s.priceRepo, userID, menuMeals(activeMenu), menuVariants(activeMenu)); err != nil {
+		return nil, err
+	}
+
+	// Fall back to the user's saved dietary preferences
+	restrictions := req.DietaryRestrictions
+	if restrictions == nil {
+		restrictions, err = s.userRepo.GetDietaryPreferences(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	restrictions = normalizeDietaryTags(restrictions)
+
+	var candidates []cartCandidate
+	var items []models.ProposedCartItem
+	for _, menuMeal := range activeMenu.Meals {
+		if !hasDietaryTags(menuMeal.Meal.DietaryTags, restrictions) {
+			continue
+		}
+
+		meal := menuMeal.Meal
+		if menuMeal.AvailableStock > 0 {
+			candidates = append(candidates, cartCandidate{
+				nutrition: models.NutritionTotals{Calories: meal.Calories, Protein: meal.Protein, Carbs: meal.Carbs, Fat: meal.Fat},
+				price:     meal.Price,
+				stock:     menuMeal.AvailableStock,
+			})
+			items = append(items, models.ProposedCartItem{Meal: meal, UnitPrice: meal.Price})
+		}
+
+		for _, menuVariant := range menuMeal.Variants {
+			if menuVariant.AvailableStock <= 0 {
+				continue
+			}
+			variant := menuVariant.Variant
+			candidates = append(candidates, cartCandidate{
+				nutrition: models.NutritionTotals{Calories: variant.Calories, Protein: variant.Protein, Carbs: variant.Carbs, Fat: variant.Fat},
+				price:     variant.Price,
+				stock:     menuVariant.AvailableStock,
+			})
+			items = append(items, models.ProposedCartItem{Meal: meal, Variant: &variant, UnitPrice: variant.Price})
+		}
+	}
+
+	// A weekly goal is the target for a full cart; a daily one is repeated
+	// for each day the cart covers
+	days := 1
+	if goals.Period != "weekly" {
+		days = mealDays(MaxCartItems, s.mealsPerDay)
+	}
+	target := models.NutritionTotals{
+		Calories: goals.Calories * days,
+		Protein:  goals.Protein * days,
+		Carbs:    goals.Carbs * days,
+		Fat:      goals.Fat * days,
+	}
+
+	quantities, score, err := optimizeCart(candidates, target, MaxCartItems, req.Budget, req.Seed)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := &models.CartProposal{
+		Items: []models.ProposedCartItem{},
+		Score: roundScore(score),
+		Seed:  req.Seed,
+	}
+	var total models.NutritionTotals
+	for i, q := range quantities {
+		if q == 0 {
+			continue
+		}
+		item := items[i]
+		item.Quantity = q
+		proposal.Items = append(proposal.Items, item)
+		proposal.TotalItems += q
+		proposal.TotalPrice += item.UnitPrice * q
+		addMealNutrition(&total, &item.Meal, item.Variant, q)
+	}
+	proposal.Nutrition = summarizeNutrition(total, proposal.TotalItems, goals, s.mealsPerDay)
+
+	return proposal, nil
+}
+
+// resolveTargets prefers targets given in the request over saved goals.
+func (s *cartBuilderService) resolveTargets(ctx context.Context, userID int, req *models.BuildCartRequest) (*models.NutritionGoals, error) {
+	if req.Targets != nil {
+		return &models.NutritionGoals{
+			UserID:   userID,
+			Period:   "daily",
+			Calories: req.Targets.Calories,
+			Protein:  req.Targets.Protein,
+			Carbs:    req.Targets.Carbs,
+			Fat:      req.Targets.Fat,
+		}, nil
+	}
+
+	goals, err := s.goalsRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if goals == nil {
+		return nil, errors.New("no nutrition targets given and no nutrition goals set")
+	}
+	return goals, nil
+}
+
+// hasDietaryTags reports whether tags include every required tag.
+func hasDietaryTags(tags, required []string) bool {
+	for _, r := range required {
+		found := false
+		for _, t := range tags {
+			if t == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
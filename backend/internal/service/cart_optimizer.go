@@ -0,0 +1,243 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+const (
+	// optimizerRestarts is how many starting carts the search explores.
+	optimizerRestarts = 24
+	// optimizerKicks is how many random perturbations are tried from each
+	// local optimum before moving on to the next restart.
+	optimizerKicks = 8
+)
+
+// cartCandidate is one portion that can be put in a cart: a menu meal's
+// standard portion or one of its variants.
+type cartCandidate struct {
+	nutrition models.NutritionTotals // per portion
+	price     int
+	stock     int
+}
+
+// cartOptimizer picks quantities for candidates so that exactly count portions
+// are chosen, no candidate exceeds its stock, the total stays within budget
+// and the cart's nutrition is as close as possible to target.
+type cartOptimizer struct {
+	candidates []cartCandidate
+	target     [4]float64 // whole-cart calories, protein, carbs, fat
+	count      int
+	budget     int // 0 for no limit
+}
+
+// optimizeCart runs an iterated local search over portion swaps with seeded
+// restarts towards the whole-cart target. The same inputs and seed always
+// give the same cart.
+func optimizeCart(candidates []cartCandidate, target models.NutritionTotals, count, budget int, seed int64) ([]int, float64, error) {
+	o := &cartOptimizer{
+		candidates: candidates,
+		target: [4]float64{
+			float64(target.Calories),
+			float64(target.Protein),
+			float64(target.Carbs),
+			float64(target.Fat),
+		},
+		count:  count,
+		budget: budget,
+	}
+
+	totalStock := 0
+	for _, c := range candidates {
+		totalStock += c.stock
+	}
+	if totalStock < count {
+		return nil, 0, errors.New("not enough meals in stock to fill a cart")
+	}
+
+	cheapest := o.cheapestFill()
+	if !o.withinBudget(cheapest) {
+		return nil, 0, errors.New("no cart fits within the budget")
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	best := o.localSearch(cheapest, rng)
+	bestCost := o.cost(best)
+
+	for restart := 1; restart < optimizerRestarts && bestCost > 0; restart++ {
+		current := o.localSearch(o.randomFill(rng), rng)
+		currentCost := o.cost(current)
+
+		for kick := 0; kick < optimizerKicks; kick++ {
+			next := o.localSearch(o.perturb(current, rng), rng)
+			if nextCost := o.cost(next); nextCost < currentCost {
+				current, currentCost = next, nextCost
+			}
+		}
+
+		if currentCost < bestCost {
+			best, bestCost = current, currentCost
+		}
+	}
+
+	return best, bestCost, nil
+}
+
+// cost is the sum of squared relative deviations from each non-zero target.
+func (o *cartOptimizer) cost(quantities []int) float64 {
+	var totals [4]float64
+	for i, q := range quantities {
+		if q == 0 {
+			continue
+		}
+		n := o.candidates[i].nutrition
+		totals[0] += float64(n.Calories * q)
+		totals[1] += float64(n.Protein * q)
+		totals[2] += float64(n.Carbs * q)
+		totals[3] += float64(n.Fat * q)
+	}
+
+	cost := 0.0
+	for m, target := range o.target {
+		if target <= 0 {
+			continue
+		}
+		d := (totals[m] - target) / target
+		cost += d * d
+	}
+	return cost
+}
+
+func (o *cartOptimizer) price(quantities []int) int {
+	total := 0
+	for i, q := range quantities {
+		total += o.candidates[i].price * q
+	}
+	return total
+}
+
+func (o *cartOptimizer) withinBudget(quantities []int) bool {
+	return o.budget == 0 || o.price(quantities) <= o.budget
+}
+
+// cheapestFill is the lowest-priced cart possible; if it is over budget no
+// cart can fit.
+func (o *cartOptimizer) cheapestFill() []int {
+	order := make([]int, len(o.candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return o.candidates[order[a]].price < o.candidates[order[b]].price
+	})
+
+	quantities := make([]int, len(o.candidates))
+	remaining := o.count
+	for _, i := range order {
+		q := min(o.candidates[i].stock, remaining)
+		quantities[i] = q
+		remaining -= q
+	}
+	return quantities
+}
+
+// randomFill draws portions at random, then swaps the priciest for the
+// cheapest until the cart fits the budget.
+func (o *cartOptimizer) randomFill(rng *rand.Rand) []int {
+	quantities := make([]int, len(o.candidates))
+	for added := 0; added < o.count; {
+		i := rng.Intn(len(o.candidates))
+		if quantities[i] < o.candidates[i].stock {
+			quantities[i]++
+			added++
+		}
+	}
+
+	for !o.withinBudget(quantities) {
+		from, to := -1, -1
+		for i, c := range o.candidates {
+			if quantities[i] > 0 && (from == -1 || c.price > o.candidates[from].price) {
+				from = i
+			}
+			if quantities[i] < c.stock && (to == -1 || c.price < o.candidates[to].price) {
+				to = i
+			}
+		}
+		if o.candidates[to].price >= o.candidates[from].price {
+			// Already as cheap as it gets
+			return o.cheapestFill()
+		}
+		quantities[from]--
+		quantities[to]++
+	}
+	return quantities
+}
+
+// perturb makes a few random in-budget swaps to escape a local optimum.
+func (o *cartOptimizer) perturb(quantities []int, rng *rand.Rand) []int {
+	next := append([]int(nil), quantities...)
+	for swaps := 0; swaps < 3; swaps++ {
+		from := rng.Intn(len(next))
+		to := rng.Intn(len(next))
+		if from == to || next[from] == 0 || next[to] >= o.candidates[to].stock {
+			continue
+		}
+		next[from]--
+		next[to]++
+		if !o.withinBudget(next) {
+			next[from]++
+			next[to]--
+		}
+	}
+	return next
+}
+
+// localSearch repeatedly applies the best single-portion swap until none
+// improves the cost. Candidates are visited in a seeded random order so ties
+// are broken differently across restarts.
+func (o *cartOptimizer) localSearch(quantities []int, rng *rand.Rand) []int {
+	current := append([]int(nil), quantities...)
+	currentCost := o.cost(current)
+	order := rng.Perm(len(current))
+
+	for {
+		bestFrom, bestTo := -1, -1
+		bestCost := currentCost
+
+		for _, from := range order {
+			if current[from] == 0 {
+				continue
+			}
+			for _, to := range order {
+				if to == from || current[to] >= o.candidates[to].stock {
+					continue
+				}
+				current[from]--
+				current[to]++
+				if o.withinBudget(current) {
+					if c := o.cost(current); c < bestCost-1e-12 {
+						bestFrom, bestTo, bestCost = from, to, c
+					}
+				}
+				current[from]++
+				current[to]--
+			}
+		}
+
+		if bestFrom == -1 {
+			return current
+		}
+		current[bestFrom]--
+		current[bestTo]++
+		currentCost = bestCost
+	}
+}
+
+// roundScore keeps scores readable in responses.
+func roundScore(score float64) float64 {
+	return math.Round(score*10000) / 10000
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func testCandidates() []cartCandidate {
+	return []cartCandidate{
+		{nutrition: models.NutritionTotals{Calories: 650, Protein: 50, Carbs: 60, Fat: 22}, price: 1399, stock: 4},
+		{nutrition: models.NutritionTotals{Calories: 420, Protein: 38, Carbs: 35, Fat: 12}, price: 1199, stock: 10},
+		{nutrition: models.NutritionTotals{Calories: 520, Protein: 22, Carbs: 80, Fat: 14}, price: 999, stock: 3},
+		{nutrition: models.NutritionTotals{Calories: 380, Protein: 30, Carbs: 20, Fat: 18}, price: 1099, stock: 6},
+		{nutrition: models.NutritionTotals{Calories: 700, Protein: 45, Carbs: 85, Fat: 20}, price: 1499, stock: 2},
+	}
+}
+
+// bruteForceCart enumerates every feasible cart to find the optimal cost.
+func bruteForceCart(o *cartOptimizer) float64 {
+	best := -1.0
+	quantities := make([]int, len(o.candidates))
+	var walk func(i, remaining int)
+	walk = func(i, remaining int) {
+		if i == len(o.candidates) {
+			if remaining == 0 && o.withinBudget(quantities) {
+				if c := o.cost(quantities); best < 0 || c < best {
+					best = c
+				}
+			}
+			return
+		}
+		for q := 0; q <= min(o.candidates[i].stock, remaining); q++ {
+			quantities[i] = q
+			walk(i+1, remaining-q)
+		}
+		quantities[i] = 0
+	}
+	walk(0, o.count)
+	return best
+}
+
+func TestOptimizeCartFindsOptimum(t *testing.T) {
+	target := models.NutritionTotals{Calories: 5000, Protein: 450, Carbs: 500, Fat: 150}
+
+	for _, budget := range []int{0, 12000} {
+		quantities, cost, err := optimizeCart(testCandidates(), target, 10, budget, 42)
+		if err != nil {
+			t.Fatalf("budget %d: unexpected error: %v", budget, err)
+		}
+
+		o := &cartOptimizer{candidates: testCandidates(), count: 10, budget: budget,
+			target: [4]float64{5000, 450, 500, 150}}
+
+		total := 0
+		for i, q := range quantities {
+			if q < 0 || q > o.candidates[i].stock {
+				t.Fatalf("budget %d: quantity %d out of stock range for candidate %d", budget, q, i)
+			}
+			total += q
+		}
+		if total != 10 {
+			t.Fatalf("budget %d: expected 10 meals, got %d", budget, total)
+		}
+		if !o.withinBudget(quantities) {
+			t.Fatalf("budget %d: cart costs %d", budget, o.price(quantities))
+		}
+
+		if want := bruteForceCart(o); cost > want+1e-9 {
+			t.Errorf("budget %d: expected optimal cost %.6f, got %.6f (%v)", budget, want, cost, quantities)
+		}
+	}
+}
+
+func TestOptimizeCartIsDeterministic(t *testing.T) {
+	target := models.NutritionTotals{Calories: 5500, Protein: 400}
+
+	first, _, err := optimizeCart(testCandidates(), target, 10, 0, 7)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		again, _, _ := optimizeCart(testCandidates(), target, 10, 0, 7)
+		if !reflect.DeepEqual(first, again) {
+			t.Fatalf("Expected the same cart for the same seed, got %v and %v", first, again)
+		}
+	}
+}
+
+func TestOptimizeCartInfeasible(t *testing.T) {
+	target := models.NutritionTotals{Calories: 5000}
+
+	if _, _, err := optimizeCart(testCandidates()[:2], target, 20, 0, 1); err == nil {
+		t.Error("Expected an error when stock cannot fill the cart")
+	}
+	if _, _, err := optimizeCart(testCandidates(), target, 10, 5000, 1); err == nil {
+		t.Error("Expected an error when no cart fits the budget")
+	}
+}
+
+func TestHasDietaryTags(t *testing.T) {
+	tags := []string{"vegetarian", "gluten_free"}
+	if !hasDietaryTags(tags, nil) {
+		t.Error("Expected no restrictions to match")
+	}
+	if !hasDietaryTags(tags, []string{"gluten_free"}) {
+		t.Error("Expected gluten_free to match")
+	}
+	if hasDietaryTags(tags, []string{"vegetarian", "vegan"}) {
+		t.Error("Expected vegan not to match")
+	}
+}
@@ -17,22 +17,46 @@ type CartService interface {
 	UpdateItem(ctx context.Context, userID, itemID int, req *models.UpdateCartItemRequest) (*models.Cart, error)
 	RemoveItem(ctx context.Context, userID, itemID int) error
 	ClearCart(ctx context.Context, userID int) error
+	AddAddon(ctx context.Context, userID int, req *models.AddAddonToCartRequest) (*models.Cart, error)
+	UpdateAddon(ctx context.Context, userID, cartAddonID int, req *models.UpdateCartItemRequest) (*models.Cart, error)
+	RemoveAddon(ctx context.Context, userID, cartAddonID int) error
+	AddBundle(ctx context.Context, userID int, req *models.AddBundleToCartRequest) (*models.Cart, error)
+	UpdateBundle(ctx context.Context, userID, cartBundleID int, req *models.UpdateCartItemRequest) (*models.Cart, error)
+	RemoveBundle(ctx context.Context, userID, cartBundleID int) error
 }
 
 type cartService struct {
-	cartRepo repository.CartRepository
-	mealRepo repository.MealRepository
+	cartRepo    repository.CartRepository
+	mealRepo    repository.MealRepository
+	variantRepo repository.MealVariantRepository
+	addonRepo   repository.AddonRepository
+	goalsRepo   repository.NutritionGoalsRepository
+	bundleRepo  repository.BundleRepository
+	menuRepo    repository.WeeklyMenuRepository
+	priceRepo   repository.PriceRepository
+	mealsPerDay int
 }
 
-func NewCartService(cartRepo repository.CartRepository, mealRepo repository.MealRepository) CartService {
+func NewCartService(cartRepo repository.CartRepository, mealRepo repository.MealRepository, variantRepo repository.MealVariantRepository, addonRepo repository.AddonRepository, goalsRepo repository.NutritionGoalsRepository, bundleRepo repository.BundleRepository, menuRepo repository.WeeklyMenuRepository, priceRepo repository.PriceRepository, mealsPerDay int) CartService {
 	return &cartService{
-		cartRepo: cartRepo,
-		mealRepo: mealRepo,
+		cartRepo:    cartRepo,
+		mealRepo:    mealRepo,
+		variantRepo: variantRepo,
+		addonRepo:   addonRepo,
+		goalsRepo:   goalsRepo,
+		bundleRepo:  bundleRepo,
+		menuRepo:    menuRepo,
+		priceRepo:   priceRepo,
+		mealsPerDay: mealsPerDay,
 	}
 }
 
 func (s *cartService) GetCart(ctx context.Context, userID int) (*models.Cart, error) {
-	return s.cartRepo.GetOrCreateByUserID(ctx, userID)
+	cart, err := s.cartRepo.GetOrCreateByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.fillCart(ctx, userID, cart)
 }
 
 func (s *cartService) AddItem(ctx context.Context, userID int, req *models.AddToCartRequest) (*models.Cart, error) {
@@ -45,6 +69,20 @@ func (s *cartService) AddItem(ctx context.Context, userID int, req *models.AddTo
 		return nil, errors.New("meal not found")
 	}
 
+	// Verify variant belongs to the meal and is on sale this week
+	if req.VariantID != nil {
+		variant, err := s.variantRepo.GetByID(ctx, *req.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		if variant == nil || variant.MealID != req.MealID {
+			return nil, errors.New("meal variant not found")
+		}
+		if err := s.checkVariantOnMenu(ctx, variant.ID); err != nil {
+			return nil, err
+		}
+	}
+
 	// Get or create cart
 	cart, err := s.cartRepo.GetOrCreateByUserID(ctx, userID)
 	if err != nil {
@@ -52,7 +90,7 @@ func (s *cartService) AddItem(ctx context.Context, userID int, req *models.AddTo
 	}
 
 	// Check if item already exists in cart
-	existingItem, err := s.cartRepo.GetItemByCartAndMeal(ctx, cart.ID, req.MealID)
+	existingItem, err := s.cartRepo.GetItemByCartAndMeal(ctx, cart.ID, req.MealID, req.VariantID)
 	if err != nil {
 		return nil, err
 	}
@@ -85,14 +123,14 @@ func (s *cartService) AddItem(ctx context.Context, userID int, req *models.AddTo
 		}
 
 		// Add new item
-		err = s.cartRepo.AddItem(ctx, cart.ID, req.MealID, req.Quantity)
+		err = s.cartRepo.AddItem(ctx, cart.ID, req.MealID, req.VariantID, req.Quantity)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	// Return updated cart
-	return s.cartRepo.GetByUserID(ctx, userID)
+	return s.getCart(ctx, userID)
 }
 
 func (s *cartService) UpdateItem(ctx context.Context, userID, itemID int, req *models.UpdateCartItemRequest) (*models.Cart, error) {
@@ -141,7 +179,7 @@ func (s *cartService) UpdateItem(ctx context.Context, userID, itemID int, req *m
 	}
 
 	// Return updated cart
-	return s.cartRepo.GetByUserID(ctx, userID)
+	return s.getCart(ctx, userID)
 }
 
 func (s *cartService) RemoveItem(ctx context.Context, userID, itemID int) error {
@@ -180,3 +218,248 @@ func (s *cartService) ClearCart(ctx context.Context, userID int) error {
 
 	return s.cartRepo.Clear(ctx, cart.ID)
 }
+
+// AddAddon adds an extra to the cart. Add-ons don't count toward MaxCartItems.
+func (s *cartService) AddAddon(ctx context.Context, userID int, req *models.AddAddonToCartRequest) (*models.Cart, error) {
+	// Verify add-on exists
+	addon, err := s.addonRepo.GetByID(ctx, req.AddonID)
+	if err != nil {
+		return nil, err
+	}
+	if addon == nil {
+		return nil, errors.New("add-on not found")
+	}
+
+	cart, err := s.cartRepo.GetOrCreateByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.cartRepo.GetAddonByCartAndAddon(ctx, cart.ID, req.AddonID)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		err = s.cartRepo.UpdateAddonQuantity(ctx, existing.ID, existing.Quantity+req.Quantity)
+	} else {
+		err = s.cartRepo.AddAddon(ctx, cart.ID, req.AddonID, req.Quantity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getCart(ctx, userID)
+}
+
+func (s *cartService) UpdateAddon(ctx context.Context, userID, cartAddonID int, req *models.UpdateCartItemRequest) (*models.Cart, error) {
+	if err := s.verifyAddonOwnership(ctx, userID, cartAddonID); err != nil {
+		return nil, err
+	}
+
+	if req.Quantity == 0 {
+		// Remove add-on if quantity is 0
+		return nil, s.cartRepo.RemoveAddon(ctx, cartAddonID)
+	}
+
+	if err := s.cartRepo.UpdateAddonQuantity(ctx, cartAddonID, req.Quantity); err != nil {
+		return nil, err
+	}
+
+	return s.getCart(ctx, userID)
+}
+
+func (s *cartService) RemoveAddon(ctx context.Context, userID, cartAddonID int) error {
+	if err := s.verifyAddonOwnership(ctx, userID, cartAddonID); err != nil {
+		return err
+	}
+
+	return s.cartRepo.RemoveAddon(ctx, cartAddonID)
+}
+
+func (s *cartService) verifyAddonOwnership(ctx context.Context, userID, cartAddonID int) error {
+	cart, err := s.cartRepo.GetOrCreateByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if cart == nil {
+		return errors.New("cart not found")
+	}
+
+	for _, ca := range cart.Addons {
+		if ca.ID == cartAddonID {
+			return nil
+		}
+	}
+	return errors.New("cart add-on not found")
+}
+
+// AddBundle adds a bundle to the cart. Its meals count toward MaxCartItems
+// and must be in stock alongside everything else in the cart.
+func (s *cartService) AddBundle(ctx context.Context, userID int, req *models.AddBundleToCartRequest) (*models.Cart, error) {
+	bundle, err := s.bundleRepo.GetByID(ctx, req.BundleID)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil {
+		return nil, errors.New("bundle not found")
+	}
+
+	cart, err := s.cartRepo.GetOrCreateByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.cartRepo.GetBundleByCartAndBundle(ctx, cart.ID, req.BundleID)
+	if err != nil {
+		return nil, err
+	}
+
+	currentQuantity := 0
+	if existing != nil {
+		currentQuantity = existing.Quantity
+	}
+	if err := s.checkBundle(ctx, cart, bundle, currentQuantity, currentQuantity+req.Quantity); err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		err = s.cartRepo.UpdateBundleQuantity(ctx, existing.ID, existing.Quantity+req.Quantity)
+	} else {
+		err = s.cartRepo.AddBundle(ctx, cart.ID, req.BundleID, req.Quantity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getCart(ctx, userID)
+}
+
+func (s *cartService) UpdateBundle(ctx context.Context, userID, cartBundleID int, req *models.UpdateCartItemRequest) (*models.Cart, error) {
+	cart, cb, err := s.findCartBundle(ctx, userID, cartBundleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Quantity == 0 {
+		// Remove bundle if quantity is 0
+		return nil, s.cartRepo.RemoveBundle(ctx, cartBundleID)
+	}
+
+	if req.Quantity > cb.Quantity {
+		if err := s.checkBundle(ctx, cart, &cb.Bundle, cb.Quantity, req.Quantity); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.cartRepo.UpdateBundleQuantity(ctx, cartBundleID, req.Quantity); err != nil {
+		return nil, err
+	}
+
+	return s.getCart(ctx, userID)
+}
+
+func (s *cartService) RemoveBundle(ctx context.Context, userID, cartBundleID int) error {
+	if _, _, err := s.findCartBundle(ctx, userID, cartBundleID); err != nil {
+		return err
+	}
+
+	return s.cartRepo.RemoveBundle(ctx, cartBundleID)
+}
+
+func (s *cartService) findCartBundle(ctx context.Context, userID, cartBundleID int) (*models.Cart, *models.CartBundle, error) {
+	cart, err := s.cartRepo.GetOrCreateByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cart == nil {
+		return nil, nil, errors.New("cart not found")
+	}
+
+	for i := range cart.Bundles {
+		if cart.Bundles[i].ID == cartBundleID {
+			return cart, &cart.Bundles[i], nil
+		}
+	}
+	return nil, nil, errors.New("cart bundle not found")
+}
+
+// checkVariantOnMenu verifies that the active menu stocks the variant.
+func (s *cartService) checkVariantOnMenu(ctx context.Context, variantID int) error {
+	activeMenu, err := s.menuRepo.GetActive(ctx)
+	if err != nil {
+		return err
+	}
+	if activeMenu == nil {
+		return errors.New("no active weekly menu")
+	}
+	if _, err := s.menuRepo.GetVariantStock(ctx, activeMenu.ID, variantID); err != nil {
+		if errors.Is(err, repository.ErrVariantNotOnMenu) {
+			return errors.New("meal variant is not on this week's menu")
+		}
+		return err
+	}
+	return nil
+}
+
+// checkBundle verifies that the cart can go from currentQuantity to
+// newQuantity of the bundle: the bundle is on sale this week, the meal limit
+// holds and the menu has stock for it on top of the rest of the cart.
+func (s *cartService) checkBundle(ctx context.Context, cart *models.Cart, bundle *models.Bundle, currentQuantity, newQuantity int) error {
+	activeMenu, err := s.menuRepo.GetActive(ctx)
+	if err != nil {
+		return err
+	}
+	if !bundle.IsActive || activeMenu == nil || bundle.MenuID != activeMenu.ID {
+		return errors.New("bundle is not available")
+	}
+
+	added := newQuantity - currentQuantity
+	if cart.TotalItems+bundle.TotalItems*added > MaxCartItems {
+		return fmt.Errorf("cannot add %d bundles of %d meals, cart limit is %d meals", added, bundle.TotalItems, MaxCartItems)
+	}
+
+	demand := make(map[stockKey]int)
+	for _, d := range cartStockDemand(cart) {
+		demand[newStockKey(d.MealID, d.VariantID)] = d.Quantity
+	}
+	for _, item := range bundle.Items {
+		needed := demand[newStockKey(item.MealID, item.VariantID)] + item.Quantity*added
+		if needed > item.AvailableStock {
+			name := item.Meal.Name
+			if item.Variant != nil {
+				name += " (" + item.Variant.Name + ")"
+			}
+			return errors.New("insufficient stock for meal: " + name)
+		}
+	}
+	return nil
+}
+
+// getCart loads the user's cart, priced and with its nutrition summary.
+func (s *cartService) getCart(ctx context.Context, userID int) (*models.Cart, error) {
+	cart, err := s.cartRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.fillCart(ctx, userID, cart)
+}
+
+// fillCart prices the cart from the user's plan and attaches its nutrition
+// summary.
+func (s *cartService) fillCart(ctx context.Context, userID int, cart *models.Cart) (*models.Cart, error) {
+	if cart == nil {
+		return nil, nil
+	}
+
+	if err := applyCartPlanPrices(ctx, s.priceRepo, userID, cart); err != nil {
+		return nil, err
+	}
+
+	goals, err := s.goalsRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	cart.Nutrition = cartNutrition(cart, goals, s.mealsPerDay)
+	return cart, nil
+}
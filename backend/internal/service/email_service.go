@@ -2,7 +2,9 @@ package service
 
 import (
 	"fmt"
+	"html"
 	"log"
+	"time"
 
 	"github.com/jopari/preptoplate/internal/config"
 	"github.com/jopari/preptoplate/internal/models"
@@ -11,6 +13,12 @@ import (
 
 type EmailService interface {
 	SendOrderReceipt(to string, order *models.Order) error
+	SendPasswordReset(to, resetURL string) error
+	SendEmailVerification(to, verifyURL string) error
+	SendEmailChangeNotice(to, newEmail string) error
+	SendAccountLocked(to string, lockedUntil time.Time) error
+	SendDataExportReady(to, downloadURL string, expiresAt time.Time) error
+	SendAccountDeletionConfirmation(to, confirmURL string) error
 }
 
 type resendEmailService struct {
@@ -51,6 +59,114 @@ func (s *resendEmailService) SendOrderReceipt(to string, order *models.Order) er
 	return nil
 }
 
+func (s *resendEmailService) SendPasswordReset(to, resetURL string) error {
+	params := &resend.SendEmailRequest{
+		From:    s.fromAddress,
+		To:      []string{to},
+		Subject: "Reset your password - PrepToPlate",
+		Html:    generatePasswordResetHTML(resetURL),
+	}
+
+	_, err := s.client.Emails.Send(params)
+	if err != nil {
+		log.Printf("❌ Failed to send password reset email to %s: %v", to, err)
+		return err
+	}
+
+	log.Printf("✅ Password reset email sent to %s", to)
+	return nil
+}
+
+func (s *resendEmailService) SendEmailVerification(to, verifyURL string) error {
+	params := &resend.SendEmailRequest{
+		From:    s.fromAddress,
+		To:      []string{to},
+		Subject: "Verify your email - PrepToPlate",
+		Html:    generateEmailVerificationHTML(verifyURL),
+	}
+
+	_, err := s.client.Emails.Send(params)
+	if err != nil {
+		log.Printf("❌ Failed to send verification email to %s: %v", to, err)
+		return err
+	}
+
+	log.Printf("✅ Verification email sent to %s", to)
+	return nil
+}
+
+func (s *resendEmailService) SendEmailChangeNotice(to, newEmail string) error {
+	params := &resend.SendEmailRequest{
+		From:    s.fromAddress,
+		To:      []string{to},
+		Subject: "Your email is being changed - PrepToPlate",
+		Html:    generateEmailChangeNoticeHTML(newEmail),
+	}
+
+	_, err := s.client.Emails.Send(params)
+	if err != nil {
+		log.Printf("❌ Failed to send email change notice to %s: %v", to, err)
+		return err
+	}
+
+	log.Printf("✅ Email change notice sent to %s", to)
+	return nil
+}
+
+func (s *resendEmailService) SendAccountLocked(to string, lockedUntil time.Time) error {
+	params := &resend.SendEmailRequest{
+		From:    s.fromAddress,
+		To:      []string{to},
+		Subject: "Your account has been locked - PrepToPlate",
+		Html:    generateAccountLockedHTML(lockedUntil),
+	}
+
+	_, err := s.client.Emails.Send(params)
+	if err != nil {
+		log.Printf("❌ Failed to send account locked email to %s: %v", to, err)
+		return err
+	}
+
+	log.Printf("✅ Account locked email sent to %s", to)
+	return nil
+}
+
+func (s *resendEmailService) SendDataExportReady(to, downloadURL string, expiresAt time.Time) error {
+	params := &resend.SendEmailRequest{
+		From:    s.fromAddress,
+		To:      []string{to},
+		Subject: "Your data is ready to download - PrepToPlate",
+		Html:    generateDataExportReadyHTML(downloadURL, expiresAt),
+	}
+
+	_, err := s.client.Emails.Send(params)
+	if err != nil {
+		log.Printf("❌ Failed to send data export email to %s: %v", to, err)
+		return err
+	}
+
+	log.Printf("✅ Data export email sent to %s", to)
+	return nil
+}
+
+func (s *resendEmailService) SendAccountDeletionConfirmation(to, confirmURL string) error {
+	params := &resend.SendEmailRequest{
+		From:    s.fromAddress,
+		To:      []string{to},
+		Subject: "Confirm deleting your account - PrepToPlate",
+		Html:    generateAccountDeletionHTML(confirmURL),
+	}
+
+	_, err := s.client.Emails.Send(params)
+	if err != nil {
+		log.Printf("❌ Failed to send account deletion email to %s: %v", to, err)
+		return err
+	}
+
+	log.Printf("✅ Account deletion email sent to %s", to)
+	return nil
+}
+
 // noopEmailService is used when email is not configured
 type noopEmailService struct{}
 
@@ -59,22 +175,124 @@ func (s *noopEmailService) SendOrderReceipt(to string, order *models.Order) erro
 	return nil
 }
 
+func (s *noopEmailService) SendPasswordReset(to, resetURL string) error {
+	log.Printf("📧 [Mock] Sending password reset link to %s (Email service not configured)", to)
+	return nil
+}
+
+func (s *noopEmailService) SendEmailVerification(to, verifyURL string) error {
+	log.Printf("📧 [Mock] Sending verification link to %s (Email service not configured)", to)
+	return nil
+}
+
+func (s *noopEmailService) SendEmailChangeNotice(to, newEmail string) error {
+	log.Printf("📧 [Mock] Sending email change notice to %s (Email service not configured)", to)
+	return nil
+}
+
+func (s *noopEmailService) SendAccountLocked(to string, lockedUntil time.Time) error {
+	log.Printf("📧 [Mock] Sending account locked notice to %s (Email service not configured)", to)
+	return nil
+}
+
+func (s *noopEmailService) SendDataExportReady(to, downloadURL string, expiresAt time.Time) error {
+	log.Printf("📧 [Mock] Sending data export link to %s (Email service not configured)", to)
+	return nil
+}
+
+func (s *noopEmailService) SendAccountDeletionConfirmation(to, confirmURL string) error {
+	log.Printf("📧 [Mock] Sending account deletion link to %s (Email service not configured)", to)
+	return nil
+}
+
 func generateOrderReceiptHTML(order *models.Order) string {
 	// Basic HTML receipt
 	// In a real app, this would use a template engine
 	itemsHTML := ""
+	for _, bundle := range order.Bundles {
+		itemsHTML += fmt.Sprintf("<li>%s x%d - $%.2f (save $%.2f)</li>", bundle.Name, bundle.Quantity, float64(bundle.Price)/100, float64(bundle.Discount)/100)
+	}
 	for _, item := range order.Items {
-		itemsHTML += fmt.Sprintf("<li>%s x%d - $%.2f</li>", item.Meal.Name, item.Quantity, float64(item.Meal.Price)/100)
+		if item.BundleID != nil {
+			// Listed as part of its bundle above
+			continue
+		}
+		name := item.Meal.Name
+		if item.Variant != nil {
+			name += " (" + item.Variant.Name + ")"
+		}
+		itemsHTML += fmt.Sprintf("<li>%s x%d - $%.2f</li>", name, item.Quantity, float64(item.Price)/100)
+	}
+	for _, addon := range order.Addons {
+		itemsHTML += fmt.Sprintf("<li>%s x%d - $%.2f</li>", addon.Addon.Name, addon.Quantity, float64(addon.Price)/100)
+	}
+
+	discountHTML := ""
+	if order.Discount > 0 {
+		discountHTML = fmt.Sprintf("<p>Bundle savings: $%.2f</p>", float64(order.Discount)/100)
 	}
 
 	return fmt.Sprintf(`
 		<h1>Thank you for your order!</h1>
 		<p>Order ID: #%d</p>
 		<p>Total: $%.2f</p>
+		%s
 		<h3>Items:</h3>
 		<ul>
 			%s
 		</ul>
 		<p>We will notify you when your meals are on the way!</p>
-	`, order.ID, float64(order.TotalPrice)/100, itemsHTML)
+	`, order.ID, float64(order.TotalPrice)/100, discountHTML, itemsHTML)
+}
+
+func generatePasswordResetHTML(resetURL string) string {
+	return fmt.Sprintf(`
+		<h1>Reset your password</h1>
+		<p>We received a request to reset the password for your PrepToPlate account.</p>
+		<p><a href="%s">Choose a new password</a></p>
+		<p>This link can be used once and expires soon. If you did not ask to reset your password, you can ignore this email.</p>
+	`, html.EscapeString(resetURL))
+}
+
+func generateEmailVerificationHTML(verifyURL string) string {
+	return fmt.Sprintf(`
+		<h1>Welcome to PrepToPlate!</h1>
+		<p>Please confirm your email address so you can start placing orders.</p>
+		<p><a href="%s">Verify my email</a></p>
+		<p>If you did not create an account, you can ignore this email.</p>
+	`, html.EscapeString(verifyURL))
+}
+
+func generateEmailChangeNoticeHTML(newEmail string) string {
+	return fmt.Sprintf(`
+		<h1>Your email is being changed</h1>
+		<p>Someone asked to change the email address of your PrepToPlate account to %s. The change takes effect once it is confirmed from that address.</p>
+		<p>If this was not you, reset your password now to keep your account.</p>
+	`, html.EscapeString(newEmail))
+}
+
+func generateAccountLockedHTML(lockedUntil time.Time) string {
+	return fmt.Sprintf(`
+		<h1>Your account has been locked</h1>
+		<p>There were too many failed attempts to sign in to your PrepToPlate account, so we have locked it until %s.</p>
+		<p>If this was you, you can try again after that time or reset your password. If it was not, we recommend resetting your password now.</p>
+	`, lockedUntil.UTC().Format("15:04 MST on 2 January 2006"))
+}
+
+func generateDataExportReadyHTML(downloadURL string, expiresAt time.Time) string {
+	return fmt.Sprintf(`
+		<h1>Your data is ready</h1>
+		<p>The copy of your PrepToPlate data you asked for is ready. Sign in to download it.</p>
+		<p><a href="%s">Download my data</a></p>
+		<p>The download is available until %s. If you did not ask for a copy of your data, we recommend changing your password.</p>
+	`, html.EscapeString(downloadURL), expiresAt.UTC().Format("2 January 2006"))
+}
+
+func generateAccountDeletionHTML(confirmURL string) string {
+	return fmt.Sprintf(`
+		<h1>Delete your account</h1>
+		<p>We received a request to delete your PrepToPlate account. Your personal details, cart, reviews and saved settings will be removed for good.</p>
+		<p><a href="%s">Delete my account</a></p>
+		<p>This link expires soon. If you did not ask to delete your account, you can ignore this email, and we recommend signing out of your other devices.</p>
+	`, html.EscapeString(confirmURL))
 }
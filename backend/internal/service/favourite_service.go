@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+type FavouriteService interface {
+	Add(ctx context.Context, userID int, req *models.AddFavouriteRequest) error
+	Remove(ctx context.Context, userID, mealID int) error
+	GetAll(ctx context.Context, userID int) ([]models.Favourite, error)
+}
+
+type favouriteService struct {
+	favouriteRepo repository.FavouriteRepository
+	mealRepo      repository.MealRepository
+	priceRepo     repository.PriceRepository
+}
+
+func NewFavouriteService(favouriteRepo repository.FavouriteRepository, mealRepo repository.MealRepository, priceRepo repository.PriceRepository) FavouriteService {
+	return &favouriteService{
+		favouriteRepo: favouriteRepo,
+		mealRepo:      mealRepo,
+		priceRepo:     priceRepo,
+	}
+}
+
+func (s *favouriteService) Add(ctx context.Context, userID int, req *models.AddFavouriteRequest) error {
+	meal, err := s.mealRepo.GetByID(ctx, req.MealID)
+	if err != nil {
+		return err
+	}
+	if meal == nil {
+		return errors.New("meal not found")
+	}
+
+	return s.favouriteRepo.Add(ctx, userID, req.MealID)
+}
+
+func (s *favouriteService) Remove(ctx context.Context, userID, mealID int) error {
+	return s.favouriteRepo.Remove(ctx, userID, mealID)
+}
+
+func (s *favouriteService) GetAll(ctx context.Context, userID int) ([]models.Favourite, error) {
+	favourites, err := s.favouriteRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	meals := make([]*models.Meal, len(favourites))
+	for i := range favourites {
+		meals[i] = &favourites[i].Meal
+	}
+	if err := applyPlanPrices(ctx, s.priceRepo, userID, meals, nil); err != nil {
+		return nil, err
+	}
+	return favourites, nil
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+const (
+	// MaxImageBytes is the largest upload accepted
+	MaxImageBytes = 10 << 20
+	// maxImagePixels guards against decompression bombs
+	maxImagePixels = 40_000_000
+	jpegQuality    = 85
+)
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image type. only jpeg, png and webp are allowed")
+	ErrImageTooLarge    = errors.New("image is too large")
+)
+
+// imageSizes are the widths generated for each upload, largest first. Images
+// are never upscaled.
+var imageSizes = []struct {
+	Name  string
+	Width int
+}{
+	{"hero", 1600},
+	{"card", 600},
+	{"thumbnail", 200},
+}
+
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// encodedImage is one rendition ready to be stored.
+type encodedImage struct {
+	Name        string
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// processImage checks the upload by its content, applies the EXIF
+// orientation and renders every size as JPEG (PNG when the image has
+// transparency) plus WebP. Re-encoding drops EXIF and other metadata, such
+// as the GPS position a phone camera records.
+//
+// Every size gets a WebP rendition, so clients that prefer WebP always find
+// one. The encoder is lossless: photos come out larger than the JPEG, flat
+// artwork usually smaller. Upload.URL always points at the JPEG or PNG.
+func processImage(data []byte) ([]encodedImage, error) {
+	if len(data) > MaxImageBytes {
+		return nil, ErrImageTooLarge
+	}
+	if !allowedImageTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedImage
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	src := orientImage(toNRGBA(decoded), jpegOrientation(data))
+
+	var renditions []encodedImage
+	lastWidth := 0
+	for _, size := range imageSizes {
+		// Scale down from the previous, larger size: much faster than going
+		// back to the original each time and looks the same
+		src = resizeToWidth(src, size.Width)
+		width, height := src.Bounds().Dx(), src.Bounds().Dy()
+		if width == lastWidth {
+			continue // small original, a same-sized rendition adds nothing
+		}
+		lastWidth = width
+
+		fallback := encodedImage{Name: size.Name, Width: width, Height: height}
+		var buf bytes.Buffer
+		if src.Opaque() {
+			fallback.ContentType, fallback.Ext = "image/jpeg", ".jpg"
+			err = jpeg.Encode(&buf, src, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			fallback.ContentType, fallback.Ext = "image/png", ".png"
+			err = png.Encode(&buf, src)
+		}
+		if err != nil {
+			return nil, err
+		}
+		fallback.Data = buf.Bytes()
+		renditions = append(renditions, fallback)
+
+		var webp bytes.Buffer
+		if err := nativewebp.Encode(&webp, src, nil); err != nil {
+			return nil, err
+		}
+		renditions = append(renditions, encodedImage{
+			Name: size.Name, ContentType: "image/webp", Ext: ".webp",
+			Width: width, Height: height, Data: webp.Bytes(),
+		})
+	}
+	return renditions, nil
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Bounds().Min == (image.Point{}) {
+		return nrgba
+	}
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// resizeToWidth scales the image down to width, keeping its aspect ratio.
+func resizeToWidth(src *image.NRGBA, width int) *image.NRGBA {
+	b := src.Bounds()
+	if b.Dx() <= width {
+		return src
+	}
+	height := max(1, b.Dy()*width/b.Dx())
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst
+}
+
+// orientImage turns the image the way the EXIF orientation tag (1-8) says
+// it should be displayed, since the tag is lost on re-encoding.
+func orientImage(src *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // upside down
+				dx, dy = w-1-x, h-1-y
+			case 4: // upside down, mirrored
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotate 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate 90° anticlockwise
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag of a JPEG, or 1 (as
+// stored) when there isn't one.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // image data starts, no EXIF
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation finds tag 0x0112 in IFD0 of a TIFF-structured EXIF block.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
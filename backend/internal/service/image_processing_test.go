@@ -0,0 +1,206 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"testing"
+)
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF block with the orientation tag after the
+// JPEG's SOI marker.
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")  // big endian, IFD0 at 8
+	tiff = binary.BigEndian.AppendUint16(tiff, 1) // one entry
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0) // padding, no next IFD
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+func TestProcessImageSizes(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2000, 1000))
+	renditions, err := processImage(encodeJPEG(t, img))
+	if err != nil {
+		t.Fatalf("Failed to process image: %v", err)
+	}
+
+	widths := map[string]int{}
+	for _, r := range renditions {
+		if r.ContentType == "image/jpeg" {
+			widths[r.Name] = r.Width
+			if r.Height != r.Width/2 {
+				t.Errorf("Expected %s to keep the aspect ratio, got %dx%d", r.Name, r.Width, r.Height)
+			}
+		}
+	}
+	want := map[string]int{"hero": 1600, "card": 600, "thumbnail": 200}
+	for name, width := range want {
+		if widths[name] != width {
+			t.Errorf("Expected %s to be %dpx wide, got %d", name, width, widths[name])
+		}
+	}
+
+	if len(renditions) != 6 {
+		t.Errorf("Expected JPEG and WebP for each size, got %d renditions", len(renditions))
+	}
+}
+
+func TestProcessImagePhoto(t *testing.T) {
+	data, err := os.ReadFile("../../../frontend/public/hero-meal.png")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	renditions, err := processImage(data)
+	if err != nil {
+		t.Fatalf("Failed to process image: %v", err)
+	}
+
+	types := map[string][]string{}
+	for _, r := range renditions {
+		types[r.Name] = append(types[r.Name], r.ContentType)
+		if r.ContentType == "image/webp" && !bytes.HasPrefix(r.Data[8:], []byte("WEBP")) {
+			t.Errorf("Expected %s to be a WebP file", r.Name)
+		}
+	}
+	for _, size := range imageSizes {
+		got := types[size.Name]
+		if len(got) != 2 || got[1] != "image/webp" {
+			t.Errorf("Expected %s as JPEG or PNG and WebP, got %v", size.Name, got)
+		}
+	}
+}
+
+func TestProcessImageSmallOriginal(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	renditions, err := processImage(encodeJPEG(t, img))
+	if err != nil {
+		t.Fatalf("Failed to process image: %v", err)
+	}
+
+	// Never upscaled, and hero and card would be identical
+	for _, r := range renditions {
+		if r.Name == "card" || r.Width > 400 {
+			t.Errorf("Unexpected rendition %s at %dpx", r.Name, r.Width)
+		}
+	}
+	if renditions[0].Name != "hero" || renditions[0].Width != 400 {
+		t.Errorf("Expected the original size as hero, got %s at %dpx", renditions[0].Name, renditions[0].Width)
+	}
+}
+
+func TestProcessImageTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	img.Set(10, 10, color.NRGBA{R: 255, A: 128})
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+
+	renditions, err := processImage(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to process image: %v", err)
+	}
+	for _, r := range renditions {
+		if r.ContentType == "image/jpeg" {
+			t.Errorf("Expected transparent image to stay PNG")
+		}
+	}
+}
+
+func TestProcessImageRejectsBadInput(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not an image", []byte("<html><script>alert(1)</script></html>"), ErrUnsupportedImage},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), ErrUnsupportedImage},
+		{"truncated jpeg", encodeJPEG(t, image.NewRGBA(image.Rect(0, 0, 10, 10)))[:20], ErrUnsupportedImage},
+		{"too many bytes", make([]byte, MaxImageBytes+1), ErrImageTooLarge},
+	}
+
+	for _, tc := range cases {
+		if _, err := processImage(tc.data); !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestProcessImageAppliesOrientation(t *testing.T) {
+	// Landscape as stored, but the camera says to rotate it 90° clockwise
+	img := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	data := withOrientation(encodeJPEG(t, img), 6)
+
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("Expected orientation 6, got %d", got)
+	}
+
+	renditions, err := processImage(data)
+	if err != nil {
+		t.Fatalf("Failed to process image: %v", err)
+	}
+	hero := renditions[0]
+	if hero.Width != 100 || hero.Height != 300 {
+		t.Errorf("Expected a 100x300 portrait, got %dx%d", hero.Width, hero.Height)
+	}
+
+	// Re-encoding leaves the EXIF block behind
+	if jpegOrientation(hero.Data) != 1 || bytes.Contains(hero.Data, []byte("Exif")) {
+		t.Errorf("Expected EXIF to be stripped")
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	// 2x1 image: red, blue
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	cases := []struct {
+		orientation int
+		w, h        int
+		first       color.NRGBA // pixel at (0, 0)
+	}{
+		{1, 2, 1, red},
+		{2, 2, 1, blue},
+		{3, 2, 1, blue},
+		{6, 1, 2, red},
+		{8, 1, 2, blue},
+	}
+
+	for _, tc := range cases {
+		dst := orientImage(src, tc.orientation)
+		if dst.Bounds().Dx() != tc.w || dst.Bounds().Dy() != tc.h {
+			t.Errorf("Orientation %d: expected %dx%d, got %v", tc.orientation, tc.w, tc.h, dst.Bounds())
+		}
+		if got := dst.NRGBAAt(0, 0); got != tc.first {
+			t.Errorf("Orientation %d: unexpected first pixel %v", tc.orientation, got)
+		}
+	}
+}
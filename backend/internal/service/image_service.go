@@ -1,57 +1,136 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
-	"github.com/cloudinary/cloudinary-go/v2"
-	"github.com/cloudinary/cloudinary-go/v2/api"
-	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"github.com/jopari/preptoplate/internal/config"
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+// Image storage backends, selected with IMAGE_STORAGE
+const (
+	StorageLocal      = "local"
+	StorageCloudinary = "cloudinary"
+	StorageS3         = "s3"
 )
 
 type ImageService interface {
-	UploadImage(ctx context.Context, file interface{}, filename string) (string, error)
+	UploadImage(ctx context.Context, file io.Reader, filename string) (*models.Upload, error)
+	DeleteImage(ctx context.Context, storageKey string) error
+	// OpenImage opens an image kept by the API itself (local storage). It
+	// returns ErrImageNotFound for any other backend.
+	OpenImage(storageKey string) (*os.File, error)
 }
 
 type imageService struct {
-	cld *cloudinary.Cloudinary
+	storage ImageStorage
 }
 
-func NewImageService() (ImageService, error) {
-	cloudName := os.Getenv("CLOUDINARY_CLOUD_NAME")
-	apiKey := os.Getenv("CLOUDINARY_API_KEY")
-	apiSecret := os.Getenv("CLOUDINARY_API_SECRET")
+func NewImageService(cfg *config.Config) (ImageService, error) {
+	storage, err := newImageStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &imageService{storage: storage}, nil
+}
 
-	if cloudName == "" || apiKey == "" || apiSecret == "" {
-		// Return service with nil client, will error on upload attempt
-		return &imageService{cld: nil}, nil
+// newImageStorage picks the configured backend. Without IMAGE_STORAGE,
+// Cloudinary is used when its credentials are set and local disk otherwise,
+// so uploads work out of the box in development and CI.
+func newImageStorage(cfg *config.Config) (ImageStorage, error) {
+	backend := strings.ToLower(cfg.ImageStorage)
+	if backend == "" {
+		backend = StorageLocal
+		if cfg.CloudinaryCloudName != "" && cfg.CloudinaryAPIKey != "" && cfg.CloudinaryAPISecret != "" {
+			backend = StorageCloudinary
+		}
 	}
 
-	cld, err := cloudinary.NewFromParams(cloudName, apiKey, apiSecret)
+	switch backend {
+	case StorageLocal:
+		return newLocalStorage(cfg.LocalStorageDir, cfg.PublicURL+"/api/images")
+	case StorageCloudinary:
+		if cfg.CloudinaryCloudName == "" || cfg.CloudinaryAPIKey == "" || cfg.CloudinaryAPISecret == "" {
+			return nil, errors.New("cloudinary storage requires CLOUDINARY_CLOUD_NAME, CLOUDINARY_API_KEY and CLOUDINARY_API_SECRET")
+		}
+		return newCloudinaryStorage(cfg.CloudinaryCloudName, cfg.CloudinaryAPIKey, cfg.CloudinaryAPISecret)
+	case StorageS3:
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+			return nil, errors.New("s3 storage requires S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY")
+		}
+		return newS3Storage(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3PublicURL), nil
+	default:
+		return nil, fmt.Errorf("unknown image storage %q", cfg.ImageStorage)
+	}
+}
+
+var unsafeKeyChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// UploadImage checks and processes the image, then stores every rendition.
+// The upload's URL and storage key are those of its largest JPEG or PNG.
+func (s *imageService) UploadImage(ctx context.Context, file io.Reader, filename string) (*models.Upload, error) {
+	data, err := io.ReadAll(io.LimitReader(file, MaxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	renditions, err := processImage(data)
 	if err != nil {
 		return nil, err
 	}
 
-	return &imageService{
-		cld: cld,
-	}, nil
-}
-
-func (s *imageService) UploadImage(ctx context.Context, file interface{}, filename string) (string, error) {
-	if s.cld == nil {
-		return "", errors.New("cloudinary credentials not configured")
+	// Unique per upload, so re-using a filename never replaces an image
+	// that is still referenced elsewhere
+	base := unsafeKeyChars.ReplaceAllString(strings.ToLower(strings.TrimSuffix(filename, filepath.Ext(filename))), "-")
+	base = strings.Trim(base, "-")
+	if base == "" {
+		base = "image"
 	}
+	prefix := fmt.Sprintf("meals/%s-%d", base, time.Now().UnixNano())
 
-	resp, err := s.cld.Upload.Upload(ctx, file, uploader.UploadParams{
-		PublicID:       filename,
-		Folder:         "preptoplate/meals",
-		Overwrite:      api.Bool(true),
-		Transformation: "c_limit,w_1000", // Resize to max width 1000px
-	})
-	if err != nil {
-		return "", err
+	upload := &models.Upload{Filename: filename}
+	for _, r := range renditions {
+		key := prefix + "-" + r.Name + r.Ext
+		url, err := s.storage.Put(ctx, key, bytes.NewReader(r.Data), r.ContentType)
+		if err != nil {
+			// Don't leave a partial set of renditions behind
+			for _, v := range upload.Variants {
+				s.storage.Delete(ctx, v.StorageKey)
+			}
+			return nil, err
+		}
+
+		upload.Variants = append(upload.Variants, models.ImageVariant{
+			Name:        r.Name,
+			ContentType: r.ContentType,
+			Width:       r.Width,
+			Height:      r.Height,
+			URL:         url,
+			StorageKey:  key,
+		})
+		if upload.URL == "" && r.ContentType != "image/webp" {
+			upload.URL, upload.StorageKey = url, key
+		}
 	}
+	return upload, nil
+}
 
-	return resp.SecureURL, nil
+func (s *imageService) DeleteImage(ctx context.Context, storageKey string) error {
+	return s.storage.Delete(ctx, storageKey)
+}
+
+func (s *imageService) OpenImage(storageKey string) (*os.File, error) {
+	servable, ok := s.storage.(servableStorage)
+	if !ok {
+		return nil, ErrImageNotFound
+	}
+	return servable.Open(storageKey)
 }
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// ImageStorage stores image bytes under a key and returns the public URL.
+type ImageStorage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// servableStorage is implemented by storage whose images are served by the
+// API itself rather than a CDN.
+type servableStorage interface {
+	Open(key string) (*os.File, error)
+}
+
+// ErrImageNotFound is returned when a stored image doesn't exist or isn't
+// served by the API.
+var ErrImageNotFound = errors.New("image not found")
+
+// localStorage keeps images on disk and serves them through GET /api/images.
+type localStorage struct {
+	root    *os.Root
+	baseURL string
+}
+
+func newLocalStorage(dir, baseURL string) (*localStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	// os.Root keeps every key inside dir, whatever the key contains
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &localStorage{root: root, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (s *localStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	if dir := path.Dir(key); dir != "." {
+		if err := s.root.MkdirAll(dir, 0o755); err != nil {
+			return "", err
+		}
+	}
+
+	f, err := s.root.Create(key)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		s.root.Remove(key)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	return s.baseURL + "/" + key, nil
+}
+
+func (s *localStorage) Delete(ctx context.Context, key string) error {
+	err := s.root.Remove(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *localStorage) Open(key string) (*os.File, error) {
+	f, err := s.root.Open(key)
+	if err != nil {
+		return nil, ErrImageNotFound
+	}
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return nil, ErrImageNotFound
+	}
+	return f, nil
+}
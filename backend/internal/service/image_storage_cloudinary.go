@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+)
+
+const cloudinaryFolder = "preptoplate"
+
+type cloudinaryStorage struct {
+	cld *cloudinary.Cloudinary
+}
+
+func newCloudinaryStorage(cloudName, apiKey, apiSecret string) (*cloudinaryStorage, error) {
+	cld, err := cloudinary.NewFromParams(cloudName, apiKey, apiSecret)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudinaryStorage{cld: cld}, nil
+}
+
+func (s *cloudinaryStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	resp, err := s.cld.Upload.Upload(ctx, r, uploader.UploadParams{
+		PublicID:  cloudinaryPublicID(key),
+		Overwrite: api.Bool(false),
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.SecureURL, nil
+}
+
+func (s *cloudinaryStorage) Delete(ctx context.Context, key string) error {
+	_, err := s.cld.Upload.Destroy(ctx, uploader.DestroyParams{PublicID: cloudinaryPublicID(key)})
+	return err
+}
+
+// cloudinaryPublicID maps a storage key such as "meals/bowl-1-card.jpg" to
+// "preptoplate/meals/bowl-1-card_jpg". Cloudinary adds the extension to the
+// URL itself, but renditions that only differ by format need distinct IDs.
+// Keys that are already public IDs pass through.
+func cloudinaryPublicID(key string) string {
+	if strings.HasPrefix(key, cloudinaryFolder+"/") {
+		return key
+	}
+	ext := path.Ext(key)
+	id := cloudinaryFolder + "/" + strings.TrimSuffix(key, ext)
+	if ext != "" {
+		id += "_" + ext[1:]
+	}
+	return id
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+// orphanGracePeriod gives an admin time to attach a fresh upload to a meal
+// before it counts as orphaned.
+const orphanGracePeriod = 24 * time.Hour
+
+type MealImageService interface {
+	Upload(ctx context.Context, userID int, file io.Reader, filename string, mealID *int, altText string, isPrimary bool) (*models.UploadResult, error)
+	GetImages(ctx context.Context, mealID int) ([]models.MealImage, error)
+	AddImage(ctx context.Context, userID, mealID int, req *models.AddMealImageRequest) (*models.MealImage, error)
+	UpdateImage(ctx context.Context, userID, mealID, imageID int, req *models.UpdateMealImageRequest) (*models.MealImage, error)
+	ReorderImages(ctx context.Context, mealID int, req *models.ReorderMealImagesRequest) ([]models.MealImage, error)
+	DeleteImage(ctx context.Context, userID, mealID, imageID int) error
+	CleanupOrphans(ctx context.Context) (int, error)
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type mealImageService struct {
+	imageRepo    repository.MealImageRepository
+	uploadRepo   repository.UploadRepository
+	mealRepo     repository.MealRepository
+	imageService ImageService
+}
+
+func NewMealImageService(imageRepo repository.MealImageRepository, uploadRepo repository.UploadRepository, mealRepo repository.MealRepository, imageService ImageService) MealImageService {
+	return &mealImageService{
+		imageRepo:    imageRepo,
+		uploadRepo:   uploadRepo,
+		mealRepo:     mealRepo,
+		imageService: imageService,
+	}
+}
+
+// Upload stores the file and records it so it can be cleaned up if it is
+// never used. With a meal ID it is added straight to that meal's gallery.
+func (s *mealImageService) Upload(ctx context.Context, userID int, file io.Reader, filename string, mealID *int, altText string, isPrimary bool) (*models.UploadResult, error) {
+	if mealID != nil {
+		if _, err := s.getMeal(ctx, *mealID); err != nil {
+			return nil, err
+		}
+	}
+
+	upload, err := s.imageService.UploadImage(ctx, file, filename)
+	if err != nil {
+		return nil, err
+	}
+	upload.UploadedBy = &userID
+	if err := s.uploadRepo.Create(ctx, upload); err != nil {
+		return nil, err
+	}
+
+	result := &models.UploadResult{URL: upload.URL, UploadID: upload.ID, Variants: upload.Variants}
+	if mealID != nil {
+		result.Image, err = s.AddImage(ctx, userID, *mealID, &models.AddMealImageRequest{
+			UploadID:  upload.ID,
+			AltText:   altText,
+			IsPrimary: isPrimary,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *mealImageService) GetImages(ctx context.Context, mealID int) ([]models.MealImage, error) {
+	if _, err := s.getMeal(ctx, mealID); err != nil {
+		return nil, err
+	}
+	images, err := s.imageRepo.GetByMealID(ctx, mealID)
+	if err != nil {
+		return nil, err
+	}
+	if images == nil {
+		images = []models.MealImage{}
+	}
+	return images, nil
+}
+
+// AddImage appends an upload to the meal's gallery. The first image of a
+// gallery is always primary.
+func (s *mealImageService) AddImage(ctx context.Context, userID, mealID int, req *models.AddMealImageRequest) (*models.MealImage, error) {
+	if _, err := s.getMeal(ctx, mealID); err != nil {
+		return nil, err
+	}
+
+	upload, err := s.uploadRepo.GetByID(ctx, req.UploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload == nil {
+		return nil, errors.New("upload not found")
+	}
+
+	image := &models.MealImage{MealID: mealID, UploadID: upload.ID, URL: upload.URL, AltText: req.AltText, Variants: upload.Variants}
+	if err := s.imageRepo.Create(ctx, image, req.IsPrimary, userID); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+func (s *mealImageService) UpdateImage(ctx context.Context, userID, mealID, imageID int, req *models.UpdateMealImageRequest) (*models.MealImage, error) {
+	image, err := s.getImage(ctx, mealID, imageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.AltText != nil {
+		if err := s.imageRepo.UpdateAltText(ctx, imageID, *req.AltText); err != nil {
+			return nil, err
+		}
+		image.AltText = *req.AltText
+	}
+
+	if req.IsPrimary != nil && *req.IsPrimary != image.IsPrimary {
+		if !*req.IsPrimary {
+			return nil, errors.New("choose another primary image instead")
+		}
+		if err := s.imageRepo.SetPrimary(ctx, mealID, imageID, userID); err != nil {
+			return nil, err
+		}
+		image.IsPrimary = true
+	}
+	return image, nil
+}
+
+// ReorderImages takes every image ID of the gallery in the new order.
+func (s *mealImageService) ReorderImages(ctx context.Context, mealID int, req *models.ReorderMealImagesRequest) ([]models.MealImage, error) {
+	images, err := s.GetImages(ctx, mealID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.ImageIDs) != len(images) {
+		return nil, errors.New("image_ids must list every image in the gallery once")
+	}
+	inGallery := make(map[int]bool, len(images))
+	for _, img := range images {
+		inGallery[img.ID] = true
+	}
+	for _, id := range req.ImageIDs {
+		if !inGallery[id] {
+			return nil, errors.New("image_ids must list every image in the gallery once")
+		}
+		delete(inGallery, id)
+	}
+
+	if err := s.imageRepo.Reorder(ctx, mealID, req.ImageIDs); err != nil {
+		return nil, err
+	}
+	return s.imageRepo.GetByMealID(ctx, mealID)
+}
+
+// DeleteImage removes the image from the gallery. The upload itself is left
+// for orphan cleanup, since past meal versions may still show it. If the
+// primary image is removed the next image in the gallery takes its place.
+func (s *mealImageService) DeleteImage(ctx context.Context, userID, mealID, imageID int) error {
+	return s.imageRepo.Delete(ctx, mealID, imageID, userID)
+}
+
+// CleanupOrphans deletes uploads that nothing refers to once the grace
+// period has passed, from storage first so no stored file is ever lost track of.
+func (s *mealImageService) CleanupOrphans(ctx context.Context) (int, error) {
+	orphans, err := s.uploadRepo.GetOrphans(ctx, time.Now().Add(-orphanGracePeriod))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, upload := range orphans {
+		for _, key := range uploadStorageKeys(&upload) {
+			if err := s.imageService.DeleteImage(ctx, key); err != nil {
+				return removed, err
+			}
+		}
+		if err := s.uploadRepo.Delete(ctx, upload.ID); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Run cleans up orphaned uploads on every interval until ctx is cancelled.
+func (s *mealImageService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		removed, err := s.CleanupOrphans(ctx)
+		if err != nil {
+			log.Printf("Failed to clean up orphaned uploads: %v", err)
+		}
+		if removed > 0 {
+			log.Printf("Removed %d orphaned uploads", removed)
+		}
+	}
+}
+
+func (s *mealImageService) getMeal(ctx context.Context, mealID int) (*models.Meal, error) {
+	meal, err := s.mealRepo.GetByID(ctx, mealID)
+	if err != nil {
+		return nil, err
+	}
+	if meal == nil {
+		return nil, errors.New("meal not found")
+	}
+	return meal, nil
+}
+
+// getImage loads an image and checks it belongs to the meal.
+func (s *mealImageService) getImage(ctx context.Context, mealID, imageID int) (*models.MealImage, error) {
+	image, err := s.imageRepo.GetByID(ctx, imageID)
+	if err != nil {
+		return nil, err
+	}
+	if image == nil || image.MealID != mealID {
+		return nil, errors.New("meal image not found")
+	}
+	return image, nil
+}
+
+// uploadStorageKeys lists everything stored for an upload. Uploads made
+// before renditions were generated only have their own key.
+func uploadStorageKeys(upload *models.Upload) []string {
+	keys := []string{upload.StorageKey}
+	for _, v := range upload.Variants {
+		if v.StorageKey != upload.StorageKey {
+			keys = append(keys, v.StorageKey)
+		}
+	}
+	return keys
+}
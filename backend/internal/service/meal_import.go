@@ -0,0 +1,244 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+// MealCSVHeader is the column order used for export. Imports accept the
+// columns in any order; sku, name and price are required.
+var MealCSVHeader = []string{"sku", "name", "description", "image_url", "calories", "protein", "carbs", "fat", "price", "dietary_tags"}
+
+const (
+	dietaryTagSeparator = "|"
+	// MaxImportBytes is the largest import file accepted
+	MaxImportBytes = 5 << 20
+)
+
+var ErrImportTooLarge = errors.New("import file is too large")
+
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ParseMealCSV reads meal records from CSV, reporting every cell that can't
+// be parsed. The returned row numbers line up with the records.
+func ParseMealCSV(r io.Reader) ([]models.MealRecord, []int, []models.MealImportError) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, []models.MealImportError{{Row: 1, Message: "missing header row: " + err.Error()}}
+	}
+
+	columns := make(map[string]int)
+	var importErrors []models.MealImportError
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(MealCSVHeader, name) {
+			importErrors = append(importErrors, models.MealImportError{Row: 1, Field: name, Message: "unknown column"})
+			continue
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"sku", "name", "price"} {
+		if _, ok := columns[required]; !ok {
+			importErrors = append(importErrors, models.MealImportError{Row: 1, Field: required, Message: "missing required column"})
+		}
+	}
+	if len(importErrors) > 0 {
+		return nil, nil, importErrors
+	}
+
+	var records []models.MealRecord
+	var rows []int
+	for row := 2; ; row++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			importErrors = append(importErrors, models.MealImportError{Row: row, Message: err.Error()})
+			// A malformed line can't be recovered from reliably
+			if !errors.Is(err, csv.ErrFieldCount) {
+				break
+			}
+			continue
+		}
+
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		record := models.MealRecord{
+			SKU:         cell("sku"),
+			Name:        cell("name"),
+			Description: cell("description"),
+			ImageURL:    cell("image_url"),
+		}
+		if tags := cell("dietary_tags"); tags != "" {
+			record.DietaryTags = strings.Split(tags, dietaryTagSeparator)
+		}
+
+		numbers := []struct {
+			field string
+			dest  *int
+		}{
+			{"calories", &record.Calories},
+			{"protein", &record.Protein},
+			{"carbs", &record.Carbs},
+			{"fat", &record.Fat},
+			{"price", &record.Price},
+		}
+		for _, n := range numbers {
+			value := cell(n.field)
+			if value == "" {
+				continue
+			}
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				importErrors = append(importErrors, models.MealImportError{
+					Row: row, SKU: record.SKU, Field: n.field, Message: fmt.Sprintf("%q is not a whole number", value),
+				})
+				continue
+			}
+			*n.dest = parsed
+		}
+
+		records = append(records, record)
+		rows = append(rows, row)
+	}
+
+	return records, rows, importErrors
+}
+
+// ParseMealJSON reads a JSON array of meal records.
+func ParseMealJSON(r io.Reader) ([]models.MealRecord, []int, []models.MealImportError) {
+	var records []models.MealRecord
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, nil, []models.MealImportError{{Row: 0, Message: "invalid JSON: " + err.Error()}}
+	}
+
+	rows := make([]int, len(records))
+	for i := range records {
+		rows[i] = i + 1
+	}
+	return records, rows, nil
+}
+
+// WriteMealCSV writes records in the same format ParseMealCSV reads.
+func WriteMealCSV(w io.Writer, records []models.MealRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(MealCSVHeader); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		err := writer.Write([]string{
+			r.SKU,
+			r.Name,
+			r.Description,
+			r.ImageURL,
+			strconv.Itoa(r.Calories),
+			strconv.Itoa(r.Protein),
+			strconv.Itoa(r.Carbs),
+			strconv.Itoa(r.Fat),
+			strconv.Itoa(r.Price),
+			strings.Join(r.DietaryTags, dietaryTagSeparator),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// validateMealRecords checks each record on its own and for SKUs repeated
+// within the file.
+func validateMealRecords(records []models.MealRecord, rows []int) []models.MealImportError {
+	var importErrors []models.MealImportError
+	fail := func(i int, field, message string) {
+		importErrors = append(importErrors, models.MealImportError{Row: rows[i], SKU: records[i].SKU, Field: field, Message: message})
+	}
+
+	seen := make(map[string]int)
+	for i := range records {
+		records[i].SKU = strings.TrimSpace(records[i].SKU)
+		records[i].Name = strings.TrimSpace(records[i].Name)
+		r := records[i]
+
+		switch {
+		case r.SKU == "":
+			fail(i, "sku", "is required")
+		case !skuPattern.MatchString(r.SKU):
+			fail(i, "sku", "must be 1-64 letters, digits, '.', '_' or '-'")
+		default:
+			if first, ok := seen[r.SKU]; ok {
+				fail(i, "sku", fmt.Sprintf("duplicates row %d", first))
+			} else {
+				seen[r.SKU] = rows[i]
+			}
+		}
+
+		if r.Name == "" {
+			fail(i, "name", "is required")
+		} else if len(r.Name) > 100 {
+			fail(i, "name", "must be at most 100 characters")
+		}
+		if r.Price <= 0 {
+			fail(i, "price", "must be a positive number of cents")
+		}
+		for _, macro := range []struct {
+			field string
+			value int
+		}{{"calories", r.Calories}, {"protein", r.Protein}, {"carbs", r.Carbs}, {"fat", r.Fat}} {
+			if macro.value < 0 {
+				fail(i, macro.field, "must not be negative")
+			}
+		}
+	}
+	return importErrors
+}
+
+func mealToRecord(meal *models.Meal) models.MealRecord {
+	return models.MealRecord{
+		SKU:         meal.SKU,
+		Name:        meal.Name,
+		Description: meal.Description,
+		ImageURL:    meal.ImageURL,
+		Calories:    meal.Calories,
+		Protein:     meal.Protein,
+		Carbs:       meal.Carbs,
+		Fat:         meal.Fat,
+		Price:       meal.Price,
+		DietaryTags: meal.DietaryTags,
+	}
+}
+
+// recordMatchesMeal reports whether importing the record would change nothing.
+func recordMatchesMeal(r models.MealRecord, meal *models.Meal) bool {
+	current := mealToRecord(meal)
+	r.DietaryTags = normalizeDietaryTags(r.DietaryTags)
+	return r.Name == current.Name &&
+		r.Description == current.Description &&
+		r.ImageURL == current.ImageURL &&
+		r.Calories == current.Calories &&
+		r.Protein == current.Protein &&
+		r.Carbs == current.Carbs &&
+		r.Fat == current.Fat &&
+		r.Price == current.Price &&
+		slices.Equal(r.DietaryTags, current.DietaryTags)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestParseMealCSVRoundTrip(t *testing.T) {
+	// The seed data must stay importable
+	f, err := os.Open("../../cmd/seed_meals/meals.csv")
+	if err != nil {
+		t.Fatalf("Failed to open seed data: %v", err)
+	}
+	defer f.Close()
+
+	records, rows, errs := ParseMealCSV(f)
+	errs = append(errs, validateMealRecords(records, rows)...)
+	if len(errs) != 0 {
+		t.Fatalf("Expected seed data to be valid, got %+v", errs)
+	}
+	if len(records) != 15 {
+		t.Fatalf("Expected 15 seed meals, got %d", len(records))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMealCSV(&buf, records); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	again, _, errs := ParseMealCSV(&buf)
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors re-reading export: %+v", errs)
+	}
+	if !reflect.DeepEqual(records, again) {
+		t.Errorf("Export did not round-trip")
+	}
+}
+
+func TestMealImportRowErrors(t *testing.T) {
+	input := strings.Join([]string{
+		"SKU,Name,Price,Calories,Dietary_Tags",
+		"BOWL-1,Chicken Bowl,1299,450,gluten_free|dairy_free",
+		"BOWL-1,Duplicate Bowl,1299,450,",
+		",No SKU,abc,-5,",
+		"BAD SKU,Bad,999,100,",
+	}, "\n")
+
+	records, rows, errs := ParseMealCSV(strings.NewReader(input))
+	errs = append(errs, validateMealRecords(records, rows)...)
+
+	want := []models.MealImportError{
+		{Row: 4, Field: "price", Message: `"abc" is not a whole number`},
+		{Row: 3, SKU: "BOWL-1", Field: "sku", Message: "duplicates row 2"},
+		{Row: 4, Field: "sku", Message: "is required"},
+		{Row: 4, Field: "price", Message: "must be a positive number of cents"},
+		{Row: 4, Field: "calories", Message: "must not be negative"},
+		{Row: 5, SKU: "BAD SKU", Field: "sku", Message: "must be 1-64 letters, digits, '.', '_' or '-'"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("Unexpected errors:\n got %+v\nwant %+v", errs, want)
+	}
+
+	if got := records[0].DietaryTags; !reflect.DeepEqual(got, []string{"gluten_free", "dairy_free"}) {
+		t.Errorf("Unexpected dietary tags: %v", got)
+	}
+
+	// Unknown and missing columns are reported against the header
+	_, _, errs = ParseMealCSV(strings.NewReader("sku,colour\nA,red\n"))
+	if len(errs) != 3 {
+		t.Errorf("Expected 3 header errors, got %+v", errs)
+	}
+}
@@ -3,29 +3,55 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"strings"
 
 	"github.com/jopari/preptoplate/internal/models"
 	"github.com/jopari/preptoplate/internal/repository"
 )
 
 type MealService interface {
-	Create(ctx context.Context, req *models.CreateMealRequest) (*models.Meal, error)
-	GetByID(ctx context.Context, id int) (*models.Meal, error)
-	GetAll(ctx context.Context) ([]models.Meal, error)
-	Update(ctx context.Context, id int, req *models.UpdateMealRequest) (*models.Meal, error)
+	Create(ctx context.Context, userID int, req *models.CreateMealRequest) (*models.Meal, error)
+	GetByID(ctx context.Context, id int, languages []string) (*models.Meal, error)
+	GetAll(ctx context.Context, languages []string) ([]models.Meal, error)
+	Update(ctx context.Context, userID, id int, req *models.UpdateMealRequest) (*models.Meal, error)
 	Delete(ctx context.Context, id int) error
+	GetVersions(ctx context.Context, id int) ([]models.MealVersion, error)
+	GetVersion(ctx context.Context, id, version int) (*models.MealVersion, error)
+	DiffVersions(ctx context.Context, id, fromVersion, toVersion int) (*models.MealVersionDiff, error)
+	RevertToVersion(ctx context.Context, userID, id, version int) (*models.Meal, error)
+	CreateVariant(ctx context.Context, mealID int, req *models.CreateMealVariantRequest) (*models.MealVariant, error)
+	UpdateVariant(ctx context.Context, mealID, variantID int, req *models.UpdateMealVariantRequest) (*models.MealVariant, error)
+	DeleteVariant(ctx context.Context, mealID, variantID int) error
+	Import(ctx context.Context, userID int, format string, r io.Reader, dryRun bool) (*models.MealImportReport, error)
+	Export(ctx context.Context) ([]models.MealRecord, error)
 }
 
 type mealService struct {
-	repo repository.MealRepository
+	repo        repository.MealRepository
+	variantRepo repository.MealVariantRepository
+	reviewRepo  repository.ReviewRepository
+	imageRepo   repository.MealImageRepository
+
+	translationRepo repository.MealTranslationRepository
+	defaultLanguage string
 }
 
-func NewMealService(repo repository.MealRepository) MealService {
-	return &mealService{repo: repo}
+func NewMealService(repo repository.MealRepository, variantRepo repository.MealVariantRepository, reviewRepo repository.ReviewRepository, imageRepo repository.MealImageRepository, translationRepo repository.MealTranslationRepository, defaultLanguage string) MealService {
+	return &mealService{
+		repo:            repo,
+		variantRepo:     variantRepo,
+		reviewRepo:      reviewRepo,
+		imageRepo:       imageRepo,
+		translationRepo: translationRepo,
+		defaultLanguage: normalizeDefaultLanguage(defaultLanguage),
+	}
 }
 
-func (s *mealService) Create(ctx context.Context, req *models.CreateMealRequest) (*models.Meal, error) {
+func (s *mealService) Create(ctx context.Context, userID int, req *models.CreateMealRequest) (*models.Meal, error) {
 	meal := &models.Meal{
+		SKU:         strings.TrimSpace(req.SKU),
 		Name:        req.Name,
 		Description: req.Description,
 		ImageURL:    req.ImageURL,
@@ -34,16 +60,20 @@ func (s *mealService) Create(ctx context.Context, req *models.CreateMealRequest)
 		Carbs:       req.Carbs,
 		Fat:         req.Fat,
 		Price:       req.Price,
+		DietaryTags: normalizeDietaryTags(req.DietaryTags),
+		Ingredients: normalizeIngredients(req.Ingredients),
 	}
 
-	if err := s.repo.Create(ctx, meal); err != nil {
+	if err := s.repo.Create(ctx, meal, userID); err != nil {
 		return nil, err
 	}
 
 	return meal, nil
 }
 
-func (s *mealService) GetByID(ctx context.Context, id int) (*models.Meal, error) {
+// GetByID returns the meal with its text in the first of the preferred
+// languages it has been translated into.
+func (s *mealService) GetByID(ctx context.Context, id int, languages []string) (*models.Meal, error) {
 	meal, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -51,14 +81,66 @@ func (s *mealService) GetByID(ctx context.Context, id int) (*models.Meal, error)
 	if meal == nil {
 		return nil, errors.New("meal not found")
 	}
+
+	variants, err := s.variantRepo.GetByMealID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	meal.Variants = variants
+
+	images, err := s.imageRepo.GetByMealID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	meal.Images = images
+
+	if err := attachRatings(ctx, s.reviewRepo, []*models.Meal{meal}); err != nil {
+		return nil, err
+	}
+	if err := localizeMeals(ctx, s.translationRepo, s.defaultLanguage, languages, []*models.Meal{meal}); err != nil {
+		return nil, err
+	}
+
 	return meal, nil
 }
 
-func (s *mealService) GetAll(ctx context.Context) ([]models.Meal, error) {
-	return s.repo.GetAll(ctx)
+func (s *mealService) GetAll(ctx context.Context, languages []string) ([]models.Meal, error) {
+	meals, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mealIDs := make([]int, len(meals))
+	for i, meal := range meals {
+		mealIDs[i] = meal.ID
+	}
+
+	variants, err := s.variantRepo.GetByMealIDs(ctx, mealIDs)
+	if err != nil {
+		return nil, err
+	}
+	images, err := s.imageRepo.GetByMealIDs(ctx, mealIDs)
+	if err != nil {
+		return nil, err
+	}
+	mealPtrs := make([]*models.Meal, len(meals))
+	for i := range meals {
+		meals[i].Variants = variants[meals[i].ID]
+		meals[i].Images = images[meals[i].ID]
+		mealPtrs[i] = &meals[i]
+	}
+
+	if err := attachRatings(ctx, s.reviewRepo, mealPtrs); err != nil {
+		return nil, err
+	}
+	if err := localizeMeals(ctx, s.translationRepo, s.defaultLanguage, languages, mealPtrs); err != nil {
+		return nil, err
+	}
+
+	return meals, nil
 }
 
-func (s *mealService) Update(ctx context.Context, id int, req *models.UpdateMealRequest) (*models.Meal, error) {
+func (s *mealService) Update(ctx context.Context, userID, id int, req *models.UpdateMealRequest) (*models.Meal, error) {
 	// Get existing meal
 	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -69,6 +151,9 @@ func (s *mealService) Update(ctx context.Context, id int, req *models.UpdateMeal
 	}
 
 	// Apply updates only for non-nil fields
+	if req.SKU != nil {
+		existing.SKU = strings.TrimSpace(*req.SKU)
+	}
 	if req.Name != nil {
 		existing.Name = *req.Name
 	}
@@ -93,8 +178,14 @@ func (s *mealService) Update(ctx context.Context, id int, req *models.UpdateMeal
 	if req.Price != nil {
 		existing.Price = *req.Price
 	}
+	if req.DietaryTags != nil {
+		existing.DietaryTags = normalizeDietaryTags(req.DietaryTags)
+	}
+	if req.Ingredients != nil {
+		existing.Ingredients = normalizeIngredients(req.Ingredients)
+	}
 
-	if err := s.repo.Update(ctx, id, existing); err != nil {
+	if err := s.repo.Update(ctx, id, existing, userID); err != nil {
 		return nil, err
 	}
 
@@ -114,3 +205,299 @@ func (s *mealService) Delete(ctx context.Context, id int) error {
 
 	return s.repo.Delete(ctx, id)
 }
+
+func (s *mealService) GetVersions(ctx context.Context, id int) ([]models.MealVersion, error) {
+	meal, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if meal == nil {
+		return nil, errors.New("meal not found")
+	}
+
+	return s.repo.GetVersions(ctx, id)
+}
+
+func (s *mealService) GetVersion(ctx context.Context, id, version int) (*models.MealVersion, error) {
+	v, err := s.repo.GetVersion(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, errors.New("meal version not found")
+	}
+	return v, nil
+}
+
+func (s *mealService) DiffVersions(ctx context.Context, id, fromVersion, toVersion int) (*models.MealVersionDiff, error) {
+	from, err := s.GetVersion(ctx, id, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.GetVersion(ctx, id, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MealVersionDiff{
+		MealID:      id,
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+		Changes:     diffMealVersions(from, to),
+	}, nil
+}
+
+// RevertToVersion restores a previous version's fields as a new version,
+// so the history is never rewritten.
+func (s *mealService) RevertToVersion(ctx context.Context, userID, id, version int) (*models.Meal, error) {
+	target, err := s.GetVersion(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// SKU, dietary tags and ingredients aren't versioned, so the current ones are kept
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, errors.New("meal not found")
+	}
+
+	meal := &models.Meal{
+		Name:        target.Name,
+		Description: target.Description,
+		ImageURL:    target.ImageURL,
+		Calories:    target.Calories,
+		Protein:     target.Protein,
+		Carbs:       target.Carbs,
+		Fat:         target.Fat,
+		Price:       target.Price,
+		SKU:         current.SKU,
+		DietaryTags: current.DietaryTags,
+		Ingredients: current.Ingredients,
+	}
+
+	if err := s.repo.Update(ctx, id, meal, userID); err != nil {
+		return nil, err
+	}
+
+	return meal, nil
+}
+
+func (s *mealService) CreateVariant(ctx context.Context, mealID int, req *models.CreateMealVariantRequest) (*models.MealVariant, error) {
+	meal, err := s.repo.GetByID(ctx, mealID)
+	if err != nil {
+		return nil, err
+	}
+	if meal == nil {
+		return nil, errors.New("meal not found")
+	}
+
+	variant := &models.MealVariant{
+		MealID:   mealID,
+		Name:     req.Name,
+		Calories: req.Calories,
+		Protein:  req.Protein,
+		Carbs:    req.Carbs,
+		Fat:      req.Fat,
+		Price:    req.Price,
+	}
+
+	if err := s.variantRepo.Create(ctx, variant); err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+func (s *mealService) UpdateVariant(ctx context.Context, mealID, variantID int, req *models.UpdateMealVariantRequest) (*models.MealVariant, error) {
+	existing, err := s.getVariant(ctx, mealID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply updates only for non-nil fields
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Calories != nil {
+		existing.Calories = *req.Calories
+	}
+	if req.Protein != nil {
+		existing.Protein = *req.Protein
+	}
+	if req.Carbs != nil {
+		existing.Carbs = *req.Carbs
+	}
+	if req.Fat != nil {
+		existing.Fat = *req.Fat
+	}
+	if req.Price != nil {
+		existing.Price = *req.Price
+	}
+
+	if err := s.variantRepo.Update(ctx, variantID, existing); err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+func (s *mealService) DeleteVariant(ctx context.Context, mealID, variantID int) error {
+	if _, err := s.getVariant(ctx, mealID, variantID); err != nil {
+		return err
+	}
+
+	isUsed, err := s.variantRepo.IsUsedInMenus(ctx, variantID)
+	if err != nil {
+		return err
+	}
+
+	if isUsed {
+		return errors.New("cannot delete variant: it is currently used in one or more weekly menus")
+	}
+
+	return s.variantRepo.Delete(ctx, variantID)
+}
+
+// getVariant loads a variant and checks it belongs to the given meal.
+func (s *mealService) getVariant(ctx context.Context, mealID, variantID int) (*models.MealVariant, error) {
+	variant, err := s.variantRepo.GetByID(ctx, variantID)
+	if err != nil {
+		return nil, err
+	}
+	if variant == nil || variant.MealID != mealID {
+		return nil, errors.New("meal variant not found")
+	}
+	return variant, nil
+}
+
+// Import upserts meals by SKU from a CSV or JSON file. Nothing is written if
+// any row is invalid or any meal fails to save; with dryRun the report only
+// says what would change. Every created or changed meal gets a new version as
+// with Create and Update.
+func (s *mealService) Import(ctx context.Context, userID int, format string, r io.Reader, dryRun bool) (*models.MealImportReport, error) {
+	var records []models.MealRecord
+	var rows []int
+	var importErrors []models.MealImportError
+	switch format {
+	case "csv":
+		records, rows, importErrors = ParseMealCSV(r)
+	case "json":
+		records, rows, importErrors = ParseMealJSON(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q, use csv or json", format)
+	}
+
+	report := &models.MealImportReport{
+		DryRun: dryRun,
+		Total:  len(records),
+		Errors: []models.MealImportError{},
+	}
+	report.Errors = append(report.Errors, importErrors...)
+	report.Errors = append(report.Errors, validateMealRecords(records, rows)...)
+
+	// Work out what each row would do
+	skus := make([]string, len(records))
+	for i, record := range records {
+		skus[i] = record.SKU
+	}
+	existing, err := s.repo.GetBySKUs(ctx, skus)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []*models.Meal
+	for _, record := range records {
+		meal := existing[record.SKU]
+		switch {
+		case meal == nil:
+			report.Created++
+			meal = &models.Meal{SKU: record.SKU}
+		case recordMatchesMeal(record, meal):
+			report.Unchanged++
+			continue
+		default:
+			report.Updated++
+		}
+
+		// Fields not in the import format, such as ingredients, are kept
+		meal.Name = record.Name
+		meal.Description = record.Description
+		meal.ImageURL = record.ImageURL
+		meal.Calories = record.Calories
+		meal.Protein = record.Protein
+		meal.Carbs = record.Carbs
+		meal.Fat = record.Fat
+		meal.Price = record.Price
+		meal.DietaryTags = normalizeDietaryTags(record.DietaryTags)
+		changed = append(changed, meal)
+	}
+
+	if dryRun || len(report.Errors) > 0 || len(changed) == 0 {
+		return report, nil
+	}
+
+	if err := s.repo.SaveAll(ctx, changed, userID); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Export returns every meal in the import format.
+func (s *mealService) Export(ctx context.Context) ([]models.MealRecord, error) {
+	meals, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]models.MealRecord, len(meals))
+	for i := range meals {
+		records[i] = mealToRecord(&meals[i])
+	}
+	return records, nil
+}
+
+// normalizeDietaryTags lower-cases, trims and de-duplicates tags so they can
+// be matched against dietary restrictions.
+func normalizeDietaryTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+func diffMealVersions(from, to *models.MealVersion) []models.MealFieldChange {
+	changes := []models.MealFieldChange{}
+
+	addString := func(field, a, b string) {
+		if a != b {
+			changes = append(changes, models.MealFieldChange{Field: field, From: a, To: b})
+		}
+	}
+	addInt := func(field string, a, b int) {
+		if a != b {
+			changes = append(changes, models.MealFieldChange{Field: field, From: a, To: b})
+		}
+	}
+
+	addString("name", from.Name, to.Name)
+	addString("description", from.Description, to.Description)
+	addString("image_url", from.ImageURL, to.ImageURL)
+	addInt("calories", from.Calories, to.Calories)
+	addInt("protein", from.Protein, to.Protein)
+	addInt("carbs", from.Carbs, to.Carbs)
+	addInt("fat", from.Fat, to.Fat)
+	addInt("price", from.Price, to.Price)
+
+	return changes
+}
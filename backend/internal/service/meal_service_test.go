@@ -0,0 +1,30 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestDiffMealVersions(t *testing.T) {
+	from := &models.MealVersion{Name: "Chicken Bowl", Calories: 450, Price: 1299}
+	to := &models.MealVersion{Name: "Chicken Bowl", Calories: 480, Price: 1399}
+
+	changes := diffMealVersions(from, to)
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %d: %+v", len(changes), changes)
+	}
+
+	if changes[0].Field != "calories" || changes[0].From != 450 || changes[0].To != 480 {
+		t.Errorf("Unexpected calories change: %+v", changes[0])
+	}
+
+	if changes[1].Field != "price" || changes[1].From != 1299 || changes[1].To != 1399 {
+		t.Errorf("Unexpected price change: %+v", changes[1])
+	}
+
+	// Identical versions produce no changes
+	if changes := diffMealVersions(from, from); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %+v", changes)
+	}
+}
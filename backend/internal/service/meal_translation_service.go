@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+type MealTranslationService interface {
+	List(ctx context.Context, mealID int) ([]models.MealTranslation, error)
+	Set(ctx context.Context, mealID int, language string, req *models.SetMealTranslationRequest) (*models.MealTranslation, error)
+	Delete(ctx context.Context, mealID int, language string) error
+}
+
+type mealTranslationService struct {
+	translationRepo repository.MealTranslationRepository
+	mealRepo        repository.MealRepository
+	defaultLanguage string
+}
+
+func NewMealTranslationService(translationRepo repository.MealTranslationRepository, mealRepo repository.MealRepository, defaultLanguage string) MealTranslationService {
+	return &mealTranslationService{
+		translationRepo: translationRepo,
+		mealRepo:        mealRepo,
+		defaultLanguage: normalizeDefaultLanguage(defaultLanguage),
+	}
+}
+
+func (s *mealTranslationService) List(ctx context.Context, mealID int) ([]models.MealTranslation, error) {
+	if err := s.checkMeal(ctx, mealID); err != nil {
+		return nil, err
+	}
+	return s.translationRepo.GetByMealID(ctx, mealID)
+}
+
+func (s *mealTranslationService) Set(ctx context.Context, mealID int, language string, req *models.SetMealTranslationRequest) (*models.MealTranslation, error) {
+	lang, err := s.translationLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkMeal(ctx, mealID); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	translation := &models.MealTranslation{
+		MealID:      mealID,
+		Language:    lang,
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		Ingredients: normalizeIngredients(req.Ingredients),
+	}
+	if err := s.translationRepo.Upsert(ctx, translation); err != nil {
+		return nil, err
+	}
+	return translation, nil
+}
+
+func (s *mealTranslationService) Delete(ctx context.Context, mealID int, language string) error {
+	lang, err := s.translationLanguage(language)
+	if err != nil {
+		return err
+	}
+	return s.translationRepo.Delete(ctx, mealID, lang)
+}
+
+func (s *mealTranslationService) translationLanguage(language string) (string, error) {
+	lang, ok := NormalizeLanguage(language)
+	if !ok {
+		return "", errors.New("invalid language")
+	}
+	if lang == s.defaultLanguage {
+		return "", errors.New("the default language is edited on the meal itself")
+	}
+	return lang, nil
+}
+
+func (s *mealTranslationService) checkMeal(ctx context.Context, mealID int) error {
+	meal, err := s.mealRepo.GetByID(ctx, mealID)
+	if err != nil {
+		return err
+	}
+	if meal == nil {
+		return errors.New("meal not found")
+	}
+	return nil
+}
+
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{1,8})*$`)
+
+// NormalizeLanguage lower-cases a language tag such as "pt_BR" to "pt-br"
+// and reports whether it is well formed.
+func NormalizeLanguage(tag string) (string, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if len(tag) > 35 || !languagePattern.MatchString(tag) {
+		return "", false
+	}
+	return tag, true
+}
+
+func normalizeDefaultLanguage(tag string) string {
+	if lang, ok := NormalizeLanguage(tag); ok {
+		return lang
+	}
+	return "en"
+}
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header,
+// most preferred first. Malformed entries, "*" and q=0 are skipped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var entries []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		lang, ok := NormalizeLanguage(tag)
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if found && strings.TrimSpace(key) == "q" {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		entries = append(entries, weighted{lang, q})
+	}
+
+	sort.SliceStable(entries, func(a, b int) bool { return entries[a].q > entries[b].q })
+
+	languages := make([]string, len(entries))
+	for i, e := range entries {
+		languages[i] = e.lang
+	}
+	return languages
+}
+
+// languageFallbacks turns preferred languages into the order translations
+// are tried in: each regional tag is followed by its base language
+// ("fr-ca" then "fr"), and nothing after the default language is tried since
+// the meal's own text is in it.
+func languageFallbacks(preferred []string, defaultLanguage string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	add := func(lang string) {
+		if !seen[lang] {
+			seen[lang] = true
+			chain = append(chain, lang)
+		}
+	}
+
+	for _, tag := range preferred {
+		lang, ok := NormalizeLanguage(tag)
+		if !ok {
+			continue
+		}
+		for {
+			if lang == defaultLanguage {
+				return chain
+			}
+			add(lang)
+			i := strings.LastIndex(lang, "-")
+			if i < 0 {
+				break
+			}
+			lang = lang[:i]
+		}
+	}
+	return chain
+}
+
+// localizeMeals replaces the meals' text with the best translation for the
+// preferred languages, field by field falling back to the default language.
+func localizeMeals(ctx context.Context, repo repository.MealTranslationRepository, defaultLanguage string, preferred []string, meals []*models.Meal) error {
+	chain := languageFallbacks(preferred, defaultLanguage)
+	if len(chain) == 0 || len(meals) == 0 {
+		applyTranslations(meals, nil, chain, defaultLanguage)
+		return nil
+	}
+
+	ids := make([]int, len(meals))
+	for i, meal := range meals {
+		ids[i] = meal.ID
+	}
+	translations, err := repo.GetForMeals(ctx, ids, chain)
+	if err != nil {
+		return err
+	}
+
+	applyTranslations(meals, translations, chain, defaultLanguage)
+	return nil
+}
+
+func applyTranslations(meals []*models.Meal, translations map[int]map[string]models.MealTranslation, chain []string, defaultLanguage string) {
+	for _, meal := range meals {
+		meal.Language = defaultLanguage
+		for _, lang := range chain {
+			t, ok := translations[meal.ID][lang]
+			if !ok {
+				continue
+			}
+			meal.Language = lang
+			meal.Name = t.Name
+			if t.Description != "" {
+				meal.Description = t.Description
+			}
+			if len(t.Ingredients) > 0 {
+				meal.Ingredients = t.Ingredients
+			}
+			break
+		}
+	}
+}
+
+// normalizeIngredients trims the ingredients and drops empty ones, keeping
+// their order.
+func normalizeIngredients(ingredients []string) []string {
+	normalized := make([]string, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		if ingredient = strings.TrimSpace(ingredient); ingredient != "" {
+			normalized = append(normalized, ingredient)
+		}
+	}
+	return normalized
+}
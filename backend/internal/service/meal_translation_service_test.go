@@ -0,0 +1,94 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	cases := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"fr", []string{"fr"}},
+		{"fr-CA,fr;q=0.9,en;q=0.8", []string{"fr-ca", "fr", "en"}},
+		{"en;q=0.5, de", []string{"de", "en"}},
+		{"es;q=0, it", []string{"it"}},
+		{"*, pt_BR;q=0.7, not a tag", []string{"pt-br"}},
+		{"nl;q=abc, sv", []string{"sv"}},
+	}
+
+	for _, tc := range cases {
+		if got := ParseAcceptLanguage(tc.header); !slices.Equal(got, tc.want) {
+			t.Errorf("ParseAcceptLanguage(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestLanguageFallbacks(t *testing.T) {
+	cases := []struct {
+		preferred []string
+		want      []string
+	}{
+		{nil, nil},
+		{[]string{"fr-ca"}, []string{"fr-ca", "fr"}},
+		{[]string{"fr-ca", "fr", "de"}, []string{"fr-ca", "fr", "de"}},
+		// Nothing beyond the default language is tried
+		{[]string{"de", "en", "fr"}, []string{"de"}},
+		{[]string{"en-gb", "fr"}, []string{"en-gb"}},
+	}
+
+	for _, tc := range cases {
+		if got := languageFallbacks(tc.preferred, "en"); !slices.Equal(got, tc.want) {
+			t.Errorf("languageFallbacks(%v) = %v, want %v", tc.preferred, got, tc.want)
+		}
+	}
+}
+
+func TestApplyTranslations(t *testing.T) {
+	meals := []*models.Meal{
+		{ID: 1, Name: "Chicken Bowl", Description: "Grilled chicken", Ingredients: []string{"chicken", "rice"}},
+		{ID: 2, Name: "Tofu Curry", Description: "Mild curry"},
+	}
+	translations := map[int]map[string]models.MealTranslation{
+		// No description, so that falls back to the default language
+		1: {"fr": {Name: "Bol de poulet", Ingredients: []string{"poulet", "riz"}}},
+		2: {"de": {Name: "Tofu-Curry", Description: "Mildes Curry"}},
+	}
+
+	applyTranslations(meals, translations, []string{"fr-ca", "fr", "de"}, "en")
+
+	if meals[0].Language != "fr" || meals[0].Name != "Bol de poulet" || meals[0].Description != "Grilled chicken" {
+		t.Errorf("Unexpected first meal %+v", meals[0])
+	}
+	if !slices.Equal(meals[0].Ingredients, []string{"poulet", "riz"}) {
+		t.Errorf("Expected translated ingredients, got %v", meals[0].Ingredients)
+	}
+	if meals[1].Language != "de" || meals[1].Name != "Tofu-Curry" || meals[1].Description != "Mildes Curry" {
+		t.Errorf("Unexpected second meal %+v", meals[1])
+	}
+
+	untranslated := []*models.Meal{{ID: 3, Name: "Salmon"}}
+	applyTranslations(untranslated, translations, []string{"fr"}, "en")
+	if untranslated[0].Language != "en" || untranslated[0].Name != "Salmon" {
+		t.Errorf("Expected the default language, got %+v", untranslated[0])
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	valid := map[string]string{"FR": "fr", "pt_BR": "pt-br", " zh-Hant-TW ": "zh-hant-tw"}
+	for input, want := range valid {
+		if got, ok := NormalizeLanguage(input); !ok || got != want {
+			t.Errorf("NormalizeLanguage(%q) = %q, %v, want %q", input, got, ok, want)
+		}
+	}
+
+	for _, input := range []string{"", "*", "f", "french!", "en--us", "12"} {
+		if _, ok := NormalizeLanguage(input); ok {
+			t.Errorf("Expected %q to be invalid", input)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"math"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+type NutritionService interface {
+	GetGoals(ctx context.Context, userID int) (*models.NutritionGoals, error)
+	SetGoals(ctx context.Context, userID int, req *models.SetNutritionGoalsRequest) (*models.NutritionGoals, error)
+	DeleteGoals(ctx context.Context, userID int) error
+	GetDietaryPreferences(ctx context.Context, userID int) (*models.DietaryPreferences, error)
+	SetDietaryPreferences(ctx context.Context, userID int, req *models.SetDietaryPreferencesRequest) (*models.DietaryPreferences, error)
+}
+
+type nutritionService struct {
+	goalsRepo repository.NutritionGoalsRepository
+	userRepo  repository.UserRepository
+}
+
+func NewNutritionService(goalsRepo repository.NutritionGoalsRepository, userRepo repository.UserRepository) NutritionService {
+	return &nutritionService{
+		goalsRepo: goalsRepo,
+		userRepo:  userRepo,
+	}
+}
+
+func (s *nutritionService) GetGoals(ctx context.Context, userID int) (*models.NutritionGoals, error) {
+	return s.goalsRepo.GetByUserID(ctx, userID)
+}
+
+func (s *nutritionService) SetGoals(ctx context.Context, userID int, req *models.SetNutritionGoalsRequest) (*models.NutritionGoals, error) {
+	goals := &models.NutritionGoals{
+		UserID:   userID,
+		Period:   req.Period,
+		Calories: req.Calories,
+		Protein:  req.Protein,
+		Carbs:    req.Carbs,
+		Fat:      req.Fat,
+	}
+
+	if err := s.goalsRepo.Upsert(ctx, goals); err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+func (s *nutritionService) DeleteGoals(ctx context.Context, userID int) error {
+	return s.goalsRepo.Delete(ctx, userID)
+}
+
+func (s *nutritionService) GetDietaryPreferences(ctx context.Context, userID int) (*models.DietaryPreferences, error) {
+	preferences, err := s.userRepo.GetDietaryPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DietaryPreferences{Preferences: preferences}, nil
+}
+
+// SetDietaryPreferences stores the tags a user's meals should carry. They use
+// the same vocabulary as meal dietary tags.
+func (s *nutritionService) SetDietaryPreferences(ctx context.Context, userID int, req *models.SetDietaryPreferencesRequest) (*models.DietaryPreferences, error) {
+	preferences := normalizeDietaryTags(req.Preferences)
+	if err := s.userRepo.SetDietaryPreferences(ctx, userID, preferences); err != nil {
+		return nil, err
+	}
+	return &models.DietaryPreferences{Preferences: preferences}, nil
+}
+
+// mealDays is how many days a number of meals covers at mealsPerDay meals a
+// day (MEALS_PER_DAY). A full cart of MaxCartItems meals covers a week.
+func mealDays(meals, mealsPerDay int) int {
+	return (meals + mealsPerDay - 1) / mealsPerDay
+}
+
+// cartNutrition totals the nutrition of a cart's meals, bundles and add-ons.
+func cartNutrition(cart *models.Cart, goals *models.NutritionGoals, mealsPerDay int) *models.NutritionSummary {
+	var total models.NutritionTotals
+	for _, item := range cart.Items {
+		addMealNutrition(&total, &item.Meal, item.Variant, item.Quantity)
+	}
+	for _, ca := range cart.Addons {
+		addNutrition(&total, ca.Addon.Calories, ca.Addon.Protein, ca.Addon.Carbs, ca.Addon.Fat, ca.Quantity)
+	}
+	for _, cb := range cart.Bundles {
+		for _, item := range cb.Bundle.Items {
+			addMealNutrition(&total, &item.Meal, item.Variant, item.Quantity*cb.Quantity)
+		}
+	}
+	return summarizeNutrition(total, cart.TotalItems, goals, mealsPerDay)
+}
+
+// orderNutrition totals the nutrition of an order's meals and add-ons.
+func orderNutrition(order *models.Order, goals *models.NutritionGoals, mealsPerDay int) *models.NutritionSummary {
+	var total models.NutritionTotals
+	meals := 0
+	for _, item := range order.Items {
+		addMealNutrition(&total, &item.Meal, item.Variant, item.Quantity)
+		meals += item.Quantity
+	}
+	for _, oa := range order.Addons {
+		addNutrition(&total, oa.Addon.Calories, oa.Addon.Protein, oa.Addon.Carbs, oa.Addon.Fat, oa.Quantity)
+	}
+	return summarizeNutrition(total, meals, goals, mealsPerDay)
+}
+
+// addMealNutrition uses the variant's nutrition when one is chosen, otherwise
+// the meal's standard portion.
+func addMealNutrition(total *models.NutritionTotals, meal *models.Meal, variant *models.MealVariant, quantity int) {
+	if variant != nil {
+		addNutrition(total, variant.Calories, variant.Protein, variant.Carbs, variant.Fat, quantity)
+		return
+	}
+	addNutrition(total, meal.Calories, meal.Protein, meal.Carbs, meal.Fat, quantity)
+}
+
+func addNutrition(total *models.NutritionTotals, calories, protein, carbs, fat, quantity int) {
+	total.Calories += calories * quantity
+	total.Protein += protein * quantity
+	total.Carbs += carbs * quantity
+	total.Fat += fat * quantity
+}
+
+// summarizeNutrition averages the totals over the days the meals cover and,
+// if the user has goals, compares the daily averages against them. A weekly
+// goal is spread evenly over the days a full cart of MaxCartItems meals
+// covers, whatever this cart holds: the averages are already per day, so a
+// half-full cart is held to the same daily target as a full one.
+func summarizeNutrition(total models.NutritionTotals, meals int, goals *models.NutritionGoals, mealsPerDay int) *models.NutritionSummary {
+	summary := &models.NutritionSummary{
+		Total: total,
+		Days:  mealDays(meals, mealsPerDay),
+	}
+
+	if summary.Days > 0 {
+		days := float64(summary.Days)
+		summary.PerDay = models.NutritionAverages{
+			Calories: round1(float64(total.Calories) / days),
+			Protein:  round1(float64(total.Protein) / days),
+			Carbs:    round1(float64(total.Carbs) / days),
+			Fat:      round1(float64(total.Fat) / days),
+		}
+	}
+
+	if goals == nil {
+		return summary
+	}
+
+	divisor := 1.0
+	if goals.Period == "weekly" {
+		divisor = float64(mealDays(MaxCartItems, mealsPerDay))
+	}
+
+	summary.Goals = goals
+	summary.Comparison = []models.MacroComparison{
+		compareMacro("calories", float64(goals.Calories)/divisor, summary.PerDay.Calories),
+		compareMacro("protein", float64(goals.Protein)/divisor, summary.PerDay.Protein),
+		compareMacro("carbs", float64(goals.Carbs)/divisor, summary.PerDay.Carbs),
+		compareMacro("fat", float64(goals.Fat)/divisor, summary.PerDay.Fat),
+	}
+	return summary
+}
+
+func compareMacro(macro string, goal, actual float64) models.MacroComparison {
+	comparison := models.MacroComparison{
+		Macro:      macro,
+		Goal:       round1(goal),
+		Actual:     actual,
+		Difference: round1(actual - goal),
+	}
+	if goal > 0 {
+		comparison.PercentOfGoal = round1(actual / goal * 100)
+	}
+	return comparison
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestCartNutrition(t *testing.T) {
+	cart := &models.Cart{
+		Items: []models.CartItem{
+			{Meal: models.Meal{Calories: 500, Protein: 40, Carbs: 50, Fat: 15}, Quantity: 6},
+			{
+				Meal:     models.Meal{Calories: 400, Protein: 30, Carbs: 40, Fat: 10},
+				Variant:  &models.MealVariant{Calories: 600, Protein: 45, Carbs: 60, Fat: 15},
+				Quantity: 4,
+			},
+		},
+		Addons: []models.CartAddon{
+			{Addon: models.Addon{Calories: 100, Protein: 0, Carbs: 25, Fat: 0}, Quantity: 2},
+		},
+		TotalItems: 10,
+	}
+	// A full cart covers five days, so weekly goals are spread over five
+	goals := &models.NutritionGoals{Period: "weekly", Calories: 5000, Protein: 500, Carbs: 1000, Fat: 0}
+
+	summary := cartNutrition(cart, goals, 2)
+
+	want := models.NutritionTotals{Calories: 5600, Protein: 420, Carbs: 590, Fat: 150}
+	if summary.Total != want {
+		t.Fatalf("Expected totals %+v, got %+v", want, summary.Total)
+	}
+	if summary.Days != 5 {
+		t.Fatalf("Expected 5 days, got %d", summary.Days)
+	}
+	if summary.PerDay.Calories != 1120 || summary.PerDay.Protein != 84 {
+		t.Errorf("Unexpected per-day averages: %+v", summary.PerDay)
+	}
+
+	if len(summary.Comparison) != 4 {
+		t.Fatalf("Expected 4 comparisons, got %d", len(summary.Comparison))
+	}
+	calories := summary.Comparison[0]
+	if calories.Goal != 1000 || calories.Difference != 120 || calories.PercentOfGoal != 112 {
+		t.Errorf("Unexpected calories comparison: %+v", calories)
+	}
+	// A zero goal has no percentage
+	if fat := summary.Comparison[3]; fat.PercentOfGoal != 0 {
+		t.Errorf("Expected no percentage for a zero goal, got %+v", fat)
+	}
+
+	// Without goals there is nothing to compare
+	if summary := cartNutrition(cart, nil, 2); summary.Goals != nil || summary.Comparison != nil {
+		t.Errorf("Expected no comparison without goals, got %+v", summary)
+	}
+}
+
+func TestNutritionDaysFollowMealsPerDay(t *testing.T) {
+	cart := &models.Cart{
+		Items:      []models.CartItem{{Meal: models.Meal{Calories: 600}, Quantity: 9}},
+		TotalItems: 9,
+	}
+	goals := &models.NutritionGoals{Period: "weekly", Calories: 6000}
+
+	tests := []struct {
+		mealsPerDay int
+		days        int
+		goal        float64
+	}{
+		{2, 5, 1200},
+		{3, 3, 1500},
+		{1, 9, 600},
+	}
+
+	for _, tt := range tests {
+		summary := cartNutrition(cart, goals, tt.mealsPerDay)
+		if summary.Days != tt.days {
+			t.Errorf("%d meals a day: expected %d days, got %d", tt.mealsPerDay, tt.days, summary.Days)
+		}
+		if got := summary.Comparison[0].Goal; got != tt.goal {
+			t.Errorf("%d meals a day: expected a daily calorie goal of %v, got %v", tt.mealsPerDay, tt.goal, got)
+		}
+	}
+}
+
+func TestWeeklyGoalsUseFullCartDays(t *testing.T) {
+	goals := &models.NutritionGoals{Period: "weekly", Calories: 5000}
+
+	// Half a cart covers fewer days but is held to the same daily target
+	for _, meals := range []int{10, 5, 1} {
+		cart := &models.Cart{
+			Items:      []models.CartItem{{Meal: models.Meal{Calories: 500}, Quantity: meals}},
+			TotalItems: meals,
+		}
+		summary := cartNutrition(cart, goals, 2)
+		if got := summary.Comparison[0].Goal; got != 1000 {
+			t.Errorf("%d meals: expected a daily calorie goal of 1000, got %v", meals, got)
+		}
+	}
+}
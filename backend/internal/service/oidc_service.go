@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/jopari/preptoplate/internal/config"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+	"github.com/jopari/preptoplate/internal/utils"
+	"golang.org/x/oauth2"
+)
+
+// OIDCLoginTTL is how long a user has to sign in at the provider
+const OIDCLoginTTL = 10 * time.Minute
+
+type OIDCService interface {
+	Providers() []string
+	AuthURL(ctx context.Context, provider string) (string, string, error)
+	Callback(ctx context.Context, provider, code, state string) (*models.AuthResponse, error)
+}
+
+type oidcService struct {
+	repo        repository.IdentityRepository
+	userRepo    repository.UserRepository
+	authService AuthService
+	providers   map[string]*oidcProvider
+	names       []string
+}
+
+// oidcProvider is a configured provider. Its discovery document is fetched
+// on first use, so the server starts even when a provider is unreachable.
+type oidcProvider struct {
+	config      config.OIDCProvider
+	redirectURL string
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewOIDCService(repo repository.IdentityRepository, userRepo repository.UserRepository, authService AuthService, cfg *config.Config) OIDCService {
+	s := &oidcService{
+		repo:        repo,
+		userRepo:    userRepo,
+		authService: authService,
+		providers:   make(map[string]*oidcProvider),
+	}
+	for _, p := range cfg.OIDCProviders {
+		s.providers[p.Name] = &oidcProvider{
+			config:      p,
+			redirectURL: cfg.PublicURL + "/api/auth/oidc/" + p.Name + "/callback",
+		}
+		s.names = append(s.names, p.Name)
+	}
+	return s
+}
+
+func (s *oidcService) Providers() []string {
+	return append([]string{}, s.names...)
+}
+
+// AuthURL starts a login: it returns the provider URL to send the user to,
+// using the authorization code flow with PKCE, and the login's state, which
+// the caller ties to the user's browser.
+func (s *oidcService) AuthURL(ctx context.Context, name string) (string, string, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return "", "", errors.New("unknown provider")
+	}
+	oauth, _, err := p.client()
+	if err != nil {
+		log.Printf("OIDC discovery for %s failed: %v", name, err)
+		return "", "", errors.New("provider unavailable")
+	}
+
+	state, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	err = s.repo.SaveLoginState(ctx, utils.HashToken(state), &models.OIDCLoginState{
+		Provider:     name,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(OIDCLoginTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return oauth.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oidc.Nonce(nonce)), state, nil
+}
+
+// Callback finishes a login when the provider sends the user back. The
+// provider's account is matched to a linked user, else to a user with the
+// same verified email, else a new user is created.
+func (s *oidcService) Callback(ctx context.Context, name, code, state string) (*models.AuthResponse, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, errors.New("unknown provider")
+	}
+
+	login, err := s.repo.TakeLoginState(ctx, utils.HashToken(state))
+	if err != nil {
+		return nil, err
+	}
+	if login == nil || login.Provider != name || time.Now().After(login.ExpiresAt) {
+		return nil, errors.New("invalid or expired login, please try again")
+	}
+
+	oauth, verifier, err := p.client()
+	if err != nil {
+		log.Printf("OIDC discovery for %s failed: %v", name, err)
+		return nil, errors.New("provider unavailable")
+	}
+
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(login.CodeVerifier))
+	if err != nil {
+		log.Printf("OIDC code exchange with %s failed: %v", name, err)
+		return nil, errors.New("could not complete sign-in with provider")
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("provider did not return an ID token")
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		log.Printf("OIDC ID token from %s rejected: %v", name, err)
+		return nil, errors.New("could not complete sign-in with provider")
+	}
+	if idToken.Nonce != login.Nonce {
+		return nil, errors.New("could not complete sign-in with provider")
+	}
+
+	var claims struct {
+		Email         string      `json:"email"`
+		EmailVerified interface{} `json:"email_verified"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(ctx, &models.UserIdentity{
+		Provider: name,
+		Subject:  idToken.Subject,
+		Email:    claims.Email,
+	}, claimIsTrue(claims.EmailVerified))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authService.SignIn(ctx, user)
+}
+
+// resolveUser finds or creates the user for a provider account. Accounts
+// are only linked by email when both the provider and we have verified it,
+// so nobody can take over an account by registering its email elsewhere.
+func (s *oidcService) resolveUser(ctx context.Context, identity *models.UserIdentity, emailVerified bool) (*models.User, error) {
+	linked, err := s.repo.GetByProviderSubject(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		user, err := s.userRepo.GetByID(ctx, linked.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, errors.New("user not found")
+		}
+		return user, nil
+	}
+
+	if identity.Email == "" || !emailVerified {
+		return nil, errors.New("your email address is not verified by " + identity.Provider)
+	}
+
+	existing, err := s.userRepo.GetByEmail(ctx, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if !existing.EmailVerified {
+			return nil, errors.New("an account with this email exists but is not verified, sign in with your password and verify it first")
+		}
+		identity.UserID = existing.ID
+		if err := s.repo.Link(ctx, identity); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	// New users have no password until they set one with a password reset
+	passwordHash, err := unusablePasswordHash()
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Email:        identity.Email,
+		PasswordHash: passwordHash,
+		Role:         DefaultRole,
+	}
+	if err := s.repo.CreateUserWithIdentity(ctx, user, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// client returns the provider's OAuth2 config and ID token verifier,
+// running discovery the first time. A failed discovery is retried on the
+// next login.
+func (p *oidcProvider) client() (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	// The context is kept for fetching signing keys later, so it must not
+	// be cancelled
+	ctx := oidc.ClientContext(context.Background(), &http.Client{Timeout: 10 * time.Second})
+	provider, err := oidc.NewProvider(ctx, p.config.Issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.oauth = &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.redirectURL,
+		Scopes:       p.config.Scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+	return p.oauth, p.verifier, nil
+}
+
+// claimIsTrue reads a boolean claim. Some providers send "true" as a string.
+func claimIsTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
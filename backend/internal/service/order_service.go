@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jopari/preptoplate/internal/models"
@@ -13,6 +14,12 @@ type OrderService interface {
 	Checkout(ctx context.Context, userID int, req *models.CheckoutRequest) (*models.Order, error)
 	GetByID(ctx context.Context, userID, orderID int) (*models.Order, error)
 	GetUserOrders(ctx context.Context, userID int) ([]models.Order, error)
+	UpdateStatus(ctx context.Context, orderID int, req *models.UpdateOrderStatusRequest) (*models.Order, error)
+	Reorder(ctx context.Context, userID, orderID int) (*models.ReorderResult, error)
+	List(ctx context.Context, status, deliveryDate string) ([]models.Order, error)
+	GetForStaff(ctx context.Context, orderID int) (*models.Order, error)
+	GetProductionList(ctx context.Context, deliveryDate string) ([]models.ProductionItem, error)
+	GetDeliveries(ctx context.Context, deliveryDate string) ([]models.Delivery, error)
 }
 
 type orderService struct {
@@ -21,28 +28,57 @@ type orderService struct {
 	menuRepo     repository.WeeklyMenuRepository
 	emailService EmailService
 	userRepo     repository.UserRepository
+	goalsRepo    repository.NutritionGoalsRepository
+	priceRepo    repository.PriceRepository
+	mealsPerDay  int
 }
 
-func NewOrderService(orderRepo repository.OrderRepository, cartRepo repository.CartRepository, menuRepo repository.WeeklyMenuRepository, emailService EmailService, userRepo repository.UserRepository) OrderService {
+func NewOrderService(orderRepo repository.OrderRepository, cartRepo repository.CartRepository, menuRepo repository.WeeklyMenuRepository, emailService EmailService, userRepo repository.UserRepository, goalsRepo repository.NutritionGoalsRepository, priceRepo repository.PriceRepository, mealsPerDay int) OrderService {
 	return &orderService{
 		orderRepo:    orderRepo,
 		cartRepo:     cartRepo,
 		menuRepo:     menuRepo,
 		emailService: emailService,
 		userRepo:     userRepo,
+		goalsRepo:    goalsRepo,
+		priceRepo:    priceRepo,
+		mealsPerDay:  mealsPerDay,
 	}
 }
 
 func (s *orderService) Checkout(ctx context.Context, userID int, req *models.CheckoutRequest) (*models.Order, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if !user.EmailVerified {
+		return nil, errors.New("verify your email address before checking out")
+	}
+	profile, err := s.userRepo.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil || profile.Address == nil {
+		return nil, errors.New("add a delivery address to your profile before checking out")
+	}
+
 	// Get or create user's cart
 	cart, err := s.cartRepo.GetOrCreateByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	if cart == nil || len(cart.Items) == 0 {
+	if cart == nil || (len(cart.Items) == 0 && len(cart.Bundles) == 0) {
 		return nil, errors.New("cart is empty")
 	}
 
+	// Charge the prices on the user's plan, as the cart shows them
+	if err := applyCartPlanPrices(ctx, s.priceRepo, userID, cart); err != nil {
+		return nil, err
+	}
+
 	// Validate cart has exactly 10 meals
 	if cart.TotalItems != 10 {
 		return nil, errors.New("cart must contain exactly 10 meals")
@@ -58,59 +94,101 @@ func (s *orderService) Checkout(ctx context.Context, userID int, req *models.Che
 	}
 
 	// Parse delivery date
-	deliveryDate, err := time.Parse("2006-01-02", req.DeliveryDate)
+	deliveryDate, err := parseDeliveryDate(req.DeliveryDate)
 	if err != nil {
-		return nil, errors.New("invalid delivery date format, use YYYY-MM-DD")
+		return nil, err
 	}
 
-	// Verify stock for all cart items
-	for _, item := range cart.Items {
-		stock, err := s.menuRepo.GetMealStock(ctx, activeMenu.ID, item.MealID)
+	// Verify bundles are still on sale this week
+	for _, cb := range cart.Bundles {
+		if !cb.Bundle.IsActive || cb.Bundle.MenuID != activeMenu.ID {
+			return nil, errors.New("bundle no longer available: " + cb.Bundle.Name)
+		}
+	}
+
+	// Verify stock for all meals, loose and in bundles
+	for _, d := range cartStockDemand(cart) {
+		var stock int
+		if d.VariantID != nil {
+			stock, err = s.menuRepo.GetVariantStock(ctx, activeMenu.ID, *d.VariantID)
+		} else {
+			stock, err = s.menuRepo.GetMealStock(ctx, activeMenu.ID, d.MealID)
+		}
 		if err != nil {
 			return nil, err
 		}
-		if stock < item.Quantity {
-			return nil, errors.New("insufficient stock for meal: " + item.Meal.Name)
+		if stock < d.Quantity {
+			return nil, errors.New("insufficient stock for meal: " + d.Name)
+		}
+	}
+
+	// Verify stock for all add-ons
+	for _, ca := range cart.Addons {
+		stock, err := s.menuRepo.GetAddonStock(ctx, activeMenu.ID, ca.AddonID)
+		if err != nil {
+			return nil, err
+		}
+		if stock < ca.Quantity {
+			return nil, errors.New("insufficient stock for add-on: " + ca.Addon.Name)
 		}
 	}
 
 	// Create order
 	order := &models.Order{
-		UserID:       userID,
-		WeekID:       activeMenu.ID,
-		Status:       "pending",
-		TotalPrice:   cart.TotalPrice,
-		DeliveryDate: deliveryDate,
+		UserID:          userID,
+		WeekID:          activeMenu.ID,
+		Status:          "pending",
+		TotalPrice:      cart.TotalPrice,
+		DeliveryDate:    deliveryDate,
+		DeliveryAddress: profile.Address,
 	}
 
-	err = s.orderRepo.Create(ctx, order)
-	if err != nil {
-		return nil, err
+	for _, cartItem := range cart.Items {
+		order.Items = append(order.Items, models.OrderItem{
+			MealID:      cartItem.MealID,
+			MealVersion: cartItem.Meal.Version,
+			VariantID:   cartItem.VariantID,
+			Variant:     cartItem.Variant,
+			Quantity:    cartItem.Quantity,
+			Price:       cartItem.UnitPrice,
+		})
 	}
 
-	// Add items to order and decrement stock
-	for _, cartItem := range cart.Items {
-		orderItem := &models.OrderItem{
-			OrderID:  order.ID,
-			MealID:   cartItem.MealID,
-			Quantity: cartItem.Quantity,
-			Price:    cartItem.Meal.Price,
-		}
+	// Bundles keep their meals at the regular price so the discount stays
+	// visible
+	for _, cb := range cart.Bundles {
+		bundleID := cb.BundleID
+		order.Bundles = append(order.Bundles, models.OrderBundle{
+			BundleID:     cb.BundleID,
+			Name:         cb.Bundle.Name,
+			Quantity:     cb.Quantity,
+			Price:        cb.Bundle.Price,
+			RegularPrice: cb.Bundle.RegularPrice,
+		})
 
-		err = s.orderRepo.AddItem(ctx, order.ID, orderItem)
-		if err != nil {
-			return nil, err
+		for _, bundleItem := range cb.Bundle.Items {
+			order.Items = append(order.Items, models.OrderItem{
+				MealID:      bundleItem.MealID,
+				MealVersion: bundleItem.Meal.Version,
+				VariantID:   bundleItem.VariantID,
+				Variant:     bundleItem.Variant,
+				Quantity:    bundleItem.Quantity * cb.Quantity,
+				Price:       bundleItem.UnitPrice,
+				BundleID:    &bundleID,
+			})
 		}
+	}
 
-		// Decrement stock
-		err = s.menuRepo.DecrementStock(ctx, activeMenu.ID, cartItem.MealID, cartItem.Quantity)
-		if err != nil {
-			return nil, err
-		}
+	for _, ca := range cart.Addons {
+		order.Addons = append(order.Addons, models.OrderAddon{
+			AddonID:  ca.AddonID,
+			Quantity: ca.Quantity,
+			Price:    ca.Addon.Price,
+		})
 	}
 
-	// Clear cart
-	err = s.cartRepo.Clear(ctx, cart.ID)
+	// Save the order, take its stock and clear the cart together
+	err = s.orderRepo.Place(ctx, order, cart.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -121,10 +199,8 @@ func (s *orderService) Checkout(ctx context.Context, userID int, req *models.Che
 		return nil, err
 	}
 
-	// Get user email
-	user, err := s.userRepo.GetByID(ctx, userID)
-	if err == nil && user != nil {
-		// Send receipt asynchronously
+	// Send receipt asynchronously, unless the user has turned order emails off
+	if profile.Communication.OrderUpdateEmails {
 		go func() {
 			err := s.emailService.SendOrderReceipt(user.Email, finalOrder)
 			if err != nil {
@@ -134,7 +210,7 @@ func (s *orderService) Checkout(ctx context.Context, userID int, req *models.Che
 		}()
 	}
 
-	return finalOrder, nil
+	return s.attachNutrition(ctx, userID, finalOrder)
 }
 
 func (s *orderService) GetByID(ctx context.Context, userID, orderID int) (*models.Order, error) {
@@ -151,9 +227,252 @@ func (s *orderService) GetByID(ctx context.Context, userID, orderID int) (*model
 		return nil, errors.New("unauthorized")
 	}
 
-	return order, nil
+	return s.attachNutrition(ctx, userID, order)
 }
 
 func (s *orderService) GetUserOrders(ctx context.Context, userID int) ([]models.Order, error) {
 	return s.orderRepo.GetByUserID(ctx, userID)
 }
+
+func (s *orderService) UpdateStatus(ctx context.Context, orderID int, req *models.UpdateOrderStatusRequest) (*models.Order, error) {
+	if err := s.orderRepo.UpdateStatus(ctx, orderID, req.Status); err != nil {
+		return nil, err
+	}
+	return s.orderRepo.GetByID(ctx, orderID)
+}
+
+// List returns all customers' orders for staff, optionally only those with
+// a status or delivery date.
+func (s *orderService) List(ctx context.Context, status, deliveryDate string) ([]models.Order, error) {
+	filter := &models.OrderFilter{Status: status}
+	if deliveryDate != "" {
+		date, err := parseDeliveryDate(deliveryDate)
+		if err != nil {
+			return nil, err
+		}
+		filter.DeliveryDate = &date
+	}
+	return s.orderRepo.List(ctx, filter)
+}
+
+// GetForStaff returns any customer's order.
+func (s *orderService) GetForStaff(ctx context.Context, orderID int) (*models.Order, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("order not found")
+	}
+	return order, nil
+}
+
+// GetProductionList is what the kitchen has to make for a delivery date.
+func (s *orderService) GetProductionList(ctx context.Context, deliveryDate string) ([]models.ProductionItem, error) {
+	date, err := parseDeliveryDate(deliveryDate)
+	if err != nil {
+		return nil, err
+	}
+	return s.orderRepo.GetProductionList(ctx, date)
+}
+
+// GetDeliveries is the drivers' list of orders for a delivery date.
+func (s *orderService) GetDeliveries(ctx context.Context, deliveryDate string) ([]models.Delivery, error) {
+	date, err := parseDeliveryDate(deliveryDate)
+	if err != nil {
+		return nil, err
+	}
+	return s.orderRepo.GetDeliveries(ctx, date)
+}
+
+func parseDeliveryDate(value string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, errors.New("invalid delivery date format, use YYYY-MM-DD")
+	}
+	return date, nil
+}
+
+// Reorder copies a past order's meals and add-ons into the user's cart,
+// limited to what is on the active menu, in stock and within MaxCartItems.
+// Meals that came in a bundle are added as loose meals, since bundles are
+// tied to the week they were sold. Anything not added in full is reported
+// back.
+func (s *orderService) Reorder(ctx context.Context, userID, orderID int) (*models.ReorderResult, error) {
+	order, err := s.GetByID(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	activeMenu, err := s.menuRepo.GetActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if activeMenu == nil {
+		return nil, errors.New("no active weekly menu")
+	}
+
+	// Index available stock on the active menu
+	mealStock := make(map[int]int)
+	variantStock := make(map[int]int)
+	for _, menuMeal := range activeMenu.Meals {
+		mealStock[menuMeal.Meal.ID] = menuMeal.AvailableStock
+		for _, menuVariant := range menuMeal.Variants {
+			variantStock[menuVariant.Variant.ID] = menuVariant.AvailableStock
+		}
+	}
+	addonStock := make(map[int]int)
+	for _, menuAddon := range activeMenu.Addons {
+		addonStock[menuAddon.Addon.ID] = menuAddon.AvailableStock
+	}
+
+	cart, err := s.cartRepo.GetOrCreateByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stock already claimed by what is in the cart
+	for _, item := range cart.Items {
+		if item.VariantID != nil {
+			variantStock[*item.VariantID] -= item.Quantity
+		} else {
+			mealStock[item.MealID] -= item.Quantity
+		}
+	}
+	for _, cb := range cart.Bundles {
+		for _, item := range cb.Bundle.Items {
+			if item.VariantID != nil {
+				variantStock[*item.VariantID] -= item.Quantity * cb.Quantity
+			} else {
+				mealStock[item.MealID] -= item.Quantity * cb.Quantity
+			}
+		}
+	}
+	for _, ca := range cart.Addons {
+		addonStock[ca.AddonID] -= ca.Quantity
+	}
+
+	result := &models.ReorderResult{Skipped: []models.ReorderShortfall{}}
+	remaining := MaxCartItems - cart.TotalItems
+
+	for _, item := range order.Items {
+		name := item.Meal.Name
+		stock, onMenu := mealStock[item.MealID]
+		if item.VariantID != nil {
+			stock, onMenu = variantStock[*item.VariantID]
+			if item.Variant != nil {
+				name += " (" + item.Variant.Name + ")"
+			}
+		}
+
+		shortfall := models.ReorderShortfall{
+			MealID:    item.MealID,
+			VariantID: item.VariantID,
+			Name:      name,
+			Requested: item.Quantity,
+		}
+
+		if !onMenu {
+			shortfall.Reason = "not on the current menu"
+			result.Skipped = append(result.Skipped, shortfall)
+			continue
+		}
+
+		quantity := min(item.Quantity, stock, remaining)
+		if quantity > 0 {
+			existing, err := s.cartRepo.GetItemByCartAndMeal(ctx, cart.ID, item.MealID, item.VariantID)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				err = s.cartRepo.UpdateItemQuantity(ctx, existing.ID, existing.Quantity+quantity)
+			} else {
+				err = s.cartRepo.AddItem(ctx, cart.ID, item.MealID, item.VariantID, quantity)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			remaining -= quantity
+			if item.VariantID != nil {
+				variantStock[*item.VariantID] -= quantity
+			} else {
+				mealStock[item.MealID] -= quantity
+			}
+		}
+
+		if quantity < item.Quantity {
+			shortfall.Added = max(quantity, 0)
+			if remaining <= 0 && quantity < stock {
+				shortfall.Reason = fmt.Sprintf("cart limit is %d meals", MaxCartItems)
+			} else {
+				shortfall.Reason = "insufficient stock"
+			}
+			result.Skipped = append(result.Skipped, shortfall)
+		}
+	}
+
+	for _, oa := range order.Addons {
+		shortfall := models.ReorderShortfall{
+			AddonID:   oa.AddonID,
+			Name:      oa.Addon.Name,
+			Requested: oa.Quantity,
+		}
+
+		stock, onMenu := addonStock[oa.AddonID]
+		if !onMenu {
+			shortfall.Reason = "not on the current menu"
+			result.Skipped = append(result.Skipped, shortfall)
+			continue
+		}
+
+		quantity := min(oa.Quantity, stock)
+		if quantity > 0 {
+			existing, err := s.cartRepo.GetAddonByCartAndAddon(ctx, cart.ID, oa.AddonID)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				err = s.cartRepo.UpdateAddonQuantity(ctx, existing.ID, existing.Quantity+quantity)
+			} else {
+				err = s.cartRepo.AddAddon(ctx, cart.ID, oa.AddonID, quantity)
+			}
+			if err != nil {
+				return nil, err
+			}
+			addonStock[oa.AddonID] -= quantity
+		}
+
+		if quantity < oa.Quantity {
+			shortfall.Added = max(quantity, 0)
+			shortfall.Reason = "insufficient stock"
+			result.Skipped = append(result.Skipped, shortfall)
+		}
+	}
+
+	result.Cart, err = s.cartRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if result.Cart != nil {
+		if err := applyCartPlanPrices(ctx, s.priceRepo, userID, result.Cart); err != nil {
+			return nil, err
+		}
+		goals, err := s.goalsRepo.GetByUserID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		result.Cart.Nutrition = cartNutrition(result.Cart, goals, s.mealsPerDay)
+	}
+
+	return result, nil
+}
+
+func (s *orderService) attachNutrition(ctx context.Context, userID int, order *models.Order) (*models.Order, error) {
+	goals, err := s.goalsRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	order.Nutrition = orderNutrition(order, goals, s.mealsPerDay)
+	return order, nil
+}
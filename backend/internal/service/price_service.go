@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+// PriceService manages price lists: scheduled changes to a meal's standard
+// price and the prices subscribers pay on each plan. Repositories read the
+// standard price in effect when they are read (see effective_meal_price in
+// schema.sql); services overlay the customer's plan prices with
+// applyPlanPrices wherever a customer sees or pays them.
+type PriceService interface {
+	List(ctx context.Context, mealID int) ([]models.MealPrice, error)
+	Create(ctx context.Context, mealID, userID int, req *models.CreateMealPriceRequest) (*models.MealPrice, error)
+	Delete(ctx context.Context, mealID, priceID int) error
+}
+
+type priceService struct {
+	repo        repository.PriceRepository
+	mealRepo    repository.MealRepository
+	variantRepo repository.MealVariantRepository
+}
+
+func NewPriceService(repo repository.PriceRepository, mealRepo repository.MealRepository, variantRepo repository.MealVariantRepository) PriceService {
+	return &priceService{repo: repo, mealRepo: mealRepo, variantRepo: variantRepo}
+}
+
+func (s *priceService) List(ctx context.Context, mealID int) ([]models.MealPrice, error) {
+	if err := s.checkMeal(ctx, mealID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByMealID(ctx, mealID)
+}
+
+// Create adds a price list entry. Without EffectiveFrom it applies
+// immediately; otherwise it is scheduled and cannot start in the past.
+func (s *priceService) Create(ctx context.Context, mealID, userID int, req *models.CreateMealPriceRequest) (*models.MealPrice, error) {
+	if err := s.checkMeal(ctx, mealID); err != nil {
+		return nil, err
+	}
+
+	if req.VariantID != nil {
+		variant, err := s.variantRepo.GetByID(ctx, *req.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		if variant == nil || variant.MealID != mealID {
+			return nil, errors.New("meal variant not found")
+		}
+	}
+
+	price := &models.MealPrice{
+		MealID:        mealID,
+		VariantID:     req.VariantID,
+		Price:         req.Price,
+		EffectiveFrom: time.Now(),
+		CreatedBy:     &userID,
+	}
+	if req.PlanType != nil {
+		planType := strings.TrimSpace(*req.PlanType)
+		if planType == "" {
+			return nil, errors.New("plan type cannot be blank")
+		}
+		price.PlanType = &planType
+	}
+	if req.EffectiveFrom != nil {
+		if req.EffectiveFrom.Before(price.EffectiveFrom) {
+			return nil, errors.New("effective_from cannot be in the past")
+		}
+		price.EffectiveFrom = *req.EffectiveFrom
+	}
+
+	if err := s.repo.Create(ctx, price); err != nil {
+		return nil, err
+	}
+	return price, nil
+}
+
+// Delete cancels a scheduled price change. Prices already in effect are
+// part of the price history and stay.
+func (s *priceService) Delete(ctx context.Context, mealID, priceID int) error {
+	price, err := s.repo.GetByID(ctx, priceID)
+	if err != nil {
+		return err
+	}
+	if price == nil || price.MealID != mealID {
+		return errors.New("price not found")
+	}
+	if !price.EffectiveFrom.After(time.Now()) {
+		return errors.New("price is already in effect, schedule a new price instead")
+	}
+	return s.repo.Delete(ctx, priceID)
+}
+
+func (s *priceService) checkMeal(ctx context.Context, mealID int) error {
+	meal, err := s.mealRepo.GetByID(ctx, mealID)
+	if err != nil {
+		return err
+	}
+	if meal == nil {
+		return errors.New("meal not found")
+	}
+	return nil
+}
+
+// applyPlanPrices shows a subscriber the prices on their plan's price list,
+// keeping the standard price alongside. Users without a plan (or a userID of
+// 0) see the standard prices unchanged.
+func applyPlanPrices(ctx context.Context, repo repository.PriceRepository, userID int, meals []*models.Meal, variants []*models.MealVariant) error {
+	if userID == 0 || len(meals) == 0 {
+		return nil
+	}
+
+	planType, err := repo.GetUserPlan(ctx, userID)
+	if err != nil || planType == nil {
+		return err
+	}
+
+	mealIDs := make([]int, len(meals))
+	for i, meal := range meals {
+		mealIDs[i] = meal.ID
+	}
+	prices, err := repo.GetPlanPrices(ctx, *planType, mealIDs)
+	if err != nil {
+		return err
+	}
+
+	overlayPlanPrices(prices, meals, variants)
+	return nil
+}
+
+func overlayPlanPrices(prices map[repository.PriceKey]int, meals []*models.Meal, variants []*models.MealVariant) {
+	for _, meal := range meals {
+		if price, ok := prices[repository.PriceKey{MealID: meal.ID}]; ok && price != meal.Price {
+			meal.StandardPrice = meal.Price
+			meal.Price = price
+		}
+	}
+	for _, variant := range variants {
+		if price, ok := prices[repository.PriceKey{MealID: variant.MealID, VariantID: variant.ID}]; ok && price != variant.Price {
+			variant.StandardPrice = variant.Price
+			variant.Price = price
+		}
+	}
+}
+
+// applyBundlePlanPrices prices the meals in bundles from the user's plan and
+// works out each bundle's regular price and discount again.
+func applyBundlePlanPrices(ctx context.Context, repo repository.PriceRepository, userID int, bundles []*models.Bundle) error {
+	var meals []*models.Meal
+	var variants []*models.MealVariant
+	for _, b := range bundles {
+		meals, variants = appendBundlePrices(b, meals, variants)
+	}
+	if err := applyPlanPrices(ctx, repo, userID, meals, variants); err != nil {
+		return err
+	}
+
+	for _, b := range bundles {
+		repriceBundle(b)
+	}
+	return nil
+}
+
+// applyCartPlanPrices prices the cart's meals, and the meals in its bundles,
+// from the user's plan and works out its totals again. Checkout charges
+// these prices, so the cart always matches what the customer is billed.
+func applyCartPlanPrices(ctx context.Context, repo repository.PriceRepository, userID int, cart *models.Cart) error {
+	var meals []*models.Meal
+	var variants []*models.MealVariant
+	for i := range cart.Items {
+		meals = append(meals, &cart.Items[i].Meal)
+		if cart.Items[i].Variant != nil {
+			variants = append(variants, cart.Items[i].Variant)
+		}
+	}
+	for i := range cart.Bundles {
+		meals, variants = appendBundlePrices(&cart.Bundles[i].Bundle, meals, variants)
+	}
+	if err := applyPlanPrices(ctx, repo, userID, meals, variants); err != nil {
+		return err
+	}
+
+	repriceCart(cart)
+	return nil
+}
+
+func appendBundlePrices(b *models.Bundle, meals []*models.Meal, variants []*models.MealVariant) ([]*models.Meal, []*models.MealVariant) {
+	for i := range b.Items {
+		meals = append(meals, &b.Items[i].Meal)
+		if b.Items[i].Variant != nil {
+			variants = append(variants, b.Items[i].Variant)
+		}
+	}
+	return meals, variants
+}
+
+// unitPrice is the price of one portion: the variant's if one was chosen,
+// otherwise the meal's.
+func unitPrice(meal *models.Meal, variant *models.MealVariant) int {
+	if variant != nil {
+		return variant.Price
+	}
+	return meal.Price
+}
+
+func repriceBundle(b *models.Bundle) {
+	b.RegularPrice = 0
+	for i := range b.Items {
+		item := &b.Items[i]
+		item.UnitPrice = unitPrice(&item.Meal, item.Variant)
+		b.RegularPrice += item.UnitPrice * item.Quantity
+	}
+	b.Discount = b.RegularPrice - b.Price
+}
+
+func repriceCart(cart *models.Cart) {
+	cart.TotalPrice, cart.Discount = 0, 0
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		item.UnitPrice = unitPrice(&item.Meal, item.Variant)
+		cart.TotalPrice += item.UnitPrice * item.Quantity
+	}
+	for _, ca := range cart.Addons {
+		cart.TotalPrice += ca.Addon.Price * ca.Quantity
+	}
+	for i := range cart.Bundles {
+		cb := &cart.Bundles[i]
+		repriceBundle(&cb.Bundle)
+		cart.TotalPrice += cb.Bundle.Price * cb.Quantity
+		cart.Discount += cb.Bundle.Discount * cb.Quantity
+	}
+}
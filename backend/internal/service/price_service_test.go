@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+func TestOverlayPlanPrices(t *testing.T) {
+	chicken := &models.Meal{ID: 1, Price: 1200}
+	salmon := &models.Meal{ID: 2, Price: 1500}
+	large := &models.MealVariant{ID: 7, MealID: 1, Price: 1600}
+	small := &models.MealVariant{ID: 8, MealID: 1, Price: 900}
+
+	prices := map[repository.PriceKey]int{
+		{MealID: 1}:               1000,
+		{MealID: 1, VariantID: 7}: 1400,
+		{MealID: 1, VariantID: 8}: 900, // same as standard
+	}
+
+	overlayPlanPrices(prices, []*models.Meal{chicken, salmon}, []*models.MealVariant{large, small})
+
+	if chicken.Price != 1000 || chicken.StandardPrice != 1200 {
+		t.Errorf("Expected plan price 1000 over 1200, got %+v", chicken)
+	}
+	if salmon.Price != 1500 || salmon.StandardPrice != 0 {
+		t.Errorf("Expected the standard price without a plan price, got %+v", salmon)
+	}
+	if large.Price != 1400 || large.StandardPrice != 1600 {
+		t.Errorf("Expected plan price 1400 over 1600, got %+v", large)
+	}
+	if small.StandardPrice != 0 {
+		t.Errorf("Expected no standard price when the plan price matches it, got %+v", small)
+	}
+}
+
+func TestRepriceCart(t *testing.T) {
+	large := &models.MealVariant{ID: 7, MealID: 1, Price: 1400}
+	cart := &models.Cart{
+		Items: []models.CartItem{
+			{Meal: models.Meal{ID: 1, Price: 1000}, Quantity: 2, UnitPrice: 1000},
+			{Meal: models.Meal{ID: 1, Price: 1000}, Variant: large, Quantity: 1, UnitPrice: 1600},
+		},
+		Addons: []models.CartAddon{
+			{Addon: models.Addon{Price: 250}, Quantity: 2},
+		},
+		Bundles: []models.CartBundle{{
+			Quantity: 1,
+			Bundle: models.Bundle{
+				Price: 4000,
+				Items: []models.BundleItem{
+					{Meal: models.Meal{ID: 2, Price: 900}, Quantity: 5, UnitPrice: 1200},
+				},
+			},
+		}},
+	}
+
+	repriceCart(cart)
+
+	if cart.Items[0].UnitPrice != 1000 || cart.Items[1].UnitPrice != 1400 {
+		t.Errorf("Expected unit prices 1000 and 1400, got %d and %d", cart.Items[0].UnitPrice, cart.Items[1].UnitPrice)
+	}
+	bundle := cart.Bundles[0].Bundle
+	if bundle.RegularPrice != 4500 || bundle.Discount != 500 {
+		t.Errorf("Expected the bundle at 4500 regular, 500 off, got %d and %d", bundle.RegularPrice, bundle.Discount)
+	}
+	// 2x1000 + 1400 meals, 2x250 add-ons and a 4000 bundle
+	if cart.TotalPrice != 7900 || cart.Discount != 500 {
+		t.Errorf("Expected a total of 7900 with 500 off, got %d with %d off", cart.TotalPrice, cart.Discount)
+	}
+}
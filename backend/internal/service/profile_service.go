@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+// phonePattern accepts an optional leading + and digits, allowing the
+// spaces, dashes and brackets people type
+var phonePattern = regexp.MustCompile(`^\+?[0-9(][0-9 ()-]{5,18}$`)
+
+type ProfileService interface {
+	GetProfile(ctx context.Context, userID int) (*models.Profile, error)
+	UpdateProfile(ctx context.Context, userID int, req *models.UpdateProfileRequest) (*models.Profile, error)
+}
+
+type profileService struct {
+	userRepo repository.UserRepository
+}
+
+func NewProfileService(userRepo repository.UserRepository) ProfileService {
+	return &profileService{userRepo: userRepo}
+}
+
+func (s *profileService) GetProfile(ctx context.Context, userID int) (*models.Profile, error) {
+	profile, err := s.userRepo.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, errors.New("user not found")
+	}
+	return profile, nil
+}
+
+// UpdateProfile changes the fields set in the request. The email address is
+// changed separately, as the new one has to be confirmed.
+func (s *profileService) UpdateProfile(ctx context.Context, userID int, req *models.UpdateProfileRequest) (*models.Profile, error) {
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		profile.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Phone != nil {
+		phone := strings.TrimSpace(*req.Phone)
+		if phone != "" && !phonePattern.MatchString(phone) {
+			return nil, errors.New("invalid phone number")
+		}
+		profile.Phone = phone
+	}
+	if req.ClearAddress {
+		profile.Address = nil
+	} else if req.Address != nil {
+		profile.Address = normalizeAddress(req.Address)
+	}
+	if req.DietaryPreferences != nil {
+		profile.DietaryPreferences = normalizeDietaryTags(req.DietaryPreferences)
+	}
+	if req.Communication != nil {
+		profile.Communication = *req.Communication
+	}
+
+	if err := s.userRepo.UpdateProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+	return s.GetProfile(ctx, userID)
+}
+
+func normalizeAddress(address *models.Address) *models.Address {
+	return &models.Address{
+		Line1:        strings.TrimSpace(address.Line1),
+		Line2:        strings.TrimSpace(address.Line2),
+		City:         strings.TrimSpace(address.City),
+		Postcode:     strings.ToUpper(strings.TrimSpace(address.Postcode)),
+		Instructions: strings.TrimSpace(address.Instructions),
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestPhonePattern(t *testing.T) {
+	valid := []string{"+447700900123", "07700 900123", "(020) 7946-0018"}
+	invalid := []string{"call me", "123", "+44 7700 900123 ext 5", "++447700900123"}
+
+	for _, phone := range valid {
+		if !phonePattern.MatchString(phone) {
+			t.Errorf("Expected %q to be a valid phone number", phone)
+		}
+	}
+	for _, phone := range invalid {
+		if phonePattern.MatchString(phone) {
+			t.Errorf("Expected %q to be an invalid phone number", phone)
+		}
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	got := normalizeAddress(&models.Address{
+		Line1:    " 1 High Street ",
+		City:     "London ",
+		Postcode: " sw1a 1aa",
+	})
+	if got.Line1 != "1 High Street" || got.City != "London" || got.Postcode != "SW1A 1AA" {
+		t.Errorf("Unexpected address %+v", got)
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+type RecommendationService interface {
+	Refresh(ctx context.Context) error
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type recommendationService struct {
+	recRepo  repository.RecommendationRepository
+	menuRepo repository.WeeklyMenuRepository
+}
+
+func NewRecommendationService(recRepo repository.RecommendationRepository, menuRepo repository.WeeklyMenuRepository) RecommendationService {
+	return &recommendationService{
+		recRepo:  recRepo,
+		menuRepo: menuRepo,
+	}
+}
+
+// Refresh recomputes every user's ranking of the active menu.
+func (s *recommendationService) Refresh(ctx context.Context) error {
+	activeMenu, err := s.menuRepo.GetActive(ctx)
+	if err != nil {
+		return err
+	}
+	if activeMenu == nil {
+		return nil
+	}
+
+	preferences, err := s.recRepo.GetDietaryPreferences(ctx)
+	if err != nil {
+		return err
+	}
+
+	interactions, err := s.recRepo.GetInteractions(ctx)
+	if err != nil {
+		return err
+	}
+
+	meals := make([]models.Meal, len(activeMenu.Meals))
+	for i, menuMeal := range activeMenu.Meals {
+		meals[i] = menuMeal.Meal
+	}
+
+	recommendations := rankMeals(meals, preferences, interactions)
+	return s.recRepo.ReplaceForMenu(ctx, activeMenu.ID, recommendations)
+}
+
+// Run refreshes recommendations straight away and then on every interval
+// until ctx is cancelled, so ranking never happens on the request path.
+func (s *recommendationService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if err := s.Refresh(ctx); err != nil {
+			log.Printf("Failed to refresh recommendations: %v", err)
+		} else {
+			log.Printf("Refreshed recommendations in %s", time.Since(start))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+const (
+	// dietaryMismatchPenalty pushes meals that don't meet a user's dietary
+	// preferences below every meal that does.
+	dietaryMismatchPenalty = 10.0
+	// neighbourBaseWeight lets every customer count a little towards a new
+	// user's ranking, so popular meals still surface when no one shares their
+	// preferences.
+	neighbourBaseWeight = 0.1
+)
+
+// Recommendation reasons
+const (
+	ReasonOrderedBefore      = "ordered_before"
+	ReasonSimilarToLiked     = "similar_to_liked"
+	ReasonPopularWithSimilar = "popular_with_similar_customers"
+	ReasonPopular            = "popular"
+	ReasonDietaryMismatch    = "dietary_mismatch"
+)
+
+// interactionAffinity turns an interaction into a preference signal: ordering
+// more is a diminishing positive, and ratings move it up or down by up to one.
+func interactionAffinity(i models.MealInteraction) float64 {
+	affinity := 0.5 * math.Log1p(float64(i.OrderCount))
+	if i.Rating != nil {
+		affinity += float64(*i.Rating-3) / 2
+	}
+	return affinity
+}
+
+// rankMeals scores every menu meal for every user. Users with history get
+// their own affinity plus item-to-item collaborative filtering from the meals
+// they have tried; users without history get the affinities of other
+// customers, weighted by how closely their dietary preferences overlap.
+func rankMeals(menuMeals []models.Meal, preferences map[int][]string, interactions []models.MealInteraction) []models.MealRecommendation {
+	byUser := make(map[int]map[int]float64)
+	byMeal := make(map[int]map[int]float64)
+	for _, i := range interactions {
+		a := interactionAffinity(i)
+		if byUser[i.UserID] == nil {
+			byUser[i.UserID] = make(map[int]float64)
+		}
+		if byMeal[i.MealID] == nil {
+			byMeal[i.MealID] = make(map[int]float64)
+		}
+		byUser[i.UserID][i.MealID] = a
+		byMeal[i.MealID][i.UserID] = a
+	}
+
+	sims := newMealSimilarity(byMeal)
+
+	userIDs := make([]int, 0, len(preferences))
+	for userID := range preferences {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Ints(userIDs)
+
+	// New users with the same preferences get the same ranking
+	fallbackCache := make(map[string][]float64)
+
+	var recommendations []models.MealRecommendation
+	for _, userID := range userIDs {
+		prefs := normalizeDietaryTags(preferences[userID])
+		history := byUser[userID]
+
+		var scores []float64
+		var reasons []string
+		if len(history) > 0 {
+			scores, reasons = personalScores(menuMeals, history, sims)
+		} else {
+			key := strings.Join(sortedCopy(prefs), ",")
+			cached, ok := fallbackCache[key]
+			if !ok {
+				cached = neighbourScores(menuMeals, prefs, preferences, byUser)
+				fallbackCache[key] = cached
+			}
+			scores = append([]float64(nil), cached...)
+			reasons = make([]string, len(menuMeals))
+			for i := range reasons {
+				if len(prefs) > 0 {
+					reasons[i] = ReasonPopularWithSimilar
+				} else {
+					reasons[i] = ReasonPopular
+				}
+			}
+		}
+
+		for i, meal := range menuMeals {
+			if !hasDietaryTags(meal.DietaryTags, prefs) {
+				scores[i] -= dietaryMismatchPenalty
+				reasons[i] = ReasonDietaryMismatch
+			}
+			recommendations = append(recommendations, models.MealRecommendation{
+				UserID: userID,
+				MealID: meal.ID,
+				Score:  roundScore(scores[i]),
+				Reason: reasons[i],
+			})
+		}
+	}
+	return recommendations
+}
+
+// personalScores combines what the user thought of a meal with how similar
+// it is to the other meals they have tried.
+func personalScores(menuMeals []models.Meal, history map[int]float64, sims *mealSimilarity) ([]float64, []string) {
+	scores := make([]float64, len(menuMeals))
+	reasons := make([]string, len(menuMeals))
+
+	for i, meal := range menuMeals {
+		var weighted, total float64
+		for mealID, affinity := range history {
+			if mealID == meal.ID {
+				continue
+			}
+			s := sims.get(meal.ID, mealID)
+			weighted += s * affinity
+			total += math.Abs(s)
+		}
+		cf := 0.0
+		if total > 0 {
+			cf = weighted / total
+		}
+
+		if direct, tried := history[meal.ID]; tried {
+			scores[i] = direct + 0.5*cf
+			reasons[i] = ReasonOrderedBefore
+		} else {
+			scores[i] = cf
+			reasons[i] = ReasonSimilarToLiked
+		}
+	}
+	return scores, reasons
+}
+
+// neighbourScores averages other customers' affinities, weighting customers
+// who share more dietary preferences more heavily.
+func neighbourScores(menuMeals []models.Meal, prefs []string, preferences map[int][]string, byUser map[int]map[int]float64) []float64 {
+	scores := make([]float64, len(menuMeals))
+
+	var totalWeight float64
+	for userID, history := range byUser {
+		weight := neighbourBaseWeight + jaccard(prefs, normalizeDietaryTags(preferences[userID]))
+		totalWeight += weight
+		for i, meal := range menuMeals {
+			scores[i] += weight * history[meal.ID]
+		}
+	}
+
+	if totalWeight > 0 {
+		for i := range scores {
+			scores[i] /= totalWeight
+		}
+	}
+	return scores
+}
+
+// mealSimilarity lazily computes and caches cosine similarity between meals
+// based on the customers who interacted with them.
+type mealSimilarity struct {
+	byMeal map[int]map[int]float64
+	norms  map[int]float64
+	cache  map[[2]int]float64
+}
+
+func newMealSimilarity(byMeal map[int]map[int]float64) *mealSimilarity {
+	norms := make(map[int]float64, len(byMeal))
+	for mealID, users := range byMeal {
+		var sum float64
+		for _, a := range users {
+			sum += a * a
+		}
+		norms[mealID] = math.Sqrt(sum)
+	}
+	return &mealSimilarity{byMeal: byMeal, norms: norms, cache: make(map[[2]int]float64)}
+}
+
+func (s *mealSimilarity) get(a, b int) float64 {
+	if a > b {
+		a, b = b, a
+	}
+	key := [2]int{a, b}
+	if v, ok := s.cache[key]; ok {
+		return v
+	}
+
+	var v float64
+	if s.norms[a] > 0 && s.norms[b] > 0 {
+		var dot float64
+		for userID, affinity := range s.byMeal[a] {
+			dot += affinity * s.byMeal[b][userID]
+		}
+		v = dot / (s.norms[a] * s.norms[b])
+	}
+	s.cache[key] = v
+	return v
+}
+
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t] = true
+	}
+	shared := 0
+	for _, t := range b {
+		if set[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
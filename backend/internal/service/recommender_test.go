@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestRankMeals(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	menu := []models.Meal{
+		{ID: 1, DietaryTags: []string{"vegetarian"}},
+		{ID: 2},
+		{ID: 3, DietaryTags: []string{"vegetarian"}},
+	}
+	preferences := map[int][]string{
+		10: nil,
+		11: nil,
+		12: {"vegetarian"},
+		13: nil, // no history
+		14: {"vegetarian"},
+	}
+	interactions := []models.MealInteraction{
+		// Users 10 and 11 both like meals 1 and 3 together
+		{UserID: 10, MealID: 1, OrderCount: 3, Rating: intPtr(5)},
+		{UserID: 10, MealID: 3, OrderCount: 2},
+		{UserID: 11, MealID: 1, OrderCount: 1},
+		{UserID: 11, MealID: 3, OrderCount: 2, Rating: intPtr(5)},
+		{UserID: 11, MealID: 2, OrderCount: 1, Rating: intPtr(1)},
+		// User 12 has only tried meal 1
+		{UserID: 12, MealID: 1, OrderCount: 1},
+	}
+
+	recs := rankMeals(menu, preferences, interactions)
+	if len(recs) != len(menu)*len(preferences) {
+		t.Fatalf("Expected %d recommendations, got %d", len(menu)*len(preferences), len(recs))
+	}
+
+	byUser := make(map[int]map[int]models.MealRecommendation)
+	for _, rec := range recs {
+		if byUser[rec.UserID] == nil {
+			byUser[rec.UserID] = make(map[int]models.MealRecommendation)
+		}
+		byUser[rec.UserID][rec.MealID] = rec
+	}
+
+	// User 12 should be pointed to meal 3, which is liked alongside meal 1
+	if rec := byUser[12][3]; rec.Reason != ReasonSimilarToLiked || rec.Score <= 0 {
+		t.Errorf("Expected a positive similar-to-liked score for meal 3, got %+v", rec)
+	}
+	if rec := byUser[12][1]; rec.Reason != ReasonOrderedBefore {
+		t.Errorf("Expected meal 1 to be ordered before, got %+v", rec)
+	}
+	// Meal 2 isn't vegetarian
+	if rec := byUser[12][2]; rec.Reason != ReasonDietaryMismatch || rec.Score >= byUser[12][3].Score {
+		t.Errorf("Expected meal 2 to be penalised, got %+v", rec)
+	}
+
+	// New user without preferences falls back to popularity
+	if byUser[13][1].Reason != ReasonPopular {
+		t.Errorf("Expected popular reason, got %+v", byUser[13][1])
+	}
+	if byUser[13][1].Score <= byUser[13][2].Score {
+		t.Errorf("Expected meal 1 to outrank poorly rated meal 2, got %+v and %+v", byUser[13][1], byUser[13][2])
+	}
+
+	// New user with preferences leans on customers who share them
+	if byUser[14][1].Reason != ReasonPopularWithSimilar {
+		t.Errorf("Expected popular-with-similar reason, got %+v", byUser[14][1])
+	}
+}
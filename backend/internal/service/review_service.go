@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+type ReviewService interface {
+	Create(ctx context.Context, userID, mealID int, req *models.CreateReviewRequest) (*models.Review, error)
+	GetMealReviews(ctx context.Context, mealID int) ([]models.PublicReview, error)
+	GetAll(ctx context.Context, status string) ([]models.Review, error)
+	Moderate(ctx context.Context, id int, req *models.ModerateReviewRequest) (*models.Review, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type reviewService struct {
+	reviewRepo repository.ReviewRepository
+	orderRepo  repository.OrderRepository
+}
+
+func NewReviewService(reviewRepo repository.ReviewRepository, orderRepo repository.OrderRepository) ReviewService {
+	return &reviewService{
+		reviewRepo: reviewRepo,
+		orderRepo:  orderRepo,
+	}
+}
+
+// Create records a review after verifying the user received the meal in a
+// delivered order.
+func (s *reviewService) Create(ctx context.Context, userID, mealID int, req *models.CreateReviewRequest) (*models.Review, error) {
+	order, err := s.orderRepo.GetByID(ctx, req.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || order.UserID != userID {
+		return nil, errors.New("order not found")
+	}
+	if order.Status != "delivered" {
+		return nil, errors.New("only delivered orders can be reviewed")
+	}
+
+	var ordered bool
+	for _, item := range order.Items {
+		if item.MealID == mealID {
+			ordered = true
+			break
+		}
+	}
+	if !ordered {
+		return nil, errors.New("meal was not part of this order")
+	}
+
+	review := &models.Review{
+		UserID:  userID,
+		MealID:  mealID,
+		OrderID: order.ID,
+		WeekID:  order.WeekID,
+		Rating:  req.Rating,
+		Comment: req.Comment,
+		Status:  "published",
+	}
+
+	if err := s.reviewRepo.Create(ctx, review); err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+// GetMealReviews lists a meal's published reviews for anyone to read.
+func (s *reviewService) GetMealReviews(ctx context.Context, mealID int) ([]models.PublicReview, error) {
+	reviews, err := s.reviewRepo.GetByMealID(ctx, mealID, "published")
+	if err != nil {
+		return nil, err
+	}
+
+	public := make([]models.PublicReview, len(reviews))
+	for i, review := range reviews {
+		public[i] = models.PublicReview{
+			ID:        review.ID,
+			MealID:    review.MealID,
+			Rating:    review.Rating,
+			Comment:   review.Comment,
+			CreatedAt: review.CreatedAt,
+			UpdatedAt: review.UpdatedAt,
+		}
+	}
+	return public, nil
+}
+
+func (s *reviewService) GetAll(ctx context.Context, status string) ([]models.Review, error) {
+	return s.reviewRepo.GetAll(ctx, status)
+}
+
+func (s *reviewService) Moderate(ctx context.Context, id int, req *models.ModerateReviewRequest) (*models.Review, error) {
+	if err := s.reviewRepo.UpdateStatus(ctx, id, req.Status); err != nil {
+		return nil, err
+	}
+	return s.reviewRepo.GetByID(ctx, id)
+}
+
+func (s *reviewService) Delete(ctx context.Context, id int) error {
+	return s.reviewRepo.Delete(ctx, id)
+}
+
+// attachRatings sets the aggregate rating on each meal that has published reviews.
+func attachRatings(ctx context.Context, reviewRepo repository.ReviewRepository, meals []*models.Meal) error {
+	if len(meals) == 0 {
+		return nil
+	}
+
+	mealIDs := make([]int, len(meals))
+	for i, meal := range meals {
+		mealIDs[i] = meal.ID
+	}
+
+	ratings, err := reviewRepo.GetRatings(ctx, mealIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, meal := range meals {
+		if rating, ok := ratings[meal.ID]; ok {
+			rating.Average = math.Round(rating.Average*10) / 10
+			meal.Rating = &rating
+		}
+	}
+	return nil
+}
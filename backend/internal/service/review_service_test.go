@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+// fakeReviews is a ReviewRepository that only lists a meal's reviews.
+type fakeReviews struct {
+	repository.ReviewRepository
+	reviews []models.Review
+}
+
+func (f fakeReviews) GetByMealID(ctx context.Context, mealID int, status string) ([]models.Review, error) {
+	return f.reviews, nil
+}
+
+func TestGetMealReviewsHidesCustomers(t *testing.T) {
+	s := &reviewService{reviewRepo: fakeReviews{reviews: []models.Review{
+		{ID: 1, UserID: 7, MealID: 3, OrderID: 42, WeekID: 5, Rating: 4, Comment: "Tasty", Status: "published"},
+	}}}
+
+	reviews, err := s.GetMealReviews(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("GetMealReviews: %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].ID != 1 || reviews[0].Rating != 4 || reviews[0].Comment != "Tasty" {
+		t.Fatalf("Expected the published review, got %+v", reviews)
+	}
+
+	body, _ := json.Marshal(reviews)
+	for _, field := range []string{"user_id", "order_id"} {
+		if strings.Contains(string(body), field) {
+			t.Errorf("Expected no %s in public reviews, got %s", field, body)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+// SuperAdminRole holds every permission and cannot be changed, so there is
+// always a role that can manage the others.
+const SuperAdminRole = "super_admin"
+
+// DefaultRole is given to new users: a customer with no staff permissions.
+const DefaultRole = "user"
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,19}$`)
+
+type RoleService interface {
+	GetAll(ctx context.Context) ([]models.Role, error)
+	GetByName(ctx context.Context, name string) (*models.Role, error)
+	GetPermissions(ctx context.Context) ([]models.Permission, error)
+	Create(ctx context.Context, req *models.CreateRoleRequest) (*models.Role, error)
+	Update(ctx context.Context, name string, req *models.UpdateRoleRequest) (*models.Role, error)
+	Delete(ctx context.Context, name string) error
+}
+
+type roleService struct {
+	repo repository.RoleRepository
+}
+
+func NewRoleService(repo repository.RoleRepository) RoleService {
+	return &roleService{repo: repo}
+}
+
+func (s *roleService) GetAll(ctx context.Context) ([]models.Role, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *roleService) GetByName(ctx context.Context, name string) (*models.Role, error) {
+	role, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, errors.New("role not found")
+	}
+	return role, nil
+}
+
+func (s *roleService) GetPermissions(ctx context.Context) ([]models.Permission, error) {
+	return s.repo.GetPermissions(ctx)
+}
+
+func (s *roleService) Create(ctx context.Context, req *models.CreateRoleRequest) (*models.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, errors.New("role name must be lower-case letters, digits and underscores, starting with a letter")
+	}
+	existing, err := s.repo.GetByName(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("role already exists")
+	}
+	if err := s.checkPermissions(ctx, req.Permissions); err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}
+	if err := s.repo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+	return s.GetByName(ctx, role.Name)
+}
+
+func (s *roleService) Update(ctx context.Context, name string, req *models.UpdateRoleRequest) (*models.Role, error) {
+	if name == SuperAdminRole {
+		return nil, errors.New("the super_admin role cannot be changed")
+	}
+	if err := s.checkPermissions(ctx, req.Permissions); err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}
+	if err := s.repo.Update(ctx, role); err != nil {
+		return nil, err
+	}
+	return s.GetByName(ctx, name)
+}
+
+// Delete removes a role no one holds. The built-in super_admin and user
+// roles cannot be deleted.
+func (s *roleService) Delete(ctx context.Context, name string) error {
+	if name == SuperAdminRole || name == DefaultRole {
+		return fmt.Errorf("the %s role cannot be deleted", name)
+	}
+
+	role, err := s.GetByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if role.UserCount > 0 {
+		return errors.New("role is still assigned to users")
+	}
+
+	return s.repo.Delete(ctx, name)
+}
+
+// checkPermissions makes sure every permission exists.
+func (s *roleService) checkPermissions(ctx context.Context, permissions []string) error {
+	known, err := s.repo.GetPermissions(ctx)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(known))
+	for _, p := range known {
+		names[p.Name] = true
+	}
+	for _, p := range permissions {
+		if !names[p] {
+			return fmt.Errorf("unknown permission: %s", p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package service
+
+import "testing"
+
+func TestRoleNamePattern(t *testing.T) {
+	valid := []string{"kitchen", "super_admin", "driver2", "a"}
+	invalid := []string{"", "Kitchen", "2driver", "night-shift", "a_role_name_that_is_too_long"}
+
+	for _, name := range valid {
+		if !roleNamePattern.MatchString(name) {
+			t.Errorf("Expected %q to be a valid role name", name)
+		}
+	}
+	for _, name := range invalid {
+		if roleNamePattern.MatchString(name) {
+			t.Errorf("Expected %q to be an invalid role name", name)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jopari/preptoplate/internal/config"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+	"github.com/jopari/preptoplate/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// TwoFactorIssuer names the account in authenticator apps
+	TwoFactorIssuer = "PrepToPlate"
+	// RecoveryCodeCount is how many single-use recovery codes a user gets
+	RecoveryCodeCount = 10
+)
+
+type TwoFactorService interface {
+	Status(ctx context.Context, userID int) (*models.TwoFactorStatus, error)
+	Setup(ctx context.Context, userID int) (*models.TwoFactorSetupResponse, error)
+	Enable(ctx context.Context, userID, sessionID int, code string) (*models.RecoveryCodesResponse, error)
+	Disable(ctx context.Context, userID int, req *models.DisableTwoFactorRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int, code string) (*models.RecoveryCodesResponse, error)
+}
+
+type twoFactorService struct {
+	repo        repository.TwoFactorRepository
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	roleRepo    repository.RoleRepository
+	config      *config.Config
+}
+
+func NewTwoFactorService(repo repository.TwoFactorRepository, userRepo repository.UserRepository, sessionRepo repository.SessionRepository, roleRepo repository.RoleRepository, cfg *config.Config) TwoFactorService {
+	return &twoFactorService{repo: repo, userRepo: userRepo, sessionRepo: sessionRepo, roleRepo: roleRepo, config: cfg}
+}
+
+func (s *twoFactorService) Status(ctx context.Context, userID int) (*models.TwoFactorStatus, error) {
+	user, settings, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := s.required(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorStatus{
+		Enabled:                settings.EnabledAt != nil,
+		EnabledAt:              settings.EnabledAt,
+		RecoveryCodesRemaining: settings.RecoveryCodesRemaining,
+		Required:               required,
+	}, nil
+}
+
+// Setup starts enrolment with a new secret. Two-factor authentication is
+// not on until Enable confirms a code from the authenticator app.
+func (s *twoFactorService) Setup(ctx context.Context, userID int) (*models.TwoFactorSetupResponse, error) {
+	user, settings, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.EnabledAt != nil {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetPendingSecret(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(secret, TwoFactorIssuer, user.Email),
+	}, nil
+}
+
+// Enable confirms enrolment with a code from the authenticator app and
+// returns the recovery codes. The current session counts as signed in with
+// two factors; every other session is signed out.
+func (s *twoFactorService) Enable(ctx context.Context, userID, sessionID int, code string) (*models.RecoveryCodesResponse, error) {
+	_, settings, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.EnabledAt != nil {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if settings.Secret == nil {
+		return nil, errors.New("start two-factor setup first")
+	}
+
+	step, ok := utils.ValidateTOTP(*settings.Secret, code, time.Now())
+	if !ok {
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Enable(ctx, userID, sessionID, step, hashes); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.RevokeOthersForUser(ctx, userID, sessionID, "two_factor_enabled"); err != nil {
+		return nil, err
+	}
+
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable turns two-factor authentication off. It needs the password and a
+// current code, and is refused where the policy requires it.
+func (s *twoFactorService) Disable(ctx context.Context, userID int, req *models.DisableTwoFactorRequest) error {
+	user, settings, err := s.load(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if settings.EnabledAt == nil {
+		return errors.New("two-factor authentication is not enabled")
+	}
+	required, err := s.required(ctx, user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return errors.New("two-factor authentication is required for your account")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return errors.New("password is incorrect")
+	}
+	ok, err := verifySecondFactor(ctx, s.repo, userID, settings, req.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("invalid two-factor code")
+	}
+
+	return s.repo.Disable(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, e.g. when
+// they are running out.
+func (s *twoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) (*models.RecoveryCodesResponse, error) {
+	_, settings, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.EnabledAt == nil {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	ok, err := verifySecondFactor(ctx, s.repo, userID, settings, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *twoFactorService) load(ctx context.Context, userID int) (*models.User, *models.TwoFactorSettings, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, errors.New("user not found")
+	}
+
+	settings, err := s.repo.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if settings == nil {
+		return nil, nil, errors.New("user not found")
+	}
+	return user, settings, nil
+}
+
+// required reports whether policy forces two-factor authentication on the
+// user, which it does for staff: anyone whose role has permissions.
+func (s *twoFactorService) required(ctx context.Context, user *models.User) (bool, error) {
+	if !s.config.RequireAdmin2FA {
+		return false, nil
+	}
+	role, err := s.roleRepo.GetByName(ctx, user.Role)
+	if err != nil {
+		return false, err
+	}
+	return role != nil && len(role.Permissions) > 0, nil
+}
+
+// verifySecondFactor checks an authenticator code or, failing that, a
+// recovery code, and uses it up so it cannot be replayed.
+func verifySecondFactor(ctx context.Context, repo repository.TwoFactorRepository, userID int, settings *models.TwoFactorSettings, code string) (bool, error) {
+	if settings == nil || settings.Secret == nil || settings.EnabledAt == nil {
+		return false, nil
+	}
+
+	if step, ok := utils.ValidateTOTP(*settings.Secret, code, time.Now()); ok {
+		return repo.UseTOTPStep(ctx, userID, step)
+	}
+
+	normalised := normaliseRecoveryCode(code)
+	if normalised == "" {
+		return false, nil
+	}
+	return repo.UseRecoveryCode(ctx, userID, utils.HashToken(normalised))
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateRecoveryCodes returns n random codes formatted for display, e.g.
+// "abcde-fghij", along with the hashes to store.
+func generateRecoveryCodes(n int) ([]string, []string, error) {
+	codes := make([]string, 0, n)
+	hashes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, utils.HashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normaliseRecoveryCode lets users type recovery codes in any case, with or
+// without the dash.
+func normaliseRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
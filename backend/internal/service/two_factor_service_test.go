@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/config"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+	"github.com/jopari/preptoplate/internal/utils"
+)
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		t.Fatalf("generateRecoveryCodes: %v", err)
+	}
+	if len(codes) != RecoveryCodeCount || len(hashes) != RecoveryCodeCount {
+		t.Fatalf("Expected %d codes and hashes, got %d and %d", RecoveryCodeCount, len(codes), len(hashes))
+	}
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("Expected code like abcde-fghij, got %q", code)
+		}
+		if seen[code] {
+			t.Errorf("Duplicate code %q", code)
+		}
+		seen[code] = true
+
+		// The stored hash matches however the user types the code
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+		if utils.HashToken(normaliseRecoveryCode(typed)) != hashes[i] {
+			t.Errorf("Hash of %q does not match the stored hash", typed)
+		}
+	}
+}
+
+// fakeRoles is a RoleRepository that only looks roles up by name.
+type fakeRoles struct {
+	repository.RoleRepository
+	roles map[string]*models.Role
+}
+
+func (f fakeRoles) GetByName(ctx context.Context, name string) (*models.Role, error) {
+	return f.roles[name], nil
+}
+
+func TestTwoFactorRequired(t *testing.T) {
+	roles := fakeRoles{roles: map[string]*models.Role{
+		"customer": {Name: "customer"},
+		"kitchen":  {Name: "kitchen", Permissions: []string{"production:read"}},
+		"driver":   {Name: "driver", Permissions: []string{"deliveries:read"}},
+		"support":  {Name: "support", Permissions: []string{"orders:read"}},
+		"admin":    {Name: "admin", Permissions: []string{"orders:read", "orders:write"}},
+	}}
+
+	tests := []struct {
+		role string
+		want bool
+	}{
+		{"customer", false},
+		{"kitchen", true},
+		{"driver", true},
+		{"support", true},
+		{"admin", true},
+		{"deleted_role", false},
+	}
+
+	s := &twoFactorService{roleRepo: roles, config: &config.Config{RequireAdmin2FA: true}}
+	for _, tt := range tests {
+		got, err := s.required(context.Background(), &models.User{Role: tt.role})
+		if err != nil {
+			t.Fatalf("required(%s): %v", tt.role, err)
+		}
+		if got != tt.want {
+			t.Errorf("required(%s) = %v, want %v", tt.role, got, tt.want)
+		}
+	}
+
+	// Without the setting nobody has to
+	s.config.RequireAdmin2FA = false
+	if got, _ := s.required(context.Background(), &models.User{Role: "admin"}); got {
+		t.Error("Expected two-factor not to be required when RequireAdmin2FA is off")
+	}
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+// Page sizes for the admin user list
+const (
+	DefaultUserPageSize = 20
+	MaxUserPageSize     = 100
+)
+
+// UserService is admin management of user accounts. Methods that change a
+// user take the acting admin's ID and role, so admins cannot lock
+// themselves out or hand out more access than they have.
+type UserService interface {
+	List(ctx context.Context, filter *models.UserFilter) (*models.UserList, error)
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	Create(ctx context.Context, actorRole string, req *models.AdminCreateUserRequest) (*models.User, error)
+	SetRole(ctx context.Context, actorID int, actorRole string, id int, role string) (*models.User, error)
+	Disable(ctx context.Context, actorID int, actorRole string, id int) (*models.User, error)
+	Enable(ctx context.Context, actorRole string, id int) (*models.User, error)
+	ForcePasswordReset(ctx context.Context, actorRole string, id int) error
+}
+
+type userService struct {
+	repo        repository.UserRepository
+	roleRepo    repository.RoleRepository
+	sessionRepo repository.SessionRepository
+	authService AuthService
+}
+
+func NewUserService(repo repository.UserRepository, roleRepo repository.RoleRepository, sessionRepo repository.SessionRepository, authService AuthService) UserService {
+	return &userService{repo: repo, roleRepo: roleRepo, sessionRepo: sessionRepo, authService: authService}
+}
+
+func (s *userService) List(ctx context.Context, filter *models.UserFilter) (*models.UserList, error) {
+	if filter.Status != "" && filter.Status != "active" && filter.Status != "disabled" {
+		return nil, errors.New("status must be active or disabled")
+	}
+	filter.Page, filter.Limit = normalisePage(filter.Page, filter.Limit)
+
+	users, total, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &models.UserList{Users: users, Total: total, Page: filter.Page, Limit: filter.Limit}, nil
+}
+
+func (s *userService) GetByID(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// Create makes an account with a verified email and no usable password,
+// and emails the user a link to set one.
+func (s *userService) Create(ctx context.Context, actorRole string, req *models.AdminCreateUserRequest) (*models.User, error) {
+	role := req.Role
+	if role == "" {
+		role = DefaultRole
+	}
+	if err := s.checkCanAssign(ctx, actorRole, role); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("email already in use")
+	}
+
+	passwordHash, err := unusablePasswordHash()
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{
+		Email:        req.Email,
+		PasswordHash: passwordHash,
+		Role:         role,
+	}
+	if err := s.repo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	if err := s.repo.MarkEmailVerified(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if err := s.authService.ForgotPassword(ctx, user.Email); err != nil {
+		log.Printf("Failed to send password link to new user %d: %v", user.ID, err)
+	}
+
+	return s.GetByID(ctx, user.ID)
+}
+
+// SetRole changes a user's role. It takes effect on their next request.
+func (s *userService) SetRole(ctx context.Context, actorID int, actorRole string, id int, role string) (*models.User, error) {
+	if id == actorID {
+		return nil, errors.New("you cannot change your own role")
+	}
+	user, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, errors.New("account has been deleted")
+	}
+	if err := s.checkCanAssign(ctx, actorRole, user.Role); err != nil {
+		return nil, err
+	}
+	if err := s.checkCanAssign(ctx, actorRole, role); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateRole(ctx, id, role); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, id)
+}
+
+// Disable stops the user signing in and ends all their sessions.
+func (s *userService) Disable(ctx context.Context, actorID int, actorRole string, id int) (*models.User, error) {
+	if id == actorID {
+		return nil, errors.New("you cannot disable your own account")
+	}
+	user, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCanAssign(ctx, actorRole, user.Role); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetDisabled(ctx, id, true); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.RevokeAllForUser(ctx, id, "account_disabled"); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, id)
+}
+
+// Enable lets a disabled user sign in again.
+func (s *userService) Enable(ctx context.Context, actorRole string, id int) (*models.User, error) {
+	user, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Deleted accounts stay disabled; their personal data is gone
+	if user.DeletedAt != nil {
+		return nil, errors.New("account has been deleted")
+	}
+	if err := s.checkCanAssign(ctx, actorRole, user.Role); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetDisabled(ctx, id, false); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, id)
+}
+
+// ForcePasswordReset signs the user out everywhere and emails them a reset
+// link. They cannot log in with their old password in the meantime.
+func (s *userService) ForcePasswordReset(ctx context.Context, actorRole string, id int) error {
+	user, err := s.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt != nil {
+		return errors.New("account has been deleted")
+	}
+	if err := s.checkCanAssign(ctx, actorRole, user.Role); err != nil {
+		return err
+	}
+
+	if err := s.repo.RequirePasswordReset(ctx, id); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.RevokeAllForUser(ctx, id, "password_reset_required"); err != nil {
+		return err
+	}
+	return s.authService.ForgotPassword(ctx, user.Email)
+}
+
+// checkCanAssign checks that the role exists and that the acting admin may
+// manage users who hold it: roles that can manage roles are reserved for
+// admins who can themselves.
+func (s *userService) checkCanAssign(ctx context.Context, actorRole, role string) error {
+	target, err := s.roleRepo.GetByName(ctx, role)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return errors.New("role not found")
+	}
+
+	managesRoles, err := s.roleRepo.HasPermission(ctx, role, "roles:write")
+	if err != nil {
+		return err
+	}
+	if !managesRoles {
+		return nil
+	}
+	allowed, err := s.roleRepo.HasPermission(ctx, actorRole, "roles:write")
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.New("managing users with this role needs the roles:write permission")
+	}
+	return nil
+}
+
+// normalisePage fills in a missing page or page size and caps the size.
+func normalisePage(page, limit int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = DefaultUserPageSize
+	}
+	if limit > MaxUserPageSize {
+		limit = MaxUserPageSize
+	}
+	return page, limit
+}
@@ -0,0 +1,22 @@
+package service
+
+import "testing"
+
+func TestNormalisePage(t *testing.T) {
+	tests := []struct {
+		page, limit         int
+		wantPage, wantLimit int
+	}{
+		{0, 0, 1, DefaultUserPageSize},
+		{3, 50, 3, 50},
+		{-1, -5, 1, DefaultUserPageSize},
+		{2, 1000, 2, MaxUserPageSize},
+	}
+
+	for _, tt := range tests {
+		page, limit := normalisePage(tt.page, tt.limit)
+		if page != tt.wantPage || limit != tt.wantLimit {
+			t.Errorf("normalisePage(%d, %d) = %d, %d, want %d, %d", tt.page, tt.limit, page, limit, tt.wantPage, tt.wantLimit)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"sort"
 	"time"
 
 	"github.com/jopari/preptoplate/internal/models"
@@ -13,21 +14,37 @@ type WeeklyMenuService interface {
 	Create(ctx context.Context, req *models.CreateWeeklyMenuRequest) (*models.WeeklyMenu, error)
 	GetByID(ctx context.Context, id int) (*models.WeeklyMenu, error)
 	GetAll(ctx context.Context) ([]models.WeeklyMenu, error)
-	GetActive(ctx context.Context) (*models.WeeklyMenu, error)
+	GetActive(ctx context.Context, userID int, languages []string) (*models.WeeklyMenu, error)
+	GetActiveRecommended(ctx context.Context, userID int, languages []string) (*models.WeeklyMenu, error)
 	Update(ctx context.Context, id int, req *models.UpdateWeeklyMenuRequest) (*models.WeeklyMenu, error)
 	Delete(ctx context.Context, id int) error
 	Activate(ctx context.Context, id int) error
 }
 
 type weeklyMenuService struct {
-	menuRepo repository.WeeklyMenuRepository
-	mealRepo repository.MealRepository
+	menuRepo    repository.WeeklyMenuRepository
+	mealRepo    repository.MealRepository
+	variantRepo repository.MealVariantRepository
+	addonRepo   repository.AddonRepository
+	reviewRepo  repository.ReviewRepository
+	recRepo     repository.RecommendationRepository
+	priceRepo   repository.PriceRepository
+
+	translationRepo repository.MealTranslationRepository
+	defaultLanguage string
 }
 
-func NewWeeklyMenuService(menuRepo repository.WeeklyMenuRepository, mealRepo repository.MealRepository) WeeklyMenuService {
+func NewWeeklyMenuService(menuRepo repository.WeeklyMenuRepository, mealRepo repository.MealRepository, variantRepo repository.MealVariantRepository, addonRepo repository.AddonRepository, reviewRepo repository.ReviewRepository, recRepo repository.RecommendationRepository, priceRepo repository.PriceRepository, translationRepo repository.MealTranslationRepository, defaultLanguage string) WeeklyMenuService {
 	return &weeklyMenuService{
-		menuRepo: menuRepo,
-		mealRepo: mealRepo,
+		menuRepo:        menuRepo,
+		mealRepo:        mealRepo,
+		variantRepo:     variantRepo,
+		addonRepo:       addonRepo,
+		reviewRepo:      reviewRepo,
+		recRepo:         recRepo,
+		priceRepo:       priceRepo,
+		translationRepo: translationRepo,
+		defaultLanguage: normalizeDefaultLanguage(defaultLanguage),
 	}
 }
 
@@ -38,15 +55,12 @@ func (s *weeklyMenuService) Create(ctx context.Context, req *models.CreateWeekly
 		return nil, errors.New("invalid date format, use YYYY-MM-DD")
 	}
 
-	// Validate all meals exist
-	for _, mealInput := range req.Meals {
-		meal, err := s.mealRepo.GetByID(ctx, mealInput.MealID)
-		if err != nil {
-			return nil, err
-		}
-		if meal == nil {
-			return nil, errors.New("meal not found")
-		}
+	// Validate all meals, variants and add-ons exist
+	if err := s.validateMenuMeals(ctx, req.Meals); err != nil {
+		return nil, err
+	}
+	if err := s.validateMenuAddons(ctx, req.Addons); err != nil {
+		return nil, err
 	}
 
 	// Create menu
@@ -66,6 +80,21 @@ func (s *weeklyMenuService) Create(ctx context.Context, req *models.CreateWeekly
 		if err != nil {
 			return nil, err
 		}
+
+		for _, variantInput := range mealInput.Variants {
+			err = s.menuRepo.AddVariant(ctx, menu.ID, variantInput.VariantID, variantInput.Stock)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Add add-ons to menu
+	for _, addonInput := range req.Addons {
+		err = s.menuRepo.AddAddon(ctx, menu.ID, addonInput.AddonID, addonInput.Stock)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Return full menu with meals
@@ -80,6 +109,10 @@ func (s *weeklyMenuService) GetByID(ctx context.Context, id int) (*models.Weekly
 	if menu == nil {
 		return nil, errors.New("weekly menu not found")
 	}
+
+	if err := s.attachMenuRatings(ctx, menu); err != nil {
+		return nil, err
+	}
 	return menu, nil
 }
 
@@ -87,8 +120,80 @@ func (s *weeklyMenuService) GetAll(ctx context.Context) ([]models.WeeklyMenu, er
 	return s.menuRepo.GetAll(ctx)
 }
 
-func (s *weeklyMenuService) GetActive(ctx context.Context) (*models.WeeklyMenu, error) {
-	return s.menuRepo.GetActive(ctx)
+// GetActive returns the active menu with the meals' text in the first of the
+// preferred languages each has been translated into. Translations are not
+// versioned, so a translated field shows the meal's latest translation even
+// though the rest of the menu shows the version pinned when it was built.
+// A signed-in subscriber (userID other than 0) sees their plan's prices.
+func (s *weeklyMenuService) GetActive(ctx context.Context, userID int, languages []string) (*models.WeeklyMenu, error) {
+	menu, err := s.menuRepo.GetActive(ctx)
+	if err != nil || menu == nil {
+		return menu, err
+	}
+
+	if err := s.attachMenuRatings(ctx, menu); err != nil {
+		return nil, err
+	}
+	if err := localizeMeals(ctx, s.translationRepo, s.defaultLanguage, languages, menuMeals(menu)); err != nil {
+		return nil, err
+	}
+	if err := applyPlanPrices(ctx, s.priceRepo, userID, menuMeals(menu), menuVariants(menu)); err != nil {
+		return nil, err
+	}
+	return menu, nil
+}
+
+// GetActiveRecommended returns the active menu ranked for the user by the
+// precomputed recommendations. Meals without a recommendation yet keep their
+// usual order after the ranked ones.
+func (s *weeklyMenuService) GetActiveRecommended(ctx context.Context, userID int, languages []string) (*models.WeeklyMenu, error) {
+	menu, err := s.GetActive(ctx, userID, languages)
+	if err != nil || menu == nil {
+		return menu, err
+	}
+
+	recommendations, err := s.recRepo.GetForUser(ctx, userID, menu.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range menu.Meals {
+		if rec, ok := recommendations[menu.Meals[i].Meal.ID]; ok {
+			menu.Meals[i].Recommendation = &rec
+		}
+	}
+
+	sort.SliceStable(menu.Meals, func(a, b int) bool {
+		ra, rb := menu.Meals[a].Recommendation, menu.Meals[b].Recommendation
+		if ra == nil || rb == nil {
+			return ra != nil && rb == nil
+		}
+		return ra.Score > rb.Score
+	})
+
+	return menu, nil
+}
+
+func (s *weeklyMenuService) attachMenuRatings(ctx context.Context, menu *models.WeeklyMenu) error {
+	return attachRatings(ctx, s.reviewRepo, menuMeals(menu))
+}
+
+func menuMeals(menu *models.WeeklyMenu) []*models.Meal {
+	meals := make([]*models.Meal, len(menu.Meals))
+	for i := range menu.Meals {
+		meals[i] = &menu.Meals[i].Meal
+	}
+	return meals
+}
+
+func menuVariants(menu *models.WeeklyMenu) []*models.MealVariant {
+	var variants []*models.MealVariant
+	for i := range menu.Meals {
+		for j := range menu.Meals[i].Variants {
+			variants = append(variants, &menu.Meals[i].Variants[j].Variant)
+		}
+	}
+	return variants
 }
 
 func (s *weeklyMenuService) Activate(ctx context.Context, id int) error {
@@ -114,15 +219,12 @@ func (s *weeklyMenuService) Update(ctx context.Context, id int, req *models.Upda
 		return nil, errors.New("invalid date format, use YYYY-MM-DD")
 	}
 
-	// Validate all meals exist
-	for _, mealInput := range req.Meals {
-		meal, err := s.mealRepo.GetByID(ctx, mealInput.MealID)
-		if err != nil {
-			return nil, err
-		}
-		if meal == nil {
-			return nil, errors.New("meal not found")
-		}
+	// Validate all meals, variants and add-ons exist
+	if err := s.validateMenuMeals(ctx, req.Meals); err != nil {
+		return nil, err
+	}
+	if err := s.validateMenuAddons(ctx, req.Addons); err != nil {
+		return nil, err
 	}
 
 	// Prepare updated menu
@@ -136,6 +238,20 @@ func (s *weeklyMenuService) Update(ctx context.Context, id int, req *models.Upda
 			Meal:         models.Meal{ID: mealInput.MealID},
 			InitialStock: mealInput.Stock,
 		}
+
+		for _, variantInput := range mealInput.Variants {
+			menu.Meals[i].Variants = append(menu.Meals[i].Variants, models.WeeklyMenuVariant{
+				Variant:      models.MealVariant{ID: variantInput.VariantID},
+				InitialStock: variantInput.Stock,
+			})
+		}
+	}
+
+	for _, addonInput := range req.Addons {
+		menu.Addons = append(menu.Addons, models.WeeklyMenuAddon{
+			Addon:        models.Addon{ID: addonInput.AddonID},
+			InitialStock: addonInput.Stock,
+		})
 	}
 
 	err = s.menuRepo.Update(ctx, id, menu)
@@ -161,3 +277,40 @@ func (s *weeklyMenuService) Delete(ctx context.Context, id int) error {
 
 	return s.menuRepo.Delete(ctx, id)
 }
+
+// validateMenuMeals checks every meal exists and every variant belongs to its meal.
+func (s *weeklyMenuService) validateMenuMeals(ctx context.Context, inputs []models.MenuMealInput) error {
+	for _, mealInput := range inputs {
+		meal, err := s.mealRepo.GetByID(ctx, mealInput.MealID)
+		if err != nil {
+			return err
+		}
+		if meal == nil {
+			return errors.New("meal not found")
+		}
+
+		for _, variantInput := range mealInput.Variants {
+			variant, err := s.variantRepo.GetByID(ctx, variantInput.VariantID)
+			if err != nil {
+				return err
+			}
+			if variant == nil || variant.MealID != mealInput.MealID {
+				return errors.New("meal variant not found")
+			}
+		}
+	}
+	return nil
+}
+
+func (s *weeklyMenuService) validateMenuAddons(ctx context.Context, inputs []models.MenuAddonInput) error {
+	for _, addonInput := range inputs {
+		addon, err := s.addonRepo.GetByID(ctx, addonInput.AddonID)
+		if err != nil {
+			return err
+		}
+		if addon == nil {
+			return errors.New("add-on not found")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B test vectors (SHA-1), truncated to six digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(secret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+	now := time.Now()
+	step := TOTPStep(now)
+
+	code, _ := TOTPCode(secret, step-1)
+	if got, ok := ValidateTOTP(secret, code, now); !ok || got != step-1 {
+		t.Errorf("Expected previous step's code to be accepted, got step %d ok %v", got, ok)
+	}
+
+	code, _ = TOTPCode(secret, step+2)
+	if _, ok := ValidateTOTP(secret, code, now); ok {
+		t.Error("Expected code outside the allowed skew to be rejected")
+	}
+
+	if _, ok := ValidateTOTP(secret, "12345", now); ok {
+		t.Error("Expected short code to be rejected")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("JBSWY3DPEHPK3PXP", "PrepToPlate", "admin@preptoplate.com")
+	if !strings.HasPrefix(uri, "otpauth://totp/PrepToPlate:admin@preptoplate.com?") {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+	for _, param := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=PrepToPlate", "digits=6", "period=30"} {
+		if !strings.Contains(uri, param) {
+			t.Errorf("Expected URI to contain %s, got %s", param, uri)
+		}
+	}
+}
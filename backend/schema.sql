@@ -6,6 +6,27 @@ CREATE TABLE IF NOT EXISTS users (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+ALTER TABLE users ADD COLUMN IF NOT EXISTS dietary_preferences TEXT[] NOT NULL DEFAULT '{}'; -- e.g., "vegetarian"
+-- Accounts that existed before verification was introduced count as verified;
+-- new accounts start unverified
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ DEFAULT NOW();
+ALTER TABLE users ALTER COLUMN email_verified_at DROP DEFAULT;
+-- TOTP two-factor authentication. The secret is set at enrolment and only
+-- takes effect once confirmed (totp_enabled_at).
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT; -- last accepted time step, so a code cannot be replayed
+-- Profile: contact details, default delivery address and communication
+-- preferences
+ALTER TABLE users ADD COLUMN IF NOT EXISTS name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS address_line1 VARCHAR(200);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS address_line2 VARCHAR(200);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS address_city VARCHAR(100);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS address_postcode VARCHAR(20);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS address_instructions TEXT; -- for the driver, e.g., "leave with neighbour"
+ALTER TABLE users ADD COLUMN IF NOT EXISTS order_update_emails BOOLEAN NOT NULL DEFAULT TRUE;
+
 CREATE TABLE IF NOT EXISTS meals (
     id SERIAL PRIMARY KEY,
     name VARCHAR(100) NOT NULL,
@@ -18,6 +39,60 @@ CREATE TABLE IF NOT EXISTS meals (
     price INTEGER -- stored in cents
 );
 
+ALTER TABLE meals ADD COLUMN IF NOT EXISTS current_version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE meals ADD COLUMN IF NOT EXISTS dietary_tags TEXT[] NOT NULL DEFAULT '{}'; -- e.g., "vegetarian", "gluten_free"
+ALTER TABLE meals ADD COLUMN IF NOT EXISTS sku VARCHAR(64) UNIQUE; -- stable external identifier for import/export
+UPDATE meals SET sku = 'MEAL-' || id WHERE sku IS NULL;
+ALTER TABLE meals ADD COLUMN IF NOT EXISTS ingredients TEXT[] NOT NULL DEFAULT '{}'; -- in the default language, see meal_translations
+
+CREATE TABLE IF NOT EXISTS meal_versions (
+    id SERIAL PRIMARY KEY,
+    meal_id INTEGER REFERENCES meals(id) ON DELETE CASCADE NOT NULL,
+    version INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    image_url TEXT,
+    calories INTEGER,
+    protein INTEGER,
+    carbs INTEGER,
+    fat INTEGER,
+    price INTEGER, -- stored in cents
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (meal_id, version)
+);
+
+-- Backfill the current state of meals created before versioning
+INSERT INTO meal_versions (meal_id, version, name, description, image_url, calories, protein, carbs, fat, price)
+SELECT id, current_version, name, description, image_url, calories, protein, carbs, fat, price FROM meals
+ON CONFLICT (meal_id, version) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS meal_variants (
+    id SERIAL PRIMARY KEY,
+    meal_id INTEGER REFERENCES meals(id) ON DELETE CASCADE NOT NULL,
+    name VARCHAR(50) NOT NULL, -- e.g., "Large"
+    calories INTEGER NOT NULL DEFAULT 0,
+    protein INTEGER NOT NULL DEFAULT 0,
+    carbs INTEGER NOT NULL DEFAULT 0,
+    fat INTEGER NOT NULL DEFAULT 0,
+    price INTEGER NOT NULL, -- stored in cents
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (meal_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS addons (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    image_url TEXT,
+    category VARCHAR(20) NOT NULL, -- e.g., "snack", "drink", "sauce"
+    calories INTEGER NOT NULL DEFAULT 0,
+    protein INTEGER NOT NULL DEFAULT 0,
+    carbs INTEGER NOT NULL DEFAULT 0,
+    fat INTEGER NOT NULL DEFAULT 0,
+    price INTEGER NOT NULL -- stored in cents
+);
+
 CREATE TABLE IF NOT EXISTS carts (
     id SERIAL PRIMARY KEY,
     user_id INTEGER REFERENCES users(id) UNIQUE NOT NULL,
@@ -33,6 +108,17 @@ CREATE TABLE IF NOT EXISTS cart_items (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES meal_variants(id);
+
+CREATE TABLE IF NOT EXISTS cart_addons (
+    id SERIAL PRIMARY KEY,
+    cart_id INTEGER REFERENCES carts(id) ON DELETE CASCADE,
+    addon_id INTEGER REFERENCES addons(id),
+    quantity INTEGER DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (cart_id, addon_id)
+);
+
 CREATE TABLE IF NOT EXISTS weekly_menus (
     id SERIAL PRIMARY KEY,
     week_start_date DATE NOT NULL,
@@ -47,6 +133,24 @@ CREATE TABLE IF NOT EXISTS menu_meals (
     PRIMARY KEY (menu_id, meal_id)
 );
 
+ALTER TABLE menu_meals ADD COLUMN IF NOT EXISTS meal_version INTEGER;
+
+CREATE TABLE IF NOT EXISTS menu_variant_stock (
+    menu_id INTEGER REFERENCES weekly_menus(id),
+    variant_id INTEGER REFERENCES meal_variants(id),
+    initial_stock INTEGER DEFAULT 100,
+    available_stock INTEGER DEFAULT 100,
+    PRIMARY KEY (menu_id, variant_id)
+);
+
+CREATE TABLE IF NOT EXISTS menu_addons (
+    menu_id INTEGER REFERENCES weekly_menus(id),
+    addon_id INTEGER REFERENCES addons(id),
+    initial_stock INTEGER DEFAULT 100,
+    available_stock INTEGER DEFAULT 100,
+    PRIMARY KEY (menu_id, addon_id)
+);
+
 CREATE TABLE IF NOT EXISTS subscriptions (
     id SERIAL PRIMARY KEY,
     user_id INTEGER REFERENCES users(id),
@@ -71,3 +175,443 @@ CREATE TABLE IF NOT EXISTS order_items (
     quantity INTEGER DEFAULT 1,
     PRIMARY KEY (order_id, meal_id)
 );
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS meal_version INTEGER;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES meal_variants(id);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS price INTEGER; -- unit price at time of order, in cents
+-- The variant as it was ordered, so editing a variant does not change past orders
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_name VARCHAR(50);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_calories INTEGER;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_protein INTEGER;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_carbs INTEGER;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_fat INTEGER;
+UPDATE order_items oi
+SET variant_name = v.name, variant_calories = v.calories, variant_protein = v.protein,
+    variant_carbs = v.carbs, variant_fat = v.fat
+FROM meal_variants v
+WHERE oi.variant_id = v.id AND oi.variant_name IS NULL;
+
+-- An order can contain several variants of the same meal
+-- (and the same meal in and outside a bundle, see order_items_order_meal_variant_bundle_idx)
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_pkey;
+DROP INDEX IF EXISTS order_items_order_meal_variant_idx;
+
+CREATE TABLE IF NOT EXISTS order_addons (
+    order_id INTEGER REFERENCES orders(id),
+    addon_id INTEGER REFERENCES addons(id),
+    quantity INTEGER DEFAULT 1,
+    price INTEGER NOT NULL, -- unit price at time of order, in cents
+    PRIMARY KEY (order_id, addon_id)
+);
+
+CREATE TABLE IF NOT EXISTS meal_reviews (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) NOT NULL,
+    meal_id INTEGER REFERENCES meals(id) ON DELETE CASCADE NOT NULL,
+    order_id INTEGER REFERENCES orders(id) NOT NULL,
+    week_id INTEGER REFERENCES weekly_menus(id) NOT NULL,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'published', -- "published" or "hidden"
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, meal_id, week_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_favourites (
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    meal_id INTEGER REFERENCES meals(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, meal_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_nutrition_goals (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    period VARCHAR(10) NOT NULL DEFAULT 'daily', -- "daily" or "weekly"
+    calories INTEGER NOT NULL DEFAULT 0,
+    protein INTEGER NOT NULL DEFAULT 0,
+    carbs INTEGER NOT NULL DEFAULT 0,
+    fat INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Precomputed by the recommendation job for the active menu
+CREATE TABLE IF NOT EXISTS meal_recommendations (
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    menu_id INTEGER REFERENCES weekly_menus(id) ON DELETE CASCADE,
+    meal_id INTEGER REFERENCES meals(id) ON DELETE CASCADE,
+    score DOUBLE PRECISION NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    computed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, menu_id, meal_id)
+);
+
+-- Every uploaded file, so uploads no longer referenced anywhere can be cleaned up
+CREATE TABLE IF NOT EXISTS uploads (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    storage_key TEXT NOT NULL, -- key in the image storage, e.g. "meals/bowl-1700000000.jpg"
+    filename TEXT NOT NULL,
+    uploaded_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Resized renditions generated for each upload
+CREATE TABLE IF NOT EXISTS upload_variants (
+    upload_id INTEGER REFERENCES uploads(id) ON DELETE CASCADE NOT NULL,
+    name VARCHAR(20) NOT NULL, -- "thumbnail", "card" or "hero"
+    content_type VARCHAR(50) NOT NULL, -- e.g., "image/webp"
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    storage_key TEXT NOT NULL,
+    PRIMARY KEY (upload_id, name, content_type)
+);
+
+CREATE TABLE IF NOT EXISTS meal_images (
+    id SERIAL PRIMARY KEY,
+    meal_id INTEGER REFERENCES meals(id) ON DELETE CASCADE NOT NULL,
+    upload_id INTEGER REFERENCES uploads(id) NOT NULL,
+    alt_text TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (meal_id, upload_id)
+);
+
+-- At most one primary image per meal
+CREATE UNIQUE INDEX IF NOT EXISTS meal_images_primary_idx ON meal_images (meal_id) WHERE is_primary;
+
+-- Meal text in languages other than the default one (DEFAULT_LANGUAGE)
+CREATE TABLE IF NOT EXISTS meal_translations (
+    meal_id INTEGER REFERENCES meals(id) ON DELETE CASCADE NOT NULL,
+    language VARCHAR(35) NOT NULL, -- lower-case language tag, e.g., "fr" or "pt-br"
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    ingredients TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (meal_id, language)
+);
+
+-- Fixed sets of meals from a weekly menu sold at a bundle price
+CREATE TABLE IF NOT EXISTS bundles (
+    id SERIAL PRIMARY KEY,
+    menu_id INTEGER REFERENCES weekly_menus(id) ON DELETE CASCADE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    price INTEGER NOT NULL, -- bundle price, in cents
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS bundle_items (
+    id SERIAL PRIMARY KEY,
+    bundle_id INTEGER REFERENCES bundles(id) ON DELETE CASCADE NOT NULL,
+    meal_id INTEGER REFERENCES meals(id) NOT NULL,
+    variant_id INTEGER REFERENCES meal_variants(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS bundle_items_bundle_meal_variant_idx
+    ON bundle_items (bundle_id, meal_id, COALESCE(variant_id, 0));
+
+CREATE TABLE IF NOT EXISTS cart_bundles (
+    id SERIAL PRIMARY KEY,
+    cart_id INTEGER REFERENCES carts(id) ON DELETE CASCADE,
+    bundle_id INTEGER REFERENCES bundles(id) ON DELETE CASCADE,
+    quantity INTEGER DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (cart_id, bundle_id)
+);
+
+CREATE TABLE IF NOT EXISTS order_bundles (
+    order_id INTEGER REFERENCES orders(id) NOT NULL,
+    bundle_id INTEGER REFERENCES bundles(id) NOT NULL,
+    name VARCHAR(100) NOT NULL, -- at time of order
+    quantity INTEGER NOT NULL,
+    price INTEGER NOT NULL, -- bundle price at time of order, in cents
+    regular_price INTEGER NOT NULL, -- the meals' regular price at time of order, in cents
+    PRIMARY KEY (order_id, bundle_id)
+);
+
+-- Meals bought in a bundle are order items tagged with the bundle, kept apart
+-- from the same meal bought on its own
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS bundle_id INTEGER REFERENCES bundles(id);
+CREATE UNIQUE INDEX IF NOT EXISTS order_items_order_meal_variant_bundle_idx
+    ON order_items (order_id, meal_id, COALESCE(variant_id, 0), COALESCE(bundle_id, 0));
+
+-- Price lists: effective-dated prices for meals and variants. Entries without
+-- a plan make up the standard list; entries with a plan (subscriptions.plan_type)
+-- make up that plan's list. Changing a meal's or variant's price directly adds
+-- a standard entry effective immediately.
+CREATE TABLE IF NOT EXISTS meal_prices (
+    id SERIAL PRIMARY KEY,
+    meal_id INTEGER NOT NULL REFERENCES meals(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES meal_variants(id) ON DELETE CASCADE, -- NULL for the standard portion
+    plan_type VARCHAR(50), -- NULL for the standard list
+    price INTEGER NOT NULL CHECK (price > 0), -- in cents
+    effective_from TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS meal_prices_lookup_idx
+    ON meal_prices (meal_id, variant_id, plan_type, effective_from DESC);
+
+-- The price of a meal, or one of its variants, at a point in time: the
+-- plan's price list first, then the standard list, then p_fallback (the
+-- catalogue price). A NULL fallback means there is no such meal or variant
+-- and gives NULL.
+CREATE OR REPLACE FUNCTION effective_meal_price(
+    p_meal_id INTEGER, p_variant_id INTEGER, p_plan_type VARCHAR, p_at TIMESTAMPTZ, p_fallback INTEGER
+) RETURNS INTEGER LANGUAGE sql STABLE AS $$
+    SELECT CASE WHEN p_fallback IS NULL THEN NULL ELSE COALESCE((
+        SELECT price
+        FROM meal_prices
+        WHERE meal_id = p_meal_id
+          AND variant_id IS NOT DISTINCT FROM p_variant_id
+          AND (plan_type IS NULL OR plan_type = p_plan_type)
+          AND effective_from <= p_at
+        ORDER BY plan_type IS NULL, effective_from DESC, id DESC
+        LIMIT 1
+    ), p_fallback) END
+$$;
+
+-- Login sessions. Each holds a family of rotating refresh tokens; revoking
+-- the session ends the family and the access tokens issued for it.
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ,
+    revoked_reason VARCHAR(50) -- e.g., "logout", "reuse_detected"
+);
+
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS two_factor BOOLEAN NOT NULL DEFAULT FALSE; -- signed in with a second factor
+
+CREATE INDEX IF NOT EXISTS user_sessions_user_idx ON user_sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the token, which is never stored
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ, -- set when exchanged for a new token; using it again revokes the session
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the emailed token
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS password_reset_tokens_user_idx ON password_reset_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the emailed token
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS email_verification_tokens_user_idx ON email_verification_tokens (user_id, created_at);
+
+-- Set when the token confirms a change of email address; the user's email
+-- becomes new_email once the link sent there is followed
+ALTER TABLE email_verification_tokens ADD COLUMN IF NOT EXISTS new_email VARCHAR(100);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL, -- SHA-256 of the normalised code
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
+
+-- The second step of a login for users with two-factor authentication
+CREATE TABLE IF NOT EXISTS two_factor_challenges (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Accounts at external OpenID Connect providers linked to users
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL, -- name from OIDC_PROVIDERS, e.g., "google"
+    subject VARCHAR(255) NOT NULL, -- the provider's stable user ID ("sub")
+    email VARCHAR(100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_idx ON user_identities (user_id);
+
+-- In-flight OIDC logins, between the redirect to the provider and its callback
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state_hash CHAR(64) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL, -- PKCE
+    nonce VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- Failed logins, per account (by email) and per client IP, for backoff and
+-- lockout. Kept here so every server instance sees the same counts.
+CREATE TABLE IF NOT EXISTS login_throttles (
+    scope VARCHAR(10) NOT NULL, -- "account", "ip" or "2fa"
+    subject VARCHAR(100) NOT NULL, -- lower-cased email, IP address or user ID
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (scope, subject)
+);
+
+-- Roles and what they may do. users.role names one of these; "user" is a
+-- customer with no staff permissions. Routes check permissions, never role
+-- names, so roles can be added and changed without a deploy.
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(20) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(20) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('meals:write', 'Manage meals, add-ons, images, translations and prices'),
+    ('menus:write', 'Manage weekly menus, bundles and recommendations'),
+    ('orders:read', 'View all customer orders'),
+    ('orders:write', 'Change order status'),
+    ('production:read', 'View what to cook for a delivery date'),
+    ('deliveries:read', 'View the orders to deliver on a date'),
+    ('reviews:moderate', 'Hide and delete meal reviews'),
+    ('users:write', 'Verify, unlock and manage user accounts'),
+    ('roles:write', 'Manage roles and their permissions')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description) VALUES
+    ('user', 'Customer'),
+    ('admin', 'Runs the shop: everything except managing roles'),
+    ('super_admin', 'Everything, including managing roles'),
+    ('kitchen', 'Kitchen staff'),
+    ('driver', 'Delivery driver'),
+    ('support', 'Customer support, can view but not change orders')
+ON CONFLICT (name) DO NOTHING;
+
+-- super_admin always has every permission, including ones added later
+INSERT INTO role_permissions (role, permission)
+SELECT 'super_admin', name FROM permissions
+ON CONFLICT DO NOTHING;
+
+-- Default permissions for the other built-in roles, given only to roles that
+-- have none so changes made through the admin API are kept
+
+INSERT INTO role_permissions (role, permission)
+SELECT r.role, r.permission
+FROM (VALUES
+    ('admin', 'meals:write'), ('admin', 'menus:write'), ('admin', 'orders:read'),
+    ('admin', 'orders:write'), ('admin', 'production:read'), ('admin', 'deliveries:read'),
+    ('admin', 'reviews:moderate'), ('admin', 'users:write'),
+    ('kitchen', 'production:read'),
+    ('driver', 'deliveries:read'),
+    ('support', 'orders:read')
+) AS r (role, permission)
+WHERE NOT EXISTS (SELECT 1 FROM role_permissions rp WHERE rp.role = r.role)
+ON CONFLICT DO NOTHING;
+
+-- Admin user management: disabled users cannot sign in, and users told to
+-- reset their password cannot sign in with the old one
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Viewing users, a permission new to existing databases, for the roles that
+-- should have it
+WITH added AS (
+    INSERT INTO permissions (name, description)
+    VALUES ('users:read', 'View and search user accounts')
+    ON CONFLICT (name) DO NOTHING
+    RETURNING name
+)
+INSERT INTO role_permissions (role, permission)
+SELECT r.role, added.name
+FROM added, (VALUES ('super_admin'), ('admin'), ('support')) AS r (role)
+ON CONFLICT DO NOTHING;
+
+-- Deleted accounts are anonymised rather than removed, so their orders are
+-- kept for accounting
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- "Download my data" exports. They are built in the background and can be
+-- downloaded until they expire.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- "pending", "ready" or "failed"
+    data BYTEA, -- the export as a JSON document, once ready
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_idx ON data_exports (user_id, created_at);
+
+-- One export at a time: only a user's newest pending export is kept going
+UPDATE data_exports d
+SET status = 'failed', error = 'export was interrupted, please request a new one', completed_at = NOW()
+WHERE d.status = 'pending'
+  AND EXISTS (SELECT 1 FROM data_exports n WHERE n.user_id = d.user_id AND n.status = 'pending' AND n.id > d.id);
+CREATE UNIQUE INDEX IF NOT EXISTS data_exports_pending_idx ON data_exports (user_id) WHERE status = 'pending';
+
+-- Emailed links that confirm an account deletion, for users who have no
+-- password to confirm it with, e.g. those who only sign in with a provider
+CREATE TABLE IF NOT EXISTS account_deletion_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the emailed token
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS account_deletion_tokens_user_idx ON account_deletion_tokens (user_id);
+
+-- The address an order is delivered to, copied from the customer's profile
+-- at checkout so later profile changes do not redirect past orders
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_address_line1 VARCHAR(200);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_address_line2 VARCHAR(200);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_address_city VARCHAR(100);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_address_postcode VARCHAR(20);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_address_instructions TEXT;
+
+-- Orders placed before addresses were copied go to the customer's current one
+UPDATE orders o
+SET delivery_address_line1 = u.address_line1, delivery_address_line2 = u.address_line2,
+    delivery_address_city = u.address_city, delivery_address_postcode = u.address_postcode,
+    delivery_address_instructions = u.address_instructions
+FROM users u
+WHERE u.id = o.user_id AND o.delivery_address_line1 IS NULL AND u.address_line1 IS NOT NULL;
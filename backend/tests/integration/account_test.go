@@ -0,0 +1,168 @@
+package integration
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestDataExportAndAccountDeletion(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+
+	testEmail := "test_account@example.com"
+	otherEmail := "test_account_other@example.com"
+	var userID int
+	defer func() {
+		db.Exec(context.Background(), "DELETE FROM subscriptions WHERE user_id = $1", userID)
+		_, err := db.Exec(context.Background(), "DELETE FROM users WHERE email = ANY($1) OR id = $2", []string{testEmail, otherEmail}, userID)
+		if err != nil {
+			t.Logf("Failed to cleanup test users: %v", err)
+		}
+	}()
+
+	send := func(method, path, token string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	register := func(email string) models.AuthResponse {
+		w := send("POST", "/api/auth/register", "", map[string]string{"email": email, "password": "password123"})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Failed to setup test user. Status: %d", w.Code)
+		}
+		var resp models.AuthResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	registered := register(testEmail)
+	token := registered.Token
+	userID = registered.User.ID
+	other := register(otherEmail)
+
+	if w := send("PUT", "/api/me", token, map[string]string{"name": "Test Customer"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	w := send("POST", "/api/me/data-exports", token, nil)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var export models.DataExport
+	json.Unmarshal(w.Body.Bytes(), &export)
+	downloadPath := "/api/me/data-exports/" + strconv.Itoa(export.ID) + "/download"
+
+	// The export is built in the background
+	deadline := time.Now().Add(10 * time.Second)
+	for export.Status == "pending" && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		var exports []models.DataExport
+		json.Unmarshal(send("GET", "/api/me/data-exports", token, nil).Body.Bytes(), &exports)
+		if len(exports) > 0 {
+			export = exports[0]
+		}
+	}
+	if export.Status != "ready" || export.ExpiresAt == nil {
+		t.Fatalf("Expected the export to be ready, got %+v", export)
+	}
+
+	w = send("GET", downloadPath, token, nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("Expected a ZIP download, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("Expected a valid ZIP archive: %v", err)
+	}
+	names := map[string]bool{}
+	for _, f := range archive.File {
+		names[f.Name] = true
+	}
+	if !names["profile.json"] || !names["orders.json"] {
+		t.Errorf("Expected profile.json and orders.json in the archive, got %v", names)
+	}
+
+	w = send("GET", downloadPath+"?format=json", token, nil)
+	var data models.AccountData
+	if err := json.Unmarshal(w.Body.Bytes(), &data); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected the JSON document, got %d: %v", w.Code, err)
+	}
+	if data.Profile == nil || data.Profile.Name != "Test Customer" {
+		t.Errorf("Expected the user's profile in the export, got %+v", data.Profile)
+	}
+
+	// Other users cannot download it
+	if w := send("GET", downloadPath, other.Token, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another user's export, got %d", w.Code)
+	}
+
+	// Only one export is prepared at a time, even for requests made together
+	pending := "INSERT INTO data_exports (user_id) VALUES ($1)"
+	if _, err := db.Exec(context.Background(), pending, userID); err != nil {
+		t.Fatalf("Failed to setup pending export: %v", err)
+	}
+	if w := send("POST", "/api/me/data-exports", token, nil); w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 with an export pending, got %d", w.Code)
+	}
+	if _, err := db.Exec(context.Background(), pending, userID); err == nil {
+		t.Error("Expected a second pending export to be refused")
+	}
+
+	if _, err := db.Exec(context.Background(), "INSERT INTO subscriptions (user_id, plan_type) VALUES ($1, '10_meals')", userID); err != nil {
+		t.Fatalf("Failed to setup subscription: %v", err)
+	}
+
+	// Deleting the account needs the password
+	if w := send("DELETE", "/api/me", token, map[string]string{"password": "wrong"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a wrong password, got %d", w.Code)
+	}
+	if w := send("DELETE", "/api/me", token, map[string]string{"password": "password123"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Signed out, unable to sign in, and the personal data is gone
+	if w := send("GET", "/api/me", token, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 after deletion, got %d", w.Code)
+	}
+	login := map[string]string{"email": testEmail, "password": "password123"}
+	if w := send("POST", "/api/auth/login", "", login); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 logging in to a deleted account, got %d", w.Code)
+	}
+
+	var email, name string
+	var deleted bool
+	err = db.QueryRow(context.Background(), "SELECT email, name, deleted_at IS NOT NULL FROM users WHERE id = $1", userID).Scan(&email, &name, &deleted)
+	if err != nil {
+		t.Fatalf("Expected the anonymised user to be kept: %v", err)
+	}
+	if email == testEmail || name != "" || !deleted {
+		t.Errorf("Expected anonymised user, got email %q name %q deleted %v", email, name, deleted)
+	}
+
+	var exports int
+	db.QueryRow(context.Background(), "SELECT COUNT(*) FROM data_exports WHERE user_id = $1", userID).Scan(&exports)
+	if exports != 0 {
+		t.Errorf("Expected data exports to be deleted, got %d", exports)
+	}
+
+	var status string
+	db.QueryRow(context.Background(), "SELECT status FROM subscriptions WHERE user_id = $1", userID).Scan(&status)
+	if status != "cancelled" {
+		t.Errorf("Expected the subscription to be cancelled, got %q", status)
+	}
+}
@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestDeleteAddon(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+	ctx := context.Background()
+
+	token := signInStaff(t, r, db, "test_addon_admin@example.com", "admin")
+	var adminID int
+	db.QueryRow(ctx, "SELECT id FROM users WHERE email = $1", "test_addon_admin@example.com").Scan(&adminID)
+
+	send := func(method, path string, payload interface{}, out interface{}) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if out != nil && w.Code < 300 {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+		}
+		return w.Code
+	}
+	create := func(name string) int {
+		var addon models.Addon
+		req := map[string]interface{}{"name": name, "category": "snack", "price": 250}
+		if code := send("POST", "/api/addons", req, &addon); code != http.StatusCreated {
+			t.Fatalf("Failed to create add-on. Status: %d", code)
+		}
+		return addon.ID
+	}
+
+	inCart := create("Test Cart Add-on")
+	onMenu := create("Test Menu Add-on")
+	ordered := create("Test Ordered Add-on")
+
+	var cartID, menuID, orderID int
+	setup := []struct {
+		query string
+		args  []interface{}
+		dest  *int
+	}{
+		{"INSERT INTO carts (user_id) VALUES ($1) RETURNING id", []interface{}{adminID}, &cartID},
+		{"INSERT INTO weekly_menus (week_start_date) VALUES (CURRENT_DATE) RETURNING id", nil, &menuID},
+		{"INSERT INTO orders (user_id, status) VALUES ($1, 'delivered') RETURNING id", []interface{}{adminID}, &orderID},
+	}
+	for _, s := range setup {
+		if err := db.QueryRow(ctx, s.query, s.args...).Scan(s.dest); err != nil {
+			t.Fatalf("Failed to setup test data: %v", err)
+		}
+	}
+	defer func() {
+		for _, query := range []string{
+			"DELETE FROM order_addons WHERE order_id = $1",
+			"DELETE FROM orders WHERE id = $1",
+		} {
+			db.Exec(ctx, query, orderID)
+		}
+		db.Exec(ctx, "DELETE FROM menu_addons WHERE menu_id = $1", menuID)
+		db.Exec(ctx, "DELETE FROM weekly_menus WHERE id = $1", menuID)
+		db.Exec(ctx, "DELETE FROM carts WHERE id = $1", cartID)
+		db.Exec(ctx, "DELETE FROM addons WHERE id = ANY($1)", []int{inCart, onMenu, ordered})
+	}()
+	db.Exec(ctx, "INSERT INTO cart_addons (cart_id, addon_id, quantity) VALUES ($1, $2, 1)", cartID, inCart)
+	db.Exec(ctx, "INSERT INTO menu_addons (menu_id, addon_id) VALUES ($1, $2)", menuID, onMenu)
+	db.Exec(ctx, "INSERT INTO order_addons (order_id, addon_id, quantity, price) VALUES ($1, $2, 1, 250)", orderID, ordered)
+
+	// Add-ons in carts are removed from them
+	if code := send("DELETE", "/api/addons/"+strconv.Itoa(inCart), nil, nil); code != http.StatusOK {
+		t.Errorf("Expected status 200 deleting an add-on in a cart, got %d", code)
+	}
+	var remaining int
+	db.QueryRow(ctx, "SELECT COUNT(*) FROM cart_addons WHERE cart_id = $1", cartID).Scan(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected the add-on to be removed from the cart, %d left", remaining)
+	}
+
+	if code := send("DELETE", "/api/addons/"+strconv.Itoa(onMenu), nil, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 deleting an add-on on a menu, got %d", code)
+	}
+	if code := send("DELETE", "/api/addons/"+strconv.Itoa(ordered), nil, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 deleting an ordered add-on, got %d", code)
+	}
+	if code := send("DELETE", "/api/addons/"+strconv.Itoa(inCart), nil, nil); code != http.StatusNotFound {
+		t.Errorf("Expected status 404 deleting a missing add-on, got %d", code)
+	}
+}
@@ -6,9 +6,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/service"
+	"github.com/jopari/preptoplate/internal/utils"
 )
 
 func TestRegisterUser(t *testing.T) {
@@ -50,6 +55,10 @@ func TestRegisterUser(t *testing.T) {
 					t.Errorf("Expected user email 'test_user@example.com', got '%s'", res.User.Email)
 				}
 
+				if res.User.EmailVerified {
+					t.Error("Expected new account to start unverified")
+				}
+
 				if res.User.ID == 0 {
 					t.Error("Expected user ID to be set, got 0")
 				}
@@ -269,3 +278,438 @@ func TestLoginUser(t *testing.T) {
 		})
 	}
 }
+
+func TestRefreshAndLogout(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+
+	testEmail := "test_refresh_user@example.com"
+	defer func() {
+		_, err := db.Exec(context.Background(), "DELETE FROM users WHERE email = $1", testEmail)
+		if err != nil {
+			t.Logf("Failed to cleanup test user: %v", err)
+		}
+	}()
+
+	post := func(path, token string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) models.AuthResponse {
+		var res models.AuthResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return res
+	}
+
+	w := post("/api/auth/register", "", map[string]interface{}{
+		"email":    testEmail,
+		"password": "password123",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to setup test user. Status: %d, Body: %s", w.Code, w.Body.String())
+	}
+	first := decode(w)
+	if first.RefreshToken == "" {
+		t.Fatal("Expected refresh token to be present, got empty string")
+	}
+
+	// Refreshing rotates the refresh token
+	w = post("/api/auth/refresh", "", map[string]string{"refresh_token": first.RefreshToken})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	second := decode(w)
+	if second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Fatal("Expected a new refresh token")
+	}
+
+	// Reusing the old token revokes the session, including the new token
+	w = post("/api/auth/refresh", "", map[string]string{"refresh_token": first.RefreshToken})
+	if w.Code != http.StatusUnauthorized || !bytes.Contains(w.Body.Bytes(), []byte("reuse detected")) {
+		t.Fatalf("Expected reuse to be detected, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	w = post("/api/auth/refresh", "", map[string]string{"refresh_token": second.RefreshToken})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected revoked session's token to be rejected, got %d", w.Code)
+	}
+	w = post("/api/auth/logout", second.Token, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected revoked session's access token to be rejected, got %d", w.Code)
+	}
+
+	// Logging out ends a fresh session
+	w = post("/api/auth/login", "", map[string]interface{}{
+		"email":    testEmail,
+		"password": "password123",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	login := decode(w)
+
+	w = post("/api/auth/logout", login.Token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	w = post("/api/auth/logout", login.Token, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected access token to be rejected after logout, got %d", w.Code)
+	}
+	w = post("/api/auth/refresh", "", map[string]string{"refresh_token": login.RefreshToken})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected refresh token to be rejected after logout, got %d", w.Code)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+
+	testEmail := "test_change_password@example.com"
+	defer func() {
+		_, err := db.Exec(context.Background(), "DELETE FROM users WHERE email = $1", testEmail)
+		if err != nil {
+			t.Logf("Failed to cleanup test user: %v", err)
+		}
+	}()
+
+	send := func(method, path, token string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	login := func(password string) *httptest.ResponseRecorder {
+		return send("POST", "/api/auth/login", "", map[string]string{"email": testEmail, "password": password})
+	}
+	token := func(w *httptest.ResponseRecorder) string {
+		var res models.AuthResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return res.Token
+	}
+
+	w := send("POST", "/api/auth/register", "", map[string]string{"email": testEmail, "password": "password123"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to setup test user. Status: %d, Body: %s", w.Code, w.Body.String())
+	}
+	current := token(w)
+	other := token(login("password123"))
+
+	w = send("PUT", "/api/me/password", current, map[string]string{"current_password": "wrong", "new_password": "newpassword123"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for wrong current password, got %d", w.Code)
+	}
+
+	w = send("PUT", "/api/me/password", current, map[string]string{"current_password": "password123", "new_password": "newpassword123"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// The other session is signed out, this one is not
+	if w := send("GET", "/api/me/nutrition-goals", other, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected other session to be revoked, got %d", w.Code)
+	}
+	if w := send("PUT", "/api/me/password", current, map[string]string{"current_password": "newpassword123", "new_password": "password123"}); w.Code != http.StatusOK {
+		t.Errorf("Expected current session to stay signed in, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if w := login("password123"); w.Code != http.StatusOK {
+		t.Errorf("Expected login with the latest password to succeed, got %d", w.Code)
+	}
+
+	// Unknown emails get the same answer as known ones
+	w = send("POST", "/api/auth/forgot-password", "", map[string]string{"email": "test_nobody@example.com"})
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for unknown email, got %d", w.Code)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+	ctx := context.Background()
+
+	testEmail := "test_reset_password@example.com"
+	defer func() {
+		_, err := db.Exec(ctx, "DELETE FROM users WHERE email = $1", testEmail)
+		if err != nil {
+			t.Logf("Failed to cleanup test user: %v", err)
+		}
+	}()
+
+	send := func(method, path, token string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	login := func(password string) *httptest.ResponseRecorder {
+		return send("POST", "/api/auth/login", "", map[string]string{"email": testEmail, "password": password})
+	}
+	// requestReset asks for a reset link and stands in for the emailed token
+	// with one we know
+	requestReset := func(expiresAt time.Time) string {
+		if w := send("POST", "/api/auth/forgot-password", "", map[string]string{"email": testEmail}); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 requesting a reset, got %d", w.Code)
+		}
+		emailed, _ := utils.GenerateOpaqueToken()
+		_, err := db.Exec(ctx, `
+			UPDATE password_reset_tokens SET token_hash = $1, expires_at = $2
+			WHERE user_id = (SELECT id FROM users WHERE email = $3) AND used_at IS NULL AND expires_at > NOW()`,
+			utils.HashToken(emailed), expiresAt, testEmail,
+		)
+		if err != nil {
+			t.Fatalf("Failed to set reset token: %v", err)
+		}
+		return emailed
+	}
+	reset := func(token, password string) *httptest.ResponseRecorder {
+		return send("POST", "/api/auth/reset-password", "", map[string]string{"token": token, "new_password": password})
+	}
+
+	w := send("POST", "/api/auth/register", "", map[string]string{"email": testEmail, "password": "password123"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to setup test user. Status: %d, Body: %s", w.Code, w.Body.String())
+	}
+	var session models.AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &session); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	// An expired link changes nothing
+	expired := requestReset(time.Now().Add(-time.Minute))
+	if w := reset(expired, "expiredpassword"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an expired token, got %d", w.Code)
+	}
+	if w := login("password123"); w.Code != http.StatusOK {
+		t.Errorf("Expected the old password to still work, got %d", w.Code)
+	}
+
+	token := requestReset(time.Now().Add(time.Hour))
+	if w := reset(token, "newpassword123"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 resetting the password, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// Every session is signed out, refresh tokens included
+	if w := send("GET", "/api/me", session.Token, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the existing session to be revoked, got %d", w.Code)
+	}
+	if w := send("POST", "/api/auth/refresh", "", map[string]string{"refresh_token": session.RefreshToken}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the refresh token to be revoked, got %d", w.Code)
+	}
+
+	// The link works once
+	if w := reset(token, "anotherpassword"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 reusing the token, got %d", w.Code)
+	}
+	if w := login("password123"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the old password to be refused, got %d", w.Code)
+	}
+	if w := login("newpassword123"); w.Code != http.StatusOK {
+		t.Errorf("Expected login with the new password to succeed, got %d", w.Code)
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+
+	testEmail := "test_two_factor@example.com"
+	defer func() {
+		_, err := db.Exec(context.Background(), "DELETE FROM users WHERE email = $1", testEmail)
+		if err != nil {
+			t.Logf("Failed to cleanup test user: %v", err)
+		}
+	}()
+
+	send := func(path, token string, payload interface{}, out interface{}) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if out != nil && w.Code < 300 {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+		}
+		return w.Code
+	}
+	credentials := map[string]string{"email": testEmail, "password": "password123"}
+
+	var registered models.AuthResponse
+	if code := send("/api/auth/register", "", credentials, &registered); code != http.StatusCreated {
+		t.Fatalf("Failed to setup test user. Status: %d", code)
+	}
+
+	var setup models.TwoFactorSetupResponse
+	if code := send("/api/me/2fa/setup", registered.Token, nil, &setup); code != http.StatusOK {
+		t.Fatalf("Expected status 200 from setup, got %d", code)
+	}
+	if !strings.HasPrefix(setup.ProvisioningURI, "otpauth://totp/") {
+		t.Errorf("Expected an otpauth URI, got %q", setup.ProvisioningURI)
+	}
+
+	totp, _ := utils.TOTPCode(setup.Secret, utils.TOTPStep(time.Now()))
+	var recovery models.RecoveryCodesResponse
+	if code := send("/api/me/2fa/enable", registered.Token, map[string]string{"code": totp}, &recovery); code != http.StatusOK {
+		t.Fatalf("Expected status 200 from enable, got %d", code)
+	}
+	if len(recovery.RecoveryCodes) == 0 {
+		t.Fatal("Expected recovery codes")
+	}
+
+	// The password alone is no longer enough
+	var challenge models.AuthResponse
+	if code := send("/api/auth/login", "", credentials, &challenge); code != http.StatusOK {
+		t.Fatalf("Expected status 200 from login, got %d", code)
+	}
+	if !challenge.TwoFactorRequired || challenge.Token != "" {
+		t.Fatalf("Expected a two-factor challenge instead of tokens, got %+v", challenge)
+	}
+
+	wrong := map[string]string{"two_factor_token": challenge.TwoFactorToken, "code": "000000"}
+	if code := send("/api/auth/login/2fa", "", wrong, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a wrong code, got %d", code)
+	}
+
+	// The TOTP code was used to enable, so finish with a recovery code
+	right := map[string]string{"two_factor_token": challenge.TwoFactorToken, "code": recovery.RecoveryCodes[0]}
+	var login models.AuthResponse
+	if code := send("/api/auth/login/2fa", "", right, &login); code != http.StatusOK {
+		t.Fatalf("Expected status 200 from login/2fa, got %d", code)
+	}
+	if login.Token == "" || login.RefreshToken == "" {
+		t.Error("Expected tokens after the second factor")
+	}
+
+	// Challenges and recovery codes work once
+	if code := send("/api/auth/login/2fa", "", right, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected a used challenge to be rejected, got %d", code)
+	}
+}
+
+func TestTwoFactorLoginLimit(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+	ctx := context.Background()
+
+	testEmail := "test_two_factor_limit@example.com"
+	var userID int
+	defer func() {
+		db.Exec(ctx, "DELETE FROM login_throttles WHERE scope = '2fa' AND subject = $1", strconv.Itoa(userID))
+		_, err := db.Exec(ctx, "DELETE FROM users WHERE email = $1", testEmail)
+		if err != nil {
+			t.Logf("Failed to cleanup test user: %v", err)
+		}
+	}()
+
+	send := func(path, token string, payload interface{}, out interface{}) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if out != nil && w.Code < 300 {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+		}
+		return w.Code
+	}
+	credentials := map[string]string{"email": testEmail, "password": "password123"}
+
+	var registered models.AuthResponse
+	if code := send("/api/auth/register", "", credentials, &registered); code != http.StatusCreated {
+		t.Fatalf("Failed to setup test user. Status: %d", code)
+	}
+	userID = registered.User.ID
+	var setup models.TwoFactorSetupResponse
+	if code := send("/api/me/2fa/setup", registered.Token, nil, &setup); code != http.StatusOK {
+		t.Fatalf("Expected status 200 from setup, got %d", code)
+	}
+	totp, _ := utils.TOTPCode(setup.Secret, utils.TOTPStep(time.Now()))
+	var recovery models.RecoveryCodesResponse
+	if code := send("/api/me/2fa/enable", registered.Token, map[string]string{"code": totp}, &recovery); code != http.StatusOK {
+		t.Fatalf("Expected status 200 from enable, got %d", code)
+	}
+
+	challenge := func() string {
+		var res models.AuthResponse
+		if code := send("/api/auth/login", "", credentials, &res); code != http.StatusOK || !res.TwoFactorRequired {
+			t.Fatalf("Expected a two-factor challenge, got %d", code)
+		}
+		return res.TwoFactorToken
+	}
+	guess := func(token string) int {
+		return send("/api/auth/login/2fa", "", map[string]string{"two_factor_token": token, "code": "000000"}, nil)
+	}
+
+	// Each challenge allows a few guesses
+	first := challenge()
+	for i := 0; i < service.MaxTwoFactorAttempts; i++ {
+		if code := guess(first); code != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401 for a wrong code, got %d", code)
+		}
+	}
+	if code := guess(first); code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a used-up challenge, got %d", code)
+	}
+	var attempts int
+	db.QueryRow(ctx, "SELECT attempts FROM two_factor_challenges WHERE user_id = $1", userID).Scan(&attempts)
+	if attempts != service.MaxTwoFactorAttempts {
+		t.Errorf("Expected %d attempts on the challenge, got %d", service.MaxTwoFactorAttempts, attempts)
+	}
+
+	// Logging in again gives more guesses, up to the limit for the user
+	for failures := service.MaxTwoFactorAttempts; failures < service.MaxTwoFactorFailures; {
+		token := challenge()
+		for i := 0; i < service.MaxTwoFactorAttempts && failures < service.MaxTwoFactorFailures; i++ {
+			if code := guess(token); code != http.StatusUnauthorized {
+				t.Fatalf("Expected status 401 for a wrong code, got %d", code)
+			}
+			failures++
+		}
+	}
+
+	// Now even the right code is refused
+	right := map[string]string{"two_factor_token": challenge(), "code": recovery.RecoveryCodes[0]}
+	if code := send("/api/auth/login/2fa", "", right, nil); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 once the user is locked, got %d", code)
+	}
+
+	// Until the lock is lifted
+	if _, err := db.Exec(ctx, "DELETE FROM login_throttles WHERE scope = '2fa' AND subject = $1", strconv.Itoa(userID)); err != nil {
+		t.Fatalf("Failed to lift the lock: %v", err)
+	}
+	right["two_factor_token"] = challenge()
+	if code := send("/api/auth/login/2fa", "", right, nil); code != http.StatusOK {
+		t.Errorf("Expected status 200 after the lock is lifted, got %d", code)
+	}
+}
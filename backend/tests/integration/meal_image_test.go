@@ -0,0 +1,194 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/repository"
+)
+
+func TestMealImageGallery(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+	ctx := context.Background()
+
+	token := signInStaff(t, r, db, "test_gallery_admin@example.com", "admin")
+
+	send := func(method, path string, payload interface{}, out interface{}) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if out != nil && w.Code < 300 {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+		}
+		return w.Code
+	}
+
+	var meal models.Meal
+	if code := send("POST", "/api/meals", map[string]interface{}{"name": "Test Gallery Meal", "price": 1000}, &meal); code != http.StatusCreated {
+		t.Fatalf("Failed to create meal. Status: %d", code)
+	}
+	mealPath := "/api/meals/" + strconv.Itoa(meal.ID)
+
+	var uploadIDs []int
+	defer func() {
+		db.Exec(ctx, "DELETE FROM meal_images WHERE meal_id = $1", meal.ID)
+		db.Exec(ctx, "DELETE FROM uploads WHERE id = ANY($1)", uploadIDs)
+		db.Exec(ctx, "DELETE FROM meal_versions WHERE meal_id = $1", meal.ID)
+		db.Exec(ctx, "DELETE FROM meals WHERE id = $1", meal.ID)
+	}()
+	for _, name := range []string{"first.jpg", "second.jpg", "third.jpg"} {
+		var id int
+		err := db.QueryRow(ctx,
+			"INSERT INTO uploads (url, storage_key, filename) VALUES ($1, $2, $2) RETURNING id",
+			"https://cdn.example.com/"+name, name,
+		).Scan(&id)
+		if err != nil {
+			t.Fatalf("Failed to setup uploads: %v", err)
+		}
+		uploadIDs = append(uploadIDs, id)
+	}
+
+	mealImageURL := func() string {
+		var url string
+		db.QueryRow(ctx, "SELECT image_url FROM meals WHERE id = $1", meal.ID).Scan(&url)
+		return url
+	}
+	gallery := func() []models.MealImage {
+		var images []models.MealImage
+		if code := send("GET", mealPath+"/images", nil, &images); code != http.StatusOK {
+			t.Fatalf("Expected status 200 listing images, got %d", code)
+		}
+		return images
+	}
+
+	// The first image of a gallery becomes primary
+	var images [3]models.MealImage
+	for i, uploadID := range uploadIDs {
+		if code := send("POST", mealPath+"/images", map[string]interface{}{"upload_id": uploadID}, &images[i]); code != http.StatusCreated {
+			t.Fatalf("Expected status 201 adding an image, got %d", code)
+		}
+	}
+	if !images[0].IsPrimary || images[1].IsPrimary || images[2].IsPrimary {
+		t.Errorf("Expected only the first image to be primary, got %v %v %v", images[0].IsPrimary, images[1].IsPrimary, images[2].IsPrimary)
+	}
+	if url := mealImageURL(); url != images[0].URL {
+		t.Errorf("Expected the meal image to be %s, got %s", images[0].URL, url)
+	}
+
+	// Choosing another primary moves the flag and the meal image
+	isPrimary := true
+	if code := send("PUT", mealPath+"/images/"+strconv.Itoa(images[2].ID), map[string]*bool{"is_primary": &isPrimary}, nil); code != http.StatusOK {
+		t.Fatalf("Expected status 200 setting the primary image, got %d", code)
+	}
+	primaries := 0
+	for _, img := range gallery() {
+		if img.IsPrimary {
+			primaries++
+			if img.ID != images[2].ID {
+				t.Errorf("Expected image %d to be primary, got %d", images[2].ID, img.ID)
+			}
+		}
+	}
+	if primaries != 1 {
+		t.Errorf("Expected exactly one primary image, got %d", primaries)
+	}
+	if url := mealImageURL(); url != images[2].URL {
+		t.Errorf("Expected the meal image to be %s, got %s", images[2].URL, url)
+	}
+
+	// Reordering
+	order := []int{images[2].ID, images[0].ID, images[1].ID}
+	var reordered []models.MealImage
+	if code := send("PUT", mealPath+"/images/order", map[string][]int{"image_ids": order}, &reordered); code != http.StatusOK {
+		t.Fatalf("Expected status 200 reordering, got %d", code)
+	}
+	for i, img := range reordered {
+		if img.ID != order[i] {
+			t.Errorf("Expected image %d at position %d, got %d", order[i], i, img.ID)
+		}
+	}
+	if code := send("PUT", mealPath+"/images/order", map[string][]int{"image_ids": order[:2]}, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an incomplete order, got %d", code)
+	}
+
+	// Deleting the primary promotes the next image in the gallery
+	if code := send("DELETE", mealPath+"/images/"+strconv.Itoa(images[2].ID), nil, nil); code != http.StatusOK {
+		t.Fatalf("Expected status 200 deleting the primary image, got %d", code)
+	}
+	remaining := gallery()
+	if len(remaining) != 2 || remaining[0].ID != images[0].ID || !remaining[0].IsPrimary {
+		t.Errorf("Expected image %d to become primary, got %+v", images[0].ID, remaining)
+	}
+	if url := mealImageURL(); url != images[0].URL {
+		t.Errorf("Expected the meal image to be %s, got %s", images[0].URL, url)
+	}
+
+	// Emptying the gallery clears the meal image
+	for _, img := range remaining {
+		if code := send("DELETE", mealPath+"/images/"+strconv.Itoa(img.ID), nil, nil); code != http.StatusOK {
+			t.Fatalf("Expected status 200 deleting an image, got %d", code)
+		}
+	}
+	if url := mealImageURL(); url != "" {
+		t.Errorf("Expected no meal image after emptying the gallery, got %s", url)
+	}
+}
+
+func TestOrphanedUploads(t *testing.T) {
+	_, db := setupTestEnv()
+	defer db.Close()
+	ctx := context.Background()
+
+	menu := setupTestMenu(t, db)
+
+	// Two uploads from before the cut-off, one used as a bundle's image
+	var bundleUpload, orphanUpload int
+	for name, id := range map[string]*int{"bundle.jpg": &bundleUpload, "orphan.jpg": &orphanUpload} {
+		err := db.QueryRow(ctx,
+			"INSERT INTO uploads (url, storage_key, filename, created_at) VALUES ($1, $2, $2, NOW() - INTERVAL '2 days') RETURNING id",
+			"https://cdn.example.com/test-orphans/"+name, "test-orphans/"+name,
+		).Scan(id)
+		if err != nil {
+			t.Fatalf("Failed to setup uploads: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		db.Exec(ctx, "DELETE FROM bundles WHERE menu_id = $1", menu.MenuID)
+		db.Exec(ctx, "DELETE FROM uploads WHERE id = ANY($1)", []int{bundleUpload, orphanUpload})
+	})
+	_, err := db.Exec(ctx,
+		"INSERT INTO bundles (menu_id, name, image_url, price) VALUES ($1, 'Test Orphan Bundle', 'https://cdn.example.com/test-orphans/bundle.jpg', 5000)",
+		menu.MenuID,
+	)
+	if err != nil {
+		t.Fatalf("Failed to setup bundle: %v", err)
+	}
+
+	orphans, err := repository.NewUploadRepository(db).GetOrphans(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetOrphans: %v", err)
+	}
+	found := map[int]bool{}
+	for _, upload := range orphans {
+		found[upload.ID] = true
+	}
+	if found[bundleUpload] {
+		t.Error("Expected the bundle's image not to be an orphan")
+	}
+	if !found[orphanUpload] {
+		t.Error("Expected the unused upload to be an orphan")
+	}
+}
@@ -0,0 +1,77 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestMealImport(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+	ctx := context.Background()
+
+	token := signInStaff(t, r, db, "test_import_admin@example.com", "admin")
+	skus := []string{"TEST-IMPORT-1", "TEST-IMPORT-2", "TEST-IMPORT-3", "TEST-IMPORT-4"}
+	defer func() {
+		for _, query := range []string{
+			"DELETE FROM meal_versions WHERE meal_id IN (SELECT id FROM meals WHERE sku = ANY($1))",
+			"DELETE FROM meals WHERE sku = ANY($1)",
+		} {
+			if _, err := db.Exec(ctx, query, skus); err != nil {
+				t.Logf("Failed to cleanup test meals: %v", err)
+			}
+		}
+	}()
+
+	send := func(body []byte) (int, models.MealImportReport) {
+		req, _ := http.NewRequest("POST", "/api/admin/meals/import", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var report models.MealImportReport
+		json.Unmarshal(w.Body.Bytes(), &report)
+		return w.Code, report
+	}
+	meal := func(sku string, price int) models.MealRecord {
+		return models.MealRecord{SKU: sku, Name: "Test " + sku, Calories: 500, Protein: 30, Price: price}
+	}
+	encode := func(records ...models.MealRecord) []byte {
+		body, _ := json.Marshal(records)
+		return body
+	}
+
+	code, report := send(encode(meal(skus[0], 1000), meal(skus[1], 1200)))
+	if code != http.StatusOK || report.Created != 2 {
+		t.Fatalf("Expected 2 meals created, got %d %+v", code, report)
+	}
+
+	code, report = send(encode(meal(skus[0], 1000), meal(skus[1], 1300), meal(skus[2], 900)))
+	if code != http.StatusOK || report.Unchanged != 1 || report.Updated != 1 || report.Created != 1 {
+		t.Fatalf("Expected 1 unchanged, 1 updated and 1 created, got %d %+v", code, report)
+	}
+
+	// A row that fails to save leaves every other row unsaved
+	code, _ = send(encode(meal(skus[1], 1400), meal(skus[3], 3_000_000_000)))
+	if code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a price out of range, got %d", code)
+	}
+	var price, created int
+	db.QueryRow(ctx, "SELECT price FROM meals WHERE sku = $1", skus[1]).Scan(&price)
+	db.QueryRow(ctx, "SELECT COUNT(*) FROM meals WHERE sku = $1", skus[3]).Scan(&created)
+	if price != 1300 || created != 0 {
+		t.Errorf("Expected the failed import to be rolled back, got price %d and %d new meals", price, created)
+	}
+
+	// Oversized files are refused
+	if code, _ := send([]byte("[" + strings.Repeat(" ", 6<<20) + "]")); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413 for an oversized file, got %d", code)
+	}
+}
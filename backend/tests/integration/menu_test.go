@@ -0,0 +1,76 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestUpdateMenuKeepsMealVersions(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+	ctx := context.Background()
+
+	token := signInStaff(t, r, db, "test_menu_admin@example.com", "admin")
+	menu := setupTestMenu(t, db)
+
+	// A meal added to the menu later, and edits to the meal already on it,
+	// both at version 2
+	var newMealID int
+	err := db.QueryRow(ctx, `INSERT INTO meals (name, description, calories, protein, carbs, fat, price, current_version)
+		VALUES ('Test Added Meal', 'For integration tests', 400, 20, 40, 15, 900, 2) RETURNING id`).Scan(&newMealID)
+	if err != nil {
+		t.Fatalf("Failed to setup meal: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec(ctx, `DELETE FROM menu_meals WHERE meal_id = $1`, newMealID)
+		if _, err := db.Exec(ctx, `DELETE FROM meals WHERE id = $1`, newMealID); err != nil {
+			t.Logf("Failed to cleanup test meal: %v", err)
+		}
+	})
+	for _, query := range []string{
+		`INSERT INTO meal_versions (meal_id, version, name, description, calories, protein, carbs, fat, price)
+		 SELECT id, v, name, description, calories, protein, carbs, fat, price FROM meals, (VALUES (1), (2)) AS versions (v) WHERE id = $1
+		 ON CONFLICT (meal_id, version) DO NOTHING`,
+		`UPDATE meals SET current_version = 2 WHERE id = $1`,
+	} {
+		for _, id := range []int{menu.MealID, newMealID} {
+			if _, err := db.Exec(ctx, query, id); err != nil {
+				t.Fatalf("Failed to setup meal versions: %v", err)
+			}
+		}
+	}
+
+	update := map[string]interface{}{
+		"week_start_date": "2030-01-07",
+		"meals": []map[string]interface{}{
+			{"meal_id": menu.MealID, "stock": 50},
+			{"meal_id": newMealID, "stock": 50},
+		},
+	}
+	body, _ := json.Marshal(update)
+	req, _ := http.NewRequest("PUT", "/api/admin/weekly-menus/"+strconv.Itoa(menu.MenuID), bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 updating the menu, got %d: %s", w.Code, w.Body.String())
+	}
+
+	version := func(mealID int) int {
+		var v int
+		db.QueryRow(ctx, `SELECT meal_version FROM menu_meals WHERE menu_id = $1 AND meal_id = $2`, menu.MenuID, mealID).Scan(&v)
+		return v
+	}
+	if v := version(menu.MealID); v != 1 {
+		t.Errorf("Expected the meal already on the menu to stay at version 1, got %d", v)
+	}
+	if v := version(newMealID); v != 2 {
+		t.Errorf("Expected the added meal at its current version 2, got %d", v)
+	}
+}
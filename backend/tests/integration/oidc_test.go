@@ -0,0 +1,379 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jopari/preptoplate/internal/utils"
+)
+
+// standInIDP is a minimal OpenID Connect provider: discovery, an authorize
+// endpoint that signs in a fixed user without asking, a token endpoint that
+// checks PKCE, and its signing keys.
+type standInIDP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	user  standInUser
+	codes map[string]standInCode
+}
+
+type standInUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type standInCode struct {
+	user      standInUser
+	nonce     string
+	challenge string
+}
+
+func newStandInIDP(t *testing.T) *standInIDP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	idp := &standInIDP{key: key, codes: make(map[string]standInCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/jwks", idp.jwks)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *standInIDP) signInAs(user standInUser) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.user = user
+}
+
+func (idp *standInIDP) discovery(w http.ResponseWriter, r *http.Request) {
+	base := idp.server.URL
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                base,
+		"authorization_endpoint":                base + "/authorize",
+		"token_endpoint":                        base + "/token",
+		"jwks_uri":                              base + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (idp *standInIDP) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "PKCE required", http.StatusBadRequest)
+		return
+	}
+
+	code := rand.Text()
+	idp.mu.Lock()
+	idp.codes[code] = standInCode{user: idp.user, nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	idp.mu.Unlock()
+
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (idp *standInIDP) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	idp.mu.Lock()
+	grant, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+	if !ok {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	clientID, _, _ := r.BasicAuth()
+	if clientID == "" {
+		clientID = r.PostForm.Get("client_id")
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"sub":            grant.user.Subject,
+		"aud":            clientID,
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          grant.nonce,
+		"email":          grant.user.Email,
+		"email_verified": grant.user.EmailVerified,
+	})
+	idToken.Header["kid"] = "test-key"
+	signed, err := idToken.SignedString(idp.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "stand-in-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     signed,
+	})
+}
+
+func (idp *standInIDP) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := idp.key.PublicKey
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newStandInIDP(t)
+	t.Setenv("OIDC_PROVIDERS", "standin")
+	t.Setenv("OIDC_STANDIN_ISSUER", idp.server.URL)
+	t.Setenv("OIDC_STANDIN_CLIENT_ID", "preptoplate")
+	t.Setenv("OIDC_STANDIN_CLIENT_SECRET", "secret")
+	t.Setenv("FRONTEND_URL", "http://frontend.test")
+
+	r, db := setupTestEnv()
+	defer db.Close()
+
+	defer func() {
+		_, err := db.Exec(context.Background(), "DELETE FROM users WHERE email LIKE 'test_oidc_%'")
+		if err != nil {
+			t.Logf("Failed to cleanup test users: %v", err)
+		}
+	}()
+
+	noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	// startLogin sends the user to the provider and back, returning the
+	// callback and the cookies the browser holds
+	startLogin := func() (string, []*http.Cookie) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/auth/oidc/standin/login", nil)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusFound {
+			t.Fatalf("Expected redirect to provider, got %d. Body: %s", w.Code, w.Body.String())
+		}
+
+		res, err := noRedirects.Get(w.Header().Get("Location"))
+		if err != nil {
+			t.Fatalf("Provider authorize failed: %v", err)
+		}
+		res.Body.Close()
+		callback, _ := url.Parse(res.Header.Get("Location"))
+		return callback.RequestURI(), w.Result().Cookies()
+	}
+	// finishLogin follows the callback and returns the fragment the web app
+	// gets
+	finishLogin := func(callback string, cookies []*http.Cookie) url.Values {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", callback, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusFound {
+			t.Fatalf("Expected redirect to frontend, got %d. Body: %s", w.Code, w.Body.String())
+		}
+
+		back, _ := url.Parse(w.Header().Get("Location"))
+		if back.Host != "frontend.test" || back.Path != "/auth/callback" {
+			t.Fatalf("Unexpected redirect %s", back)
+		}
+		fragment, _ := url.ParseQuery(back.Fragment)
+		return fragment
+	}
+	// signIn runs a whole login in one browser
+	signIn := func() url.Values {
+		return finishLogin(startLogin())
+	}
+
+	t.Run("New user is created", func(t *testing.T) {
+		idp.signInAs(standInUser{Subject: "new-user", Email: "test_oidc_new@example.com", EmailVerified: true})
+		fragment := signIn()
+		if fragment.Get("token") == "" || fragment.Get("refresh_token") == "" {
+			t.Fatalf("Expected tokens, got %v", fragment)
+		}
+
+		var verified bool
+		err := db.QueryRow(context.Background(),
+			"SELECT email_verified_at IS NOT NULL FROM users WHERE email = $1", "test_oidc_new@example.com",
+		).Scan(&verified)
+		if err != nil || !verified {
+			t.Errorf("Expected a verified user to be created, err %v", err)
+		}
+
+		// Signing in again finds the same user by its link
+		if fragment := signIn(); fragment.Get("token") == "" {
+			t.Errorf("Expected second sign-in to succeed, got %v", fragment)
+		}
+	})
+
+	t.Run("Existing verified user is linked", func(t *testing.T) {
+		var userID int
+		err := db.QueryRow(context.Background(), `
+			INSERT INTO users (email, password_hash, role, email_verified_at)
+			VALUES ('test_oidc_existing@example.com', 'x', 'user', NOW())
+			RETURNING id
+		`).Scan(&userID)
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+
+		idp.signInAs(standInUser{Subject: "existing-user", Email: "test_oidc_existing@example.com", EmailVerified: true})
+		if fragment := signIn(); fragment.Get("token") == "" {
+			t.Fatalf("Expected tokens, got %v", fragment)
+		}
+
+		var linkedID int
+		err = db.QueryRow(context.Background(),
+			"SELECT user_id FROM user_identities WHERE provider = 'standin' AND subject = 'existing-user'",
+		).Scan(&linkedID)
+		if err != nil || linkedID != userID {
+			t.Errorf("Expected identity linked to user %d, got %d (err %v)", userID, linkedID, err)
+		}
+	})
+
+	t.Run("Unverified provider email is refused", func(t *testing.T) {
+		idp.signInAs(standInUser{Subject: "unverified-user", Email: "test_oidc_unverified@example.com", EmailVerified: false})
+		fragment := signIn()
+		if fragment.Get("token") != "" || fragment.Get("error") == "" {
+			t.Errorf("Expected an error, got %v", fragment)
+		}
+	})
+
+	t.Run("Login is finished in the browser that started it", func(t *testing.T) {
+		idp.signInAs(standInUser{Subject: "new-user", Email: "test_oidc_new@example.com", EmailVerified: true})
+		callback, cookies := startLogin()
+
+		var stateCookie *http.Cookie
+		for _, cookie := range cookies {
+			if cookie.Name == "oidc_state" {
+				stateCookie = cookie
+			}
+		}
+		if stateCookie == nil || !stateCookie.HttpOnly || stateCookie.SameSite != http.SameSiteLaxMode {
+			t.Fatalf("Expected an HttpOnly, SameSite=Lax state cookie, got %+v", stateCookie)
+		}
+
+		if fragment := finishLogin(callback, nil); fragment.Get("token") != "" || fragment.Get("error") == "" {
+			t.Errorf("Expected a callback without the cookie to be refused, got %v", fragment)
+		}
+		other := &http.Cookie{Name: "oidc_state", Value: "another-login"}
+		if fragment := finishLogin(callback, []*http.Cookie{other}); fragment.Get("token") != "" {
+			t.Errorf("Expected a callback with another login's cookie to be refused, got %v", fragment)
+		}
+	})
+
+	t.Run("Provider-only user deletes their account with an emailed link", func(t *testing.T) {
+		idp.signInAs(standInUser{Subject: "deleting-user", Email: "test_oidc_deleting@example.com", EmailVerified: true})
+		token := signIn().Get("token")
+		if token == "" {
+			t.Fatalf("Expected tokens")
+		}
+		var userID int
+		err := db.QueryRow(context.Background(), "SELECT id FROM users WHERE email = $1", "test_oidc_deleting@example.com").Scan(&userID)
+		if err != nil {
+			t.Fatalf("Failed to find user: %v", err)
+		}
+		defer db.Exec(context.Background(), "DELETE FROM users WHERE id = $1", userID)
+
+		deleteAccount := func(payload map[string]string) int {
+			body, _ := json.Marshal(payload)
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/api/me", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		// There is no password to confirm with
+		if code := deleteAccount(map[string]string{"password": "password123"}); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 confirming with a password, got %d", code)
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/me/delete-confirmation", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("Expected status 202 asking for a deletion link, got %d. Body: %s", w.Code, w.Body.String())
+		}
+
+		// Stand in for the emailed token
+		emailed := "emailed-deletion-token"
+		_, err = db.Exec(context.Background(),
+			"UPDATE account_deletion_tokens SET token_hash = $1 WHERE user_id = $2 AND used_at IS NULL",
+			utils.HashToken(emailed), userID,
+		)
+		if err != nil {
+			t.Fatalf("Failed to set deletion token: %v", err)
+		}
+
+		if code := deleteAccount(map[string]string{"token": "not-the-emailed-token"}); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 with the wrong token, got %d", code)
+		}
+		if code := deleteAccount(map[string]string{"token": emailed}); code != http.StatusOK {
+			t.Fatalf("Expected status 200 deleting with the emailed token, got %d", code)
+		}
+
+		var deleted bool
+		var identities int
+		err = db.QueryRow(context.Background(), `
+			SELECT u.deleted_at IS NOT NULL, (SELECT COUNT(*) FROM user_identities i WHERE i.user_id = u.id)
+			FROM users u WHERE u.id = $1
+		`, userID).Scan(&deleted, &identities)
+		if err != nil || !deleted || identities != 0 {
+			t.Errorf("Expected a deleted account without its provider link, got deleted %v identities %d (err %v)", deleted, identities, err)
+		}
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/auth/oidc/nope/login", nil)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestCheckoutWithVariant(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+
+	testEmail := "test_variant_order@example.com"
+	defer func() {
+		_, err := db.Exec(context.Background(), "DELETE FROM users WHERE email = $1", testEmail)
+		if err != nil {
+			t.Logf("Failed to cleanup test user: %v", err)
+		}
+	}()
+	menu := setupTestMenu(t, db)
+
+	send := func(method, path, token string, payload interface{}, out interface{}) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if out != nil && w.Code < 300 {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+		}
+		return w.Code
+	}
+
+	var registered models.AuthResponse
+	if code := send("POST", "/api/auth/register", "", map[string]string{"email": testEmail, "password": "password123"}, &registered); code != http.StatusCreated {
+		t.Fatalf("Failed to setup test user. Status: %d", code)
+	}
+	token := registered.Token
+	if _, err := db.Exec(context.Background(), "UPDATE users SET email_verified_at = NOW() WHERE id = $1", registered.User.ID); err != nil {
+		t.Fatalf("Failed to verify test user: %v", err)
+	}
+
+	// Only variants stocked on this week's menu can be added
+	offMenu := map[string]interface{}{"meal_id": menu.MealID, "variant_id": menu.OffMenuVariant, "quantity": 1}
+	if code := send("POST", "/api/cart/items", token, offMenu, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a variant not on the menu, got %d", code)
+	}
+
+	item := map[string]interface{}{"meal_id": menu.MealID, "variant_id": menu.VariantID, "quantity": 10}
+	if code := send("POST", "/api/cart/items", token, item, nil); code != http.StatusOK {
+		t.Fatalf("Expected status 200 adding the variant, got %d", code)
+	}
+
+	// Orders need somewhere to be delivered
+	checkout := map[string]string{"delivery_date": "2030-01-07"}
+	if code := send("POST", "/api/orders/checkout", token, checkout, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 checking out without an address, got %d", code)
+	}
+	address := map[string]interface{}{"address": map[string]string{"line1": "1 High Street", "city": "London", "postcode": "SW1A 1AA"}}
+	if code := send("PUT", "/api/me", token, address, nil); code != http.StatusOK {
+		t.Fatalf("Expected status 200 setting the address, got %d", code)
+	}
+
+	var order models.Order
+	if code := send("POST", "/api/orders/checkout", token, checkout, &order); code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", code)
+	}
+
+	// Editing the variant or moving house afterwards does not change the order
+	_, err := db.Exec(context.Background(), "UPDATE meal_variants SET name = 'Extra Large', calories = 1000 WHERE id = $1", menu.VariantID)
+	if err != nil {
+		t.Fatalf("Failed to edit variant: %v", err)
+	}
+	moved := map[string]interface{}{"address": map[string]string{"line1": "2 Low Road", "city": "Leeds", "postcode": "LS1 1AA"}}
+	if code := send("PUT", "/api/me", token, moved, nil); code != http.StatusOK {
+		t.Fatalf("Expected status 200 changing the address, got %d", code)
+	}
+
+	if code := send("GET", "/api/orders/"+strconv.Itoa(order.ID), token, nil, &order); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if len(order.Items) != 1 || order.Items[0].Variant == nil {
+		t.Fatalf("Expected one variant item, got %+v", order.Items)
+	}
+	variant := order.Items[0].Variant
+	if variant.Name != "Large" || variant.Calories != 750 {
+		t.Errorf("Expected the variant as ordered (Large, 750 kcal), got %s, %d kcal", variant.Name, variant.Calories)
+	}
+	if order.Nutrition == nil || order.Nutrition.Total.Calories != 7500 {
+		t.Errorf("Expected order nutrition from the variant as ordered, got %+v", order.Nutrition)
+	}
+	if order.DeliveryAddress == nil || order.DeliveryAddress.Line1 != "1 High Street" {
+		t.Errorf("Expected the order to keep the address at checkout, got %+v", order.DeliveryAddress)
+	}
+
+	// The driver is sent to the address the order was placed with
+	staff := signInStaff(t, r, db, "test_variant_order_driver@example.com", "driver")
+	var deliveries []models.Delivery
+	if code := send("GET", "/api/admin/deliveries?delivery_date=2030-01-07", staff, nil, &deliveries); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	for _, d := range deliveries {
+		if d.OrderID == order.ID && (d.Address == nil || d.Address.Line1 != "1 High Street") {
+			t.Errorf("Expected the delivery to the address at checkout, got %+v", d.Address)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+)
+
+func TestEffectiveMealPrice(t *testing.T) {
+	_, db := setupTestEnv()
+	defer db.Close()
+	ctx := context.Background()
+
+	menu := setupTestMenu(t, db)
+	for _, query := range []string{
+		// An old and a current standard price, and one scheduled for next week
+		`INSERT INTO meal_prices (meal_id, price, effective_from) VALUES ($1, 1100, NOW() - INTERVAL '2 days')`,
+		`INSERT INTO meal_prices (meal_id, price, effective_from) VALUES ($1, 1050, NOW() - INTERVAL '1 day')`,
+		`INSERT INTO meal_prices (meal_id, price, effective_from) VALUES ($1, 1300, NOW() + INTERVAL '7 days')`,
+		`INSERT INTO meal_prices (meal_id, plan_type, price, effective_from) VALUES ($1, 'test_plan', 900, NOW() - INTERVAL '1 day')`,
+	} {
+		if _, err := db.Exec(ctx, query, menu.MealID); err != nil {
+			t.Fatalf("Failed to setup prices: %v", err)
+		}
+	}
+
+	price := func(variantID *int, planType *string, at string, fallback *int) *int {
+		var p *int
+		err := db.QueryRow(ctx, `SELECT effective_meal_price($1, $2, $3, `+at+`, $4)`, menu.MealID, variantID, planType, fallback).Scan(&p)
+		if err != nil {
+			t.Fatalf("Failed to get the effective price: %v", err)
+		}
+		return p
+	}
+	plan, otherPlan := "test_plan", "other_plan"
+	fallback := 1000
+
+	tests := []struct {
+		name      string
+		variantID *int
+		planType  *string
+		at        string
+		want      int
+	}{
+		{"latest standard price", nil, nil, "NOW()", 1050},
+		{"scheduled standard price", nil, nil, "NOW() + INTERVAL '8 days'", 1300},
+		{"earlier standard price", nil, nil, "NOW() - INTERVAL '36 hours'", 1100},
+		{"plan price", nil, &plan, "NOW()", 900},
+		{"plan without a price list", nil, &otherPlan, "NOW()", 1050},
+		{"variant without a price list", &menu.VariantID, &plan, "NOW()", fallback},
+	}
+	for _, tt := range tests {
+		got := price(tt.variantID, tt.planType, tt.at, &fallback)
+		if got == nil || *got != tt.want {
+			t.Errorf("%s: expected %d, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	if got := price(nil, nil, "NOW()", nil); got != nil {
+		t.Errorf("Expected no price without a fallback, got %d", *got)
+	}
+}
+
+func TestCheckoutAtPlanPrices(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+	ctx := context.Background()
+
+	testEmail := "test_plan_order@example.com"
+	t.Cleanup(func() {
+		db.Exec(ctx, "DELETE FROM subscriptions WHERE user_id IN (SELECT id FROM users WHERE email = $1)", testEmail)
+		if _, err := db.Exec(ctx, "DELETE FROM users WHERE email = $1", testEmail); err != nil {
+			t.Logf("Failed to cleanup test user: %v", err)
+		}
+	})
+	menu := setupTestMenu(t, db)
+
+	send := func(method, path, token string, payload interface{}, out interface{}) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if out != nil && w.Code < 300 {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+		}
+		return w.Code
+	}
+
+	var registered models.AuthResponse
+	if code := send("POST", "/api/auth/register", "", map[string]string{"email": testEmail, "password": "password123"}, &registered); code != http.StatusCreated {
+		t.Fatalf("Failed to setup test user. Status: %d", code)
+	}
+	token := registered.Token
+	for _, query := range []string{
+		"UPDATE users SET email_verified_at = NOW() WHERE id = $1",
+		"UPDATE users SET address_line1 = '1 High Street', address_city = 'London', address_postcode = 'SW1A 1AA' WHERE id = $1",
+		"INSERT INTO subscriptions (user_id, plan_type) VALUES ($1, 'test_plan')",
+	} {
+		if _, err := db.Exec(ctx, query, registered.User.ID); err != nil {
+			t.Fatalf("Failed to setup test user: %v", err)
+		}
+	}
+	for _, query := range []string{
+		// The plan's price for the large portion, and a standard price
+		// change that only takes effect next week
+		`INSERT INTO meal_prices (meal_id, variant_id, plan_type, price, effective_from) VALUES ($1, $2, 'test_plan', 1200, NOW() - INTERVAL '1 day')`,
+		`INSERT INTO meal_prices (meal_id, variant_id, price, effective_from) VALUES ($1, $2, 1600, NOW() + INTERVAL '7 days')`,
+	} {
+		if _, err := db.Exec(ctx, query, menu.MealID, menu.VariantID); err != nil {
+			t.Fatalf("Failed to setup prices: %v", err)
+		}
+	}
+
+	item := map[string]interface{}{"meal_id": menu.MealID, "variant_id": menu.VariantID, "quantity": 10}
+	if code := send("POST", "/api/cart/items", token, item, nil); code != http.StatusOK {
+		t.Fatalf("Expected status 200 adding the variant, got %d", code)
+	}
+
+	// The cart, the menu and the order agree on the plan's price
+	var cart models.Cart
+	if code := send("GET", "/api/cart", token, nil, &cart); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if len(cart.Items) != 1 || cart.Items[0].UnitPrice != 1200 || cart.TotalPrice != 12000 {
+		t.Errorf("Expected the cart at the plan price of 1200, got %+v", cart)
+	}
+
+	var weekly models.WeeklyMenu
+	if code := send("GET", "/api/menu", token, nil, &weekly); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	for _, menuMeal := range weekly.Meals {
+		for _, menuVariant := range menuMeal.Variants {
+			if v := menuVariant.Variant; v.ID == menu.VariantID && (v.Price != 1200 || v.StandardPrice != 1400) {
+				t.Errorf("Expected the menu at the plan price of 1200 over 1400, got %+v", v)
+			}
+		}
+	}
+
+	var order models.Order
+	if code := send("POST", "/api/orders/checkout", token, map[string]string{"delivery_date": "2030-01-07"}, &order); code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", code)
+	}
+	if order.TotalPrice != 12000 {
+		t.Errorf("Expected the order at the plan price, 12000, got %d", order.TotalPrice)
+	}
+	var itemPrice int
+	db.QueryRow(ctx, "SELECT price FROM order_items WHERE order_id = $1", order.ID).Scan(&itemPrice)
+	if itemPrice != 1200 {
+		t.Errorf("Expected the item charged at 1200, got %d", itemPrice)
+	}
+}
+
+func TestUpdateMealKeepsPriceList(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+	ctx := context.Background()
+
+	token := signInStaff(t, r, db, "test_price_admin@example.com", "admin")
+	menu := setupTestMenu(t, db)
+
+	// A standard price change now in effect, so the catalogue price is stale
+	_, err := db.Exec(ctx, `INSERT INTO meal_prices (meal_id, price, effective_from) VALUES ($1, 1100, NOW() - INTERVAL '1 day')`, menu.MealID)
+	if err != nil {
+		t.Fatalf("Failed to setup prices: %v", err)
+	}
+	entries := func() int {
+		var n int
+		db.QueryRow(ctx, "SELECT COUNT(*) FROM meal_prices WHERE meal_id = $1", menu.MealID).Scan(&n)
+		return n
+	}
+
+	update := func(price int) {
+		body, _ := json.Marshal(map[string]interface{}{"name": "Test Menu Meal", "price": price})
+		req, _ := http.NewRequest("PUT", "/api/meals/"+strconv.Itoa(menu.MealID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 updating the meal, got %d", w.Code)
+		}
+	}
+
+	// Saving the meal at the price it shows records nothing
+	update(1100)
+	if n := entries(); n != 1 {
+		t.Errorf("Expected no new price entry for an unchanged price, got %d entries", n)
+	}
+
+	update(1150)
+	if n := entries(); n != 2 {
+		t.Errorf("Expected a new price entry for a changed price, got %d entries", n)
+	}
+}
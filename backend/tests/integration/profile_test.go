@@ -0,0 +1,119 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/utils"
+)
+
+func TestProfile(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+
+	testEmail := "test_profile@example.com"
+	newEmail := "test_profile_new@example.com"
+	defer func() {
+		_, err := db.Exec(context.Background(), "DELETE FROM users WHERE email = ANY($1)", []string{testEmail, newEmail})
+		if err != nil {
+			t.Logf("Failed to cleanup test user: %v", err)
+		}
+	}()
+
+	send := func(method, path, token string, payload interface{}, out interface{}) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if out != nil && w.Code < 300 {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+		}
+		return w.Code
+	}
+
+	var registered models.AuthResponse
+	if code := send("POST", "/api/auth/register", "", map[string]string{"email": testEmail, "password": "password123"}, &registered); code != http.StatusCreated {
+		t.Fatalf("Failed to setup test user. Status: %d", code)
+	}
+	token := registered.Token
+
+	var profile models.Profile
+	if code := send("GET", "/api/me", token, nil, &profile); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if profile.Email != testEmail || profile.Address != nil || !profile.Communication.OrderUpdateEmails {
+		t.Errorf("Unexpected new profile %+v", profile)
+	}
+
+	update := map[string]interface{}{
+		"name":                "Test Customer",
+		"phone":               "07700 900123",
+		"address":             map[string]string{"line1": "1 High Street", "city": "London", "postcode": "sw1a 1aa"},
+		"dietary_preferences": []string{"Vegetarian"},
+	}
+	if code := send("PUT", "/api/me", token, update, &profile); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if profile.Name != "Test Customer" || profile.Address == nil || profile.Address.Postcode != "SW1A 1AA" {
+		t.Errorf("Unexpected updated profile %+v", profile)
+	}
+	if len(profile.DietaryPreferences) != 1 || profile.DietaryPreferences[0] != "vegetarian" {
+		t.Errorf("Expected normalised dietary preferences, got %v", profile.DietaryPreferences)
+	}
+
+	// Fields left out are unchanged
+	if code := send("PUT", "/api/me", token, map[string]interface{}{"clear_address": true}, &profile); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if profile.Address != nil || profile.Name != "Test Customer" {
+		t.Errorf("Expected only the address to be cleared, got %+v", profile)
+	}
+
+	if code := send("PUT", "/api/me", token, map[string]string{"phone": "call me"}, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid phone number, got %d", code)
+	}
+
+	// Changing email needs the password, and only takes effect once confirmed
+	change := map[string]string{"new_email": newEmail, "password": "wrong"}
+	if code := send("POST", "/api/me/email", token, change, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a wrong password, got %d", code)
+	}
+	change["password"] = "password123"
+	if code := send("POST", "/api/me/email", token, change, nil); code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", code)
+	}
+	send("GET", "/api/me", token, nil, &profile)
+	if profile.Email != testEmail || profile.PendingEmail != newEmail {
+		t.Errorf("Expected the change to be pending, got email %q pending %q", profile.Email, profile.PendingEmail)
+	}
+
+	// Stand in for the emailed link with a token we know
+	emailed, _ := utils.GenerateOpaqueToken()
+	_, err := db.Exec(context.Background(), `
+		UPDATE email_verification_tokens SET token_hash = $1
+		WHERE user_id = $2 AND new_email = $3 AND used_at IS NULL`,
+		utils.HashToken(emailed), registered.User.ID, newEmail,
+	)
+	if err != nil {
+		t.Fatalf("Failed to set verification token: %v", err)
+	}
+	if code := send("POST", "/api/auth/verify-email", "", map[string]string{"token": emailed}, nil); code != http.StatusOK {
+		t.Fatalf("Expected status 200 confirming the new email, got %d", code)
+	}
+
+	send("GET", "/api/me", token, nil, &profile)
+	if profile.Email != newEmail || profile.PendingEmail != "" || !profile.EmailVerified {
+		t.Errorf("Expected the email to have changed, got %+v", profile)
+	}
+}
@@ -1,7 +1,14 @@
 package integration
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -9,6 +16,9 @@ import (
 	"github.com/jopari/preptoplate/internal/api"
 	"github.com/jopari/preptoplate/internal/config"
 	"github.com/jopari/preptoplate/internal/database"
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/utils"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // setupTestEnv initializes the application for testing.
@@ -36,3 +46,130 @@ func setupTestEnv() (*gin.Engine, *pgxpool.Pool) {
 
 	return r, dbPool
 }
+
+// testMenu is an active weekly menu with one meal on it, in a standard and a
+// "Large" portion. The meal also has a "Small" portion that is not on the
+// menu.
+type testMenu struct {
+	MenuID         int
+	MealID         int
+	VariantID      int // Large, on the menu
+	OffMenuVariant int // Small, not on the menu
+}
+
+// setupTestMenu creates and activates a test menu. It is removed, with any
+// orders placed from it, and the previously active menu restored when the
+// test finishes.
+func setupTestMenu(t *testing.T, db *pgxpool.Pool) *testMenu {
+	t.Helper()
+	ctx := context.Background()
+
+	var previous *int
+	_ = db.QueryRow(ctx, `SELECT id FROM weekly_menus WHERE is_active LIMIT 1`).Scan(&previous)
+
+	queryID := func(dest *int, query string, args ...interface{}) {
+		if err := db.QueryRow(ctx, query, args...).Scan(dest); err != nil {
+			t.Fatalf("Failed to setup test menu: %v", err)
+		}
+	}
+	exec := func(query string, args ...interface{}) {
+		if _, err := db.Exec(ctx, query, args...); err != nil {
+			t.Fatalf("Failed to setup test menu: %v", err)
+		}
+	}
+
+	m := &testMenu{}
+	queryID(&m.MealID, `INSERT INTO meals (name, description, calories, protein, carbs, fat, price)
+		VALUES ('Test Menu Meal', 'For integration tests', 500, 30, 50, 20, 1000) RETURNING id`)
+	exec(`INSERT INTO meal_versions (meal_id, version, name, description, calories, protein, carbs, fat, price)
+		SELECT id, current_version, name, description, calories, protein, carbs, fat, price FROM meals WHERE id = $1`, m.MealID)
+	queryID(&m.VariantID, `INSERT INTO meal_variants (meal_id, name, calories, protein, carbs, fat, price)
+		VALUES ($1, 'Large', 750, 45, 75, 30, 1400) RETURNING id`, m.MealID)
+	queryID(&m.OffMenuVariant, `INSERT INTO meal_variants (meal_id, name, calories, protein, carbs, fat, price)
+		VALUES ($1, 'Small', 350, 20, 35, 14, 800) RETURNING id`, m.MealID)
+	queryID(&m.MenuID, `INSERT INTO weekly_menus (week_start_date) VALUES (CURRENT_DATE) RETURNING id`)
+	exec(`INSERT INTO menu_meals (menu_id, meal_id, initial_stock, available_stock, meal_version) VALUES ($1, $2, 100, 100, 1)`, m.MenuID, m.MealID)
+	exec(`INSERT INTO menu_variant_stock (menu_id, variant_id, initial_stock, available_stock) VALUES ($1, $2, 100, 100)`, m.MenuID, m.VariantID)
+	exec(`UPDATE weekly_menus SET is_active = (id = $1)`, m.MenuID)
+
+	t.Cleanup(func() {
+		cleanup := []string{
+			`DELETE FROM order_items WHERE order_id IN (SELECT id FROM orders WHERE week_id = $1)`,
+			`DELETE FROM order_addons WHERE order_id IN (SELECT id FROM orders WHERE week_id = $1)`,
+			`DELETE FROM order_bundles WHERE order_id IN (SELECT id FROM orders WHERE week_id = $1)`,
+			`DELETE FROM meal_reviews WHERE week_id = $1`,
+			`DELETE FROM orders WHERE week_id = $1`,
+			`DELETE FROM menu_variant_stock WHERE menu_id = $1`,
+			`DELETE FROM menu_meals WHERE menu_id = $1`,
+			`DELETE FROM meal_recommendations WHERE menu_id = $1`,
+			`DELETE FROM weekly_menus WHERE id = $1`,
+		}
+		for _, query := range cleanup {
+			if _, err := db.Exec(ctx, query, m.MenuID); err != nil {
+				t.Logf("Failed to cleanup test menu: %v", err)
+			}
+		}
+		if _, err := db.Exec(ctx, `DELETE FROM cart_items WHERE meal_id = $1`, m.MealID); err != nil {
+			t.Logf("Failed to cleanup test menu: %v", err)
+		}
+		if _, err := db.Exec(ctx, `DELETE FROM meals WHERE id = $1`, m.MealID); err != nil {
+			t.Logf("Failed to cleanup test meal: %v", err)
+		}
+		if previous != nil {
+			db.Exec(ctx, `UPDATE weekly_menus SET is_active = (id = $1)`, *previous)
+		}
+	})
+	return m
+}
+
+// signInStaff creates a user with the given role and two-factor
+// authentication, signs them in with both factors, as staff endpoints
+// require, and returns their access token. The user is deleted when the
+// test finishes.
+func signInStaff(t *testing.T, r *gin.Engine, db *pgxpool.Pool, email, role string) string {
+	t.Helper()
+	ctx := context.Background()
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var userID int
+	err = db.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash, role, email_verified_at, totp_secret, totp_enabled_at)
+		VALUES ($1, $2, $3, NOW(), $4, NOW())
+		RETURNING id
+	`, email, string(hash), role, secret).Scan(&userID)
+	if err != nil {
+		t.Fatalf("Failed to create staff user: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec(ctx, `UPDATE meal_versions SET created_by = NULL WHERE created_by = $1`, userID)
+		if _, err := db.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+			t.Logf("Failed to cleanup staff user: %v", err)
+		}
+	})
+
+	post := func(path string, payload interface{}, out interface{}) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to sign in staff user at %s. Status: %d", path, w.Code)
+		}
+		json.Unmarshal(w.Body.Bytes(), out)
+	}
+
+	var challenge models.AuthResponse
+	post("/api/auth/login", map[string]string{"email": email, "password": "password123"}, &challenge)
+	code, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	var login models.AuthResponse
+	post("/api/auth/login/2fa", map[string]string{"two_factor_token": challenge.TwoFactorToken, "code": code}, &login)
+	return login.Token
+}
@@ -0,0 +1,215 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/jopari/preptoplate/internal/models"
+	"github.com/jopari/preptoplate/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestStaffPermissions(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+	ctx := context.Background()
+
+	kitchen := signInStaff(t, r, db, "test_kitchen_staff@example.com", "kitchen")
+	driver := signInStaff(t, r, db, "test_driver_staff@example.com", "driver")
+	support := signInStaff(t, r, db, "test_support_staff@example.com", "support")
+
+	send := func(method, path, token string, payload interface{}, out interface{}) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if out != nil && w.Code < 300 {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+		}
+		return w.Code
+	}
+
+	production := "/api/admin/production?delivery_date=2030-01-07"
+	deliveries := "/api/admin/deliveries?delivery_date=2030-01-07"
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		want   int
+	}{
+		{"kitchen production list", kitchen, "GET", production, http.StatusOK},
+		{"kitchen deliveries", kitchen, "GET", deliveries, http.StatusForbidden},
+		{"kitchen orders", kitchen, "GET", "/api/admin/orders", http.StatusForbidden},
+		{"driver deliveries", driver, "GET", deliveries, http.StatusOK},
+		{"driver production list", driver, "GET", production, http.StatusForbidden},
+		{"driver users", driver, "GET", "/api/admin/users", http.StatusForbidden},
+		{"support orders", support, "GET", "/api/admin/orders", http.StatusOK},
+		{"support changing an order", support, "PUT", "/api/admin/orders/1/status", http.StatusForbidden},
+		{"support roles", support, "GET", "/api/admin/roles", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if code := send(tt.method, tt.path, tt.token, map[string]string{"status": "delivered"}, nil); code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, code)
+		}
+	}
+
+	// Staff who have not set up two-factor authentication can sign in to
+	// enrol, but not reach staff routes
+	email := "test_kitchen_no_2fa@example.com"
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.Exec(ctx, "DELETE FROM users WHERE email = $1", email); err != nil {
+			t.Logf("Failed to cleanup test user: %v", err)
+		}
+	})
+	_, err = db.Exec(ctx, `
+		INSERT INTO users (email, password_hash, role, email_verified_at)
+		VALUES ($1, $2, 'kitchen', NOW())
+	`, email, string(hash))
+	if err != nil {
+		t.Fatalf("Failed to create staff user: %v", err)
+	}
+
+	var login models.AuthResponse
+	if code := send("POST", "/api/auth/login", "", map[string]string{"email": email, "password": "password123"}, &login); code != http.StatusOK {
+		t.Fatalf("Expected status 200 signing in, got %d", code)
+	}
+	if code := send("GET", production, login.Token, nil, nil); code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for staff without two-factor, got %d", code)
+	}
+}
+
+func TestAdminUserManagement(t *testing.T) {
+	r, db := setupTestEnv()
+	defer db.Close()
+	ctx := context.Background()
+
+	testEmail := "test_managed_user@example.com"
+	defer func() {
+		_, err := db.Exec(ctx, "DELETE FROM users WHERE email = $1", testEmail)
+		if err != nil {
+			t.Logf("Failed to cleanup test user: %v", err)
+		}
+	}()
+	admin := signInStaff(t, r, db, "test_users_admin@example.com", "admin")
+
+	send := func(method, path, token string, payload interface{}, out interface{}) int {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if out != nil && w.Code < 300 {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+		}
+		return w.Code
+	}
+	login := func(password string) int {
+		return send("POST", "/api/auth/login", "", map[string]string{"email": testEmail, "password": password}, nil)
+	}
+
+	var session models.AuthResponse
+	if code := send("POST", "/api/auth/register", "", map[string]string{"email": testEmail, "password": "password123"}, &session); code != http.StatusCreated {
+		t.Fatalf("Failed to setup test user. Status: %d", code)
+	}
+	users := "/api/admin/users/" + strconv.Itoa(session.User.ID)
+
+	// A disabled user is signed out and cannot sign back in
+	if code := send("POST", users+"/disable", admin, nil, nil); code != http.StatusOK {
+		t.Fatalf("Expected status 200 disabling the user, got %d", code)
+	}
+	if code := send("GET", "/api/me", session.Token, nil, nil); code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for the disabled user's token, got %d", code)
+	}
+	if code := send("POST", "/api/auth/refresh", "", map[string]string{"refresh_token": session.RefreshToken}, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected the refresh token to be revoked, got %d", code)
+	}
+	if code := login("password123"); code != http.StatusForbidden {
+		t.Errorf("Expected status 403 signing in while disabled, got %d", code)
+	}
+
+	if code := send("POST", users+"/enable", admin, nil, nil); code != http.StatusOK {
+		t.Fatalf("Expected status 200 enabling the user, got %d", code)
+	}
+	if code := login("password123"); code != http.StatusOK {
+		t.Errorf("Expected status 200 signing in once enabled, got %d", code)
+	}
+	// Their old sessions stay signed out
+	if code := send("GET", "/api/me", session.Token, nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for the revoked token, got %d", code)
+	}
+
+	// After a forced reset the old password is refused until the user
+	// chooses a new one
+	if code := send("POST", users+"/force-password-reset", admin, nil, nil); code != http.StatusOK {
+		t.Fatalf("Expected status 200 forcing a password reset, got %d", code)
+	}
+	if code := login("password123"); code != http.StatusForbidden {
+		t.Errorf("Expected status 403 signing in before the reset, got %d", code)
+	}
+	emailed, _ := utils.GenerateOpaqueToken()
+	_, err := db.Exec(ctx, `
+		UPDATE password_reset_tokens SET token_hash = $1
+		WHERE user_id = $2 AND used_at IS NULL AND expires_at > NOW()`,
+		utils.HashToken(emailed), session.User.ID,
+	)
+	if err != nil {
+		t.Fatalf("Failed to set reset token: %v", err)
+	}
+	if code := send("POST", "/api/auth/reset-password", "", map[string]string{"token": emailed, "new_password": "newpassword123"}, nil); code != http.StatusOK {
+		t.Fatalf("Expected status 200 resetting the password, got %d", code)
+	}
+	if code := login("newpassword123"); code != http.StatusOK {
+		t.Errorf("Expected status 200 signing in after the reset, got %d", code)
+	}
+
+	// Admins without roles:write cannot manage super admins or make anyone one
+	signInStaff(t, r, db, "test_managed_super_admin@example.com", "super_admin")
+	var superAdminID int
+	if err := db.QueryRow(ctx, "SELECT id FROM users WHERE email = 'test_managed_super_admin@example.com'").Scan(&superAdminID); err != nil {
+		t.Fatalf("Failed to find the super admin: %v", err)
+	}
+	superAdminPath := "/api/admin/users/" + strconv.Itoa(superAdminID)
+	for _, tt := range []struct {
+		name    string
+		method  string
+		path    string
+		payload interface{}
+	}{
+		{"disabling a super admin", "POST", superAdminPath + "/disable", nil},
+		{"enabling a super admin", "POST", superAdminPath + "/enable", nil},
+		{"forcing a super admin's password reset", "POST", superAdminPath + "/force-password-reset", nil},
+		{"changing a super admin's role", "PUT", superAdminPath + "/role", map[string]string{"role": "customer"}},
+		{"making a user a super admin", "PUT", users + "/role", map[string]string{"role": "super_admin"}},
+		{"creating a super admin", "POST", "/api/admin/users", map[string]string{"email": "test_new_super_admin@example.com", "role": "super_admin"}},
+	} {
+		if code := send(tt.method, tt.path, admin, tt.payload, nil); code != http.StatusForbidden {
+			t.Errorf("%s: expected status 403, got %d", tt.name, code)
+		}
+	}
+	var role string
+	db.QueryRow(ctx, "SELECT role FROM users WHERE id = $1", superAdminID).Scan(&role)
+	if role != "super_admin" {
+		t.Errorf("Expected the super admin to keep their role, got %s", role)
+	}
+}